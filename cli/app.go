@@ -76,6 +76,7 @@ type appServices interface {
 	repositoryConfigFileName() string
 	getProgress() *cliProgress
 
+	stdin() io.Reader
 	stdout() io.Writer
 	stderr() io.Writer
 }
@@ -115,6 +116,7 @@ type App struct {
 	persistCredentials            bool
 	disableInternalLog            bool
 	AdvancedCommands              string
+	timeout                       time.Duration
 
 	currentAction string
 
@@ -140,15 +142,23 @@ type App struct {
 
 	// testability hooks
 	osExit       func(int) // allows replacing os.Exit() with custom code
+	stdinReader  io.Reader
 	stdoutWriter io.Writer
 	stderrWriter io.Writer
 	rootctx      context.Context
+
+	// TimeNowFunc overrides the time provider of repositories opened by the CLI, nil means wall clock.
+	TimeNowFunc func() time.Time
 }
 
 func (c *App) getProgress() *cliProgress {
 	return c.progress
 }
 
+func (c *App) stdin() io.Reader {
+	return c.stdinReader
+}
+
 func (c *App) stdout() io.Writer {
 	return c.stdoutWriter
 }
@@ -203,6 +213,7 @@ func (c *App) setup(app *kingpin.Application) {
 	app.Flag("persist-credentials", "Persist credentials").Default("true").Envar("KOPIA_PERSIST_CREDENTIALS_ON_CONNECT").BoolVar(&c.persistCredentials)
 	app.Flag("disable-internal-log", "Disable internal log").Hidden().Envar("KOPIA_DISABLE_INTERNAL_LOG").BoolVar(&c.disableInternalLog)
 	app.Flag("advanced-commands", "Enable advanced (and potentially dangerous) commands.").Hidden().Envar("KOPIA_ADVANCED_COMMANDS").StringVar(&c.AdvancedCommands)
+	app.Flag("timeout", "Cancel the command if it does not complete within the specified time (0 means no timeout)").Envar("KOPIA_TIMEOUT").DurationVar(&c.timeout)
 
 	c.setupOSSpecificKeychainFlags(app)
 
@@ -250,6 +261,7 @@ func NewApp() *App {
 
 		// testability hooks
 		osExit:       os.Exit,
+		stdinReader:  os.Stdin,
 		stdoutWriter: os.Stdout,
 		stderrWriter: os.Stderr,
 		rootctx:      context.Background(),
@@ -290,7 +302,10 @@ func (c *App) currentActionName() string {
 
 func (c *App) noRepositoryAction(act func(ctx context.Context) error) func(ctx *kingpin.ParseContext) error {
 	return func(_ *kingpin.ParseContext) error {
-		return act(c.rootContext())
+		ctx, cancel := c.commandContext()
+		defer cancel()
+
+		return c.translateTimeoutError(ctx, act(ctx))
 	}
 }
 
@@ -306,7 +321,10 @@ func (c *App) serverAction(sf *serverClientFlags, act func(ctx context.Context,
 			return errors.Wrap(err, "unable to create API client")
 		}
 
-		return act(c.rootContext(), apiClient)
+		ctx, cancel := c.commandContext()
+		defer cancel()
+
+		return c.translateTimeoutError(ctx, act(ctx, apiClient))
 	}
 }
 
@@ -376,6 +394,24 @@ func (c *App) rootContext() context.Context {
 	return c.rootctx
 }
 
+// commandContext returns the context in which the command runs, which gets canceled when the --timeout elapses.
+func (c *App) commandContext() (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(c.rootContext())
+	}
+
+	return context.WithTimeout(c.rootContext(), c.timeout)
+}
+
+// translateTimeoutError makes errors caused by the command exceeding its --timeout explicit.
+func (c *App) translateTimeoutError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.Wrapf(err, "command timed out after %v", c.timeout)
+	}
+
+	return err
+}
+
 type repositoryAccessMode struct {
 	mustBeConnected    bool
 	disableMaintenance bool
@@ -383,7 +419,8 @@ type repositoryAccessMode struct {
 
 func (c *App) maybeRepositoryAction(act func(ctx context.Context, rep repo.Repository) error, mode repositoryAccessMode) func(ctx *kingpin.ParseContext) error {
 	return func(kpc *kingpin.ParseContext) error {
-		ctx := c.rootContext()
+		ctx, cancel := c.commandContext()
+		defer cancel()
 
 		if err := c.pf.withProfiling(func() error {
 			c.mt.startMemoryTracking(ctx)
@@ -404,16 +441,18 @@ func (c *App) maybeRepositoryAction(act func(ctx context.Context, rep repo.Repos
 				return errors.Wrap(err, "open repository")
 			}
 
-			err = act(ctx, rep)
+			err = c.translateTimeoutError(ctx, act(ctx, rep))
 
-			if rep != nil && !mode.disableMaintenance {
+			// do not start maintenance after the command has been canceled.
+			if rep != nil && !mode.disableMaintenance && ctx.Err() == nil {
 				if merr := c.maybeRunMaintenance(ctx, rep); merr != nil {
 					log(ctx).Errorf("error running maintenance: %v", merr)
 				}
 			}
 
 			if rep != nil && mode.mustBeConnected {
-				if cerr := rep.Close(ctx); cerr != nil {
+				// close using root context so that the repository is closed cleanly even after timeout.
+				if cerr := rep.Close(c.rootContext()); cerr != nil {
 					return errors.Wrap(cerr, "unable to close repository")
 				}
 			}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo"
+)
+
+// longOperation simulates a command that runs until its context is canceled.
+func longOperation(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "long operation canceled")
+	case <-time.After(time.Minute):
+		return nil
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	c := NewApp()
+	c.rootctx = testlogging.Context(t)
+	c.configPath = filepath.Join(t.TempDir(), "nonexistent.config")
+	c.timeout = 100 * time.Millisecond
+
+	var exitCode int
+
+	c.osExit = func(code int) {
+		exitCode = code
+	}
+
+	var actErr error
+
+	t0 := clock.Now()
+
+	require.NoError(t, c.maybeRepositoryAction(func(ctx context.Context, rep repo.Repository) error {
+		actErr = longOperation(ctx)
+		return actErr
+	}, repositoryAccessMode{})(nil))
+
+	require.Less(t, clock.Since(t0), 10*time.Second, "command was not canceled promptly")
+	require.ErrorIs(t, actErr, context.DeadlineExceeded)
+	require.Equal(t, 1, exitCode)
+
+	err := c.noRepositoryAction(longOperation)(nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "command timed out after 100ms")
+}
+
+func TestCommandWithoutTimeout(t *testing.T) {
+	c := NewApp()
+	c.rootctx = testlogging.Context(t)
+
+	require.NoError(t, c.noRepositoryAction(func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		require.False(t, hasDeadline)
+
+		return nil
+	})(nil))
+}
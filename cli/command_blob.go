@@ -1,17 +1,19 @@
 package cli
 
 type commandBlob struct {
-	delete commandBlobDelete
-	gc     commandBlobGC
-	list   commandBlobList
-	show   commandBlobShow
-	stats  commandBlobStats
+	delete  commandBlobDelete
+	explain commandBlobExplain
+	gc      commandBlobGC
+	list    commandBlobList
+	show    commandBlobShow
+	stats   commandBlobStats
 }
 
 func (c *commandBlob) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("blob", "Commands to manipulate BLOBs.").Hidden()
 
 	c.delete.setup(svc, cmd)
+	c.explain.setup(svc, cmd)
 	c.gc.setup(svc, cmd)
 	c.list.setup(svc, cmd)
 	c.show.setup(svc, cmd)
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+type commandBlobExplain struct {
+	blobID   string
+	contents bool
+	safety   maintenance.SafetyParameters
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandBlobExplain) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("explain", "Explain why a blob has not been deleted by maintenance").Alias("why")
+	cmd.Arg("blobID", "Blob ID").Required().StringVar(&c.blobID)
+	cmd.Flag("contents", "List individual contents stored in the blob").BoolVar(&c.contents)
+	safetyFlagVar(cmd, &c.safety)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+}
+
+func (c *commandBlobExplain) run(ctx context.Context, rep repo.DirectRepository) error {
+	r, err := maintenance.ExplainBlobRetention(ctx, rep, blob.ID(c.blobID), c.safety)
+	if err != nil {
+		return errors.Wrap(err, "unable to explain blob retention")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(r))
+		return nil
+	}
+
+	c.out.printStdout("Blob:              %v (%v)\n", r.BlobID, units.BytesStringBase10(r.Length))
+	c.out.printStdout("Written:           %v (%v ago)\n", formatTimestamp(r.Timestamp), r.Age.Truncate(time.Second))
+	c.out.printStdout("Live contents:     %v\n", r.LiveContentCount)
+	c.out.printStdout("Deleted contents:  %v\n", r.DeletedContentCount)
+
+	if r.EarliestDeletion.IsZero() {
+		c.out.printStdout("Earliest deletion: unknown\n")
+	} else {
+		c.out.printStdout("Earliest deletion: %v\n", formatTimestamp(r.EarliestDeletion))
+	}
+
+	c.out.printStdout("Reasons:\n")
+
+	for _, reason := range r.Reasons {
+		c.out.printStdout("  - %v\n", reason)
+	}
+
+	if c.contents {
+		c.out.printStdout("Contents:\n")
+
+		for _, rc := range r.Contents {
+			status := "live"
+			if rc.Deleted {
+				status = "deleted, can be dropped after " + formatTimestamp(rc.DropTime)
+			} else if rc.SubjectToGC {
+				status = "live, subject to GC"
+			}
+
+			c.out.printStdout("  %v %v ago (%v)\n", rc.ContentID, rc.Age.Truncate(time.Second), status)
+		}
+	}
+
+	return nil
+}
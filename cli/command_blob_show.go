@@ -4,7 +4,9 @@
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -16,6 +18,9 @@
 type commandBlobShow struct {
 	blobShowDecrypt bool
 	blobShowIDs     []string
+	blobShowOffset  int64
+	blobShowLength  int64
+	blobShowHex     bool
 
 	out textOutput
 }
@@ -23,6 +28,9 @@ type commandBlobShow struct {
 func (c *commandBlobShow) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("show", "Show contents of BLOBs").Alias("cat")
 	cmd.Flag("decrypt", "Decrypt blob if possible").BoolVar(&c.blobShowDecrypt)
+	cmd.Flag("offset", "Offset of the first byte to show").Int64Var(&c.blobShowOffset)
+	cmd.Flag("length", "Number of bytes to show (-1 means until the end)").Default("-1").Int64Var(&c.blobShowLength)
+	cmd.Flag("hex", "Show hex dump instead of raw bytes").BoolVar(&c.blobShowHex)
 	cmd.Arg("blobID", "Blob IDs").Required().StringsVar(&c.blobShowIDs)
 	cmd.Action(svc.directRepositoryReadAction(c.run))
 
@@ -45,19 +53,31 @@ func (c *commandBlobShow) maybeDecryptBlob(ctx context.Context, w io.Writer, rep
 		err error
 	)
 
-	d, err = rep.BlobReader().GetBlob(ctx, blobID, 0, -1)
+	decrypt := c.blobShowDecrypt && canDecryptBlob(blobID)
 
-	if c.blobShowDecrypt && canDecryptBlob(blobID) {
-		d, err = rep.Crypter().DecryptBLOB(d, blobID)
+	if !decrypt && c.blobShowLength >= 0 {
+		// fetch just the requested range from the storage.
+		d, err = rep.BlobReader().GetBlob(ctx, blobID, c.blobShowOffset, c.blobShowLength)
+	} else {
+		d, err = rep.BlobReader().GetBlob(ctx, blobID, 0, -1)
 
-		if isJSONBlob(blobID) && err == nil {
-			var b bytes.Buffer
+		if decrypt && err == nil {
+			d, err = rep.Crypter().DecryptBLOB(d, blobID)
 
-			if err = json.Indent(&b, d, "", "  "); err != nil {
-				return errors.Wrap(err, "invalid JSON")
+			if isJSONBlob(blobID) && err == nil {
+				var b bytes.Buffer
+
+				if err = json.Indent(&b, d, "", "  "); err != nil {
+					return errors.Wrap(err, "invalid JSON")
+				}
+
+				d = b.Bytes()
 			}
+		}
 
-			d = b.Bytes()
+		// the range is applied to the decrypted data.
+		if err == nil {
+			d, err = sliceRange(d, c.blobShowOffset, c.blobShowLength)
 		}
 	}
 
@@ -65,6 +85,10 @@ func (c *commandBlobShow) maybeDecryptBlob(ctx context.Context, w io.Writer, rep
 		return errors.Wrapf(err, "error getting %v", blobID)
 	}
 
+	if c.blobShowHex {
+		return writeHexDump(w, d, c.blobShowOffset)
+	}
+
 	if _, err := iocopy.Copy(w, bytes.NewReader(d)); err != nil {
 		return errors.Wrap(err, "error copying data")
 	}
@@ -72,6 +96,73 @@ func (c *commandBlobShow) maybeDecryptBlob(ctx context.Context, w io.Writer, rep
 	return nil
 }
 
+// sliceRange returns the subset of data at the provided offset and length, using the same
+// semantics as blob.Storage.GetBlob().
+func sliceRange(d []byte, offset, length int64) ([]byte, error) {
+	if offset < 0 || offset > int64(len(d)) || length < -1 {
+		return nil, errors.Wrapf(blob.ErrInvalidRange, "invalid offset %v or length %v", offset, length)
+	}
+
+	if length < 0 {
+		return d[offset:], nil
+	}
+
+	if offset+length > int64(len(d)) {
+		return nil, errors.Wrapf(blob.ErrInvalidRange, "invalid length %v at offset %v, data length is %v", length, offset, len(d))
+	}
+
+	return d[offset : offset+length], nil
+}
+
+// writeHexDump writes the hex and ASCII representation of the data in the format used by 'hexdump -C',
+// with offsets starting at baseOffset.
+func writeHexDump(w io.Writer, d []byte, baseOffset int64) error {
+	const bytesPerLine = 16
+
+	var sb strings.Builder
+
+	for i := 0; i < len(d); i += bytesPerLine {
+		line := d[i:]
+		if len(line) > bytesPerLine {
+			line = line[0:bytesPerLine]
+		}
+
+		fmt.Fprintf(&sb, "%08x ", baseOffset+int64(i))
+
+		for j := 0; j < bytesPerLine; j++ {
+			if j%8 == 0 {
+				sb.WriteByte(' ')
+			}
+
+			if j < len(line) {
+				fmt.Fprintf(&sb, "%02x ", line[j])
+			} else {
+				sb.WriteString("   ")
+			}
+		}
+
+		sb.WriteString(" |")
+
+		for _, b := range line {
+			if b < ' ' || b > '~' {
+				b = '.'
+			}
+
+			sb.WriteByte(b)
+		}
+
+		sb.WriteString("|\n")
+	}
+
+	fmt.Fprintf(&sb, "%08x\n", baseOffset+int64(len(d)))
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return errors.Wrap(err, "error writing hex dump")
+	}
+
+	return nil
+}
+
 func canDecryptBlob(b blob.ID) bool {
 	switch b[0] {
 	case '_', 'n', 'm', 'l':
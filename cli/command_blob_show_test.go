@@ -4,6 +4,8 @@
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -20,3 +22,38 @@ func TestBlobShow(t *testing.T) {
 	// --decrypt will be ignored
 	env.RunAndExpectSuccess(t, "blob", "show", "--decrypt", someQBlob)
 }
+
+func TestBlobShowRangeAndHex(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// format blob is stored unencrypted and starts with known contents.
+	require.Equal(t, []string{
+		`  "tool": "https://github.com/kopia/ko`,
+	}, env.RunAndExpectSuccess(t, "blob", "show", "kopia.repository", "--offset=2", "--length=38"))
+
+	require.Equal(t, []string{
+		`00000002  20 20 22 74 6f 6f 6c 22  3a 20 22 68 74 74 70 73  |  "tool": "https|`,
+		`00000012  3a 2f 2f 67 69 74 68 75  62 2e 63 6f 6d 2f 6b 6f  |://github.com/ko|`,
+		`00000022  70 69 61 2f 6b 6f 70 69                           |pia/kopi|`,
+		`0000002a`,
+	}, env.RunAndExpectSuccess(t, "blob", "show", "kopia.repository", "--offset=2", "--length=40", "--hex"))
+
+	require.Equal(t, []string{
+		`00000000  7b 0a                                             |{.|`,
+		`00000002`,
+	}, env.RunAndExpectSuccess(t, "blob", "show", "kopia.repository", "--length=2", "--hex"))
+
+	// ranges of decrypted blobs are applied after decryption.
+	someNBlob := strings.Split(env.RunAndExpectSuccess(t, "blob", "list", "--prefix=n")[0], " ")[0]
+	fullHex := env.RunAndExpectSuccess(t, "blob", "show", "--decrypt", "--hex", someNBlob)
+	require.Greater(t, len(fullHex), 3)
+
+	rangeHex := env.RunAndExpectSuccess(t, "blob", "show", "--decrypt", "--hex", "--offset=16", "--length=16", someNBlob)
+	require.Equal(t, fullHex[1], rangeHex[0])
+	require.Equal(t, "00000020", rangeHex[1])
+
+	env.RunAndExpectFailure(t, "blob", "show", "--decrypt", "--offset=100000000", someNBlob)
+	env.RunAndExpectFailure(t, "blob", "show", "--offset=100000000", "--length=10", someNBlob)
+}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"sort"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -12,9 +13,11 @@
 )
 
 type commandBlobStats struct {
-	raw    bool
-	prefix string
+	raw           bool
+	prefix        string
+	groupByPrefix bool
 
+	jo  jsonOutput
 	out textOutput
 }
 
@@ -22,46 +25,101 @@ func (c *commandBlobStats) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("stats", "Content statistics")
 	cmd.Flag("raw", "Raw numbers").Short('r').BoolVar(&c.raw)
 	cmd.Flag("prefix", "Blob name prefix").StringVar(&c.prefix)
+	cmd.Flag("group-by-prefix", "Group statistics by single-character blob ID prefix").BoolVar(&c.groupByPrefix)
 	cmd.Action(svc.directRepositoryReadAction(c.run))
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 }
 
-func (c *commandBlobStats) run(ctx context.Context, rep repo.DirectRepository) error {
-	var sizeThreshold int64 = 10
+// blobStatsTotals contains count and size of a group of blobs.
+type blobStatsTotals struct {
+	Count       int64 `json:"count"`
+	TotalSize   int64 `json:"totalSize"`
+	AverageSize int64 `json:"averageSize"`
+}
 
-	countMap := map[int64]int{}
-	totalSizeOfContentsUnder := map[int64]int64{}
+func (t *blobStatsTotals) add(b blob.Metadata) {
+	t.Count++
+	t.TotalSize += b.Length
+	t.AverageSize = t.TotalSize / t.Count
+}
+
+// blobStatsBucket is a single histogram bucket of blobs with sizes in [MinSize, MaxSize).
+type blobStatsBucket struct {
+	MinSize   int64 `json:"minSize"`
+	MaxSize   int64 `json:"maxSize"`
+	Count     int64 `json:"count"`
+	TotalSize int64 `json:"totalSize"`
+}
 
-	var sizeThresholds []int64
+type blobStatsResult struct {
+	blobStatsTotals
+
+	Histogram []*blobStatsBucket          `json:"histogram"`
+	ByPrefix  map[string]*blobStatsTotals `json:"byPrefix,omitempty"`
+}
+
+func (c *commandBlobStats) run(ctx context.Context, rep repo.DirectRepository) error {
+	var (
+		sizeThreshold int64 = 10
+		lastThreshold int64
+		result        blobStatsResult
+	)
 
 	for i := 0; i < 8; i++ {
-		sizeThresholds = append(sizeThresholds, sizeThreshold)
-		countMap[sizeThreshold] = 0
+		result.Histogram = append(result.Histogram, &blobStatsBucket{MinSize: lastThreshold, MaxSize: sizeThreshold})
+		lastThreshold = sizeThreshold
 		sizeThreshold *= 10
 	}
 
-	var totalSize, count int64
+	if c.groupByPrefix {
+		result.ByPrefix = map[string]*blobStatsTotals{}
+	}
 
 	if err := rep.BlobReader().ListBlobs(
 		ctx,
 		blob.ID(c.prefix),
 		func(b blob.Metadata) error {
-			totalSize += b.Length
-			count++
-			if count%10000 == 0 {
-				log(ctx).Infof("Got %v blobs...", count)
+			result.add(b)
+			if result.Count%10000 == 0 {
+				log(ctx).Infof("Got %v blobs...", result.Count)
+			}
+
+			for _, bucket := range result.Histogram {
+				if b.Length >= bucket.MinSize && b.Length < bucket.MaxSize {
+					bucket.Count++
+					bucket.TotalSize += b.Length
+				}
 			}
-			for s := range countMap {
-				if b.Length < s {
-					countMap[s]++
-					totalSizeOfContentsUnder[s] += b.Length
+
+			if result.ByPrefix != nil {
+				p := string(b.BlobID[0:1])
+
+				t := result.ByPrefix[p]
+				if t == nil {
+					t = &blobStatsTotals{}
+					result.ByPrefix[p] = t
 				}
+
+				t.add(b)
 			}
+
 			return nil
 		}); err != nil {
 		return errors.Wrap(err, "error listing blobs")
 	}
 
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(result))
+		return nil
+	}
+
+	c.printStats(&result)
+
+	return nil
+}
+
+func (c *commandBlobStats) printStats(result *blobStatsResult) {
 	sizeToString := units.BytesStringBase10
 	if c.raw {
 		sizeToString = func(l int64) string {
@@ -70,29 +128,41 @@ func(b blob.Metadata) error {
 		}
 	}
 
-	c.out.printStdout("Count: %v\n", count)
-	c.out.printStdout("Total: %v\n", sizeToString(totalSize))
+	c.out.printStdout("Count: %v\n", result.Count)
+	c.out.printStdout("Total: %v\n", sizeToString(result.TotalSize))
 
-	if count == 0 {
-		return nil
+	if result.Count == 0 {
+		return
 	}
 
-	c.out.printStdout("Average: %v\n", sizeToString(totalSize/count))
+	c.out.printStdout("Average: %v\n", sizeToString(result.AverageSize))
 
 	c.out.printStdout("Histogram:\n\n")
 
-	var lastSize int64
-
-	for _, size := range sizeThresholds {
+	for _, bucket := range result.Histogram {
 		c.out.printStdout("%9v between %v and %v (total %v)\n",
-			countMap[size]-countMap[lastSize],
-			sizeToString(lastSize),
-			sizeToString(size),
-			sizeToString(totalSizeOfContentsUnder[size]-totalSizeOfContentsUnder[lastSize]),
+			bucket.Count,
+			sizeToString(bucket.MinSize),
+			sizeToString(bucket.MaxSize),
+			sizeToString(bucket.TotalSize),
 		)
+	}
 
-		lastSize = size
+	if result.ByPrefix == nil {
+		return
 	}
 
-	return nil
+	c.out.printStdout("\nBy Prefix:\n\n")
+
+	var prefixes []string
+	for p := range result.ByPrefix {
+		prefixes = append(prefixes, p)
+	}
+
+	sort.Strings(prefixes)
+
+	for _, p := range prefixes {
+		t := result.ByPrefix[p]
+		c.out.printStdout("  %v count: %v total: %v average: %v\n", p, t.Count, sizeToString(t.TotalSize), sizeToString(t.AverageSize))
+	}
 }
@@ -0,0 +1,88 @@
+package cli_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+type blobStatsTotals struct {
+	Count       int64 `json:"count"`
+	TotalSize   int64 `json:"totalSize"`
+	AverageSize int64 `json:"averageSize"`
+}
+
+func TestBlobStats(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+
+	// compute expected totals from the blob list.
+	want := map[string]*blobStatsTotals{}
+
+	var blobs []blob.Metadata
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "blob", "list", "--json"), &blobs)
+
+	for _, b := range blobs {
+		p := string(b.BlobID[0:1])
+		if want[p] == nil {
+			want[p] = &blobStatsTotals{}
+		}
+
+		want[p].Count++
+		want[p].TotalSize += b.Length
+		want[p].AverageSize = want[p].TotalSize / want[p].Count
+	}
+
+	require.Contains(t, want, "n")
+	require.Contains(t, want, "q")
+
+	var result struct {
+		blobStatsTotals
+
+		Histogram []struct {
+			MinSize   int64 `json:"minSize"`
+			MaxSize   int64 `json:"maxSize"`
+			Count     int64 `json:"count"`
+			TotalSize int64 `json:"totalSize"`
+		} `json:"histogram"`
+		ByPrefix map[string]*blobStatsTotals `json:"byPrefix"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "blob", "stats", "--group-by-prefix", "--json"), &result)
+	require.Equal(t, want, result.ByPrefix)
+	require.Len(t, result.Histogram, 8)
+
+	var histogramCount, prefixCount int64
+
+	for _, b := range result.Histogram {
+		histogramCount += b.Count
+	}
+
+	for _, p := range result.ByPrefix {
+		prefixCount += p.Count
+	}
+
+	require.Equal(t, result.Count, prefixCount)
+	require.Equal(t, result.Count, histogramCount)
+
+	// --prefix composes with grouping.
+	result.ByPrefix = nil
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "blob", "stats", "--group-by-prefix", "--json", "--prefix=n"), &result)
+	require.Equal(t, map[string]*blobStatsTotals{"n": want["n"]}, result.ByPrefix)
+
+	// without --json, grouped text output lists all prefixes.
+	lines := env.RunAndExpectSuccess(t, "blob", "stats", "--group-by-prefix", "--raw")
+	require.Contains(t, lines, "By Prefix:")
+
+	for p, w := range want {
+		require.Contains(t, lines, fmt.Sprintf("  %v count: %v total: %v average: %v", p, w.Count, w.TotalSize, w.AverageSize))
+	}
+}
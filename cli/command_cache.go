@@ -3,6 +3,7 @@
 type commandCache struct {
 	clear commandCacheClear
 	info  commandCacheInfo
+	opts  commandCacheOptions
 	set   commandCacheSetParams
 	sync  commandCacheSync
 }
@@ -12,6 +13,7 @@ func (c *commandCache) setup(svc appServices, parent commandParent) {
 
 	c.clear.setup(svc, cmd)
 	c.info.setup(svc, cmd)
+	c.opts.setup(svc, cmd)
 	c.set.setup(svc, cmd)
 	c.sync.setup(svc, cmd)
 }
@@ -10,12 +10,14 @@
 
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
 )
 
 type commandCacheInfo struct {
 	onlyShowPath bool
 
 	svc appServices
+	jo  jsonOutput
 	out textOutput
 }
 
@@ -25,6 +27,7 @@ func (c *commandCacheInfo) setup(svc appServices, parent commandParent) {
 	cmd.Action(svc.repositoryReaderAction(c.run))
 
 	c.svc = svc
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 }
 
@@ -39,9 +42,54 @@ func (c *commandCacheInfo) run(ctx context.Context, rep repo.Repository) error {
 		return nil
 	}
 
+	subdirs, err := getCacheSubdirInfos(opts)
+	if err != nil {
+		return err
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(subdirs))
+		return nil
+	}
+
+	for _, sd := range subdirs {
+		maybeLimit := ""
+		if sd.Limit != nil {
+			maybeLimit = fmt.Sprintf(" (limit %v)", units.BytesStringBase10(*sd.Limit))
+		}
+
+		if sd.Name == "blob-list" {
+			maybeLimit = fmt.Sprintf(" (duration %vs)", opts.MaxListCacheDurationSec)
+		}
+
+		maybeOverLimit := ""
+		if sd.OverLimit {
+			maybeOverLimit = " !! EXCEEDS LIMIT"
+		}
+
+		c.out.printStdout("%v: %v files %v%v%v\n", sd.Path, sd.FileCount, units.BytesStringBase10(sd.TotalSize), maybeLimit, maybeOverLimit)
+	}
+
+	c.out.printStderr("To adjust cache sizes use 'kopia cache set'.\n")
+	c.out.printStderr("To clear caches use 'kopia cache clear'.\n")
+
+	return nil
+}
+
+// cacheSubdirInfo describes usage of a single cache subdirectory.
+type cacheSubdirInfo struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	FileCount int    `json:"fileCount"`
+	TotalSize int64  `json:"totalSize"`
+	Limit     *int64 `json:"limit,omitempty"`
+	OverLimit bool   `json:"overLimit,omitempty"`
+}
+
+func getCacheSubdirInfos(opts *content.CachingOptions) ([]cacheSubdirInfo, error) {
 	entries, err := ioutil.ReadDir(opts.CacheDirectory)
 	if err != nil {
-		return errors.Wrap(err, "unable to scan cache directory")
+		return nil, errors.Wrap(err, "unable to scan cache directory")
 	}
 
 	path2Limit := map[string]int64{
@@ -50,6 +98,8 @@ func (c *commandCacheInfo) run(ctx context.Context, rep repo.Repository) error {
 		"server-contents": opts.MaxCacheSizeBytes,
 	}
 
+	result := []cacheSubdirInfo{}
+
 	for _, ent := range entries {
 		if !ent.IsDir() {
 			continue
@@ -59,23 +109,23 @@ func (c *commandCacheInfo) run(ctx context.Context, rep repo.Repository) error {
 
 		fileCount, totalFileSize, err := scanCacheDir(subdir)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		maybeLimit := ""
-		if l, ok := path2Limit[ent.Name()]; ok {
-			maybeLimit = fmt.Sprintf(" (limit %v)", units.BytesStringBase10(l))
+		sd := cacheSubdirInfo{
+			Name:      ent.Name(),
+			Path:      subdir,
+			FileCount: fileCount,
+			TotalSize: totalFileSize,
 		}
 
-		if ent.Name() == "blob-list" {
-			maybeLimit = fmt.Sprintf(" (duration %vs)", opts.MaxListCacheDurationSec)
+		if l, ok := path2Limit[ent.Name()]; ok {
+			sd.Limit = &l
+			sd.OverLimit = totalFileSize > l
 		}
 
-		c.out.printStdout("%v: %v files %v%v\n", subdir, fileCount, units.BytesStringBase10(totalFileSize), maybeLimit)
+		result = append(result, sd)
 	}
 
-	c.out.printStderr("To adjust cache sizes use 'kopia cache set'.\n")
-	c.out.printStderr("To clear caches use 'kopia cache clear'.\n")
-
-	return nil
+	return result, nil
 }
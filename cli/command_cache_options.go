@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandCacheOptions struct {
+	svc appServices
+	out textOutput
+	jo  jsonOutput
+}
+
+func (c *commandCacheOptions) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("options", "Displays effective caching options for the current connection")
+	cmd.Action(svc.repositoryReaderAction(c.run))
+
+	c.svc = svc
+	c.out.setup(svc)
+	c.jo.setup(svc, cmd)
+}
+
+func (c *commandCacheOptions) run(ctx context.Context, rep repo.Repository) error {
+	opts, err := repo.GetCachingOptions(ctx, c.svc.repositoryConfigFileName())
+	if err != nil {
+		return errors.Wrap(err, "error getting cache options")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(opts))
+		return nil
+	}
+
+	c.out.printStdout("Cache directory:       %v\n", opts.CacheDirectory)
+	c.out.printStdout("Content cache size:    %v\n", units.BytesStringBase10(opts.MaxCacheSizeBytes))
+	c.out.printStdout("Metadata cache size:   %v\n", units.BytesStringBase10(opts.MaxMetadataCacheSizeBytes))
+	c.out.printStdout("List cache duration:   %vs\n", opts.MaxListCacheDurationSec)
+
+	return nil
+}
@@ -1,12 +1,16 @@
 package cli_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -47,3 +51,84 @@ func mustGetLineContaining(t *testing.T, lines []string, containing string) stri
 
 	return ""
 }
+
+func TestCacheOptions(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	ncd := testutil.TempDirectory(t)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir,
+		"--cache-directory", ncd,
+		"--content-cache-size-mb=33",
+		"--metadata-cache-size-mb=44",
+		"--max-list-cache-duration=55s",
+	)
+
+	var opts content.CachingOptions
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "cache", "options", "--json"), &opts)
+
+	require.Equal(t, content.CachingOptions{
+		CacheDirectory:            ncd,
+		MaxCacheSizeBytes:         33 << 20,
+		MaxMetadataCacheSizeBytes: 44 << 20,
+		MaxListCacheDurationSec:   55,
+	}, opts)
+
+	out := env.RunAndExpectSuccess(t, "cache", "options")
+	require.Contains(t, mustGetLineContaining(t, out, "Cache directory"), ncd)
+	require.Contains(t, mustGetLineContaining(t, out, "Content cache size"), "34.6 MB")
+	require.Contains(t, mustGetLineContaining(t, out, "Metadata cache size"), "46.1 MB")
+	require.Contains(t, mustGetLineContaining(t, out, "List cache duration"), "55s")
+}
+
+func TestCacheInfoJSONAndOverLimit(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	ncd := testutil.TempDirectory(t)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir,
+		"--cache-directory", ncd,
+		"--content-cache-size-mb=1",
+		"--metadata-cache-size-mb=44",
+	)
+
+	// simulate content cache that has grown beyond its limit.
+	fakeDir := filepath.Join(ncd, "contents", "fake")
+	require.NoError(t, os.MkdirAll(fakeDir, 0o700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(fakeDir, "f1"), make([]byte, 2<<20), 0o600))
+
+	var infos []struct {
+		Name      string `json:"name"`
+		Path      string `json:"path"`
+		FileCount int    `json:"fileCount"`
+		TotalSize int64  `json:"totalSize"`
+		Limit     *int64 `json:"limit"`
+		OverLimit bool   `json:"overLimit"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "cache", "info", "--json"), &infos)
+
+	byName := map[string]int{}
+	for i, inf := range infos {
+		byName[inf.Name] = i
+	}
+
+	require.Contains(t, byName, "contents")
+	require.Contains(t, byName, "metadata")
+
+	contents := infos[byName["contents"]]
+	require.Equal(t, filepath.Join(ncd, "contents"), contents.Path)
+	require.GreaterOrEqual(t, contents.FileCount, 1)
+	require.GreaterOrEqual(t, contents.TotalSize, int64(2<<20))
+	require.Equal(t, int64(1<<20), *contents.Limit)
+	require.True(t, contents.OverLimit)
+
+	metadata := infos[byName["metadata"]]
+	require.Equal(t, int64(44<<20), *metadata.Limit)
+	require.False(t, metadata.OverLimit)
+
+	out := env.RunAndExpectSuccess(t, "cache", "info")
+	require.Contains(t, mustGetLineContaining(t, out, "contents"), "EXCEEDS LIMIT")
+	require.NotContains(t, mustGetLineContaining(t, out, "metadata"), "EXCEEDS LIMIT")
+}
@@ -2,7 +2,9 @@
 
 import (
 	"context"
+	"time"
 
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/content"
@@ -16,10 +18,12 @@ type commandContentRewrite struct {
 	contentRewriteFormatVersion int
 	contentRewritePackPrefix    string
 	contentRewriteDryRun        bool
+	contentRewriteMinAge        time.Duration
 	contentRewriteSafety        maintenance.SafetyParameters
 
 	contentRange contentRangeFlags
 	svc          appServices
+	out          textOutput
 }
 
 func (c *commandContentRewrite) setup(svc appServices, parent commandParent) {
@@ -30,19 +34,20 @@ func (c *commandContentRewrite) setup(svc appServices, parent commandParent) {
 	cmd.Flag("short", "Rewrite contents from short packs").BoolVar(&c.contentRewriteShortPacks)
 	cmd.Flag("format-version", "Rewrite contents using the provided format version").Default("-1").IntVar(&c.contentRewriteFormatVersion)
 	cmd.Flag("pack-prefix", "Only rewrite contents from pack blobs with a given prefix").StringVar(&c.contentRewritePackPrefix)
+	cmd.Flag("min-age", "Only rewrite contents older than the provided age").DurationVar(&c.contentRewriteMinAge)
 	cmd.Flag("dry-run", "Do not actually rewrite, only print what would happen").Short('n').BoolVar(&c.contentRewriteDryRun)
 	c.contentRange.setup(cmd)
 	safetyFlagVar(cmd, &c.contentRewriteSafety)
 	cmd.Action(svc.directRepositoryWriteAction(c.runContentRewriteCommand))
 
 	c.svc = svc
+	c.out.setup(svc)
 }
 
 func (c *commandContentRewrite) runContentRewriteCommand(ctx context.Context, rep repo.DirectRepositoryWriter) error {
 	c.svc.advancedCommand(ctx)
 
-	// nolint:wrapcheck
-	return maintenance.RewriteContents(ctx, rep, &maintenance.RewriteContentsOptions{
+	st, err := maintenance.RewriteContents(ctx, rep, &maintenance.RewriteContentsOptions{
 		ContentIDRange: c.contentRange.contentIDRange(),
 		ContentIDs:     toContentIDs(c.contentRewriteIDs),
 		FormatVersion:  c.contentRewriteFormatVersion,
@@ -50,7 +55,28 @@ func (c *commandContentRewrite) runContentRewriteCommand(ctx context.Context, re
 		Parallel:       c.contentRewriteParallelism,
 		ShortPacks:     c.contentRewriteShortPacks,
 		DryRun:         c.contentRewriteDryRun,
+		MinAge:         c.contentRewriteMinAge,
+
+		CountEmptiedPacks: true,
 	}, c.contentRewriteSafety)
+
+	verb := "Rewrote"
+	if c.contentRewriteDryRun {
+		verb = "Would rewrite"
+
+		for _, ci := range st.Contents {
+			c.out.printStdout("content %v (%v bytes) from pack %v\n", ci.ContentID, ci.PackedLength, ci.PackBlobID)
+		}
+
+		for _, packID := range st.EmptiedPacks {
+			c.out.printStdout("emptied pack %v\n", packID)
+		}
+	}
+
+	log(ctx).Infof("%v %v contents (%v), emptying %v packs.", verb, st.RewrittenContentCount, units.BytesStringBase10(st.RewrittenBytes), st.EmptiedPackCount)
+
+	// nolint:wrapcheck
+	return err
 }
 
 func toContentIDs(s []string) []content.ID {
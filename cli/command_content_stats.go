@@ -2,6 +2,9 @@
 
 import (
 	"context"
+	"math"
+	"math/rand"
+	"sort"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -12,8 +15,13 @@
 	"github.com/kopia/kopia/repo/content"
 )
 
+// maxContentLengthSamples is the maximum number of content lengths kept in memory to compute percentiles.
+const maxContentLengthSamples = 100000
+
 type commandContentStats struct {
 	raw          bool
+	percentiles  bool
+	byPrefix     bool
 	contentRange contentRangeFlags
 	out          textOutput
 }
@@ -21,6 +29,8 @@ type commandContentStats struct {
 func (c *commandContentStats) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("stats", "Content statistics")
 	cmd.Flag("raw", "Raw numbers").Short('r').BoolVar(&c.raw)
+	cmd.Flag("percentiles", "Show percentiles of packed content lengths").BoolVar(&c.percentiles)
+	cmd.Flag("by-prefix", "Show statistics grouped by content ID prefix").BoolVar(&c.byPrefix)
 	c.contentRange.setup(cmd)
 	c.out.setup(svc)
 	cmd.Action(svc.directRepositoryReadAction(c.run))
@@ -30,6 +40,90 @@ type contentStatsTotals struct {
 	originalSize, packedSize, count int64
 }
 
+// contentLengthSampler keeps a uniform random sample of bounded size of content lengths
+// (reservoir sampling) which is used to compute approximate percentiles. Percentiles are exact
+// as long as the number of contents does not exceed the maximum number of samples.
+type contentLengthSampler struct {
+	maxSamples int
+	samples    []uint32
+	seen       int64
+	max        uint32
+	sorted     bool
+	rnd        *rand.Rand
+}
+
+func newContentLengthSampler(maxSamples int) *contentLengthSampler {
+	return &contentLengthSampler{
+		maxSamples: maxSamples,
+		rnd:        rand.New(rand.NewSource(1)), //nolint:gosec
+	}
+}
+
+func (s *contentLengthSampler) add(l uint32) {
+	s.seen++
+	s.sorted = false
+
+	if l > s.max {
+		s.max = l
+	}
+
+	if len(s.samples) < s.maxSamples {
+		s.samples = append(s.samples, l)
+		return
+	}
+
+	if j := s.rnd.Int63n(s.seen); j < int64(s.maxSamples) {
+		s.samples[j] = l
+	}
+}
+
+// percentile returns the value at the given percentile (0-100) using nearest-rank method.
+func (s *contentLengthSampler) percentile(p float64) uint32 {
+	if len(s.samples) == 0 {
+		return 0
+	}
+
+	if !s.sorted {
+		sort.Slice(s.samples, func(i, j int) bool { return s.samples[i] < s.samples[j] })
+		s.sorted = true
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(s.samples)))) //nolint:gomnd
+	if rank < 1 {
+		rank = 1
+	}
+
+	if rank > len(s.samples) {
+		rank = len(s.samples)
+	}
+
+	return s.samples[rank-1]
+}
+
+// contentStatsExtra collects optional statistics, which are only computed when requested.
+type contentStatsExtra struct {
+	lengths  *contentLengthSampler
+	byPrefix map[content.ID]*contentStatsTotals
+}
+
+func (e *contentStatsExtra) add(b content.Info) {
+	if e.lengths != nil {
+		e.lengths.add(b.GetPackedLength())
+	}
+
+	if e.byPrefix != nil {
+		t := e.byPrefix[b.GetContentID().Prefix()]
+		if t == nil {
+			t = &contentStatsTotals{}
+			e.byPrefix[b.GetContentID().Prefix()] = t
+		}
+
+		t.count++
+		t.originalSize += int64(b.GetOriginalLength())
+		t.packedSize += int64(b.GetPackedLength())
+	}
+}
+
 func (c *commandContentStats) run(ctx context.Context, rep repo.DirectRepository) error {
 	var (
 		sizeThreshold uint32 = 10
@@ -41,7 +135,17 @@ func (c *commandContentStats) run(ctx context.Context, rep repo.DirectRepository
 		sizeThreshold *= 10
 	}
 
-	grandTotal, byCompressionTotal, countMap, totalSizeOfContentsUnder, err := c.calculateStats(ctx, rep, sizeBuckets)
+	extra := &contentStatsExtra{}
+
+	if c.percentiles {
+		extra.lengths = newContentLengthSampler(maxContentLengthSamples)
+	}
+
+	if c.byPrefix {
+		extra.byPrefix = map[content.ID]*contentStatsTotals{}
+	}
+
+	grandTotal, byCompressionTotal, countMap, totalSizeOfContentsUnder, err := c.calculateStats(ctx, rep, sizeBuckets, extra)
 	if err != nil {
 		return errors.Wrap(err, "error calculating totals")
 	}
@@ -104,10 +208,46 @@ func (c *commandContentStats) run(ctx context.Context, rep repo.DirectRepository
 		lastSize = size
 	}
 
+	c.printExtraStats(extra, sizeToString)
+
 	return nil
 }
 
-func (c *commandContentStats) calculateStats(ctx context.Context, rep repo.DirectRepository, sizeBuckets []uint32) (
+func (c *commandContentStats) printExtraStats(extra *contentStatsExtra, sizeToString func(int64) string) {
+	if extra.lengths != nil {
+		c.out.printStdout("\nPacked Length Percentiles:\n")
+
+		for _, p := range []float64{50, 90, 99} {
+			c.out.printStdout("  p%-5v %v\n", p, sizeToString(int64(extra.lengths.percentile(p))))
+		}
+
+		c.out.printStdout("  %-6v %v\n", "max", sizeToString(int64(extra.lengths.max)))
+	}
+
+	if extra.byPrefix != nil {
+		c.out.printStdout("\nBy Prefix:\n")
+
+		var prefixes []content.ID
+		for p := range extra.byPrefix {
+			prefixes = append(prefixes, p)
+		}
+
+		sort.Slice(prefixes, func(i, j int) bool { return prefixes[i] < prefixes[j] })
+
+		for _, p := range prefixes {
+			t := extra.byPrefix[p]
+
+			name := string(p)
+			if name == "" {
+				name = "(none)"
+			}
+
+			c.out.printStdout("  %-8v count: %v size: %v packed: %v\n", name, t.count, sizeToString(t.originalSize), sizeToString(t.packedSize))
+		}
+	}
+}
+
+func (c *commandContentStats) calculateStats(ctx context.Context, rep repo.DirectRepository, sizeBuckets []uint32, extra *contentStatsExtra) (
 	grandTotal contentStatsTotals,
 	byCompressionTotal map[compression.HeaderID]*contentStatsTotals,
 	countMap map[uint32]int,
@@ -142,6 +282,8 @@ func(b content.Info) error {
 			bct.originalSize += int64(b.GetOriginalLength())
 			bct.count++
 
+			extra.add(b)
+
 			for s := range countMap {
 				if b.GetPackedLength() < s {
 					countMap[s]++
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/repo/content"
+)
+
+func TestContentStatsExtra(t *testing.T) {
+	extra := &contentStatsExtra{
+		lengths:  newContentLengthSampler(maxContentLengthSamples),
+		byPrefix: map[content.ID]*contentStatsTotals{},
+	}
+
+	// 100 contents with packed lengths 1..100, split between no prefix, 'k' and 'm'.
+	for i := 1; i <= 100; i++ {
+		var prefix string
+
+		switch i % 4 {
+		case 0:
+			prefix = "k"
+		case 1:
+			prefix = "m"
+		}
+
+		extra.add(&content.InfoStruct{
+			ContentID:      content.ID(prefix + "abcd"),
+			PackedLength:   uint32(i),
+			OriginalLength: uint32(2 * i),
+		})
+	}
+
+	require.Equal(t, uint32(50), extra.lengths.percentile(50))
+	require.Equal(t, uint32(90), extra.lengths.percentile(90))
+	require.Equal(t, uint32(99), extra.lengths.percentile(99))
+	require.Equal(t, uint32(100), extra.lengths.max)
+
+	// k: 4+8+...+100, m: 1+5+...+97, none: the rest.
+	require.Equal(t, &contentStatsTotals{count: 25, packedSize: 1300, originalSize: 2600}, extra.byPrefix["k"])
+	require.Equal(t, &contentStatsTotals{count: 25, packedSize: 1225, originalSize: 2450}, extra.byPrefix["m"])
+	require.Equal(t, &contentStatsTotals{count: 50, packedSize: 2525, originalSize: 5050}, extra.byPrefix[""])
+}
+
+func TestContentLengthSamplerBounded(t *testing.T) {
+	s := newContentLengthSampler(1000)
+
+	for i := 1; i <= 100000; i++ {
+		s.add(uint32(i))
+	}
+
+	require.Len(t, s.samples, 1000)
+	require.Equal(t, uint32(100000), s.max)
+
+	// uniform sample, approximate percentiles are within a few percent of exact values.
+	require.InDelta(t, 50000, s.percentile(50), 5000)
+	require.InDelta(t, 90000, s.percentile(90), 5000)
+
+	require.Equal(t, uint32(0), newContentLengthSampler(10).percentile(50))
+}
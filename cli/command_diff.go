@@ -17,7 +17,9 @@ type commandDiff struct {
 	diffSecondObjectPath string
 	diffCompareFiles     bool
 	diffCommandCommand   string
+	diffContentOnly      bool
 
+	jo  jsonOutput
 	out textOutput
 }
 
@@ -27,8 +29,10 @@ func (c *commandDiff) setup(svc appServices, parent commandParent) {
 	cmd.Arg("object-path2", "Second object/path").Required().StringVar(&c.diffSecondObjectPath)
 	cmd.Flag("files", "Compare files by launching diff command for all pairs of (old,new)").Short('f').BoolVar(&c.diffCompareFiles)
 	cmd.Flag("diff-command", "Displays differences between two repository objects (files or directories)").Default(defaultDiffCommand()).Envar("KOPIA_DIFF").StringVar(&c.diffCommandCommand)
+	cmd.Flag("content-only", "Only list entries whose contents changed, ignoring metadata").BoolVar(&c.diffContentOnly)
 	cmd.Action(svc.repositoryReaderAction(c.run))
 
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 }
 
@@ -50,6 +54,14 @@ func (c *commandDiff) run(ctx context.Context, rep repo.Repository) error {
 		return errors.New("arguments do diff must both be directories or both non-directories")
 	}
 
+	if c.diffContentOnly {
+		return c.printContentChanges(ctx, ent1, ent2)
+	}
+
+	if c.jo.jsonOutput {
+		return errors.New("--json is only supported with --content-only")
+	}
+
 	d, err := diff.NewComparer(c.out.stdout())
 	if err != nil {
 		return errors.Wrap(err, "error creating comparer")
@@ -69,6 +81,33 @@ func (c *commandDiff) run(ctx context.Context, rep repo.Repository) error {
 	return errors.New("comparing files not implemented yet")
 }
 
+func (c *commandDiff) printContentChanges(ctx context.Context, ent1, ent2 fs.Entry) error {
+	changes, err := diff.ContentChanges(ctx, ent1, ent2)
+	if err != nil {
+		return errors.Wrap(err, "error comparing directories")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(changes))
+		return nil
+	}
+
+	for _, ch := range changes {
+		switch ch.Type {
+		case diff.ChangeAdded:
+			c.out.printStdout("added %v (%v bytes)\n", ch.Path, ch.NewSize)
+		case diff.ChangeRemoved:
+			c.out.printStdout("removed %v (%v bytes)\n", ch.Path, ch.OldSize)
+		case diff.ChangeModified:
+			c.out.printStdout("modified %v (size %v -> %v)\n", ch.Path, ch.OldSize, ch.NewSize)
+		case diff.ChangeTypeChanged:
+			c.out.printStdout("type changed %v\n", ch.Path)
+		}
+	}
+
+	return nil
+}
+
 func defaultDiffCommand() string {
 	if isWindows() {
 		return "cmp"
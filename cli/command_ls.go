@@ -3,7 +3,10 @@
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -19,7 +22,14 @@ type commandList struct {
 	showOID      bool
 	errorSummary bool
 	path         string
+	sortBy       string
+	match        string
 
+	// prefix of the listed directory, used to compute relative paths of entries.
+	rootPrefix string
+
+	jo  jsonOutput
+	jl  jsonList
 	out textOutput
 }
 
@@ -30,13 +40,31 @@ func (c *commandList) setup(svc appServices, parent commandParent) {
 	cmd.Flag("recursive", "Recursive output").Short('r').BoolVar(&c.recursive)
 	cmd.Flag("show-object-id", "Show object IDs").Short('o').BoolVar(&c.showOID)
 	cmd.Flag("error-summary", "Emit error summary").Default("true").BoolVar(&c.errorSummary)
+	cmd.Flag("sort", "Sort entries within each directory by name, size (largest first) or mtime (newest first)").EnumVar(&c.sortBy, "name", "size", "mtime")
+	cmd.Flag("match", "Only show entries whose name (or path relative to the listed directory in recursive mode) matches the glob pattern").StringVar(&c.match)
 	cmd.Arg("object-path", "Path").Required().StringVar(&c.path)
 	cmd.Action(svc.repositoryReaderAction(c.run))
 
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 }
 
+// lsEntry is the JSON representation of a directory entry printed by 'ls --json'.
+type lsEntry struct {
+	Name       string    `json:"name"`
+	Mode       string    `json:"mode"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mtime"`
+	ObjectID   object.ID `json:"obj"`
+	ErrorCount int       `json:"errorCount,omitempty"`
+	IsDir      bool      `json:"isDir,omitempty"`
+}
+
 func (c *commandList) run(ctx context.Context, rep repo.Repository) error {
+	if _, err := filepath.Match(c.match, ""); err != nil {
+		return errors.Wrap(err, "invalid --match pattern")
+	}
+
 	dir, err := snapshotfs.FilesystemDirectoryFromIDWithPath(ctx, rep, c.path, false)
 	if err != nil {
 		return errors.Wrap(err, "unable to get filesystem directory entry")
@@ -50,6 +78,11 @@ func (c *commandList) run(ctx context.Context, rep repo.Repository) error {
 		}
 	}
 
+	c.rootPrefix = prefix
+
+	c.jl.begin(&c.jo)
+	defer c.jl.end()
+
 	return c.listDirectory(ctx, dir, prefix, "")
 }
 
@@ -59,6 +92,8 @@ func (c *commandList) listDirectory(ctx context.Context, d fs.Directory, prefix,
 		return errors.Wrap(err, "error reading directory")
 	}
 
+	c.sortEntries(entries)
+
 	for _, e := range entries {
 		if err := c.printDirectoryEntry(ctx, e, prefix, indent); err != nil {
 			return errors.Wrap(err, "unable to print directory entry")
@@ -84,18 +119,34 @@ func (c *commandList) printDirectoryEntry(ctx context.Context, e fs.Entry, prefi
 	col := defaultColor
 
 	var (
+		errorCount   int
 		errorSummary string
 		info         string
 	)
 
 	if dws, ok := e.(fs.DirectoryWithSummary); ok && c.errorSummary {
 		if ds, _ := dws.Summary(ctx); ds != nil && ds.FatalErrorCount > 0 {
+			errorCount = ds.FatalErrorCount
 			errorSummary = fmt.Sprintf(" (%v errors)", ds.FatalErrorCount)
 			col = errorColor
 		}
 	}
 
 	switch {
+	case !c.matches(prefix, e):
+		// entry is not printed, but directories are still descended into below.
+
+	case c.jo.jsonOutput:
+		c.jl.emit(lsEntry{
+			Name:       c.entryPath(prefix, e),
+			Mode:       e.Mode().String(),
+			Size:       e.Size(),
+			ModTime:    e.ModTime(),
+			ObjectID:   objectID,
+			ErrorCount: errorCount,
+			IsDir:      e.IsDir(),
+		})
+
 	case c.long:
 		info = fmt.Sprintf(
 			"%v %12d %v %-34v %v%v",
@@ -113,7 +164,9 @@ func (c *commandList) printDirectoryEntry(ctx context.Context, e fs.Entry, prefi
 		info = fmt.Sprintf("%v%v", c.nameToDisplay(prefix, e), errorSummary)
 	}
 
-	col.Fprintln(c.out.stdout(), info) //nolint:errcheck
+	if info != "" {
+		col.Fprintln(c.out.stdout(), info) //nolint:errcheck
+	}
 
 	if c.recursive {
 		if subdir, ok := e.(fs.Directory); ok {
@@ -126,15 +179,55 @@ func (c *commandList) printDirectoryEntry(ctx context.Context, e fs.Entry, prefi
 	return nil
 }
 
-func (c *commandList) nameToDisplay(prefix string, e fs.Entry) string {
-	suffix := ""
-	if e.IsDir() {
-		suffix = "/"
+func (c *commandList) sortEntries(entries fs.Entries) {
+	var less func(a, b fs.Entry) bool
+
+	switch c.sortBy {
+	case "name":
+		less = func(a, b fs.Entry) bool { return a.Name() < b.Name() }
+	case "size":
+		less = func(a, b fs.Entry) bool { return a.Size() > b.Size() }
+	case "mtime":
+		less = func(a, b fs.Entry) bool { return a.ModTime().After(b.ModTime()) }
+	default:
+		return
+	}
+
+	// entries are already sorted by name, so stable sort keeps ties in name order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i], entries[j])
+	})
+}
+
+// matches determines whether the entry matches the --match pattern, which is applied to
+// entry name or, in recursive mode, to its path relative to the listed directory.
+func (c *commandList) matches(prefix string, e fs.Entry) bool {
+	if c.match == "" {
+		return true
+	}
+
+	name := e.Name()
+	if c.recursive {
+		name = strings.TrimPrefix(prefix, c.rootPrefix) + name
 	}
 
+	ok, _ := filepath.Match(c.match, name)
+
+	return ok
+}
+
+func (c *commandList) entryPath(prefix string, e fs.Entry) string {
 	if c.long || c.recursive {
-		return prefix + e.Name() + suffix
+		return prefix + e.Name()
 	}
 
 	return e.Name()
 }
+
+func (c *commandList) nameToDisplay(prefix string, e fs.Entry) string {
+	if e.IsDir() {
+		return c.entryPath(prefix, e) + "/"
+	}
+
+	return c.entryPath(prefix, e)
+}
@@ -0,0 +1,147 @@
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+type lsEntry struct {
+	Name       string    `json:"name"`
+	Mode       string    `json:"mode"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mtime"`
+	ObjectID   string    `json:"obj"`
+	ErrorCount int       `json:"errorCount"`
+	IsDir      bool      `json:"isDir"`
+}
+
+func TestListSortAndJSON(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+	baseTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "a"), 300, baseTime)
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "b"), 100, baseTime.Add(2*time.Hour))
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "c"), 200, baseTime.Add(1*time.Hour))
+	require.NoError(t, os.Mkdir(filepath.Join(srcDir, "d"), 0o700))
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "d", "x"), 10, baseTime)
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "d", "y"), 20, baseTime.Add(3*time.Hour))
+	require.NoError(t, os.Chtimes(filepath.Join(srcDir, "d"), baseTime, baseTime))
+
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--json"), &man)
+
+	rootID := string(man.RootEntry.ObjectID)
+
+	require.Equal(t, []string{"a", "b", "c", "d/"}, env.RunAndExpectSuccess(t, "ls", rootID))
+	require.Equal(t, []string{"a", "b", "c", "d/"}, env.RunAndExpectSuccess(t, "ls", rootID, "--sort=name"))
+	require.Equal(t, []string{"a", "c", "b", "d/"}, env.RunAndExpectSuccess(t, "ls", rootID, "--sort=size"))
+	// "d" itself is older than all files, but snapshot directories report the newest
+	// modification time of their contents, which is the time of "d/y".
+	require.Equal(t, []string{"d/", "b", "c", "a"}, env.RunAndExpectSuccess(t, "ls", rootID, "--sort=mtime"))
+
+	// recursive listing sorts within each directory.
+	require.Equal(t, []string{
+		rootID + "/d/",
+		rootID + "/d/y",
+		rootID + "/d/x",
+		rootID + "/b",
+		rootID + "/c",
+		rootID + "/a",
+	}, env.RunAndExpectSuccess(t, "ls", "-r", rootID, "--sort=mtime"))
+
+	env.RunAndExpectFailure(t, "ls", rootID, "--sort=no-such-order")
+
+	var entries []lsEntry
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "ls", rootID, "--sort=size", "--json"), &entries)
+	require.Len(t, entries, 4)
+	require.Equal(t, "a", entries[0].Name)
+	require.Equal(t, int64(300), entries[0].Size)
+	require.True(t, baseTime.Equal(entries[0].ModTime))
+	require.NotEmpty(t, entries[0].ObjectID)
+	require.False(t, entries[0].IsDir)
+	require.Equal(t, "d", entries[3].Name)
+	require.True(t, entries[3].IsDir)
+
+	entries = nil
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "ls", "-r", rootID, "--json"), &entries)
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+
+	require.Equal(t, []string{
+		rootID + "/a",
+		rootID + "/b",
+		rootID + "/c",
+		rootID + "/d",
+		rootID + "/d/x",
+		rootID + "/d/y",
+	}, names)
+}
+
+func mustWriteFileWithModTime(t *testing.T, fname string, size int, mtime time.Time) {
+	t.Helper()
+
+	require.NoError(t, ioutil.WriteFile(fname, make([]byte, size), 0o600))
+	require.NoError(t, os.Chtimes(fname, mtime, mtime))
+}
+
+func TestListMatch(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+	baseTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub1", "sub2"), 0o700))
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "a.txt"), 1, baseTime)
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "b.log"), 1, baseTime)
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "sub1", "c.txt"), 1, baseTime)
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "sub1", "sub2", "d.txt"), 1, baseTime)
+	mustWriteFileWithModTime(t, filepath.Join(srcDir, "sub1", "sub2", "e.log"), 1, baseTime)
+
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--json"), &man)
+
+	rootID := string(man.RootEntry.ObjectID)
+
+	// leaf names
+	require.Equal(t, []string{"a.txt"}, env.RunAndExpectSuccess(t, "ls", rootID, "--match=*.txt"))
+	require.Equal(t, []string{"sub1/"}, env.RunAndExpectSuccess(t, "ls", rootID, "--match=sub?"))
+	require.Empty(t, env.RunAndExpectSuccess(t, "ls", rootID, "--match=*.none"))
+
+	// recursive paths are relative to the listed directory, non-matching directories are still descended.
+	require.Equal(t, []string{
+		rootID + "/sub1/c.txt",
+	}, env.RunAndExpectSuccess(t, "ls", "-r", rootID, "--match=sub1/*.txt"))
+
+	require.Equal(t, []string{
+		rootID + "/b.log",
+	}, env.RunAndExpectSuccess(t, "ls", "-r", rootID, "--match=*.log"))
+
+	require.Equal(t, []string{
+		rootID + "/sub1/sub2/d.txt",
+		rootID + "/sub1/sub2/e.log",
+	}, env.RunAndExpectSuccess(t, "ls", "-r", rootID, "--match=*/sub2/*"))
+
+	env.RunAndExpectFailure(t, "ls", rootID, "--match=[")
+}
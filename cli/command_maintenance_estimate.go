@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+type commandMaintenanceEstimate struct {
+	safety maintenance.SafetyParameters
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandMaintenanceEstimate) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("estimate", "Estimate the amount of storage that full maintenance would reclaim")
+	safetyFlagVar(cmd, &c.safety)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+}
+
+func (c *commandMaintenanceEstimate) run(ctx context.Context, rep repo.DirectRepository) error {
+	est, err := maintenance.EstimateReclaimableSpace(ctx, rep, c.safety)
+	if err != nil {
+		return errors.Wrap(err, "unable to estimate reclaimable space")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(est))
+		return nil
+	}
+
+	c.out.printStdout("Deleted contents:  %v (%v)\n", est.DeletedContentCount, units.BytesStringBase10(est.DeletedContentBytes))
+	c.out.printStdout("Droppable packs:   %v (%v)\n", est.DroppablePackCount, units.BytesStringBase10(est.DroppablePackBytes))
+	c.out.printStdout("Orphaned blobs:    %v (%v)\n", est.OrphanedBlobCount, units.BytesStringBase10(est.OrphanedBlobBytes))
+	c.out.printStdout("Reclaimable:       %v blobs (%v)\n", est.ReclaimableBlobCount(), units.BytesStringBase10(est.ReclaimableBytes()))
+
+	return nil
+}
@@ -6,7 +6,6 @@
 
 	"github.com/pkg/errors"
 
-	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/maintenance"
@@ -17,6 +16,64 @@ type commandMaintenanceInfo struct {
 	out textOutput
 }
 
+// maintenanceInfo is the JSON representation of maintenance information, it extends
+// maintenance.Schedule with the status of each cycle and the last run of each task.
+type maintenanceInfo struct {
+	maintenance.Schedule
+
+	QuickCycle maintenanceCycleInfo                         `json:"quickCycle"`
+	FullCycle  maintenanceCycleInfo                         `json:"fullCycle"`
+	Tasks      map[maintenance.TaskType]maintenanceTaskInfo `json:"tasks"`
+}
+
+type maintenanceCycleInfo struct {
+	Enabled  bool          `json:"enabled"`
+	Interval time.Duration `json:"interval"`
+	NextRun  time.Time     `json:"nextRun"`
+	Overdue  bool          `json:"overdue"`
+}
+
+type maintenanceTaskInfo struct {
+	LastStart   time.Time `json:"lastStart"`
+	LastEnd     time.Time `json:"lastEnd"`
+	LastSuccess bool      `json:"lastSuccess"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+func newMaintenanceInfo(p *maintenance.Params, s *maintenance.Schedule, now time.Time) *maintenanceInfo {
+	cycleInfo := func(cp *maintenance.CycleParams, next time.Time) maintenanceCycleInfo {
+		return maintenanceCycleInfo{
+			Enabled:  cp.Enabled,
+			Interval: cp.Interval,
+			NextRun:  next,
+			Overdue:  cp.Enabled && now.After(next),
+		}
+	}
+
+	mi := &maintenanceInfo{
+		Schedule:   *s,
+		QuickCycle: cycleInfo(&p.QuickCycle, s.NextQuickMaintenanceTime),
+		FullCycle:  cycleInfo(&p.FullCycle, s.NextFullMaintenanceTime),
+		Tasks:      map[maintenance.TaskType]maintenanceTaskInfo{},
+	}
+
+	for taskType, runs := range s.Runs {
+		if len(runs) == 0 {
+			continue
+		}
+
+		// runs are stored most recent first.
+		mi.Tasks[taskType] = maintenanceTaskInfo{
+			LastStart:   runs[0].Start,
+			LastEnd:     runs[0].End,
+			LastSuccess: runs[0].Success,
+			LastError:   runs[0].Error,
+		}
+	}
+
+	return mi
+}
+
 func (c *commandMaintenanceInfo) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("info", "Display maintenance information").Alias("status")
 	c.jo.setup(svc, cmd)
@@ -36,7 +93,7 @@ func (c *commandMaintenanceInfo) run(ctx context.Context, rep repo.DirectReposit
 	}
 
 	if c.jo.jsonOutput {
-		c.out.printStdout("%s\n", c.jo.jsonBytes(s))
+		c.out.printStdout("%s\n", c.jo.jsonBytes(newMaintenanceInfo(p, s, rep.Time())))
 		return nil
 	}
 
@@ -85,7 +142,7 @@ func (c *commandMaintenanceInfo) displayCycleInfo(cp *maintenance.CycleParams, t
 		c.out.printStdout("  interval: %v\n", cp.Interval)
 
 		if rep.Time().Before(t) {
-			c.out.printStdout("  next run: %v (in %v)\n", formatTimestamp(t), clock.Until(t).Truncate(time.Second))
+			c.out.printStdout("  next run: %v (in %v)\n", formatTimestamp(t), t.Sub(rep.Time()).Truncate(time.Second))
 		} else {
 			c.out.printStdout("  next run: now\n")
 		}
@@ -0,0 +1,89 @@
+package cli_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+type maintenanceCycleInfo struct {
+	Enabled  bool          `json:"enabled"`
+	Interval time.Duration `json:"interval"`
+	NextRun  time.Time     `json:"nextRun"`
+	Overdue  bool          `json:"overdue"`
+}
+
+type maintenanceTaskInfo struct {
+	LastStart   time.Time `json:"lastStart"`
+	LastEnd     time.Time `json:"lastEnd"`
+	LastSuccess bool      `json:"lastSuccess"`
+	LastError   string    `json:"lastError"`
+}
+
+type maintenanceInfo struct {
+	maintenance.Schedule
+
+	QuickCycle maintenanceCycleInfo                         `json:"quickCycle"`
+	FullCycle  maintenanceCycleInfo                         `json:"fullCycle"`
+	Tasks      map[maintenance.TaskType]maintenanceTaskInfo `json:"tasks"`
+}
+
+func TestMaintenanceInfoJSON(t *testing.T) {
+	ft := faketime.NewTimeAdvance(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	runner := testenv.NewInProcRunner(t)
+	runner.NowFunc = ft.NowFunc()
+
+	env := testenv.NewCLITest(t, runner)
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "maintenance", "run", "--full")
+
+	var mi maintenanceInfo
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+
+	// the reported schedule matches the one stored in the repository.
+	ctx := testlogging.Context(t)
+
+	rep, err := repo.Open(ctx, filepath.Join(env.ConfigDir, ".kopia.config"), testenv.TestRepoPassword, nil)
+	require.NoError(t, err)
+
+	defer rep.Close(ctx)
+
+	sched, err := maintenance.GetSchedule(ctx, rep.(repo.DirectRepository))
+	require.NoError(t, err)
+
+	require.True(t, mi.NextQuickMaintenanceTime.Equal(sched.NextQuickMaintenanceTime))
+	require.True(t, mi.NextFullMaintenanceTime.Equal(sched.NextFullMaintenanceTime))
+	require.Len(t, mi.Runs, len(sched.Runs))
+	require.Len(t, mi.Tasks, len(sched.Runs))
+
+	for taskType, runs := range sched.Runs {
+		require.Len(t, mi.Runs[taskType], len(runs))
+		require.True(t, mi.Tasks[taskType].LastStart.Equal(runs[0].Start), "task %v", taskType)
+		require.True(t, mi.Tasks[taskType].LastEnd.Equal(runs[0].End), "task %v", taskType)
+		require.Equal(t, runs[0].Success, mi.Tasks[taskType].LastSuccess, "task %v", taskType)
+	}
+
+	require.True(t, mi.QuickCycle.Enabled)
+	require.Equal(t, time.Hour, mi.QuickCycle.Interval)
+	require.True(t, mi.QuickCycle.NextRun.Equal(sched.NextQuickMaintenanceTime))
+	require.False(t, mi.QuickCycle.Overdue)
+	require.False(t, mi.FullCycle.Overdue)
+
+	// once the quick interval has passed, quick maintenance is overdue.
+	ft.Advance(2 * time.Hour)
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.True(t, mi.QuickCycle.Overdue)
+	require.False(t, mi.FullCycle.Overdue)
+}
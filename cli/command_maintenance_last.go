@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
+)
+
+type commandMaintenanceLast struct {
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandMaintenanceLast) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("last", "Display summary of the last maintenance run")
+	c.jo.setup(svc, cmd)
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+	c.out.setup(svc)
+}
+
+func (c *commandMaintenanceLast) run(ctx context.Context, rep repo.DirectRepository) error {
+	sum, err := snapshotmaintenance.GetLastSummary(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance summary")
+	}
+
+	if sum == nil {
+		return errors.Errorf("maintenance has not run yet")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(sum))
+		return nil
+	}
+
+	status := "SUCCESS"
+	if !sum.Success {
+		status = "ERROR: " + sum.Error
+	}
+
+	c.out.printStdout("Mode:          %v\n", sum.Mode)
+	c.out.printStdout("Started:       %v\n", formatTimestamp(sum.Start))
+	c.out.printStdout("Duration:      %v\n", sum.End.Sub(sum.Start).Truncate(time.Second))
+	c.out.printStdout("Status:        %v\n", status)
+	c.out.printStdout("Blobs deleted: %v (%v)\n", sum.BlobsDeleted, units.BytesStringBase10(sum.BytesDeleted))
+
+	if gc := sum.SnapshotGC; gc != nil {
+		c.out.printStdout("Snapshot GC:   %v unused contents (%v)\n", gc.UnusedCount, units.BytesStringBase10(gc.UnusedBytes))
+	}
+
+	var taskTypes []maintenance.TaskType
+	for t := range sum.Tasks {
+		taskTypes = append(taskTypes, t)
+	}
+
+	sort.Slice(taskTypes, func(i, j int) bool { return taskTypes[i] < taskTypes[j] })
+
+	c.out.printStdout("Tasks:\n")
+
+	for _, t := range taskTypes {
+		ri := sum.Tasks[t]
+
+		errInfo := "SUCCESS"
+		if !ri.Success {
+			errInfo = "ERROR: " + ri.Error
+		}
+
+		c.out.printStdout("  %v: %v %v\n", t, ri.End.Sub(ri.Start).Truncate(time.Millisecond), errInfo)
+	}
+
+	return nil
+}
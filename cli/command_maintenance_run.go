@@ -1,7 +1,13 @@
 package cli
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/maintenance"
@@ -9,17 +15,25 @@
 )
 
 type commandMaintenanceRun struct {
-	maintenanceRunFull  bool
-	maintenanceRunForce bool
-	safety              maintenance.SafetyParameters
+	maintenanceRunFull    bool
+	maintenanceRunForce   bool
+	maintenanceRunConfirm bool
+	safety                maintenance.SafetyParameters
+
+	svc appServices
+	out textOutput
 }
 
 func (c *commandMaintenanceRun) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("run", "Run repository maintenance").Default()
 	cmd.Flag("full", "Full maintenance").BoolVar(&c.maintenanceRunFull)
 	cmd.Flag("force", "Run maintenance even if not owned (unsafe)").Hidden().BoolVar(&c.maintenanceRunForce)
+	cmd.Flag("confirm", "Ask for confirmation when running with unsafe settings").Default("true").BoolVar(&c.maintenanceRunConfirm)
 	safetyFlagVar(cmd, &c.safety)
 
+	c.svc = svc
+	c.out.setup(svc)
+
 	cmd.Action(svc.directRepositoryWriteAction(c.run))
 }
 
@@ -29,6 +43,28 @@ func (c *commandMaintenanceRun) run(ctx context.Context, rep repo.DirectReposito
 		mode = maintenance.ModeFull
 	}
 
+	if c.safety == maintenance.SafetyNone && c.maintenanceRunConfirm {
+		if err := confirmUnsafeAction(c.out.stderr(), c.svc.stdin(), "Running maintenance with --safety=none may cause data loss if other clients are concurrently writing to the repository."); err != nil {
+			return err
+		}
+	}
+
 	// nolint:wrapcheck
 	return snapshotmaintenance.Run(ctx, rep, mode, c.maintenanceRunForce, c.safety)
 }
+
+// confirmUnsafeAction asks the user to confirm an unsafe action by typing 'yes'.
+func confirmUnsafeAction(out io.Writer, in io.Reader, warning string) error {
+	fmt.Fprintf(out, "%v\nType 'yes' to continue or pass --no-confirm to skip confirmation: ", warning) // nolint:errcheck
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, "error reading confirmation")
+	}
+
+	if strings.TrimSpace(line) != "yes" {
+		return errors.New("aborted by user")
+	}
+
+	return nil
+}
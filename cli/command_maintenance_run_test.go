@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmUnsafeAction(t *testing.T) {
+	var out bytes.Buffer
+
+	require.NoError(t, confirmUnsafeAction(&out, strings.NewReader("yes\n"), "some warning"))
+	require.Contains(t, out.String(), "some warning")
+
+	require.NoError(t, confirmUnsafeAction(&out, strings.NewReader(" yes "), "some warning"))
+	require.Error(t, confirmUnsafeAction(&out, strings.NewReader("no\n"), "some warning"))
+	require.Error(t, confirmUnsafeAction(&out, strings.NewReader(""), "some warning"))
+}
@@ -15,6 +15,8 @@ type policyCompressionFlags struct {
 	policySetCompressionMinSize   string
 	policySetCompressionMaxSize   string
 
+	policySetMetadataCompressionAlgorithm string
+
 	policySetAddOnlyCompress    []string
 	policySetRemoveOnlyCompress []string
 	policySetClearOnlyCompress  bool
@@ -29,6 +31,7 @@ func (c *policyCompressionFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("compression", "Compression algorithm").EnumVar(&c.policySetCompressionAlgorithm, supportedCompressionAlgorithms()...)
 	cmd.Flag("compression-min-size", "Min size of file to attempt compression for").StringVar(&c.policySetCompressionMinSize)
 	cmd.Flag("compression-max-size", "Max size of file to attempt compression for").StringVar(&c.policySetCompressionMaxSize)
+	cmd.Flag("metadata-compression", "Compression algorithm for snapshot metadata (directory listings)").EnumVar(&c.policySetMetadataCompressionAlgorithm, supportedCompressionAlgorithms()...)
 
 	// Files to only compress.
 	cmd.Flag("add-only-compress", "List of extensions to add to the only-compress list").PlaceHolder("PATTERN").StringsVar(&c.policySetAddOnlyCompress)
@@ -64,6 +67,20 @@ func (c *policyCompressionFlags) setCompressionPolicyFromFlags(ctx context.Conte
 		}
 	}
 
+	if v := c.policySetMetadataCompressionAlgorithm; v != "" {
+		*changeCount++
+
+		if v == inheritPolicyString {
+			log(ctx).Infof(" - resetting metadata compression algorithm to default value inherited from parent\n")
+
+			p.MetadataCompressorName = ""
+		} else {
+			log(ctx).Infof(" - setting metadata compression algorithm to %v\n", v)
+
+			p.MetadataCompressorName = compression.Name(v)
+		}
+	}
+
 	applyPolicyStringList(ctx, "only-compress extensions",
 		&p.OnlyCompress, c.policySetAddOnlyCompress, c.policySetRemoveOnlyCompress, c.policySetClearOnlyCompress, changeCount)
 
@@ -245,13 +245,30 @@ func printSchedulingPolicy(out *textOutput, p *policy.Policy, parents []*policy.
 }
 
 func printCompressionPolicy(out *textOutput, p *policy.Policy, parents []*policy.Policy) {
-	if p.CompressionPolicy.CompressorName != "" && p.CompressionPolicy.CompressorName != "none" {
-		out.printStdout("Compression:\n")
+	compressFiles := p.CompressionPolicy.CompressorName != "" && p.CompressionPolicy.CompressorName != "none"
+	mc := p.CompressionPolicy.MetadataCompressor()
+
+	if !compressFiles && mc == "" {
+		out.printStdout("Compression disabled.\n")
+		return
+	}
+
+	out.printStdout("Compression:\n")
+
+	if compressFiles {
 		out.printStdout("  Compressor: %q %v\n", p.CompressionPolicy.CompressorName, getDefinitionPoint(p.Target(), parents, func(pol *policy.Policy) bool {
 			return pol.CompressionPolicy.CompressorName != ""
 		}))
-	} else {
-		out.printStdout("Compression disabled.\n")
+	}
+
+	if mc != "" {
+		out.printStdout("  Metadata compressor: %q %v\n", mc, getDefinitionPoint(p.Target(), parents, func(pol *policy.Policy) bool {
+			return pol.CompressionPolicy.MetadataCompressorName != ""
+		}))
+	}
+
+	if !compressFiles {
+		out.printStdout("  File compression disabled.\n")
 		return
 	}
 
@@ -8,6 +8,7 @@ type commandRepository struct {
 	setClient      commandRepositorySetClient
 	setParameters  commandRepositorySetParameters
 	changePassword commandRepositoryChangePassword
+	compare        commandRepositoryCompare
 	status         commandRepositoryStatus
 	syncTo         commandRepositorySyncTo
 }
@@ -24,4 +25,5 @@ func (c *commandRepository) setup(svc advancedAppServices, parent commandParent)
 	c.status.setup(svc, cmd)
 	c.syncTo.setup(svc, cmd)
 	c.changePassword.setup(svc, cmd)
+	c.compare.setup(svc, cmd)
 }
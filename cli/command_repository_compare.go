@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+const fullSamplePercent = 100
+
+type commandRepositoryCompare struct {
+	comparePrefix        string
+	compareSamplePercent int
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandRepositoryCompare) setup(svc advancedAppServices, parent commandParent) {
+	cmd := parent.Command("compare", "Compares BLOBs in this repository with another location")
+	cmd.Flag("blob-prefix", "Only compare blobs with the provided ID prefix.").StringVar(&c.comparePrefix)
+	cmd.Flag("sample-percent", "Percentage of blobs to compare (deterministically sampled by ID).").Default("100").IntVar(&c.compareSamplePercent)
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+
+	for _, prov := range storageProviders {
+		f := prov.newFlags()
+		cc := cmd.Command(prov.name, "Compare repository with another repository in "+prov.description)
+		f.setup(svc, cc)
+		cc.Action(svc.noRepositoryAction(func(ctx context.Context) error {
+			st, err := f.connect(ctx, false)
+			if err != nil {
+				return errors.Wrap(err, "can't connect to storage")
+			}
+
+			defer st.Close(ctx) //nolint:errcheck
+
+			rep, err := svc.openRepository(ctx, true)
+			if err != nil {
+				return errors.Wrap(err, "open repository")
+			}
+
+			dr, ok := rep.(repo.DirectRepository)
+			if !ok {
+				return errors.Errorf("compare only supports directly-connected repositories")
+			}
+
+			return c.runCompareWithStorage(ctx, dr.BlobReader(), st)
+		}))
+	}
+}
+
+func (c *commandRepositoryCompare) runCompareWithStorage(ctx context.Context, src blob.Reader, dst blob.Reader) error {
+	if c.compareSamplePercent <= 0 || c.compareSamplePercent > fullSamplePercent {
+		return errors.Errorf("invalid sample percentage: %v", c.compareSamplePercent)
+	}
+
+	log(ctx).Infof("Comparing repositories:")
+	log(ctx).Infof("  Source:      %v", src.DisplayName())
+	log(ctx).Infof("  Destination: %v", dst.DisplayName())
+
+	var filter func(id blob.ID) bool
+
+	if c.compareSamplePercent < fullSamplePercent {
+		filter = func(id blob.ID) bool {
+			return isBlobInSample(id, c.compareSamplePercent)
+		}
+	}
+
+	r, err := blob.Diff(ctx, src, dst, blob.ID(c.comparePrefix), filter)
+	if err != nil {
+		return errors.Wrap(err, "error comparing repositories")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(r))
+	} else {
+		for _, bm := range r.Missing {
+			c.out.printStdout("missing    %-70v %10v\n", bm.BlobID, bm.Length)
+		}
+
+		for _, bm := range r.Extra {
+			c.out.printStdout("extra      %-70v %10v\n", bm.BlobID, bm.Length)
+		}
+
+		for _, bm := range r.Mismatched {
+			c.out.printStdout("mismatched %-70v %10v\n", bm.BlobID, bm.Length)
+		}
+
+		c.out.printStderr("Compared %v BLOBs: %v matching, %v missing (%v), %v extra (%v), %v mismatched.\n",
+			r.Matching+len(r.Missing)+len(r.Extra)+len(r.Mismatched),
+			r.Matching,
+			len(r.Missing), units.BytesStringBase10(blob.TotalLength(r.Missing)),
+			len(r.Extra), units.BytesStringBase10(blob.TotalLength(r.Extra)),
+			len(r.Mismatched))
+	}
+
+	if !r.Empty() {
+		return errors.Errorf("repositories are different")
+	}
+
+	return nil
+}
+
+// isBlobInSample deterministically determines whether the blob belongs to a sample
+// of the given percentage, so that the same blobs are selected on both sides.
+func isBlobInSample(id blob.ID, percent int) bool {
+	h := fnv.New32a()
+	h.Write([]byte(id)) //nolint:errcheck
+
+	return int(h.Sum32()%fullSamplePercent) < percent
+}
@@ -56,7 +56,7 @@ func (c *commandRepositoryRepair) runRepairCommandWithStorage(ctx context.Contex
 	case "auto":
 		log(ctx).Infof("looking for format blob...")
 
-		if _, err := st.GetBlob(ctx, repo.FormatBlobID, 0, -1); err == nil {
+		if exists, err := blob.Exists(ctx, st, repo.FormatBlobID); err == nil && exists {
 			log(ctx).Infof("format blob already exists, not recovering, pass --recover-format=yes")
 			return nil
 		}
@@ -4,11 +4,14 @@
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/alecthomas/kingpin"
+	"github.com/efarrer/iothrottler"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 
@@ -18,6 +21,7 @@
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
 )
 
 type commandRepositorySyncTo struct {
@@ -29,11 +33,22 @@ type commandRepositorySyncTo struct {
 	repositorySyncParallelism          int
 	repositorySyncDestinationMustExist bool
 	repositorySyncTimes                bool
+	repositorySyncPrefixes             []string
+	repositorySyncVerify               bool
+	repositorySyncMaxUploadSpeed       int
+	repositorySyncBidirectional        bool
+	repositorySyncListParallelism      int
+
+	uploadThrottler *iothrottler.IOThrottlerPool
 
 	lastSyncProgress       string
 	syncProgressMutex      sync.Mutex
 	setTimeUnsupportedOnce sync.Once
 
+	// skipSetTime is set when the destination is known not to support setting blob times.
+	skipSetTime bool
+
+	jo  jsonOutput
 	out textOutput
 }
 
@@ -45,7 +60,14 @@ func (c *commandRepositorySyncTo) setup(svc advancedAppServices, parent commandP
 	cmd.Flag("parallel", "Copy parallelism.").Default("1").IntVar(&c.repositorySyncParallelism)
 	cmd.Flag("must-exist", "Fail if destination does not have repository format blob.").BoolVar(&c.repositorySyncDestinationMustExist)
 	cmd.Flag("times", "Synchronize blob times if supported.").BoolVar(&c.repositorySyncTimes)
-
+	cmd.Flag("bidirectional", "Also copy blobs missing or newer in destination back to this repository.").BoolVar(&c.repositorySyncBidirectional)
+	cmd.Flag("max-sync-upload-speed", "Limit the aggregate upload speed of all copy workers.").PlaceHolder("BYTES_PER_SEC").IntVar(&c.repositorySyncMaxUploadSpeed)
+	cmd.Flag("verify", "Verify contents of each blob after copying it to destination.").BoolVar(&c.repositorySyncVerify)
+	cmd.Flag("list-parallel", "Number of parallel listings of blob ID prefix shards.").Default("1").IntVar(&c.repositorySyncListParallelism)
+	// not named --prefix, which storage provider subcommands such as 'sync-to s3' already define.
+	cmd.Flag("blob-prefix", "Only synchronize blobs with the provided ID prefix (can be specified multiple times).").StringsVar(&c.repositorySyncPrefixes)
+
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 
 	// needs to be 64-bit aligned on ARM
@@ -56,8 +78,7 @@ func (c *commandRepositorySyncTo) setup(svc advancedAppServices, parent commandP
 		f := prov.newFlags()
 		cc := cmd.Command(prov.name, "Synchronize repository data to another repository in "+prov.description)
 		f.setup(svc, cc)
-		cc.Action(func(_ *kingpin.ParseContext) error {
-			ctx := svc.rootContext()
+		cc.Action(svc.noRepositoryAction(func(ctx context.Context) error {
 			st, err := f.connect(ctx, false)
 			if err != nil {
 				return errors.Wrap(err, "can't connect to storage")
@@ -73,14 +94,85 @@ func (c *commandRepositorySyncTo) setup(svc advancedAppServices, parent commandP
 				return errors.Errorf("sync only supports directly-connected repositories")
 			}
 
-			return c.runSyncWithStorage(ctx, dr.BlobReader(), st)
-		})
+			if c.repositorySyncBidirectional {
+				summary, err := c.runBidirectionalSync(ctx, dr, st)
+				c.printBidirectionalSyncSummary(ctx, summary)
+
+				return err
+			}
+
+			// summary is printed even on failure to show what was done before it.
+			summary, err := c.runSyncWithStorage(ctx, dr.BlobReader(), st)
+			c.printSyncSummary(ctx, summary)
+
+			return err
+		}))
 	}
 }
 
-const syncProgressInterval = 300 * time.Millisecond
+const (
+	syncProgressInterval = 300 * time.Millisecond
 
-func (c *commandRepositorySyncTo) runSyncWithStorage(ctx context.Context, src blob.Reader, dst blob.Storage) error {
+	// syncChannelBufferSize is the number of blobs buffered between the producer and workers.
+	syncChannelBufferSize = 100
+
+	// syncDeleteBatchSize is the maximum number of blobs deleted at once from storage supporting batch deletion.
+	syncDeleteBatchSize = 100
+)
+
+// SyncSummary describes the outcome of synchronizing blobs, which may be partial when synchronization fails.
+type SyncSummary struct {
+	BlobsCopied  int           `json:"blobsCopied"`
+	BytesCopied  int64         `json:"bytesCopied"`
+	BlobsDeleted int           `json:"blobsDeleted"`
+	BytesDeleted int64         `json:"bytesDeleted"`
+	InSyncBlobs  int           `json:"inSyncBlobs"`
+	InSyncBytes  int64         `json:"inSyncBytes"`
+	Elapsed      time.Duration `json:"elapsed"`
+
+	// average number of bytes copied per second.
+	Speed float64 `json:"speed"`
+}
+
+func (s SyncSummary) String() string {
+	return fmt.Sprintf(
+		"Copied %v BLOBs (%v), deleted %v BLOBs (%v), %v in sync (%v). Elapsed %v, average speed %v.",
+		s.BlobsCopied, units.BytesStringBase10(s.BytesCopied),
+		s.BlobsDeleted, units.BytesStringBase10(s.BytesDeleted),
+		s.InSyncBlobs, units.BytesStringBase10(s.InSyncBytes),
+		s.Elapsed.Truncate(time.Millisecond), units.BitsPerSecondsString(s.Speed*8)) //nolint:gomnd
+}
+
+// BidirectionalSyncSummary describes the outcome of synchronizing blobs in both directions.
+type BidirectionalSyncSummary struct {
+	ToDestination SyncSummary `json:"toDestination"`
+	ToSource      SyncSummary `json:"toSource"`
+}
+
+func (c *commandRepositorySyncTo) printSyncSummary(ctx context.Context, s SyncSummary) {
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(s))
+		return
+	}
+
+	if !c.repositorySyncDryRun {
+		log(ctx).Infof("%v", s)
+	}
+}
+
+func (c *commandRepositorySyncTo) printBidirectionalSyncSummary(ctx context.Context, s BidirectionalSyncSummary) {
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(s))
+		return
+	}
+
+	if !c.repositorySyncDryRun {
+		log(ctx).Infof("To destination: %v", s.ToDestination)
+		log(ctx).Infof("To source: %v", s.ToSource)
+	}
+}
+
+func (c *commandRepositorySyncTo) runSyncWithStorage(ctx context.Context, src blob.Reader, dst blob.Storage) (SyncSummary, error) {
 	log(ctx).Infof("Synchronizing repositories:")
 	log(ctx).Infof("  Source:      %v", src.DisplayName())
 	log(ctx).Infof("  Destination: %v", dst.DisplayName())
@@ -90,7 +182,20 @@ func (c *commandRepositorySyncTo) runSyncWithStorage(ctx context.Context, src bl
 	}
 
 	if err := c.ensureRepositoriesHaveSameFormatBlob(ctx, src, dst); err != nil {
-		return err
+		return SyncSummary{}, err
+	}
+
+	if c.repositorySyncTimes && !c.repositorySyncDryRun {
+		supported, err := supportsSetTime(ctx, dst)
+
+		switch {
+		case err != nil:
+			log(ctx).Errorf("unable to determine whether destination repository supports setting blob times, they will not be synchronized: %v", err)
+		case !supported:
+			log(ctx).Infof("NOTE: Destination repository does not support setting blob times, they will not be synchronized.")
+		}
+
+		c.skipSetTime = err != nil || !supported
 	}
 
 	log(ctx).Infof("Looking for BLOBs to synchronize...")
@@ -111,12 +216,12 @@ func (c *commandRepositorySyncTo) runSyncWithStorage(ctx context.Context, src bl
 
 	dstMetadata, err := c.listDestinationBlobs(ctx, dst)
 	if err != nil {
-		return err
+		return SyncSummary{}, err
 	}
 
 	c.beginSyncProgress()
 
-	if err := src.ListBlobs(ctx, "", func(srcmd blob.Metadata) error {
+	if err := c.listBlobs(ctx, src, func(srcmd blob.Metadata) error {
 		totalSrcSize += srcmd.Length
 
 		dstmd, exists := dstMetadata[srcmd.BlobID]
@@ -139,7 +244,7 @@ func (c *commandRepositorySyncTo) runSyncWithStorage(ctx context.Context, src bl
 
 		return nil
 	}); err != nil {
-		return errors.Wrap(err, "error listing blobs")
+		return SyncSummary{}, errors.Wrap(err, "error listing blobs")
 	}
 
 	c.finishSyncProcess()
@@ -159,38 +264,327 @@ func (c *commandRepositorySyncTo) runSyncWithStorage(ctx context.Context, src bl
 	)
 
 	if c.repositorySyncDryRun {
-		return nil
+		return SyncSummary{InSyncBlobs: inSyncBlobs, InSyncBytes: inSyncBytes}, nil
 	}
 
 	log(ctx).Infof("Copying...")
 
 	c.beginSyncProgress()
 
-	finalErr := c.runSyncBlobs(ctx, src, dst, blobsToCopy, blobsToDelete, totalCopyBytes)
+	summary, finalErr := c.runSyncBlobs(ctx, src, dst, blobsToCopy, blobsToDelete, totalCopyBytes)
+
+	c.finishSyncProcess()
+
+	summary.InSyncBlobs = inSyncBlobs
+	summary.InSyncBytes = inSyncBytes
+
+	return summary, finalErr
+}
+
+// runBidirectionalSync synchronizes the repository with the provided storage in both directions.
+// Blobs are written directly to the storage of the repository, bypassing its content manager,
+// so this refuses to run while any client has an active write session in either location.
+func (c *commandRepositorySyncTo) runBidirectionalSync(ctx context.Context, dr repo.DirectRepository, dst blob.Storage) (BidirectionalSyncSummary, error) {
+	if err := ensureNoActiveSessions(ctx, dr.BlobReader(), "source"); err != nil {
+		return BidirectionalSyncSummary{}, err
+	}
+
+	if err := ensureNoActiveSessions(ctx, dst, "destination"); err != nil {
+		return BidirectionalSyncSummary{}, err
+	}
+
+	var summary BidirectionalSyncSummary
+
+	// nolint:wrapcheck
+	return summary, repo.DirectWriteSession(ctx, dr, repo.WriteSessionOptions{
+		Purpose: "cli:sync-to",
+	}, func(ctx context.Context, dw repo.DirectRepositoryWriter) error {
+		var err error
+
+		summary, err = c.runBidirectionalSyncWithStorage(ctx, dw.BlobStorage(), dst)
+
+		// blobs copied to the source bypassed the content manager, make sure it does not use stale metadata or lists.
+		dw.ContentManager().InvalidateBlobMetadata(ctx, "")
+
+		return err
+	})
+}
+
+// ensureNoActiveSessions returns an error if the provided storage has any session marker blobs.
+func ensureNoActiveSessions(ctx context.Context, st blob.Reader, which string) error {
+	sessionCount := 0
+
+	if err := st.ListBlobs(ctx, content.BlobIDPrefixSession, func(bm blob.Metadata) error {
+		sessionCount++
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "error listing sessions in %v repository", which)
+	}
+
+	if sessionCount > 0 {
+		return errors.Errorf("%v repository has %v active sessions, bidirectional sync requires that no other clients are writing to it", which, sessionCount)
+	}
+
+	return nil
+}
+
+// runBidirectionalSyncWithStorage copies blobs missing or newer in either storage to the other one
+// and returns the summary of work done in each direction, even on failure.
+func (c *commandRepositorySyncTo) runBidirectionalSyncWithStorage(ctx context.Context, src, dst blob.Storage) (BidirectionalSyncSummary, error) {
+	log(ctx).Infof("Synchronizing repositories in both directions:")
+	log(ctx).Infof("  Source:      %v", src.DisplayName())
+	log(ctx).Infof("  Destination: %v", dst.DisplayName())
+
+	var summary BidirectionalSyncSummary
+
+	if c.repositorySyncDelete {
+		return summary, errors.Errorf("--delete is not supported in bidirectional mode")
+	}
+
+	// without preserving blob times every copied blob would be newer than the original
+	// and would be copied back on the next synchronization.
+	if !c.repositorySyncTimes {
+		return summary, errors.Errorf("--times is required in bidirectional mode")
+	}
+
+	if err := c.ensureRepositoriesHaveSameFormatBlob(ctx, src, dst); err != nil {
+		return summary, err
+	}
+
+	if !c.repositorySyncDryRun {
+		for _, st := range []blob.Storage{src, dst} {
+			supported, err := supportsSetTime(ctx, st)
+			if err != nil {
+				return summary, err
+			}
+
+			if !supported {
+				return summary, errors.Errorf("%v does not support setting blob times required in bidirectional mode", st.DisplayName())
+			}
+		}
+	}
+
+	log(ctx).Infof("Looking for BLOBs to synchronize...")
+
+	srcMetadata, err := c.listBlobsToMap(ctx, src, "source")
+	if err != nil {
+		return summary, err
+	}
+
+	dstMetadata, err := c.listDestinationBlobs(ctx, dst)
+	if err != nil {
+		return summary, err
+	}
+
+	var (
+		inSyncBlobs int
+		inSyncBytes int64
+
+		blobsToDst, blobsToSrc []blob.Metadata
+	)
+
+	for id, srcmd := range srcMetadata {
+		dstmd, exists := dstMetadata[id]
+
+		switch {
+		case !exists:
+			blobsToDst = append(blobsToDst, srcmd)
+		case srcmd.Timestamp.After(dstmd.Timestamp) && c.repositorySyncUpdate:
+			blobsToDst = append(blobsToDst, srcmd)
+		case dstmd.Timestamp.After(srcmd.Timestamp) && c.repositorySyncUpdate:
+			blobsToSrc = append(blobsToSrc, dstmd)
+		default:
+			inSyncBlobs++
+			inSyncBytes += srcmd.Length
+		}
+	}
+
+	for id, dstmd := range dstMetadata {
+		if _, exists := srcMetadata[id]; !exists {
+			blobsToSrc = append(blobsToSrc, dstmd)
+		}
+	}
+
+	log(ctx).Infof(
+		"  Found %v BLOBs to copy to destination (%v), %v to copy to source (%v), %v in sync",
+		len(blobsToDst), units.BytesStringBase10(blob.TotalLength(blobsToDst)),
+		len(blobsToSrc), units.BytesStringBase10(blob.TotalLength(blobsToSrc)),
+		inSyncBlobs,
+	)
+
+	// blobs in sync are the same in both directions.
+	summary.ToDestination.InSyncBlobs, summary.ToDestination.InSyncBytes = inSyncBlobs, inSyncBytes
+	summary.ToSource.InSyncBlobs, summary.ToSource.InSyncBytes = inSyncBlobs, inSyncBytes
+
+	if c.repositorySyncDryRun {
+		return summary, nil
+	}
+
+	log(ctx).Infof("Copying to destination...")
+
+	c.beginSyncProgress()
+	toDst, err := c.runSyncBlobs(ctx, src, dst, blobsToDst, nil, blob.TotalLength(blobsToDst))
+	c.finishSyncProcess()
+
+	toDst.InSyncBlobs, toDst.InSyncBytes = inSyncBlobs, inSyncBytes
+	summary.ToDestination = toDst
+
+	if err != nil {
+		return summary, err
+	}
 
+	log(ctx).Infof("Copying to source...")
+
+	c.beginSyncProgress()
+	toSrc, err := c.runSyncBlobs(ctx, dst, src, blobsToSrc, nil, blob.TotalLength(blobsToSrc))
 	c.finishSyncProcess()
 
-	return finalErr
+	toSrc.InSyncBlobs, toSrc.InSyncBytes = inSyncBlobs, inSyncBytes
+	summary.ToSource = toSrc
+
+	return summary, err
+}
+
+func (c *commandRepositorySyncTo) listDestinationBlobs(ctx context.Context, dst blob.Reader) (map[blob.ID]blob.Metadata, error) {
+	return c.listBlobsToMap(ctx, dst, "destination")
 }
 
-func (c *commandRepositorySyncTo) listDestinationBlobs(ctx context.Context, dst blob.Storage) (map[blob.ID]blob.Metadata, error) {
-	dstTotalBytes := int64(0)
-	dstMetadata := map[blob.ID]blob.Metadata{}
+func (c *commandRepositorySyncTo) listBlobsToMap(ctx context.Context, st blob.Reader, which string) (map[blob.ID]blob.Metadata, error) {
+	totalBytes := int64(0)
+	result := map[blob.ID]blob.Metadata{}
 
 	c.beginSyncProgress()
 
-	if err := dst.ListBlobs(ctx, "", func(bm blob.Metadata) error {
-		dstMetadata[bm.BlobID] = bm
-		dstTotalBytes += bm.Length
-		c.outputSyncProgress(fmt.Sprintf("  Found %v BLOBs in the destination repository (%v)", len(dstMetadata), units.BytesStringBase10(dstTotalBytes)))
+	if err := c.listBlobs(ctx, st, func(bm blob.Metadata) error {
+		result[bm.BlobID] = bm
+		totalBytes += bm.Length
+		c.outputSyncProgress(fmt.Sprintf("  Found %v BLOBs in the %v repository (%v)", len(result), which, units.BytesStringBase10(totalBytes)))
 		return nil
 	}); err != nil {
-		return nil, errors.Wrap(err, "error listing BLOBs in destination repository")
+		return nil, errors.Wrapf(err, "error listing BLOBs in %v repository", which)
 	}
 
 	c.finishSyncProcess()
 
-	return dstMetadata, nil
+	return result, nil
+}
+
+// syncPrefixes returns the list of non-overlapping blob ID prefixes to synchronize.
+func (c *commandRepositorySyncTo) syncPrefixes() []blob.ID {
+	if len(c.repositorySyncPrefixes) == 0 {
+		return []blob.ID{""}
+	}
+
+	sorted := append([]string(nil), c.repositorySyncPrefixes...)
+	sort.Strings(sorted)
+
+	var result []blob.ID
+
+	for _, p := range sorted {
+		// skip prefixes already covered by a shorter one, so that no blob is reported twice.
+		if len(result) > 0 && strings.HasPrefix(p, string(result[len(result)-1])) {
+			continue
+		}
+
+		result = append(result, blob.ID(p))
+	}
+
+	return result
+}
+
+// listBlobs lists blobs with all sync prefixes, possibly in parallel. The callback is never invoked concurrently.
+func (c *commandRepositorySyncTo) listBlobs(ctx context.Context, st blob.Reader, cb func(bm blob.Metadata) error) error {
+	if c.repositorySyncListParallelism <= 1 {
+		return listBlobsWithPrefixes(ctx, st, c.syncPrefixes(), cb)
+	}
+
+	return listBlobsSharded(ctx, st, c.syncPrefixes(), c.repositorySyncListParallelism, cb)
+}
+
+// blobIDShardCharacters returns characters that blob IDs may contain, which are all printable ASCII characters.
+func blobIDShardCharacters() []byte {
+	var result []byte
+
+	for ch := byte(' '); ch <= '~'; ch++ {
+		result = append(result, ch)
+	}
+
+	return result
+}
+
+// listBlobsSharded lists blobs with the provided prefixes by splitting each of them into shards by the
+// next character of blob ID and listing up to the provided number of shards in parallel.
+// The blob whose ID is equal to the prefix itself is looked up separately, so every blob is listed exactly once
+// as long as its ID consists of printable ASCII characters, which is the case for all blobs written by kopia.
+// The callback is serialized, so it does not need to be safe for concurrent use.
+func listBlobsSharded(ctx context.Context, st blob.Reader, prefixes []blob.ID, parallelism int, cb func(bm blob.Metadata) error) error {
+	var mu sync.Mutex
+
+	serializedCallback := func(bm blob.Metadata) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return cb(bm)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	semaphore := make(chan struct{}, parallelism)
+
+	withSemaphore := func(f func() error) {
+		eg.Go(func() error {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			defer func() { <-semaphore }()
+
+			return f()
+		})
+	}
+
+	shardCharacters := blobIDShardCharacters()
+
+	for _, prefix := range prefixes {
+		prefix := prefix
+
+		if prefix != "" {
+			withSemaphore(func() error {
+				bm, err := st.GetMetadata(ctx, prefix)
+				if errors.Is(err, blob.ErrBlobNotFound) {
+					return nil
+				}
+
+				if err != nil {
+					return errors.Wrapf(err, "error getting metadata of %q", prefix)
+				}
+
+				return serializedCallback(bm)
+			})
+		}
+
+		for _, ch := range shardCharacters {
+			shard := prefix + blob.ID(ch)
+
+			withSemaphore(func() error {
+				return errors.Wrapf(st.ListBlobs(ctx, shard, serializedCallback), "error listing blobs with prefix %q", shard)
+			})
+		}
+	}
+
+	// nolint:wrapcheck
+	return eg.Wait()
+}
+
+func listBlobsWithPrefixes(ctx context.Context, st blob.Reader, prefixes []blob.ID, cb func(bm blob.Metadata) error) error {
+	for _, prefix := range prefixes {
+		if err := st.ListBlobs(ctx, prefix, cb); err != nil {
+			return errors.Wrapf(err, "error listing blobs with prefix %q", prefix)
+		}
+	}
+
+	return nil
 }
 
 func (c *commandRepositorySyncTo) beginSyncProgress() {
@@ -218,14 +612,26 @@ func (c *commandRepositorySyncTo) finishSyncProcess() {
 	c.out.printStderr("\r%v\n", c.lastSyncProgress)
 }
 
-func (c *commandRepositorySyncTo) runSyncBlobs(ctx context.Context, src blob.Reader, dst blob.Storage, blobsToCopy, blobsToDelete []blob.Metadata, totalBytes int64) error {
+// runSyncBlobs copies and deletes the provided blobs and returns the summary of work done, even on failure.
+func (c *commandRepositorySyncTo) runSyncBlobs(ctx context.Context, src blob.Reader, dst blob.Storage, blobsToCopy, blobsToDelete []blob.Metadata, totalBytes int64) (SyncSummary, error) {
+	if c.repositorySyncMaxUploadSpeed > 0 {
+		c.uploadThrottler = iothrottler.NewIOThrottlerPool(iothrottler.Bandwidth(c.repositorySyncMaxUploadSpeed) * iothrottler.BytesPerSecond)
+		defer c.uploadThrottler.ReleasePool()
+	}
+
 	eg, ctx := errgroup.WithContext(ctx)
-	copyCh := sliceToChannel(ctx, blobsToCopy)
-	deleteCh := sliceToChannel(ctx, blobsToDelete)
+	copyCh := sliceToChannel(ctx, eg, blobsToCopy)
+
+	deleteBatchSize := 1
+	if blob.SupportsBatchDelete(dst) {
+		deleteBatchSize = syncDeleteBatchSize
+	}
+
+	deleteCh := sliceToBatchChannel(ctx, eg, blobsToDelete, deleteBatchSize)
 
 	var progressMutex sync.Mutex
 
-	var totalCopied stats.CountSum
+	var totalCopied, totalDeleted stats.CountSum
 
 	tt := timetrack.Start()
 
@@ -255,37 +661,85 @@ func (c *commandRepositorySyncTo) runSyncBlobs(ctx context.Context, src blob.Rea
 				progressMutex.Unlock()
 			}
 
-			for m := range deleteCh {
-				log(ctx).Debugf("[%v] Deleting %v (%v bytes)...\n", workerID, m.BlobID, m.Length)
-				if err := syncDeleteBlob(ctx, m, dst); err != nil {
-					return errors.Wrapf(err, "error deleting %v", m.BlobID)
+			for batch := range deleteCh {
+				log(ctx).Debugf("[%v] Deleting %v blobs (%v bytes)...\n", workerID, len(batch), blob.TotalLength(batch))
+				if err := syncDeleteBlobs(ctx, batch, dst); err != nil {
+					return err
+				}
+
+				for _, m := range batch {
+					totalDeleted.Add(m.Length)
 				}
 			}
 			return nil
 		})
 	}
 
-	if err := eg.Wait(); err != nil {
-		return errors.Wrap(err, "error copying blobs")
+	err := eg.Wait()
+
+	numCopied, bytesCopied := totalCopied.Approximate()
+	numDeleted, bytesDeleted := totalDeleted.Approximate()
+	elapsed, speed := tt.Completed(float64(bytesCopied))
+
+	summary := SyncSummary{
+		BlobsCopied:  int(numCopied),
+		BytesCopied:  bytesCopied,
+		BlobsDeleted: int(numDeleted),
+		BytesDeleted: bytesDeleted,
+		Elapsed:      elapsed,
+		Speed:        speed,
 	}
 
-	return nil
+	return summary, errors.Wrap(err, "error copying blobs")
 }
 
-func sliceToChannel(ctx context.Context, md []blob.Metadata) chan blob.Metadata {
-	ch := make(chan blob.Metadata)
+// sliceToChannel returns a channel that produces all items in the provided slice.
+// The producer goroutine is part of the provided errgroup and is guaranteed to terminate once its
+// context is canceled, even if the consumers stopped reading.
+func sliceToChannel(ctx context.Context, eg *errgroup.Group, md []blob.Metadata) chan blob.Metadata {
+	ch := make(chan blob.Metadata, syncChannelBufferSize)
 
-	go func() {
+	eg.Go(func() error {
 		defer close(ch)
 
 		for _, it := range md {
 			select {
 			case ch <- it:
 			case <-ctx.Done():
-				return
+				return nil
 			}
 		}
-	}()
+
+		return nil
+	})
+
+	return ch
+}
+
+// sliceToBatchChannel is like sliceToChannel but produces batches of up to the provided number of items.
+func sliceToBatchChannel(ctx context.Context, eg *errgroup.Group, md []blob.Metadata, batchSize int) chan []blob.Metadata {
+	ch := make(chan []blob.Metadata, syncChannelBufferSize)
+
+	eg.Go(func() error {
+		defer close(ch)
+
+		for len(md) > 0 {
+			n := batchSize
+			if n > len(md) {
+				n = len(md)
+			}
+
+			select {
+			case ch <- md[0:n]:
+			case <-ctx.Done():
+				return nil
+			}
+
+			md = md[n:]
+		}
+
+		return nil
+	})
 
 	return ch
 }
@@ -301,11 +755,21 @@ func (c *commandRepositorySyncTo) syncCopyBlob(ctx context.Context, m blob.Metad
 		return errors.Wrapf(err, "error reading blob '%v' from source", m.BlobID)
 	}
 
+	if err := c.throttleUpload(data); err != nil {
+		return errors.Wrapf(err, "error throttling blob '%v'", m.BlobID)
+	}
+
 	if err := dst.PutBlob(ctx, m.BlobID, gather.FromSlice(data)); err != nil {
 		return errors.Wrapf(err, "error writing blob '%v' to destination", m.BlobID)
 	}
 
-	if c.repositorySyncTimes {
+	if c.repositorySyncVerify {
+		if err := verifyCopiedBlob(ctx, m.BlobID, data, dst); err != nil {
+			return err
+		}
+	}
+
+	if c.repositorySyncTimes && !c.skipSetTime {
 		if err := dst.SetTime(ctx, m.BlobID, m.Timestamp); err != nil {
 			if errors.Is(err, blob.ErrSetTimeUnsupported) {
 				c.setTimeUnsupportedOnce.Do(func() {
@@ -320,14 +784,69 @@ func (c *commandRepositorySyncTo) syncCopyBlob(ctx context.Context, m blob.Metad
 	return nil
 }
 
-func syncDeleteBlob(ctx context.Context, m blob.Metadata, dst blob.Storage) error {
-	err := dst.DeleteBlob(ctx, m.BlobID)
-
-	if errors.Is(err, blob.ErrBlobNotFound) {
+// throttleUpload blocks until the shared upload throttler allows the provided data to be written.
+func (c *commandRepositorySyncTo) throttleUpload(data []byte) error {
+	if c.uploadThrottler == nil {
 		return nil
 	}
 
-	return errors.Wrap(err, "error deleting blob")
+	r, err := c.uploadThrottler.AddReader(ioutil.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return errors.Wrap(err, "unable to attach throttler")
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	_, err = io.Copy(ioutil.Discard, r)
+
+	return errors.Wrap(err, "throttling error")
+}
+
+// verifyCopiedBlob re-reads the blob from destination and ensures it matches the source data.
+func verifyCopiedBlob(ctx context.Context, blobID blob.ID, srcData []byte, dst blob.Storage) error {
+	dstData, err := dst.GetBlob(ctx, blobID, 0, -1)
+	if err != nil {
+		return errors.Wrapf(err, "error reading back blob '%v' from destination", blobID)
+	}
+
+	if len(dstData) != len(srcData) {
+		return errors.Errorf("verification failed for '%v': destination length %v, expected %v", blobID, len(dstData), len(srcData))
+	}
+
+	if !bytes.Equal(dstData, srcData) {
+		return errors.Errorf("verification failed for '%v': destination contents differ from source", blobID)
+	}
+
+	return nil
+}
+
+// supportsSetTime determines whether the provided storage supports SetTime() by setting the time
+// of the format blob to its current value.
+func supportsSetTime(ctx context.Context, st blob.Storage) (bool, error) {
+	bm, err := st.GetMetadata(ctx, repo.FormatBlobID)
+	if err != nil {
+		return false, errors.Wrap(err, "error getting format blob metadata")
+	}
+
+	switch err := st.SetTime(ctx, repo.FormatBlobID, bm.Timestamp); {
+	case errors.Is(err, blob.ErrSetTimeUnsupported):
+		return false, nil
+	case err != nil:
+		return false, errors.Wrap(err, "error setting time of format blob")
+	default:
+		return true, nil
+	}
+}
+
+// syncDeleteBlobs deletes the provided blobs from destination, ignoring blobs that no longer exist.
+func syncDeleteBlobs(ctx context.Context, batch []blob.Metadata, dst blob.Storage) error {
+	for i, err := range blob.DeleteBlobs(ctx, dst, blob.IDsFromMetadata(batch)) {
+		if err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+			return errors.Wrapf(err, "error deleting %v", batch[i].BlobID)
+		}
+	}
+
+	return nil
 }
 
 func (c *commandRepositorySyncTo) ensureRepositoriesHaveSameFormatBlob(ctx context.Context, src blob.Reader, dst blob.Storage) error {
@@ -0,0 +1,676 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/timetrack"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrying"
+	"github.com/kopia/kopia/repo/content"
+)
+
+// truncatingStorage silently drops the last byte of each written blob.
+type truncatingStorage struct {
+	blob.Storage
+}
+
+func (s truncatingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes) error {
+	var tmp bytes.Buffer
+
+	if _, err := data.WriteTo(&tmp); err != nil {
+		return err
+	}
+
+	b := tmp.Bytes()
+
+	return s.Storage.PutBlob(ctx, id, gather.FromSlice(b[0:len(b)-1]))
+}
+
+// putCountingStorage counts the number of written blobs.
+type putCountingStorage struct {
+	blob.Storage
+
+	puts int
+}
+
+func (s *putCountingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes) error {
+	s.puts++
+
+	return s.Storage.PutBlob(ctx, id, data)
+}
+
+// listCountingStorage counts ListBlobs calls and the number of listed items.
+type listCountingStorage struct {
+	blob.Storage
+
+	mu        sync.Mutex
+	listCalls int
+	listed    int
+}
+
+func (s *listCountingStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb func(bm blob.Metadata) error) error {
+	s.mu.Lock()
+	s.listCalls++
+	s.mu.Unlock()
+
+	// nolint:wrapcheck
+	return s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		s.mu.Lock()
+		s.listed++
+		s.mu.Unlock()
+
+		return cb(bm)
+	})
+}
+
+func TestSyncCopyBlobVerify(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	src := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.NoError(t, src.PutBlob(ctx, "blob1", gather.FromSlice([]byte{1, 2, 3, 4})))
+
+	m, err := src.GetMetadata(ctx, "blob1")
+	require.NoError(t, err)
+
+	// without verification, the corruption goes unnoticed.
+	c := &commandRepositorySyncTo{}
+	require.NoError(t, c.syncCopyBlob(ctx, m, src, truncatingStorage{blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)}))
+
+	// with verification, the corruption is detected.
+	c = &commandRepositorySyncTo{repositorySyncVerify: true}
+	require.Error(t, c.syncCopyBlob(ctx, m, src, truncatingStorage{blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)}))
+
+	// verification succeeds on a healthy destination.
+	require.NoError(t, c.syncCopyBlob(ctx, m, src, blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)))
+}
+
+func TestSyncBlobsMaxUploadSpeed(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	const (
+		blobCount = 4
+		blobSize  = 5000
+		maxSpeed  = 10000
+	)
+
+	src := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	dst := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	for i := 0; i < blobCount; i++ {
+		require.NoError(t, src.PutBlob(ctx, blob.ID(fmt.Sprintf("blob%v", i)), gather.FromSlice(make([]byte, blobSize))))
+	}
+
+	blobsToCopy, err := blob.ListAllBlobs(ctx, src, "")
+	require.NoError(t, err)
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:           new(timetrack.Throttle),
+		repositorySyncParallelism:    blobCount,
+		repositorySyncMaxUploadSpeed: maxSpeed,
+	}
+
+	t0 := clock.Now()
+
+	_, err = c.runSyncBlobs(ctx, src, dst, blobsToCopy, nil, blob.TotalLength(blobsToCopy))
+	require.NoError(t, err)
+
+	// copying 20 KB at 10 KB/s must take at least one full second after the initial allowance.
+	require.GreaterOrEqual(t, clock.Since(t0), time.Second)
+
+	copied, err := blob.ListAllBlobs(ctx, dst, "")
+	require.NoError(t, err)
+	require.Len(t, copied, blobCount)
+}
+
+func TestSyncBidirectional(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	t0 := clock.Now().Add(-time.Hour)
+	t1 := t0.Add(time.Minute)
+
+	srcData := blobtesting.DataMap{}
+	srcTimes := map[blob.ID]time.Time{}
+	dstData := blobtesting.DataMap{}
+	dstTimes := map[blob.ID]time.Time{}
+
+	src := blobtesting.NewMapStorage(srcData, srcTimes, nil)
+	dst := blobtesting.NewMapStorage(dstData, dstTimes, nil)
+
+	put := func(data blobtesting.DataMap, times map[blob.ID]time.Time, id blob.ID, v string, ts time.Time) {
+		data[id] = []byte(v)
+		times[id] = ts
+	}
+
+	put(srcData, srcTimes, repo.FormatBlobID, "format", t0)
+	put(dstData, dstTimes, repo.FormatBlobID, "format", t0)
+	put(srcData, srcTimes, "only-in-src", "a", t0)
+	put(dstData, dstTimes, "only-in-dst", "b", t0)
+	put(srcData, srcTimes, "newer-in-src", "new", t1)
+	put(dstData, dstTimes, "newer-in-src", "old", t0)
+	put(srcData, srcTimes, "newer-in-dst", "old", t0)
+	put(dstData, dstTimes, "newer-in-dst", "new", t1)
+	put(srcData, srcTimes, "same", "same", t0)
+	put(dstData, dstTimes, "same", "same", t0)
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 1,
+		repositorySyncUpdate:      true,
+	}
+
+	// --times is required, otherwise blobs would be copied back and forth.
+	_, err := c.runBidirectionalSyncWithStorage(ctx, src, dst)
+	require.Error(t, err)
+	require.NotContains(t, dstData, blob.ID("only-in-src"))
+
+	c.repositorySyncTimes = true
+
+	summary, err := c.runBidirectionalSyncWithStorage(ctx, src, dst)
+	require.NoError(t, err)
+	require.Equal(t, 2, summary.ToDestination.BlobsCopied)
+	require.Equal(t, 2, summary.ToSource.BlobsCopied)
+	require.Equal(t, 2, summary.ToSource.InSyncBlobs)
+
+	want := blobtesting.DataMap{
+		repo.FormatBlobID: []byte("format"),
+		"only-in-src":     []byte("a"),
+		"only-in-dst":     []byte("b"),
+		"newer-in-src":    []byte("new"),
+		"newer-in-dst":    []byte("new"),
+		"same":            []byte("same"),
+	}
+
+	require.Equal(t, want, srcData)
+	require.Equal(t, want, dstData)
+	require.Equal(t, srcTimes, dstTimes)
+
+	// synchronizing again does not copy anything in either direction.
+	srcCounter := &putCountingStorage{Storage: src}
+	dstCounter := &putCountingStorage{Storage: dst}
+
+	_, err = c.runBidirectionalSyncWithStorage(ctx, srcCounter, dstCounter)
+	require.NoError(t, err)
+	require.Zero(t, srcCounter.puts)
+	require.Zero(t, dstCounter.puts)
+
+	// --delete is not allowed in bidirectional mode.
+	c.repositorySyncDelete = true
+	_, err = c.runBidirectionalSyncWithStorage(ctx, src, dst)
+	require.Error(t, err)
+
+	// incompatible format blobs prevent any copying.
+	c.repositorySyncDelete = false
+	put(dstData, dstTimes, repo.FormatBlobID, "other-format", t0)
+	put(srcData, srcTimes, "another-in-src", "c", t0)
+	_, err = c.runBidirectionalSyncWithStorage(ctx, src, dst)
+	require.Error(t, err)
+	require.NotContains(t, dstData, blob.ID("another-in-src"))
+}
+
+func TestSyncSummary(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	t0 := clock.Now().Add(-time.Hour)
+	t1 := t0.Add(time.Minute)
+
+	srcData := blobtesting.DataMap{}
+	srcTimes := map[blob.ID]time.Time{}
+	dstData := blobtesting.DataMap{}
+	dstTimes := map[blob.ID]time.Time{}
+
+	put := func(data blobtesting.DataMap, times map[blob.ID]time.Time, id blob.ID, v string, ts time.Time) {
+		data[id] = []byte(v)
+		times[id] = ts
+	}
+
+	put(srcData, srcTimes, repo.FormatBlobID, "format", t0)
+	put(dstData, dstTimes, repo.FormatBlobID, "format", t0)
+	put(srcData, srcTimes, "only-in-src", "aaaa", t0)
+	put(srcData, srcTimes, "newer-in-src", "bbbbbbbb", t1)
+	put(dstData, dstTimes, "newer-in-src", "old", t0)
+	put(dstData, dstTimes, "only-in-dst", "ccccc", t0)
+	put(srcData, srcTimes, "same", "dd", t0)
+	put(dstData, dstTimes, "same", "dd", t0)
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 2,
+		repositorySyncUpdate:      true,
+		repositorySyncDelete:      true,
+		repositorySyncDryRun:      true,
+	}
+
+	// dry run only reports blobs in sync.
+	summary, err := c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), blobtesting.NewMapStorage(dstData, dstTimes, nil))
+	require.NoError(t, err)
+	require.Equal(t, SyncSummary{InSyncBlobs: 2, InSyncBytes: 8}, summary)
+	require.Contains(t, dstData, blob.ID("only-in-dst"))
+
+	c.repositorySyncDryRun = false
+
+	summary, err = c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), blobtesting.NewMapStorage(dstData, dstTimes, nil))
+	require.NoError(t, err)
+	require.Equal(t, srcData, dstData)
+
+	require.Equal(t, 2, summary.BlobsCopied)
+	require.Equal(t, int64(12), summary.BytesCopied)
+	require.Equal(t, 1, summary.BlobsDeleted)
+	require.Equal(t, int64(5), summary.BytesDeleted)
+	require.Equal(t, 2, summary.InSyncBlobs)
+	require.Equal(t, int64(8), summary.InSyncBytes)
+	require.Greater(t, summary.Elapsed, time.Duration(0))
+	require.Greater(t, summary.Speed, 0.0)
+
+	// nothing to do on the second run.
+	summary, err = c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), blobtesting.NewMapStorage(dstData, dstTimes, nil))
+	require.NoError(t, err)
+	require.Zero(t, summary.BlobsCopied)
+	require.Zero(t, summary.BlobsDeleted)
+	require.Equal(t, 4, summary.InSyncBlobs)
+
+	// summary of work done before failure is returned along with the error.
+	someErr := errors.New("some error")
+
+	dst := &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(blobtesting.DataMap{repo.FormatBlobID: []byte("format")}, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"PutBlob": {{}, {Err: someErr}},
+		},
+	}
+
+	c = &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 1,
+	}
+
+	summary, err = c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), dst)
+	require.ErrorIs(t, err, someErr)
+	require.Equal(t, 1, summary.BlobsCopied)
+	require.Equal(t, 1, summary.InSyncBlobs)
+}
+
+// batchDeletingStorage implements blob.BatchDeleter and records sizes of deleted batches.
+type batchDeletingStorage struct {
+	blob.Storage
+
+	mu         sync.Mutex
+	batchSizes []int
+}
+
+func (s *batchDeletingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	s.mu.Lock()
+	s.batchSizes = append(s.batchSizes, len(ids))
+	s.mu.Unlock()
+
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		errs[i] = s.Storage.DeleteBlob(ctx, id)
+	}
+
+	return errs
+}
+
+func (s *batchDeletingStorage) SupportsBatchDelete() bool {
+	return true
+}
+
+func (s *batchDeletingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	return errors.Errorf("unexpected DeleteBlob(%v)", id)
+}
+
+func TestSyncDeleteBatches(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	const numBlobs = 250
+
+	var blobsToDelete []blob.Metadata
+
+	dstData := blobtesting.DataMap{}
+
+	for i := 0; i < numBlobs; i++ {
+		id := blob.ID(fmt.Sprintf("blob%v", i))
+		dstData[id] = []byte{1}
+		blobsToDelete = append(blobsToDelete, blob.Metadata{BlobID: id, Length: 1})
+	}
+
+	dst := &batchDeletingStorage{Storage: blobtesting.NewMapStorage(dstData, nil, nil)}
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 2,
+	}
+
+	summary, err := c.runSyncBlobs(ctx, dst, dst, nil, blobsToDelete, 0)
+	require.NoError(t, err)
+	require.Equal(t, numBlobs, summary.BlobsDeleted)
+	require.Empty(t, dstData)
+
+	sort.Ints(dst.batchSizes)
+	require.Equal(t, []int{50, syncDeleteBatchSize, syncDeleteBatchSize}, dst.batchSizes)
+
+	// storage without native batch deletion wrapped in a retrying storage deletes blobs one by one.
+	for _, bm := range blobsToDelete {
+		dstData[bm.BlobID] = []byte{1}
+	}
+
+	dc := &deleteCountingStorage{Storage: blobtesting.NewMapStorage(dstData, nil, nil)}
+	wrapped := retrying.NewWrapper(dc)
+
+	summary, err = c.runSyncBlobs(ctx, wrapped, wrapped, nil, blobsToDelete, 0)
+	require.NoError(t, err)
+	require.Equal(t, numBlobs, summary.BlobsDeleted)
+	require.Empty(t, dstData)
+	require.Equal(t, numBlobs, dc.deleteBlobsCalls)
+}
+
+// deleteCountingStorage forwards batches to a storage that does not natively support batch deletion
+// and counts DeleteBlobs calls.
+type deleteCountingStorage struct {
+	blob.Storage
+
+	mu               sync.Mutex
+	deleteBlobsCalls int
+}
+
+func (s *deleteCountingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	s.mu.Lock()
+	s.deleteBlobsCalls++
+	s.mu.Unlock()
+
+	return blob.DeleteBlobs(ctx, s.Storage, ids)
+}
+
+func (s *deleteCountingStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.Storage)
+}
+
+func TestSliceToChannelCancelation(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlogging.Context(t))
+	defer cancel()
+
+	md := make([]blob.Metadata, 10*syncChannelBufferSize)
+	for i := range md {
+		md[i].BlobID = blob.ID(fmt.Sprintf("b%v", i))
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	ch := sliceToChannel(ctx, eg, md)
+
+	// consume a few items and stop reading without draining the channel.
+	for i := 0; i < 5; i++ {
+		<-ch
+	}
+
+	cancel()
+
+	done := make(chan error)
+
+	go func() {
+		done <- eg.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("producer goroutine did not exit")
+	}
+
+	// remaining buffered items are drained and the channel is closed.
+	n := 0
+	for range ch {
+		n++
+	}
+
+	require.Less(t, n, len(md)-5)
+}
+
+func TestListBlobsSharded(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	shardCharacters := blobIDShardCharacters()
+
+	for i := 0; i < 5000; i++ {
+		id := make([]byte, 1+rnd.Intn(10))
+		for j := range id {
+			id[j] = shardCharacters[rnd.Intn(len(shardCharacters))]
+		}
+
+		require.NoError(t, st.PutBlob(ctx, blob.ID(id), gather.FromSlice([]byte{byte(i)})))
+	}
+
+	// blobs equal to the prefix and blob IDs with less common characters are listed.
+	require.NoError(t, st.PutBlob(ctx, "p", gather.FromSlice([]byte{1})))
+	require.NoError(t, st.PutBlob(ctx, "kopia", gather.FromSlice([]byte{1})))
+	require.NoError(t, st.PutBlob(ctx, "p~1", gather.FromSlice([]byte{1})))
+	require.NoError(t, st.PutBlob(ctx, "~2", gather.FromSlice([]byte{1})))
+	require.NoError(t, st.PutBlob(ctx, "x 3", gather.FromSlice([]byte{1})))
+	require.NoError(t, st.PutBlob(ctx, repo.FormatBlobID, gather.FromSlice([]byte{1})))
+
+	for _, prefixes := range [][]blob.ID{
+		{""},
+		{"p", "q"},
+		{"kopia", "x"},
+		{"no-such-prefix"},
+	} {
+		var serial, sharded []blob.ID
+
+		require.NoError(t, listBlobsWithPrefixes(ctx, st, prefixes, func(bm blob.Metadata) error {
+			serial = append(serial, bm.BlobID)
+			return nil
+		}))
+
+		require.NoError(t, listBlobsSharded(ctx, st, prefixes, 10, func(bm blob.Metadata) error {
+			sharded = append(sharded, bm.BlobID)
+			return nil
+		}))
+
+		require.ElementsMatch(t, serial, sharded, "prefixes: %v", prefixes)
+	}
+
+	// each blob is listed exactly once and each shard is listed with a single call.
+	cs := &listCountingStorage{Storage: st}
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:            new(timetrack.Throttle),
+		repositorySyncListParallelism: 10,
+	}
+
+	dstMetadata, err := c.listDestinationBlobs(ctx, cs)
+	require.NoError(t, err)
+
+	var total int
+
+	require.NoError(t, st.ListBlobs(ctx, "", func(bm blob.Metadata) error {
+		total++
+		return nil
+	}))
+
+	require.Len(t, dstMetadata, total)
+	require.Equal(t, total, cs.listed)
+	require.Equal(t, len(blobIDShardCharacters()), cs.listCalls)
+
+	// listing errors are returned.
+	someErr := errors.New("some error")
+
+	fs := &blobtesting.FaultyStorage{
+		Base: st,
+		Faults: map[string][]*blobtesting.Fault{
+			"ListBlobs": {{Err: someErr}},
+		},
+	}
+
+	require.ErrorIs(t, listBlobsSharded(ctx, fs, []blob.ID{""}, 10, func(bm blob.Metadata) error { return nil }), someErr)
+}
+
+func TestSyncWithParallelListing(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	t0 := clock.Now().Add(-time.Hour)
+
+	srcData := blobtesting.DataMap{}
+	srcTimes := map[blob.ID]time.Time{}
+	dstData := blobtesting.DataMap{}
+	dstTimes := map[blob.ID]time.Time{}
+
+	for _, m := range []map[blob.ID]time.Time{srcTimes, dstTimes} {
+		m[repo.FormatBlobID] = t0
+	}
+
+	srcData[repo.FormatBlobID] = []byte("format")
+	dstData[repo.FormatBlobID] = []byte("format")
+
+	for i := 0; i < 1000; i++ {
+		id := blob.ID(fmt.Sprintf("%c%x", "pqnx"[i%4], i))
+
+		srcData[id] = []byte{byte(i)}
+		srcTimes[id] = t0
+
+		if i%3 == 0 {
+			dstData[id] = []byte{byte(i)}
+			dstTimes[id] = t0
+		}
+	}
+
+	dstData["only-in-dst"] = []byte{1}
+	dstTimes["only-in-dst"] = t0
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:            new(timetrack.Throttle),
+		repositorySyncParallelism:     4,
+		repositorySyncListParallelism: 8,
+		repositorySyncDelete:          true,
+	}
+
+	_, err := c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), blobtesting.NewMapStorage(dstData, dstTimes, nil))
+	require.NoError(t, err)
+	require.Equal(t, srcData, dstData)
+}
+
+func TestSyncTimesUnsupportedDestination(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	t0 := clock.Now().Add(-time.Hour).Truncate(time.Second)
+
+	srcData := blobtesting.DataMap{
+		repo.FormatBlobID: []byte("format"),
+		"blob1":           []byte{1},
+		"blob2":           []byte{2},
+	}
+	srcTimes := map[blob.ID]time.Time{
+		repo.FormatBlobID: t0,
+		"blob1":           t0,
+		"blob2":           t0,
+	}
+
+	var setTimeCalls int
+
+	dstData := blobtesting.DataMap{}
+	dst := &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(dstData, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"SetTime": {{Repeat: 100, ErrCallback: func() error {
+				setTimeCalls++
+				return blob.ErrSetTimeUnsupported
+			}}},
+		},
+	}
+
+	c := &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 1,
+		repositorySyncTimes:       true,
+	}
+
+	_, err := c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), dst)
+	require.NoError(t, err)
+	require.Equal(t, srcData, dstData)
+
+	// only the probe attempted to set time.
+	require.Equal(t, 1, setTimeCalls)
+
+	// destination that supports setting time gets source times.
+	dstData = blobtesting.DataMap{}
+	dstTimes := map[blob.ID]time.Time{}
+
+	c = &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 1,
+		repositorySyncTimes:       true,
+	}
+
+	_, err = c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), blobtesting.NewMapStorage(dstData, dstTimes, nil))
+	require.NoError(t, err)
+	require.Equal(t, srcData, dstData)
+	require.True(t, dstTimes["blob1"].Equal(t0))
+	require.True(t, dstTimes["blob2"].Equal(t0))
+
+	// other errors when probing cause times not to be synchronized.
+	setTimeCalls = 0
+	dstData = blobtesting.DataMap{}
+	dst = &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(dstData, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"SetTime": {{Repeat: 100, ErrCallback: func() error {
+				setTimeCalls++
+				return errors.New("some error")
+			}}},
+		},
+	}
+
+	c = &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 1,
+		repositorySyncTimes:       true,
+	}
+
+	_, err = c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), dst)
+	require.NoError(t, err)
+	require.Equal(t, srcData, dstData)
+	require.Equal(t, 1, setTimeCalls)
+
+	// dry run does not probe destination.
+	setTimeCalls = 0
+
+	c = &commandRepositorySyncTo{
+		nextSyncOutputTime:        new(timetrack.Throttle),
+		repositorySyncParallelism: 1,
+		repositorySyncTimes:       true,
+		repositorySyncDryRun:      true,
+	}
+
+	_, err = c.runSyncWithStorage(ctx, blobtesting.NewMapStorage(srcData, srcTimes, nil), dst)
+	require.NoError(t, err)
+	require.Equal(t, 0, setTimeCalls)
+}
+
+func TestEnsureNoActiveSessions(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.NoError(t, st.PutBlob(ctx, "p1234", gather.FromSlice([]byte{1})))
+	require.NoError(t, ensureNoActiveSessions(ctx, st, "source"))
+
+	require.NoError(t, st.PutBlob(ctx, content.BlobIDPrefixSession+"1234", gather.FromSlice([]byte{1})))
+	require.Error(t, ensureNoActiveSessions(ctx, st, "source"))
+}
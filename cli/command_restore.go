@@ -17,6 +17,7 @@
 	"github.com/kopia/kopia/internal/timetrack"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/restore"
 	"github.com/kopia/kopia/snapshot/snapshotfs"
 )
@@ -101,16 +102,23 @@ type commandRestore struct {
 	restoreTargetPaths            []string
 	restoreOverwriteDirectories   bool
 	restoreOverwriteFiles         bool
+	restoreOnlyIfDifferent        bool
+	restoreVerifyContent          bool
 	restoreOverwriteSymlinks      bool
 	restoreConsistentAttributes   bool
 	restoreMode                   string
 	restoreParallel               int
 	restoreIgnorePermissionErrors bool
 	restoreSkipTimes              bool
+	restoreBirthTimes             bool
+	restoreRecordProvenance       bool
+	restoreStaging                bool
+	restoreDeleteExtra            bool
 	restoreSkipOwners             bool
 	restoreSkipPermissions        bool
 	restoreIncremental            bool
 	restoreIgnoreErrors           bool
+	restoreContinueOnError        bool
 	restoreShallowAtDepth         int32
 	minSizeForPlaceholder         int32
 
@@ -124,6 +132,8 @@ func (c *commandRestore) setup(svc appServices, parent commandParent) {
 	cmd.Arg("sources", restoreCommandSourcePathHelp).Required().StringsVar(&c.restoreTargetPaths)
 	cmd.Flag("overwrite-directories", "Overwrite existing directories").Default("true").BoolVar(&c.restoreOverwriteDirectories)
 	cmd.Flag("overwrite-files", "Specifies whether or not to overwrite already existing files").Default("true").BoolVar(&c.restoreOverwriteFiles)
+	cmd.Flag("overwrite-only-if-different", "Do not overwrite existing files that match on size and modification time").BoolVar(&c.restoreOnlyIfDifferent)
+	cmd.Flag("verify-content", "When used with --overwrite-only-if-different, also compare file hashes before skipping").BoolVar(&c.restoreVerifyContent)
 	cmd.Flag("overwrite-symlinks", "Specifies whether or not to overwrite already existing symlinks").Default("true").BoolVar(&c.restoreOverwriteSymlinks)
 	cmd.Flag("consistent-attributes", "When multiple snapshots match, fail if they have inconsistent attributes").Envar("KOPIA_RESTORE_CONSISTENT_ATTRIBUTES").BoolVar(&c.restoreConsistentAttributes)
 	cmd.Flag("mode", "Override restore mode").Default(restoreModeAuto).EnumVar(&c.restoreMode, restoreModeAuto, restoreModeLocal, restoreModeZip, restoreModeZipNoCompress, restoreModeTar, restoreModeTgz)
@@ -131,8 +141,13 @@ func (c *commandRestore) setup(svc appServices, parent commandParent) {
 	cmd.Flag("skip-owners", "Skip owners during restore").BoolVar(&c.restoreSkipOwners)
 	cmd.Flag("skip-permissions", "Skip permissions during restore").BoolVar(&c.restoreSkipPermissions)
 	cmd.Flag("skip-times", "Skip times during restore").BoolVar(&c.restoreSkipTimes)
+	cmd.Flag("restore-birth-times", "Restore file creation times where supported").BoolVar(&c.restoreBirthTimes)
+	cmd.Flag("delete-extra", "When overwriting directories, delete existing entries not present in the snapshot").BoolVar(&c.restoreDeleteExtra)
+	cmd.Flag("staging", "Restore into a temporary directory next to the target and move it into place only when the restore succeeds (replacing a non-empty directory requires --delete-extra)").BoolVar(&c.restoreStaging)
+	cmd.Flag("record-provenance", "Record the snapshot source of each restored file in the '"+restore.ProvenanceXattrName+"' extended attribute where supported").BoolVar(&c.restoreRecordProvenance)
 	cmd.Flag("ignore-permission-errors", "Ignore permission errors").Default("true").BoolVar(&c.restoreIgnorePermissionErrors)
 	cmd.Flag("ignore-errors", "Ignore all errors").BoolVar(&c.restoreIgnoreErrors)
+	cmd.Flag("continue-on-error", "Continue restoring remaining entries after an error and report all failures at the end").BoolVar(&c.restoreContinueOnError)
 	cmd.Flag("skip-existing", "Skip files and symlinks that exist in the output").BoolVar(&c.restoreIncremental)
 	cmd.Flag("shallow", "Shallow restore the directory hierarchy starting at this level (default is to deep restore the entire hierarchy.)").Int32Var(&c.restoreShallowAtDepth)
 	cmd.Flag("shallow-minsize", "When doing a shallow restore, write actual files instead of placeholders smaller than this size.").Int32Var(&c.minSizeForPlaceholder)
@@ -210,14 +225,19 @@ func (c *commandRestore) restoreOutput(ctx context.Context) (restore.Output, err
 	switch m {
 	case restoreModeLocal:
 		return &restore.FilesystemOutput{
-			TargetPath:             targetpath,
-			OverwriteDirectories:   c.restoreOverwriteDirectories,
-			OverwriteFiles:         c.restoreOverwriteFiles,
-			OverwriteSymlinks:      c.restoreOverwriteSymlinks,
-			IgnorePermissionErrors: c.restoreIgnorePermissionErrors,
-			SkipOwners:             c.restoreSkipOwners,
-			SkipPermissions:        c.restoreSkipPermissions,
-			SkipTimes:              c.restoreSkipTimes,
+			TargetPath:               targetpath,
+			OverwriteDirectories:     c.restoreOverwriteDirectories,
+			OverwriteFiles:           c.restoreOverwriteFiles,
+			OverwriteOnlyIfDifferent: c.restoreOnlyIfDifferent,
+			VerifyContentBeforeSkip:  c.restoreVerifyContent,
+			OverwriteSymlinks:        c.restoreOverwriteSymlinks,
+			IgnorePermissionErrors:   c.restoreIgnorePermissionErrors,
+			SkipOwners:               c.restoreSkipOwners,
+			SkipPermissions:          c.restoreSkipPermissions,
+			SkipTimes:                c.restoreSkipTimes,
+			RestoreBirthTimes:        c.restoreBirthTimes,
+			RemoveExtraEntries:       c.restoreDeleteExtra,
+			StagingMode:              c.restoreStaging,
 		}, nil
 
 	case restoreModeZip, restoreModeZipNoCompress:
@@ -310,6 +330,11 @@ func (c *commandRestore) setupPlaceholderExpansion(ctx context.Context, rep repo
 
 	fso.TargetPath = rstp.target
 
+	// the placeholder only references the object, not the snapshot it came from.
+	if hde, ok := rootEntry.(snapshot.HasDirEntry); ok && c.restoreRecordProvenance {
+		fso.SnapshotProvenance = hde.DirEntry().ObjectID.String()
+	}
+
 	// restoreShallowAtDepth defaults to 0 when expanding a placeholder.
 	if c.restoreShallowAtDepth == unlimitedDepth {
 		c.restoreShallowAtDepth = 0
@@ -318,6 +343,24 @@ func (c *commandRestore) setupPlaceholderExpansion(ctx context.Context, rep repo
 	return rootEntry, nil
 }
 
+// snapshotProvenance returns the ID of the snapshot manifest the source resolves to followed by the nested path,
+// if any, falling back to the root object ID when the source is not the root of any snapshot.
+func (c *commandRestore) snapshotProvenance(ctx context.Context, rep repo.Repository, source string) (string, error) {
+	man, err := snapshotfs.FindSnapshotByIDWithPath(ctx, rep, source, c.restoreConsistentAttributes)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to find snapshot")
+	}
+
+	if man == nil {
+		return source, nil
+	}
+
+	parts := strings.SplitN(source, "/", 2) // nolint:gomnd
+	parts[0] = string(man.ID)
+
+	return strings.Join(parts, "/"), nil
+}
+
 func (c *commandRestore) run(ctx context.Context, rep repo.Repository) error {
 	output, oerr := c.restoreOutput(ctx)
 	if oerr != nil {
@@ -341,6 +384,15 @@ func (c *commandRestore) run(ctx context.Context, rep repo.Repository) error {
 			}
 
 			rootEntry = re
+
+			if fso, ok := output.(*restore.FilesystemOutput); ok && c.restoreRecordProvenance {
+				prov, err := c.snapshotProvenance(ctx, rep, rstp.source)
+				if err != nil {
+					return errors.Wrap(err, "unable to determine snapshot provenance")
+				}
+
+				fso.SnapshotProvenance = prov
+			}
 		}
 
 		eta := timetrack.Start()
@@ -349,6 +401,7 @@ func (c *commandRestore) run(ctx context.Context, rep repo.Repository) error {
 			Parallel:               c.restoreParallel,
 			Incremental:            c.restoreIncremental,
 			IgnoreErrors:           c.restoreIgnoreErrors,
+			ContinueOnError:        c.restoreContinueOnError,
 			RestoreDirEntryAtDepth: c.restoreShallowAtDepth,
 			MinSizeForPlaceholder:  c.minSizeForPlaceholder,
 			ProgressCallback: func(ctx context.Context, stats restore.Stats) {
@@ -386,6 +439,14 @@ func (c *commandRestore) run(ctx context.Context, rep repo.Repository) error {
 			},
 		})
 		if err != nil {
+			if len(st.FailedEntries) > 0 {
+				for _, fe := range st.FailedEntries {
+					log(ctx).Errorf("failed to restore %v: %v", fe.Path, fe.Error)
+				}
+
+				printRestoreStats(ctx, st)
+			}
+
 			return errors.Wrap(err, "error restoring")
 		}
 
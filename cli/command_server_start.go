@@ -11,7 +11,6 @@
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
@@ -45,6 +44,8 @@ type commandServerStart struct {
 	serverStartRandomPassword  bool
 	serverStartHtpasswdFile    string
 
+	serverStartUpgradePasswordHashVersion int
+
 	serverAuthCookieSingingKey string
 
 	serverStartShutdownWhenStdinClosed bool
@@ -78,6 +79,7 @@ func (c *commandServerStart) setup(svc advancedAppServices, parent commandParent
 	cmd.Flag("without-password", "Start the server without a password").Hidden().BoolVar(&c.serverStartWithoutPassword)
 	cmd.Flag("random-password", "Generate random password and print to stderr").Hidden().BoolVar(&c.serverStartRandomPassword)
 	cmd.Flag("htpasswd-file", "Path to htpasswd file that contains allowed user@hostname entries").Hidden().ExistingFileVar(&c.serverStartHtpasswdFile)
+	cmd.Flag("upgrade-password-hash-version", "Upgrade password hashes of repository users to the provided version when they authenticate (0 disables)").Default("0").IntVar(&c.serverStartUpgradePasswordHashVersion)
 
 	cmd.Flag("auth-cookie-signing-key", "Force particular auth cookie signing key").Envar("KOPIA_AUTH_COOKIE_SIGNING_KEY").Hidden().StringVar(&c.serverAuthCookieSingingKey)
 
@@ -174,7 +176,7 @@ func (c *commandServerStart) run(ctx context.Context, rep repo.Repository) error
 
 		go func() {
 			// consume all stdin and close the server when it closes
-			ioutil.ReadAll(os.Stdin) //nolint:errcheck
+			ioutil.ReadAll(c.svc.stdin()) //nolint:errcheck
 			log(ctx).Infof("Shutting down server...")
 			httpServer.Shutdown(ctx) //nolint:errcheck
 		}()
@@ -320,7 +322,7 @@ func (c *commandServerStart) getAuthenticator(ctx context.Context) (auth.Authent
 `)
 
 	// handle user accounts stored in the repository
-	authenticators = append(authenticators, auth.AuthenticateRepositoryUsers())
+	authenticators = append(authenticators, auth.AuthenticateRepositoryUsers(c.serverStartUpgradePasswordHashVersion))
 
 	return auth.CombineAuthenticators(authenticators...), nil
 }
@@ -5,6 +5,7 @@ type commandSnapshot struct {
 	moveHistory commandSnapshotCopyMoveHistory
 	create      commandSnapshotCreate
 	delete      commandSnapshotDelete
+	duplicates  commandSnapshotDuplicates
 	estimate    commandSnapshotEstimate
 	expire      commandSnapshotExpire
 	gc          commandSnapshotGC
@@ -20,6 +21,7 @@ func (c *commandSnapshot) setup(svc advancedAppServices, parent commandParent) {
 	c.moveHistory.setup(svc, cmd, true)
 	c.create.setup(svc, cmd)
 	c.delete.setup(svc, cmd)
+	c.duplicates.setup(svc, cmd)
 	c.estimate.setup(svc, cmd)
 	c.expire.setup(svc, cmd)
 	c.gc.setup(svc, cmd)
@@ -2,7 +2,8 @@
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
 	"time"
@@ -12,6 +13,8 @@
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/fs/virtualfs"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
 	"github.com/kopia/kopia/snapshot/snapshotfs"
@@ -28,8 +31,10 @@ type commandSnapshotCreate struct {
 	snapshotCreateDescription             string
 	snapshotCreateCheckpointInterval      time.Duration
 	snapshotCreateFailFast                bool
+	snapshotCreateStopOnError             bool
 	snapshotCreateForceHash               int
 	snapshotCreateParallelUploads         int
+	snapshotCreateLargeFileParallelHash   int
 	snapshotCreateStartTime               string
 	snapshotCreateEndTime                 string
 	snapshotCreateForceEnableActions      bool
@@ -37,6 +42,9 @@ type commandSnapshotCreate struct {
 	snapshotCreateStdinFileName           string
 	snapshotCreateCheckpointUploadLimitMB int64
 	snapshotCreateTags                    []string
+	snapshotCreateIncrementalFrom         string
+	snapshotCreateOutputIDFile            string
+	snapshotCreateCompression             string
 
 	jo  jsonOutput
 	svc appServices
@@ -52,14 +60,19 @@ func (c *commandSnapshotCreate) setup(svc appServices, parent commandParent) {
 	cmd.Flag("checkpoint-interval", "Frequency for creating periodic checkpoint.").DurationVar(&c.snapshotCreateCheckpointInterval)
 	cmd.Flag("description", "Free-form snapshot description.").StringVar(&c.snapshotCreateDescription)
 	cmd.Flag("fail-fast", "Fail fast when creating snapshot.").Envar("KOPIA_SNAPSHOT_FAIL_FAST").BoolVar(&c.snapshotCreateFailFast)
+	cmd.Flag("stop-on-error", "Skip remaining sources after the first source fails.").BoolVar(&c.snapshotCreateStopOnError)
 	cmd.Flag("force-hash", "Force hashing of source files for a given percentage of files [0..100]").Default("0").IntVar(&c.snapshotCreateForceHash)
 	cmd.Flag("parallel", "Upload N files in parallel").PlaceHolder("N").Default("0").IntVar(&c.snapshotCreateParallelUploads)
+	cmd.Flag("large-file-parallel-hashing", "Hash and upload up to N chunks of each large file in parallel").PlaceHolder("N").Default("0").IntVar(&c.snapshotCreateLargeFileParallelHash)
 	cmd.Flag("start-time", "Override snapshot start timestamp.").StringVar(&c.snapshotCreateStartTime)
 	cmd.Flag("end-time", "Override snapshot end timestamp.").StringVar(&c.snapshotCreateEndTime)
 	cmd.Flag("force-enable-actions", "Enable snapshot actions even if globally disabled on this client").Hidden().BoolVar(&c.snapshotCreateForceEnableActions)
 	cmd.Flag("force-disable-actions", "Disable snapshot actions even if globally enabled on this client").Hidden().BoolVar(&c.snapshotCreateForceDisableActions)
 	cmd.Flag("stdin-file", "File path to be used for stdin data snapshot.").StringVar(&c.snapshotCreateStdinFileName)
-	cmd.Flag("tags", "Tags applied on the snapshot. Must be provided in the <key>:<value> format.").StringsVar(&c.snapshotCreateTags)
+	cmd.Flag("incremental-from", "Use the snapshot with the provided ID as the only base for incremental upload.").PlaceHolder("SNAPSHOT_ID").StringVar(&c.snapshotCreateIncrementalFrom)
+	cmd.Flag("tags", "Tags applied on the snapshot. Must be provided in the <key>:<value> or <key>=<value> format.").StringsVar(&c.snapshotCreateTags)
+	cmd.Flag("compression", "Compression algorithm to use for this snapshot instead of the one set by policy.").EnumVar(&c.snapshotCreateCompression, supportedCompressionAlgorithms()[1:]...) // skip 'inherit'
+	cmd.Flag("output-id-file", "Write IDs of created snapshots to the provided file, one per line.").PlaceHolder("FILE").StringVar(&c.snapshotCreateOutputIDFile)
 
 	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
@@ -68,6 +81,13 @@ func (c *commandSnapshotCreate) setup(svc appServices, parent commandParent) {
 	cmd.Action(svc.repositoryWriterAction(c.run))
 }
 
+// snapshotCreateError is the JSON representation of a source that could not be snapshotted.
+type snapshotCreateError struct {
+	Source  snapshot.SourceInfo `json:"source"`
+	Error   string              `json:"error"`
+	Skipped bool                `json:"skipped,omitempty"`
+}
+
 func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWriter) error {
 	sources := c.snapshotCreateSources
 
@@ -98,35 +118,71 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 
 	u := c.setupUploader(rep)
 
-	var finalErrors []string
+	var (
+		finalErrors []string
+		snapshotIDs []string
+	)
+
+	jsonResults := []interface{}{}
 
 	tags, err := getTags(c.snapshotCreateTags)
 	if err != nil {
 		return err
 	}
 
-	for _, snapshotDir := range sources {
+	for i, snapshotDir := range sources {
 		if u.IsCanceled() {
 			log(ctx).Infof("Upload canceled")
 			break
 		}
 
-		dir, err := filepath.Abs(snapshotDir)
+		sourceInfo, err := c.sourceInfo(rep, snapshotDir)
 		if err != nil {
-			return errors.Errorf("invalid source: '%s': %s", snapshotDir, err)
+			return err
 		}
 
-		sourceInfo := snapshot.SourceInfo{
-			Path:     filepath.Clean(dir),
-			Host:     rep.ClientOptions().Hostname,
-			UserName: rep.ClientOptions().Username,
+		man, err := c.snapshotSingleSource(ctx, rep, u, sourceInfo, tags)
+		if man != nil {
+			snapshotIDs = append(snapshotIDs, string(man.ID))
+			jsonResults = append(jsonResults, c.jo.cleanupForJSON(man))
 		}
 
-		if err := c.snapshotSingleSource(ctx, rep, u, sourceInfo, tags); err != nil {
+		if err != nil {
 			finalErrors = append(finalErrors, err.Error())
+
+			if man == nil {
+				jsonResults = append(jsonResults, snapshotCreateError{Source: sourceInfo, Error: err.Error()})
+			}
+
+			if c.snapshotCreateStopOnError && i+1 < len(sources) {
+				skipped := sources[i+1:]
+
+				skippedResults, err := c.skippedSourceResults(rep, skipped)
+				if err != nil {
+					return err
+				}
+
+				jsonResults = append(jsonResults, skippedResults...)
+				finalErrors = append(finalErrors, fmt.Sprintf("skipped %v remaining source(s): %v", len(skipped), strings.Join(skipped, ", ")))
+
+				break
+			}
+		}
+	}
+
+	if c.jo.jsonOutput {
+		// single source produces a single object, multiple sources produce an array.
+		if len(sources) == 1 && len(jsonResults) == 1 {
+			c.out.printStdout("%s\n", c.jo.jsonIndentedBytes(jsonResults[0], "  "))
+		} else {
+			c.out.printStdout("%s\n", c.jo.jsonIndentedBytes(jsonResults, "  "))
 		}
 	}
 
+	if err := c.writeSnapshotIDs(snapshotIDs); err != nil {
+		return err
+	}
+
 	if len(finalErrors) == 0 {
 		return nil
 	}
@@ -138,25 +194,77 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 	return errors.Errorf("encountered %v errors:\n%v", len(finalErrors), strings.Join(finalErrors, "\n"))
 }
 
+func (c *commandSnapshotCreate) sourceInfo(rep repo.RepositoryWriter, snapshotDir string) (snapshot.SourceInfo, error) {
+	dir, err := filepath.Abs(snapshotDir)
+	if err != nil {
+		return snapshot.SourceInfo{}, errors.Errorf("invalid source: '%s': %s", snapshotDir, err)
+	}
+
+	return snapshot.SourceInfo{
+		Path:     filepath.Clean(dir),
+		Host:     rep.ClientOptions().Hostname,
+		UserName: rep.ClientOptions().Username,
+	}, nil
+}
+
+// skippedSourceResults returns JSON results for sources that were not snapshotted because of --stop-on-error.
+func (c *commandSnapshotCreate) skippedSourceResults(rep repo.RepositoryWriter, sources []string) ([]interface{}, error) {
+	var result []interface{}
+
+	for _, s := range sources {
+		si, err := c.sourceInfo(rep, s)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, snapshotCreateError{Source: si, Error: "skipped after previous error", Skipped: true})
+	}
+
+	return result, nil
+}
+
+func (c *commandSnapshotCreate) writeSnapshotIDs(ids []string) error {
+	if c.snapshotCreateOutputIDFile == "" {
+		return nil
+	}
+
+	var contents string
+
+	for _, id := range ids {
+		contents += id + "\n"
+	}
+
+	if err := ioutil.WriteFile(c.snapshotCreateOutputIDFile, []byte(contents), 0o600); err != nil {
+		return errors.Wrap(err, "unable to write snapshot IDs")
+	}
+
+	return nil
+}
+
 func getTags(tagStrings []string) (map[string]string, error) {
-	numberOfPartsInTagString := 2
 	// tagKeyPrefix is the prefix for user defined tag keys.
 	tagKeyPrefix := "tag:"
 
 	tags := map[string]string{}
 
 	for _, tagkv := range tagStrings {
-		parts := strings.SplitN(tagkv, ":", numberOfPartsInTagString)
-		if len(parts) != numberOfPartsInTagString {
-			return nil, errors.New("Invalid tag format. Requires <key>:<value>")
+		// key and value are separated by the first ':' or '=', the value may contain either.
+		sep := strings.IndexAny(tagkv, ":=")
+		if sep < 0 {
+			return nil, errors.New("Invalid tag format. Requires <key>:<value> or <key>=<value>")
 		}
 
-		key := tagKeyPrefix + parts[0]
+		k, v := tagkv[0:sep], tagkv[sep+1:]
+		if k == "" || v == "" {
+			return nil, errors.Errorf("Tag <key> and <value> must not be empty. (%s)", tagkv)
+		}
+
+		key := tagKeyPrefix + k
 		if _, ok := tags[key]; ok {
-			return nil, errors.Errorf("Duplicate tag <key> found. (%s)", parts[0])
+			return nil, errors.Errorf("Duplicate tag <key> found. (%s)", k)
 		}
 
-		tags[key] = parts[1]
+		tags[key] = v
 	}
 
 	return tags, nil
@@ -200,8 +308,10 @@ func (c *commandSnapshotCreate) setupUploader(rep repo.RepositoryWriter) *snapsh
 
 	u.ForceHashPercentage = c.snapshotCreateForceHash
 	u.ParallelUploads = c.snapshotCreateParallelUploads
+	u.LargeFileParallelHashing = c.snapshotCreateLargeFileParallelHash
 
 	u.FailFast = c.snapshotCreateFailFast
+	u.OverrideCompressor = compression.Name(c.snapshotCreateCompression)
 	u.Progress = c.svc.getProgress()
 
 	return u
@@ -222,7 +332,7 @@ func startTimeAfterEndTime(startTime, endTime time.Time) bool {
 		startTime.After(endTime)
 }
 
-func (c *commandSnapshotCreate) snapshotSingleSource(ctx context.Context, rep repo.RepositoryWriter, u *snapshotfs.Uploader, sourceInfo snapshot.SourceInfo, tags map[string]string) error {
+func (c *commandSnapshotCreate) snapshotSingleSource(ctx context.Context, rep repo.RepositoryWriter, u *snapshotfs.Uploader, sourceInfo snapshot.SourceInfo, tags map[string]string) (*snapshot.Manifest, error) {
 	log(ctx).Infof("Snapshotting %v ...", sourceInfo)
 
 	var (
@@ -233,26 +343,26 @@ func (c *commandSnapshotCreate) snapshotSingleSource(ctx context.Context, rep re
 
 	if c.snapshotCreateStdinFileName != "" {
 		// stdin source will be snapshotted using a virtual static root directory with a single streaming file entry
-		// Create a new static directory with the given name and add a streaming file entry with os.Stdin reader
+		// Create a new static directory with the given name and add a streaming file entry with stdin reader
 		fsEntry = virtualfs.NewStaticDirectory(sourceInfo.Path, fs.Entries{
-			virtualfs.StreamingFileFromReader(c.snapshotCreateStdinFileName, os.Stdin),
+			virtualfs.StreamingFileFromReader(c.snapshotCreateStdinFileName, c.svc.stdin()),
 		})
 		setManual = true
 	} else {
 		fsEntry, err = getLocalFSEntry(ctx, sourceInfo.Path)
 		if err != nil {
-			return errors.Wrap(err, "unable to get local filesystem entry")
+			return nil, errors.Wrap(err, "unable to get local filesystem entry")
 		}
 	}
 
-	previous, err := findPreviousSnapshotManifest(ctx, rep, sourceInfo, nil)
+	previous, err := c.getPreviousSnapshotManifests(ctx, rep, sourceInfo)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	policyTree, err := policy.TreeForSource(ctx, rep, sourceInfo)
 	if err != nil {
-		return errors.Wrap(err, "unable to get policy tree")
+		return nil, errors.Wrap(err, "unable to get policy tree")
 	}
 
 	log(ctx).Debugf("uploading %v using %v previous manifests", sourceInfo, len(previous))
@@ -261,7 +371,7 @@ func (c *commandSnapshotCreate) snapshotSingleSource(ctx context.Context, rep re
 	if err != nil {
 		// fail-fast uploads will fail here without recording a manifest, other uploads will
 		// possibly fail later.
-		return errors.Wrap(err, "upload error")
+		return nil, errors.Wrap(err, "upload error")
 	}
 
 	manifest.Description = c.snapshotCreateDescription
@@ -289,26 +399,26 @@ func (c *commandSnapshotCreate) snapshotSingleSource(ctx context.Context, rep re
 	}
 
 	if _, err = snapshot.SaveSnapshot(ctx, rep, manifest); err != nil {
-		return errors.Wrap(err, "cannot save manifest")
+		return nil, errors.Wrap(err, "cannot save manifest")
 	}
 
 	if _, err = policy.ApplyRetentionPolicy(ctx, rep, sourceInfo, true); err != nil {
-		return errors.Wrap(err, "unable to apply retention policy")
+		return nil, errors.Wrap(err, "unable to apply retention policy")
 	}
 
 	if setManual {
 		if err = policy.SetManual(ctx, rep, sourceInfo); err != nil {
-			return errors.Wrap(err, "unable to set manual field in scheduling policy for source")
+			return nil, errors.Wrap(err, "unable to set manual field in scheduling policy for source")
 		}
 	}
 
 	if ferr := rep.Flush(ctx); ferr != nil {
-		return errors.Wrap(ferr, "flush error")
+		return nil, errors.Wrap(ferr, "flush error")
 	}
 
 	c.svc.getProgress().Finish()
 
-	return c.reportSnapshotStatus(ctx, manifest)
+	return manifest, c.reportSnapshotStatus(ctx, manifest)
 }
 
 func (c *commandSnapshotCreate) reportSnapshotStatus(ctx context.Context, manifest *snapshot.Manifest) error {
@@ -322,12 +432,15 @@ func (c *commandSnapshotCreate) reportSnapshotStatus(ctx context.Context, manife
 	snapID := manifest.ID
 
 	if c.jo.jsonOutput {
-		c.out.printStdout("%s\n", c.jo.jsonIndentedBytes(manifest, "  "))
 		return nil
 	}
 
 	log(ctx).Infof("Created%v snapshot with root %v and ID %v in %v", maybePartial, manifest.RootObjectID(), snapID, manifest.EndTime.Sub(manifest.StartTime).Truncate(time.Second))
 
+	if manifest.IncompleteReason == snapshotfs.IncompleteReasonCanceled {
+		log(ctx).Infof("Snapshot was canceled, the next snapshot of %v will resume from it.", sourceInfo)
+	}
+
 	if ds := manifest.RootEntry.DirSummary; ds != nil {
 		if ds.IgnoredErrorCount > 0 {
 			log(ctx).Errorf("Ignored %v error(s) while snapshotting %v.", ds.IgnoredErrorCount, sourceInfo)
@@ -341,6 +454,25 @@ func (c *commandSnapshotCreate) reportSnapshotStatus(ctx context.Context, manife
 	return nil
 }
 
+// getPreviousSnapshotManifests returns the snapshot manifests to be used as a base for incremental upload,
+// which is either the snapshot explicitly provided using --incremental-from or automatically determined ones.
+func (c *commandSnapshotCreate) getPreviousSnapshotManifests(ctx context.Context, rep repo.Repository, sourceInfo snapshot.SourceInfo) ([]*snapshot.Manifest, error) {
+	if c.snapshotCreateIncrementalFrom == "" {
+		return findPreviousSnapshotManifest(ctx, rep, sourceInfo, nil)
+	}
+
+	base, err := snapshot.LoadSnapshot(ctx, rep, manifest.ID(c.snapshotCreateIncrementalFrom))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load base snapshot %v", c.snapshotCreateIncrementalFrom)
+	}
+
+	if base.Source != sourceInfo {
+		return nil, errors.Errorf("base snapshot %v belongs to a different source %v", c.snapshotCreateIncrementalFrom, base.Source)
+	}
+
+	return []*snapshot.Manifest{base}, nil
+}
+
 // findPreviousSnapshotManifest returns the list of previous snapshots for a given source, including
 // last complete snapshot and possibly some number of incomplete snapshots following it.
 func findPreviousSnapshotManifest(ctx context.Context, rep repo.Repository, sourceInfo snapshot.SourceInfo, noLaterThan *time.Time) ([]*snapshot.Manifest, error) {
@@ -352,7 +484,7 @@ func findPreviousSnapshotManifest(ctx context.Context, rep repo.Repository, sour
 	// phase 1 - find latest complete snapshot.
 	var previousComplete *snapshot.Manifest
 
-	var previousCompleteStartTime time.Time
+	var previousCompleteEndTime time.Time
 
 	var result []*snapshot.Manifest
 
@@ -363,7 +495,7 @@ func findPreviousSnapshotManifest(ctx context.Context, rep repo.Repository, sour
 
 		if p.IncompleteReason == "" && (previousComplete == nil || p.StartTime.After(previousComplete.StartTime)) {
 			previousComplete = p
-			previousCompleteStartTime = p.StartTime
+			previousCompleteEndTime = p.EndTime
 		}
 	}
 
@@ -371,13 +503,14 @@ func findPreviousSnapshotManifest(ctx context.Context, rep repo.Repository, sour
 		result = append(result, previousComplete)
 	}
 
-	// add all incomplete snapshots after that
+	// add all incomplete snapshots after that, checkpoints made while the complete snapshot
+	// was being created are superseded by it.
 	for _, p := range man {
 		if noLaterThan != nil && p.StartTime.After(*noLaterThan) {
 			continue
 		}
 
-		if p.IncompleteReason != "" && p.StartTime.After(previousCompleteStartTime) {
+		if p.IncompleteReason != "" && p.StartTime.After(previousCompleteEndTime) {
 			result = append(result, p)
 		}
 	}
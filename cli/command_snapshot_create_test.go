@@ -0,0 +1,194 @@
+package cli_test
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotCreate_JSONAndOutputIDFile(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	dir1 := testutil.TempDirectory(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir1, "file1.txt"), []byte{1, 2, 3}, 0o600))
+
+	dir2 := testutil.TempDirectory(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir2, "file2.txt"), []byte{4, 5, 6}, 0o600))
+
+	idFile := filepath.Join(testutil.TempDirectory(t), "ids.txt")
+
+	// single source produces a single manifest.
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir1, "--json", "--output-id-file", idFile), &man)
+	require.NotEmpty(t, man.ID)
+	require.Equal(t, dir1, man.Source.Path)
+	require.Empty(t, man.IncompleteReason)
+	require.NotEmpty(t, man.RootObjectID())
+	require.Equal(t, string(man.ID)+"\n", mustReadFileString(t, idFile))
+
+	// multiple sources produce an array, failed sources are reported with an error.
+	var results []struct {
+		snapshot.Manifest
+
+		Error string `json:"error"`
+	}
+
+	missingDir := filepath.Join(dir1, "no-such-dir")
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir1, dir2, "--json", "--output-id-file", idFile), &results)
+	require.Len(t, results, 2)
+	require.Equal(t, dir1, results[0].Source.Path)
+	require.Equal(t, dir2, results[1].Source.Path)
+	require.Empty(t, results[0].Error)
+	require.Equal(t, string(results[0].ID)+"\n"+string(results[1].ID)+"\n", mustReadFileString(t, idFile))
+
+	stdout, _, err := env.Run(t, true, "snapshot", "create", dir1, missingDir, "--json", "--output-id-file", idFile)
+	require.Error(t, err)
+
+	results = nil
+	testutil.MustParseJSONLines(t, stdout, &results)
+	require.Len(t, results, 2)
+	require.NotEmpty(t, results[0].ID)
+	require.Empty(t, results[0].Error)
+	require.Empty(t, results[1].ID)
+	require.Equal(t, missingDir, results[1].Source.Path)
+	require.Contains(t, results[1].Error, "unable to get local filesystem entry")
+	require.Equal(t, string(results[0].ID)+"\n", mustReadFileString(t, idFile))
+}
+
+func TestSnapshotCreate_JSONPartial(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	dir := testutil.TempDirectory(t)
+
+	for _, fname := range []string{"file1.dat", "file2.dat", "file3.dat"} {
+		data := make([]byte, 2<<20)
+		rand.Read(data)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fname), data, 0o600))
+	}
+
+	idFile := filepath.Join(testutil.TempDirectory(t), "ids.txt")
+
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json", "--upload-limit-mb=1", "--output-id-file", idFile), &man)
+	require.NotEmpty(t, man.ID)
+	require.Equal(t, snapshotfs.IncompleteReasonLimitReached, man.IncompleteReason)
+	require.Equal(t, string(man.ID)+"\n", mustReadFileString(t, idFile))
+}
+
+func mustReadFileString(t *testing.T, fname string) string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(fname)
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestSnapshotCreate_Tags(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file1.txt"), []byte{1, 2, 3}, 0o600))
+
+	var tagged, untagged snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json", "--tags", "ci-build=1234", "--tags", "env:a=b"), &tagged)
+	require.Equal(t, map[string]string{"tag:ci-build": "1234", "tag:env": "a=b"}, tagged.Tags)
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json"), &untagged)
+	require.Empty(t, untagged.Tags)
+
+	var found []*snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "list", dir, "--json", "--tags", "ci-build:1234"), &found)
+	require.Len(t, found, 1)
+	require.Equal(t, tagged.ID, found[0].ID)
+
+	found = nil
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "list", "--json", "--tags", "ci-build:1234", "--tags", "env=a=b"), &found)
+	require.Len(t, found, 1)
+	require.Equal(t, tagged.ID, found[0].ID)
+
+	found = nil
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "list", dir, "--json", "--tags", "ci-build:9999"), &found)
+	require.Empty(t, found)
+
+	// invalid tags.
+	env.RunAndExpectFailure(t, "snapshot", "create", dir, "--tags", "no-value")
+	env.RunAndExpectFailure(t, "snapshot", "create", dir, "--tags", "=value")
+	env.RunAndExpectFailure(t, "snapshot", "create", dir, "--tags", "key=")
+	env.RunAndExpectFailure(t, "snapshot", "create", dir, "--tags", "key:")
+	env.RunAndExpectFailure(t, "snapshot", "create", dir, "--tags", "key=a", "--tags", "key:b")
+}
+
+func TestSnapshotCreate_StopOnError(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	good1 := testutil.TempDirectory(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(good1, "file1.txt"), []byte{1, 2, 3}, 0o600))
+
+	good2 := testutil.TempDirectory(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(good2, "file2.txt"), []byte{4, 5, 6}, 0o600))
+
+	bad := filepath.Join(good1, "no-such-dir")
+
+	// by default errors are collected and all sources are processed, also with --fail-fast.
+	env.RunAndExpectFailure(t, "snapshot", "create", good1, bad, good2)
+	require.Len(t, listSnapshotsJSON(t, env, good1), 1)
+	require.Len(t, listSnapshotsJSON(t, env, good2), 1)
+
+	env.RunAndExpectFailure(t, "snapshot", "create", good1, bad, good2, "--fail-fast")
+	require.Len(t, listSnapshotsJSON(t, env, good1), 2)
+	require.Len(t, listSnapshotsJSON(t, env, good2), 2)
+
+	// with --stop-on-error remaining sources are skipped after the first failure and reported.
+	stdout, stderr, err := env.Run(t, true, "snapshot", "create", good1, bad, good2, "--stop-on-error", "--json")
+	require.Error(t, err)
+	require.Len(t, listSnapshotsJSON(t, env, good1), 3)
+	require.Len(t, listSnapshotsJSON(t, env, good2), 2)
+	require.Contains(t, strings.Join(stderr, "\n"), "skipped 1 remaining source(s): "+good2)
+
+	var results []map[string]interface{}
+
+	testutil.MustParseJSONLines(t, stdout, &results)
+	require.Len(t, results, 3)
+	require.NotEmpty(t, results[0]["id"])
+	require.Equal(t, bad, results[1]["source"].(map[string]interface{})["path"])
+	require.NotEmpty(t, results[1]["error"])
+	require.NotContains(t, results[1], "skipped")
+	require.Equal(t, good2, results[2]["source"].(map[string]interface{})["path"])
+	require.Equal(t, true, results[2]["skipped"])
+
+	// all good sources succeed.
+	env.RunAndExpectSuccess(t, "snapshot", "create", good1, good2, "--stop-on-error")
+	require.Len(t, listSnapshotsJSON(t, env, good1), 4)
+	require.Len(t, listSnapshotsJSON(t, env, good2), 3)
+}
+
+func listSnapshotsJSON(t *testing.T, env *testenv.CLITest, source string) []*snapshot.Manifest {
+	t.Helper()
+
+	var result []*snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "list", source, "--json"), &result)
+
+	return result
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+type commandSnapshotDuplicates struct {
+	snapshotDuplicatesShowAll bool
+	snapshotDuplicatesOnly    bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+// snapshotDuplicatesGroup is a group of snapshots sharing the same root object.
+type snapshotDuplicatesGroup struct {
+	RootID    object.ID               `json:"rootID"`
+	Snapshots []*snapshotDuplicateRef `json:"snapshots"`
+}
+
+type snapshotDuplicateRef struct {
+	ID        manifest.ID         `json:"id"`
+	Source    snapshot.SourceInfo `json:"source"`
+	StartTime time.Time           `json:"startTime"`
+}
+
+func (c *commandSnapshotDuplicates) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("duplicates", "List snapshots grouped by their root object to find identical snapshots.")
+	cmd.Flag("all", "Show all shapshots (not just current username/host)").Short('a').BoolVar(&c.snapshotDuplicatesShowAll)
+	cmd.Flag("only", "Only show groups with multiple identical snapshots").BoolVar(&c.snapshotDuplicatesOnly)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandSnapshotDuplicates) run(ctx context.Context, rep repo.Repository) error {
+	ids, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list snapshots")
+	}
+
+	all, err := snapshot.LoadSnapshots(ctx, rep, ids)
+	if err != nil {
+		return errors.Wrap(err, "unable to load snapshots")
+	}
+
+	var manifests []*snapshot.Manifest
+
+	co := rep.ClientOptions()
+
+	for _, m := range all {
+		if c.snapshotDuplicatesShowAll || (m.Source.Host == co.Hostname && m.Source.UserName == co.Username) {
+			manifests = append(manifests, m)
+		}
+	}
+
+	var jl jsonList
+
+	jl.begin(&c.jo)
+	defer jl.end()
+
+	var duplicateCount int
+
+	for _, g := range snapshot.GroupByRootObjectID(manifests) {
+		if len(g) > 1 {
+			duplicateCount += len(g) - 1
+		} else if c.snapshotDuplicatesOnly {
+			continue
+		}
+
+		if c.jo.jsonOutput {
+			jl.emit(toSnapshotDuplicatesGroup(g))
+			continue
+		}
+
+		c.outputGroup(g)
+	}
+
+	if !c.jo.jsonOutput && duplicateCount > 0 {
+		c.out.printStderr("Found %v redundant snapshot(s) with identical roots.\n", duplicateCount)
+	}
+
+	return nil
+}
+
+func (c *commandSnapshotDuplicates) outputGroup(g []*snapshot.Manifest) {
+	col := defaultColor
+	suffix := ""
+
+	if len(g) > 1 {
+		col = warningColor
+		suffix = " (identical snapshots)"
+	}
+
+	col.Fprintf(c.out.stdout(), "%v%v\n", g[0].RootObjectID(), suffix) //nolint:errcheck
+
+	for _, m := range g {
+		c.out.printStdout("  %v %v manifest:%v\n", formatTimestamp(m.StartTime), m.Source, m.ID)
+	}
+}
+
+func toSnapshotDuplicatesGroup(g []*snapshot.Manifest) *snapshotDuplicatesGroup {
+	res := &snapshotDuplicatesGroup{
+		RootID: g[0].RootObjectID(),
+	}
+
+	for _, m := range g {
+		res.Snapshots = append(res.Snapshots, &snapshotDuplicateRef{
+			ID:        m.ID,
+			Source:    m.Source,
+			StartTime: m.StartTime,
+		})
+	}
+
+	return res
+}
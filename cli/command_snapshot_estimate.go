@@ -22,7 +22,9 @@ type commandSnapshotEstimate struct {
 	snapshotEstimateQuiet       bool
 	snapshotEstimateUploadSpeed float64
 	maxExamplesPerBucket        int
+	topFiles                    int
 
+	jo  jsonOutput
 	out textOutput
 }
 
@@ -33,15 +35,37 @@ func (c *commandSnapshotEstimate) setup(svc appServices, parent commandParent) {
 	cmd.Flag("quiet", "Do not display scanning progress").Short('q').BoolVar(&c.snapshotEstimateQuiet)
 	cmd.Flag("upload-speed", "Upload speed to use for estimation").Default("10").PlaceHolder("mbit/s").Float64Var(&c.snapshotEstimateUploadSpeed)
 	cmd.Flag("max-examples-per-bucket", "Max examples per bucket").Default("10").IntVar(&c.maxExamplesPerBucket)
+	cmd.Flag("top", "Show N largest included files").PlaceHolder("N").IntVar(&c.topFiles)
 	cmd.Action(svc.repositoryReaderAction(c.run))
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 }
 
+// snapshotEstimateResult is the JSON representation of the results of 'snapshot estimate --json'.
+type snapshotEstimateResult struct {
+	Source                 snapshot.SourceInfo       `json:"source"`
+	TotalFileCount         int32                     `json:"totalFileCount"`
+	TotalFileSize          int64                     `json:"totalFileSize"`
+	TotalDirectoryCount    int32                     `json:"totalDirectoryCount"`
+	ExcludedFileCount      int32                     `json:"excludedFileCount"`
+	ExcludedTotalFileSize  int64                     `json:"excludedTotalFileSize"`
+	ExcludedDirCount       int32                     `json:"excludedDirCount"`
+	ErrorCount             int32                     `json:"errorCount"`
+	IgnoredErrorCount      int32                     `json:"ignoredErrorCount"`
+	Included               snapshotfs.SampleBuckets  `json:"included"`
+	Excluded               snapshotfs.SampleBuckets  `json:"excluded"`
+	ExcludedDirs           []string                  `json:"excludedDirs"`
+	LargestFiles           []snapshotfs.FileWithSize `json:"largestFiles,omitempty"`
+	UploadSpeedMbps        float64                   `json:"uploadSpeedMbps"`
+	EstimatedUploadSeconds float64                   `json:"estimatedUploadSeconds"`
+}
+
 type estimateProgress struct {
 	stats        snapshot.Stats
 	included     snapshotfs.SampleBuckets
 	excluded     snapshotfs.SampleBuckets
 	excludedDirs []string
+	largest      *snapshotfs.LargestFiles
 	quiet        bool
 }
 
@@ -59,6 +83,10 @@ func (ep *estimateProgress) Error(ctx context.Context, filename string, err erro
 	}
 }
 
+func (ep *estimateProgress) IncludedFile(relativePath string, size int64) {
+	ep.largest.Add(relativePath, size)
+}
+
 func (ep *estimateProgress) Stats(ctx context.Context, st *snapshot.Stats, included, excluded snapshotfs.SampleBuckets, excludedDirs []string, final bool) {
 	ep.stats = *st
 	ep.included = included
@@ -91,6 +119,7 @@ func (c *commandSnapshotEstimate) run(ctx context.Context, rep repo.Repository)
 	var ep estimateProgress
 
 	ep.quiet = c.snapshotEstimateQuiet
+	ep.largest = snapshotfs.NewLargestFiles(c.topFiles)
 
 	policyTree, err := policy.TreeForSource(ctx, rep, sourceInfo)
 	if err != nil {
@@ -101,10 +130,45 @@ func (c *commandSnapshotEstimate) run(ctx context.Context, rep repo.Repository)
 		return errors.Wrap(err, "error estimating")
 	}
 
+	megabits := float64(ep.stats.TotalFileSize) * 8 / 1000000 //nolint:gomnd
+	seconds := megabits / c.snapshotEstimateUploadSpeed
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(snapshotEstimateResult{
+			Source:                 sourceInfo,
+			TotalFileCount:         ep.stats.TotalFileCount,
+			TotalFileSize:          ep.stats.TotalFileSize,
+			TotalDirectoryCount:    ep.stats.TotalDirectoryCount,
+			ExcludedFileCount:      ep.stats.ExcludedFileCount,
+			ExcludedTotalFileSize:  ep.stats.ExcludedTotalFileSize,
+			ExcludedDirCount:       ep.stats.ExcludedDirCount,
+			ErrorCount:             ep.stats.ErrorCount,
+			IgnoredErrorCount:      ep.stats.IgnoredErrorCount,
+			Included:               ep.included,
+			Excluded:               ep.excluded,
+			ExcludedDirs:           ep.excludedDirs,
+			LargestFiles:           ep.largest.Files(),
+			UploadSpeedMbps:        c.snapshotEstimateUploadSpeed,
+			EstimatedUploadSeconds: seconds,
+		}))
+
+		return nil
+	}
+
 	c.out.printStdout("Snapshot includes %v file(s), total size %v\n", ep.stats.TotalFileCount, units.BytesStringBase10(ep.stats.TotalFileSize))
 	c.showBuckets(ep.included, c.snapshotEstimateShowFiles)
 	c.out.printStdout("\n")
 
+	if largest := ep.largest.Files(); len(largest) > 0 {
+		c.out.printStdout("Largest %v included file(s):\n", len(largest))
+
+		for _, f := range largest {
+			c.out.printStdout(" - %v - %v\n", f.Path, units.BytesStringBase10(f.Size))
+		}
+
+		c.out.printStdout("\n")
+	}
+
 	if ep.stats.ExcludedFileCount > 0 {
 		c.out.printStdout("Snapshot excludes %v file(s), total size %v\n", ep.stats.ExcludedFileCount, units.BytesStringBase10(ep.stats.ExcludedTotalFileSize))
 		c.showBuckets(ep.excluded, true)
@@ -126,9 +190,6 @@ func (c *commandSnapshotEstimate) run(ctx context.Context, rep repo.Repository)
 		c.out.printStdout("Encountered %v error(s).\n", ep.stats.ErrorCount)
 	}
 
-	megabits := float64(ep.stats.TotalFileSize) * 8 / 1000000 //nolint:gomnd
-	seconds := megabits / c.snapshotEstimateUploadSpeed
-
 	c.out.printStdout("\n")
 	c.out.printStdout("Estimated upload time: %v at %v Mbit/s\n", time.Duration(seconds)*time.Second, c.snapshotEstimateUploadSpeed)
 
@@ -5,11 +5,13 @@
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -56,3 +58,148 @@ func TestSnapshotEstimate_NotADirectory(t *testing.T) {
 	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
 	env.RunAndExpectFailure(t, "snapshot", "estimate", filepath.Join(dir, "file1.txt"))
 }
+
+func TestSnapshotEstimate_TopFilesAndMaxFileSize(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "subdir"), 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "small.txt"), bytes.Repeat([]byte{1}, 1000), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "medium.txt"), bytes.Repeat([]byte{1}, 20000), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "large.txt"), bytes.Repeat([]byte{1}, 50000), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subdir", "huge.txt"), bytes.Repeat([]byte{1}, 80000), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subdir", "tiny.txt"), bytes.Repeat([]byte{1}, 10), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	out := env.RunAndExpectSuccess(t, "snapshot", "estimate", dir, "--top=3")
+	require.Contains(t, out, "Snapshot includes 5 file(s), total size 151 KB")
+	require.Equal(t, []string{
+		"Largest 3 included file(s):",
+		" - ./subdir/huge.txt - 80 KB",
+		" - ./large.txt - 50 KB",
+		" - ./medium.txt - 20 KB",
+	}, linesStartingAt(t, out, "Largest"))
+
+	// files above max size are excluded consistently with snapshot creation,
+	// but directories are not.
+	env.RunAndExpectSuccess(t, "policy", "set", "--max-file-size=30000", dir)
+
+	out = env.RunAndExpectSuccess(t, "snapshot", "estimate", dir, "--top=3")
+	require.Contains(t, out, "Snapshot includes 3 file(s), total size 21 KB")
+	require.Contains(t, out, "Snapshot excludes 2 file(s), total size 130 KB")
+	require.Contains(t, out, " - ./large.txt - 50 KB")
+	require.Contains(t, out, " - ./subdir/huge.txt - 80 KB")
+	require.Contains(t, out, "Snapshot excludes no directories.")
+	require.Equal(t, []string{
+		"Largest 3 included file(s):",
+		" - ./medium.txt - 20 KB",
+		" - ./small.txt - 1 KB",
+		" - ./subdir/tiny.txt - 10 B",
+	}, linesStartingAt(t, out, "Largest"))
+
+	// snapshot contains the same files as the estimate.
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json"), &man)
+	require.Equal(t, int64(3), man.RootEntry.DirSummary.TotalFileCount)
+	require.Equal(t, int64(21010), man.RootEntry.DirSummary.TotalFileSize)
+}
+
+// linesStartingAt returns lines starting with the one that has the given prefix, up to the next empty line.
+func linesStartingAt(t *testing.T, lines []string, prefix string) []string {
+	t.Helper()
+
+	for i, l := range lines {
+		if !strings.HasPrefix(l, prefix) {
+			continue
+		}
+
+		for j := i; j < len(lines); j++ {
+			if lines[j] == "" {
+				return lines[i:j]
+			}
+		}
+
+		return lines[i:]
+	}
+
+	t.Fatalf("no line starting with %q found in %v", prefix, lines)
+
+	return nil
+}
+
+func TestSnapshotEstimate_JSON(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "subdir"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "ignored"), 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1}, 75000), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file2.dat"), bytes.Repeat([]byte{1}, 50000), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subdir", "file3.txt"), bytes.Repeat([]byte{1}, 500), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ignored", "file4.txt"), bytes.Repeat([]byte{1}, 100), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "policy", "set", "--add-ignore", "*.dat", "--add-ignore", "ignored", dir)
+
+	var result struct {
+		Source                snapshot.SourceInfo `json:"source"`
+		TotalFileCount        int                 `json:"totalFileCount"`
+		TotalFileSize         int64               `json:"totalFileSize"`
+		TotalDirectoryCount   int                 `json:"totalDirectoryCount"`
+		ExcludedFileCount     int                 `json:"excludedFileCount"`
+		ExcludedTotalFileSize int64               `json:"excludedTotalFileSize"`
+		ExcludedDirCount      int                 `json:"excludedDirCount"`
+		ErrorCount            int                 `json:"errorCount"`
+		IgnoredErrorCount     int                 `json:"ignoredErrorCount"`
+		Included              []struct {
+			MinSize   int64    `json:"minSize"`
+			Count     int      `json:"count"`
+			TotalSize int64    `json:"totalSize"`
+			Examples  []string `json:"examples"`
+		} `json:"included"`
+		Excluded []struct {
+			MinSize   int64    `json:"minSize"`
+			Count     int      `json:"count"`
+			TotalSize int64    `json:"totalSize"`
+			Examples  []string `json:"examples"`
+		} `json:"excluded"`
+		ExcludedDirs []string `json:"excludedDirs"`
+		LargestFiles []struct {
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+		} `json:"largestFiles"`
+		UploadSpeedMbps        float64 `json:"uploadSpeedMbps"`
+		EstimatedUploadSeconds float64 `json:"estimatedUploadSeconds"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "estimate", dir, "--json", "--top=1", "--upload-speed=1"), &result)
+
+	require.Equal(t, dir, result.Source.Path)
+	require.Equal(t, 2, result.TotalFileCount)
+	require.Equal(t, int64(75500), result.TotalFileSize)
+	require.Equal(t, 2, result.TotalDirectoryCount)
+	require.Equal(t, 1, result.ExcludedFileCount)
+	require.Equal(t, int64(50000), result.ExcludedTotalFileSize)
+	require.Equal(t, 1, result.ExcludedDirCount)
+	require.Equal(t, []string{"./ignored"}, result.ExcludedDirs)
+	require.Equal(t, 0, result.ErrorCount)
+	require.Len(t, result.LargestFiles, 1)
+	require.Equal(t, "./file1.txt", result.LargestFiles[0].Path)
+	require.Equal(t, int64(75000), result.LargestFiles[0].Size)
+	require.Equal(t, 1.0, result.UploadSpeedMbps)
+	require.InDelta(t, 0.604, result.EstimatedUploadSeconds, 0.0001)
+
+	var bucketFiles int
+
+	var bucketSize int64
+
+	for _, b := range result.Included {
+		bucketFiles += b.Count
+		bucketSize += b.TotalSize
+	}
+
+	require.Equal(t, result.TotalFileCount, bucketFiles)
+	require.Equal(t, result.TotalFileSize, bucketSize)
+}
@@ -36,7 +36,7 @@ func (c *commandServerUserAddSet) setup(svc appServices, parent commandParent, i
 	cmd.Flag("ask-password", "Ask for user password").BoolVar(&c.userAskPassword)
 	cmd.Flag("user-password", "Password").StringVar(&c.userSetPassword)
 	cmd.Flag("user-password-hash", "Password hash").StringVar(&c.userSetPasswordHash)
-	cmd.Flag("user-password-hash-version", "Password hash version").Default("1").IntVar(&c.userSetPasswordHashVersion)
+	cmd.Flag("user-password-hash-version", "Password hash version, 2 (Argon2id) requires servers that support it").Default("1").IntVar(&c.userSetPasswordHashVersion)
 	cmd.Arg("username", "Username").Required().StringVar(&c.userSetName)
 	cmd.Action(svc.repositoryWriterAction(c.runServerUserAddSet))
 
@@ -74,7 +74,7 @@ func (c *commandServerUserAddSet) runServerUserAddSet(ctx context.Context, rep r
 	if p := c.userSetPassword; p != "" {
 		changed = true
 
-		if err := up.SetPassword(p); err != nil {
+		if err := up.SetPasswordWithVersion(p, c.userSetPasswordHashVersion); err != nil {
 			return errors.Wrap(err, "error setting password")
 		}
 	}
@@ -107,7 +107,7 @@ func (c *commandServerUserAddSet) runServerUserAddSet(ctx context.Context, rep r
 
 		changed = true
 
-		if err := up.SetPassword(pwd); err != nil {
+		if err := up.SetPasswordWithVersion(pwd, c.userSetPasswordHashVersion); err != nil {
 			return errors.Wrap(err, "error setting password")
 		}
 	}
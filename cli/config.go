@@ -67,6 +67,7 @@ func (c *App) optionsFromFlags(ctx context.Context) *repo.Options {
 	}
 
 	opts.DisableInternalLog = c.disableInternalLog
+	opts.TimeNowFunc = c.TimeNowFunc
 
 	return &opts
 }
@@ -13,11 +13,21 @@
 // RunSubcommand executes the subcommand asynchronously in current process
 // with flags in an isolated CLI environment and returns standard output and standard error.
 func (c *App) RunSubcommand(ctx context.Context, argsAndFlags []string) (stdout, stderr io.Reader, wait func() error, kill func()) {
+	return c.RunSubcommandWithStdin(ctx, nil, argsAndFlags)
+}
+
+// RunSubcommandWithStdin is like RunSubcommand but the subcommand reads its standard input from the provided reader.
+// When stdin is nil, the standard input of the current process is used.
+func (c *App) RunSubcommandWithStdin(ctx context.Context, stdin io.Reader, argsAndFlags []string) (stdout, stderr io.Reader, wait func() error, kill func()) {
 	kpapp := kingpin.New("test", "test")
 
 	stdoutReader, stdoutWriter := io.Pipe()
 	stderrReader, stderrWriter := io.Pipe()
 
+	if stdin != nil {
+		c.stdinReader = stdin
+	}
+
 	c.stdoutWriter = stdoutWriter
 	c.stderrWriter = stderrWriter
 	c.rootctx = logging.WithLogger(ctx, logging.Writer(stderrWriter))
@@ -25,16 +25,22 @@ func (c *storageSFTPFlags) setup(_ storageProviderServices, cmd *kingpin.CmdClau
 	cmd.Flag("username", "SFTP/SSH server username").Required().StringVar(&c.options.Username)
 	cmd.Flag("keyfile", "path to private key file for SFTP/SSH server").StringVar(&c.options.Keyfile)
 	cmd.Flag("key-data", "private key data").StringVar(&c.options.KeyData)
+	cmd.Flag("ssh-agent", "Authenticate using ssh-agent listening on SSH_AUTH_SOCK").BoolVar(&c.options.UseSSHAgent)
 	cmd.Flag("known-hosts", "path to known_hosts file").StringVar(&c.options.KnownHostsFile)
 	cmd.Flag("known-hosts-data", "known_hosts file entries").StringVar(&c.options.KnownHostsData)
 	cmd.Flag("embed-credentials", "Embed key and known_hosts in Kopia configuration").BoolVar(&c.embedCredentials)
 
+	cmd.Flag("jump-host", "SSH jump host (bastion) used to reach the SFTP/SSH server").StringVar(&c.options.JumpHost)
+	cmd.Flag("jump-host-port", "SSH jump host port").IntVar(&c.options.JumpHostPort)
+	cmd.Flag("jump-host-user", "SSH jump host username (defaults to --username)").StringVar(&c.options.JumpHostUser)
+
 	cmd.Flag("external", "Launch external passwordless SSH command").BoolVar(&c.options.ExternalSSH)
 	cmd.Flag("ssh-command", "SSH command").Default("ssh").StringVar(&c.options.SSHCommand)
 	cmd.Flag("ssh-args", "Arguments to external SSH command").StringVar(&c.options.SSHArguments)
 
 	cmd.Flag("flat", "Use flat directory structure").BoolVar(&c.connectFlat)
 	cmd.Flag("max-connections", "Maximum number of SFTP server connections to establish").Default("1").IntVar(&c.options.MaxConnections)
+	cmd.Flag("keepalive-interval", "Interval between SSH keepalive requests (0 to disable)").DurationVar(&c.options.KeepAliveInterval)
 	cmd.Flag("list-parallelism", "Set list parallelism").Hidden().IntVar(&c.options.ListParallelism)
 }
 
@@ -44,7 +50,7 @@ func (c *storageSFTPFlags) getOptions() (*sftp.Options, error) {
 	// nolint:nestif
 	if !sftpo.ExternalSSH {
 		if c.embedCredentials {
-			if sftpo.KeyData == "" {
+			if sftpo.KeyData == "" && sftpo.Keyfile != "" {
 				d, err := ioutil.ReadFile(sftpo.Keyfile)
 				if err != nil {
 					return nil, errors.Wrap(err, "unable to read key file")
@@ -75,8 +81,11 @@ func (c *storageSFTPFlags) getOptions() (*sftp.Options, error) {
 			}
 
 			sftpo.Keyfile = a
+
+		case sftpo.UseSSHAgent: // ok
+
 		default:
-			return nil, errors.Errorf("must provide either --keyfile or --key-data")
+			return nil, errors.Errorf("must provide either --keyfile or --key-data (or use --ssh-agent)")
 		}
 
 		switch {
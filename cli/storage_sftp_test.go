@@ -137,6 +137,26 @@ func TestSFTPOptions(t *testing.T) {
 				DirectoryShards: []int{},
 			},
 		},
+		// 7
+		{
+			input: storageSFTPFlags{
+				options: sftp.Options{
+					Host:           "some-host",
+					Port:           222,
+					Username:       "user",
+					KnownHostsFile: myKnownHostsFile,
+					UseSSHAgent:    true,
+				},
+				embedCredentials: true,
+			},
+			want: &sftp.Options{
+				Host:           "some-host",
+				Port:           222,
+				Username:       "user",
+				KnownHostsData: "fake-known-hosts-data",
+				UseSSHAgent:    true,
+			},
+		},
 	}
 
 	for i, tc := range cases {
@@ -68,6 +68,11 @@ type DirectoryWithSummary interface {
 	Summary(ctx context.Context) (*DirectorySummary, error)
 }
 
+// EntryWithBirthTime is optionally implemented by entries that know the time they were created at.
+type EntryWithBirthTime interface {
+	BirthTime() time.Time // zero time if unknown
+}
+
 // ErrorEntry represents entry in a Directory that had encountered an error or is unknown/unsupported (ErrUnknown).
 type ErrorEntry interface {
 	Entry
@@ -61,6 +61,19 @@ func (c *ignoreContext) shouldIncludeByName(path string, e fs.Entry) bool {
 	return true
 }
 
+func (c *ignoreContext) shouldIncludeBySize(path string, e fs.Entry) bool {
+	// max file size does not apply to directories.
+	if c.maxFileSize <= 0 || e.IsDir() || e.Size() <= c.maxFileSize {
+		return true
+	}
+
+	for _, oi := range c.onIgnore {
+		oi(path, e)
+	}
+
+	return false
+}
+
 func (c *ignoreContext) shouldIncludeByDevice(e fs.Entry, parent *ignoreDirectory) bool {
 	if !c.oneFileSystem {
 		return true
@@ -162,7 +175,7 @@ func (d *ignoreDirectory) Readdir(ctx context.Context) (fs.Entries, error) {
 			continue
 		}
 
-		if maxSize := thisContext.maxFileSize; maxSize > 0 && e.Size() > maxSize {
+		if !thisContext.shouldIncludeBySize(d.relativePath+"/"+e.Name(), e) {
 			continue
 		}
 
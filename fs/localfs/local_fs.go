@@ -23,6 +23,7 @@ type filesystemEntry struct {
 	name       string
 	size       int64
 	mtimeNanos int64
+	btimeNanos int64 // 0 if unknown
 	mode       os.FileMode
 	owner      fs.OwnerInfo
 	device     fs.DeviceInfo
@@ -50,6 +51,15 @@ func (e *filesystemEntry) ModTime() time.Time {
 	return time.Unix(0, e.mtimeNanos)
 }
 
+// BirthTime implements fs.EntryWithBirthTime.
+func (e *filesystemEntry) BirthTime() time.Time {
+	if e.btimeNanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, e.btimeNanos)
+}
+
 func (e *filesystemEntry) Sys() interface{} {
 	return nil
 }
@@ -77,6 +87,7 @@ func newEntry(fi os.FileInfo, parentDir string) filesystemEntry {
 		TrimShallowSuffix(fi.Name()),
 		fi.Size(),
 		fi.ModTime().UnixNano(),
+		platformSpecificBirthTimeNanos(fi),
 		fi.Mode(),
 		platformSpecificOwnerInfo(fi),
 		platformSpecificDeviceInfo(fi),
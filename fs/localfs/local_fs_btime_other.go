@@ -0,0 +1,13 @@
+// +build !windows
+
+package localfs
+
+import (
+	"os"
+)
+
+// platformSpecificBirthTimeNanos returns 0 since birth time is either not available through os.FileInfo
+// or cannot be restored on this platform, in which case capturing it would only change directory manifests.
+func platformSpecificBirthTimeNanos(fi os.FileInfo) int64 {
+	return 0
+}
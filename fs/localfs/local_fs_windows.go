@@ -2,6 +2,7 @@
 
 import (
 	"os"
+	"syscall"
 
 	"github.com/kopia/kopia/fs"
 )
@@ -13,3 +14,11 @@ func platformSpecificOwnerInfo(fi os.FileInfo) fs.OwnerInfo {
 func platformSpecificDeviceInfo(fi os.FileInfo) fs.DeviceInfo {
 	return fs.DeviceInfo{}
 }
+
+func platformSpecificBirthTimeNanos(fi os.FileInfo) int64 {
+	if fad, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return fad.CreationTime.Nanoseconds()
+	}
+
+	return 0
+}
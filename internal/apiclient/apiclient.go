@@ -5,6 +5,7 @@
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -49,15 +50,79 @@ func (c *KopiaAPIClient) Delete(ctx context.Context, urlSuffix string, onNotFoun
 	return c.runRequest(ctx, http.MethodDelete, c.BaseURL+urlSuffix, onNotFound, reqPayload, respPayload)
 }
 
+// GetStream is a helper that performs HTTP GET on a URL with the specified suffix and returns the response
+// body for the caller to consume without buffering it. The caller must close the returned reader.
+func (c *KopiaAPIClient) GetStream(ctx context.Context, urlSuffix string, onNotFound error) (io.ReadCloser, error) {
+	resp, err := c.sendRequest(ctx, http.MethodGet, c.BaseURL+urlSuffix, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponseStatus(resp, onNotFound); err != nil {
+		resp.Body.Close() //nolint:errcheck
+
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// GetStreamRange is like GetStream but uses HTTP Range header to request only length bytes starting at
+// the provided offset. Length of -1 means until the end. The server must respond with partial content.
+func (c *KopiaAPIClient) GetStreamRange(ctx context.Context, urlSuffix string, onNotFound error, offset, length int64) (io.ReadCloser, error) {
+	rangeHeader := fmt.Sprintf("bytes=%v-", offset)
+	if length >= 0 {
+		rangeHeader = fmt.Sprintf("bytes=%v-%v", offset, offset+length-1)
+	}
+
+	resp, err := c.sendRequest(ctx, http.MethodGet, c.BaseURL+urlSuffix, http.Header{"Range": {rangeHeader}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound && onNotFound != nil {
+		resp.Body.Close() //nolint:errcheck
+
+		return nil, onNotFound
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close() //nolint:errcheck
+
+		return nil, HTTPStatusError{resp.StatusCode, resp.Status}
+	}
+
+	return resp.Body, nil
+}
+
 func (c *KopiaAPIClient) runRequest(ctx context.Context, method, url string, notFoundError error, reqPayload, respPayload interface{}) error {
+	resp, err := c.sendRequest(ctx, method, url, nil, reqPayload)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if err := checkResponseStatus(resp, notFoundError); err != nil {
+		return err
+	}
+
+	return decodeResponse(resp, respPayload)
+}
+
+func (c *KopiaAPIClient) sendRequest(ctx context.Context, method, url string, header http.Header, reqPayload interface{}) (*http.Response, error) {
 	payload, contentType, err := requestReader(reqPayload)
 	if err != nil {
-		return errors.Wrap(err, "error getting reader")
+		return nil, errors.Wrap(err, "error getting reader")
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, url, payload)
 	if err != nil {
-		return errors.Wrap(err, "error creating request")
+		return nil, errors.Wrap(err, "error creating request")
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
 	}
 
 	if contentType != "" {
@@ -66,16 +131,10 @@ func (c *KopiaAPIClient) runRequest(ctx context.Context, method, url string, not
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "error running http request")
+		return nil, errors.Wrap(err, "error running http request")
 	}
 
-	defer resp.Body.Close() //nolint:errcheck
-
-	if resp.StatusCode == http.StatusNotFound && notFoundError != nil {
-		return notFoundError
-	}
-
-	return decodeResponse(resp, respPayload)
+	return resp, nil
 }
 
 func requestReader(reqPayload interface{}) (io.Reader, string, error) {
@@ -105,11 +164,19 @@ func (e HTTPStatusError) Error() string {
 	return e.ErrorMessage
 }
 
-func decodeResponse(resp *http.Response, respPayload interface{}) error {
+func checkResponseStatus(resp *http.Response, notFoundError error) error {
+	if resp.StatusCode == http.StatusNotFound && notFoundError != nil {
+		return notFoundError
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return HTTPStatusError{resp.StatusCode, resp.Status}
 	}
 
+	return nil
+}
+
+func decodeResponse(resp *http.Response, respPayload interface{}) error {
 	if respPayload == nil {
 		return nil
 	}
@@ -19,9 +19,43 @@ type repositoryUserAuthenticator struct {
 	nextRefreshTime             time.Time
 	userProfiles                map[string]*user.Profile
 	userProfileRefreshFrequency time.Duration
+	upgradePasswordHashVersion  int
 }
 
 func (ac *repositoryUserAuthenticator) IsValid(ctx context.Context, rep repo.Repository, username, password string) bool {
+	key, cached := ac.findUserProfile(ctx, rep, username)
+	if cached == nil {
+		// IsValidPassword can be safely called on nil and the call will take as much time as for a valid user
+		// thus not revealing anything about whether the user exists.
+		return cached.IsValidPassword(password)
+	}
+
+	// the cached profile is shared, upgrade the password hash on a copy.
+	p := *cached
+
+	valid, upgraded, err := p.VerifyPassword(password, ac.upgradePasswordHashVersion)
+	if err != nil {
+		log(ctx).Errorf("unable to upgrade password hash for %v: %v", p.Username, err)
+	}
+
+	if upgraded {
+		if err := repo.WriteSession(ctx, rep, repo.WriteSessionOptions{
+			Purpose: "upgradePasswordHash",
+		}, func(ctx context.Context, w repo.RepositoryWriter) error {
+			return user.SetUserProfile(ctx, w, &p)
+		}); err != nil {
+			log(ctx).Errorf("unable to save upgraded password hash for %v: %v", p.Username, err)
+		} else {
+			ac.replaceUserProfile(rep, key, cached, &p)
+		}
+	}
+
+	return valid
+}
+
+// findUserProfile returns the cached user profile matching the provided username and its key in the cache,
+// refreshing the cache if needed.
+func (ac *repositoryUserAuthenticator) findUserProfile(ctx context.Context, rep repo.Repository, username string) (string, *user.Profile) {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
 
@@ -46,9 +80,28 @@ func (ac *repositoryUserAuthenticator) IsValid(ctx context.Context, rep repo.Rep
 		}
 	}
 
-	// IsValidPassword can be safely called on nil and the call will take as much time as for a valid user
-	// thus not revealing anything about whether the user exists.
-	return ac.userProfiles[username].IsValidPassword(password)
+	// look up the exact username first to support profiles stored before usernames were normalized.
+	if p := ac.userProfiles[username]; p != nil {
+		return username, p
+	}
+
+	key := user.NormalizeUsername(username)
+
+	return key, ac.userProfiles[key]
+}
+
+// replaceUserProfile replaces the cached user profile with the one that has been saved,
+// unless the cache has been refreshed in the meantime.
+func (ac *repositoryUserAuthenticator) replaceUserProfile(rep repo.Repository, key string, old, saved *user.Profile) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if rep != ac.lastRep || ac.userProfiles[key] != old {
+		return
+	}
+
+	delete(ac.userProfiles, key)
+	ac.userProfiles[saved.Username] = saved
 }
 
 func (ac *repositoryUserAuthenticator) Refresh(ctx context.Context) error {
@@ -61,10 +114,12 @@ func (ac *repositoryUserAuthenticator) Refresh(ctx context.Context) error {
 }
 
 // AuthenticateRepositoryUsers returns authenticator that accepts username/password combinations
-// stored in 'user' manifests in the repository.
-func AuthenticateRepositoryUsers() Authenticator {
+// stored in 'user' manifests in the repository. After successful authentication, password hashes
+// older than upgradePasswordHashVersion are re-hashed and saved, 0 disables upgrades.
+func AuthenticateRepositoryUsers(upgradePasswordHashVersion int) Authenticator {
 	a := &repositoryUserAuthenticator{
 		userProfileRefreshFrequency: defaultProfileRefreshFrequency,
+		upgradePasswordHashVersion:  upgradePasswordHashVersion,
 	}
 
 	return a
@@ -10,10 +10,11 @@
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/internal/user"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
 )
 
 func TestRepositoryAuthenticator(t *testing.T) {
-	a := auth.AuthenticateRepositoryUsers()
+	a := auth.AuthenticateRepositoryUsers(0)
 	ctx, env := repotesting.NewEnvironment(t)
 
 	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{},
@@ -32,6 +33,11 @@ func(ctx context.Context, w repo.RepositoryWriter) error {
 	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password11", false)
 	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1a", "password1", false)
 	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1a", "password1a", false)
+
+	// usernames are normalized before lookup.
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "User1@HOST1", "password1", true)
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, " user1@host1 ", "password1", true)
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "User1@HOST1", "password2", false)
 }
 
 func verifyRepoAuthenticator(ctx context.Context, t *testing.T, a auth.Authenticator, r repo.Repository, username, password string, want bool) {
@@ -41,3 +47,72 @@ func verifyRepoAuthenticator(ctx context.Context, t *testing.T, a auth.Authentic
 		t.Errorf("invalid authenticator result for %v/%v: %v, want %v", username, password, got, want)
 	}
 }
+
+func TestRepositoryAuthenticatorUpgradesPasswordHash(t *testing.T) {
+	a := auth.AuthenticateRepositoryUsers(user.PasswordHashVersionArgon2id)
+	ctx, env := repotesting.NewEnvironment(t)
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{},
+		func(ctx context.Context, w repo.RepositoryWriter) error {
+			p := &user.Profile{
+				Username: "user1@host1",
+			}
+
+			require.NoError(t, p.SetPasswordWithVersion("password1", user.PasswordHashVersionScrypt))
+
+			return user.SetUserProfile(ctx, w, p)
+		}))
+
+	// failed authentication does not upgrade the hash.
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password2", false)
+
+	p, err := user.GetUserProfile(ctx, env.Repository, "user1@host1")
+	require.NoError(t, err)
+	require.Equal(t, user.PasswordHashVersionScrypt, p.PasswordHashVersion)
+
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password1", true)
+
+	p, err = user.GetUserProfile(ctx, env.Repository, "user1@host1")
+	require.NoError(t, err)
+	require.Equal(t, user.PasswordHashVersionArgon2id, p.PasswordHashVersion)
+	require.True(t, p.IsValidPassword("password1"))
+
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password1", true)
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password2", false)
+}
+
+func TestRepositoryAuthenticatorUpgradesLegacyProfile(t *testing.T) {
+	a := auth.AuthenticateRepositoryUsers(user.PasswordHashVersionArgon2id)
+	ctx, env := repotesting.NewEnvironment(t)
+
+	// simulate legacy profile stored with mixed-case username.
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{},
+		func(ctx context.Context, w repo.RepositoryWriter) error {
+			p := &user.Profile{
+				Username: "User1@Host1",
+			}
+
+			require.NoError(t, p.SetPasswordWithVersion("password1", user.PasswordHashVersionScrypt))
+
+			_, err := w.PutManifest(ctx, map[string]string{
+				manifest.TypeLabelKey:        user.ManifestType,
+				user.UsernameAtHostnameLabel: p.Username,
+			}, p)
+
+			return err
+		}))
+
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "User1@Host1", "password1", true)
+
+	// the upgraded profile is stored under the normalized username.
+	profiles, err := user.ListUserProfiles(ctx, env.Repository)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	require.Equal(t, "user1@host1", profiles[0].Username)
+	require.Equal(t, user.PasswordHashVersionArgon2id, profiles[0].PasswordHashVersion)
+	require.True(t, profiles[0].IsValidPassword("password1"))
+
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "User1@Host1", "password1", true)
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password1", true)
+	verifyRepoAuthenticator(ctx, t, a, env.Repository, "user1@host1", "password2", false)
+}
@@ -0,0 +1,54 @@
+package blobtesting
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// BatchDeletingStorage wraps a storage, implements blob.BatchDeleter and records sizes of deleted batches.
+// Deleting individual blobs fails, which ensures that batches are forwarded by wrappers.
+type BatchDeletingStorage struct {
+	blob.Storage
+
+	mu         sync.Mutex
+	batchSizes []int
+}
+
+// DeleteBlobs implements blob.BatchDeleter.
+func (s *BatchDeletingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	s.mu.Lock()
+	s.batchSizes = append(s.batchSizes, len(ids))
+	s.mu.Unlock()
+
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		errs[i] = s.Storage.DeleteBlob(ctx, id)
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *BatchDeletingStorage) SupportsBatchDelete() bool {
+	return true
+}
+
+// DeleteBlob implements blob.Storage and always fails.
+func (s *BatchDeletingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	return errors.Errorf("unexpected DeleteBlob(%v)", id)
+}
+
+// BatchSizes returns sizes of all batches deleted so far.
+func (s *BatchDeletingStorage) BatchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]int(nil), s.batchSizes...)
+}
+
+var _ blob.BatchDeleter = (*BatchDeletingStorage)(nil)
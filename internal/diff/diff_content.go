@@ -0,0 +1,275 @@
+package diff
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+// ChangeType describes how an entry differs between two filesystems.
+type ChangeType string
+
+// Supported change types.
+const (
+	ChangeAdded       ChangeType = "added"
+	ChangeRemoved     ChangeType = "removed"
+	ChangeModified    ChangeType = "modified"
+	ChangeTypeChanged ChangeType = "typeChanged"
+)
+
+// Change describes a single entry whose contents differ between two filesystems.
+type Change struct {
+	Path        string     `json:"path"`
+	Type        ChangeType `json:"type"`
+	IsDir       bool       `json:"isDir,omitempty"`
+	OldObjectID object.ID  `json:"oldObjectID,omitempty"`
+	NewObjectID object.ID  `json:"newObjectID,omitempty"`
+	OldSize     int64      `json:"oldSize"`
+	NewSize     int64      `json:"newSize"`
+}
+
+// ContentChanges walks two filesystem trees side by side and returns entries whose contents differ,
+// ignoring metadata-only changes such as permissions or modification times.
+//
+// Entries with identical object IDs are considered unchanged without descending into them, thanks to
+// content-addressable storage. Entries without object IDs are compared by size and modification time.
+// Added and removed directories are reported along with all entries they contain.
+//
+// Directories are compared in parallel using snapshotfs.TreeWalker and the changes are returned
+// in the order of a depth-first walk of both trees.
+func ContentChanges(ctx context.Context, e1, e2 fs.Entry) ([]Change, error) {
+	var (
+		mu      sync.Mutex
+		changes []Change
+	)
+
+	root := newEntryPair(".", e1, e2)
+	if root == nil {
+		return nil, nil
+	}
+
+	w := snapshotfs.NewTreeWalker()
+	w.RootEntries = []fs.Entry{root}
+	w.EntryID = func(e fs.Entry) interface{} {
+		return pairOf(e).path
+	}
+	w.ProgressCallback = func(ctx context.Context, enqueued, active, completed int64) {
+		log(ctx).Debugf("compared %v entries, discovered %v", completed, enqueued)
+	}
+	w.ObjectCallback = func(e fs.Entry) error {
+		if c := pairOf(e).change; c != nil {
+			mu.Lock()
+			changes = append(changes, *c)
+			mu.Unlock()
+		}
+
+		return nil
+	}
+
+	if err := w.Run(ctx); err != nil {
+		return nil, errors.Wrap(err, "error comparing trees")
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return pathLess(changes[i].Path, changes[j].Path)
+	})
+
+	return changes, nil
+}
+
+// entryPair is a pseudo-entry holding matching entries of both trees, which allows comparing them
+// with snapshotfs.TreeWalker. The embedded entry is either of them and only satisfies fs.Entry.
+type entryPair struct {
+	fs.Entry
+
+	path   string
+	e1, e2 fs.Entry
+	change *Change // nil if the entry itself is not reported
+}
+
+// directoryPair is an entryPair whose children need to be compared.
+type directoryPair struct {
+	*entryPair
+}
+
+func pairOf(e fs.Entry) *entryPair {
+	if d, ok := e.(directoryPair); ok {
+		return d.entryPair
+	}
+
+	// nolint:forcetypeassert
+	return e.(*entryPair)
+}
+
+// newEntryPair returns the pair of entries to be walked or nil if their contents are identical.
+func newEntryPair(path string, e1, e2 fs.Entry) fs.Entry {
+	oid1, oid2 := objectIDOf(e1), objectIDOf(e2)
+
+	if e1 != nil && e2 != nil && oid1 != "" && oid1 == oid2 {
+		return nil
+	}
+
+	_, isDir1 := e1.(fs.Directory)
+	_, isDir2 := e2.(fs.Directory)
+
+	p := &entryPair{
+		path: path,
+		e1:   e1,
+		e2:   e2,
+	}
+
+	c := &Change{
+		Path:        path,
+		OldObjectID: oid1,
+		NewObjectID: oid2,
+	}
+
+	switch {
+	case e1 == nil && e2 == nil:
+		return nil
+
+	case e1 == nil:
+		p.Entry = e2
+		c.Type = ChangeAdded
+		c.IsDir = isDir2
+		c.NewSize = e2.Size()
+
+	case e2 == nil:
+		p.Entry = e1
+		c.Type = ChangeRemoved
+		c.IsDir = isDir1
+		c.OldSize = e1.Size()
+
+	case isDir1 != isDir2:
+		p.Entry = e1
+		p.change = c
+		c.Type = ChangeTypeChanged
+		c.OldSize = e1.Size()
+		c.NewSize = e2.Size()
+
+		return p
+
+	case isDir1:
+		// both are directories with different contents, only report what changed inside.
+		p.Entry = e1
+
+		return directoryPair{p}
+
+	default:
+		if oid1 == "" && e1.Size() == e2.Size() && e1.ModTime().Equal(e2.ModTime()) {
+			return nil
+		}
+
+		p.Entry = e1
+		p.change = c
+		c.Type = ChangeModified
+		c.OldSize = e1.Size()
+		c.NewSize = e2.Size()
+
+		return p
+	}
+
+	p.change = c
+
+	if c.IsDir {
+		return directoryPair{p}
+	}
+
+	return p
+}
+
+// Child implements fs.Directory.
+func (d directoryPair) Child(ctx context.Context, name string) (fs.Entry, error) {
+	return fs.ReadDirAndFindChild(ctx, d, name)
+}
+
+// Readdir implements fs.Directory and returns pairs of children whose contents differ.
+func (d directoryPair) Readdir(ctx context.Context) (fs.Entries, error) {
+	entries1, err := readdirOrNil(ctx, d.e1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read first directory %v", d.path)
+	}
+
+	entries2, err := readdirOrNil(ctx, d.e2)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read second directory %v", d.path)
+	}
+
+	var result fs.Entries
+
+	for len(entries1) > 0 || len(entries2) > 0 {
+		var e1, e2 fs.Entry
+
+		switch {
+		case len(entries2) == 0 || (len(entries1) > 0 && entries1[0].Name() < entries2[0].Name()):
+			e1, entries1 = entries1[0], entries1[1:]
+		case len(entries1) == 0 || entries2[0].Name() < entries1[0].Name():
+			e2, entries2 = entries2[0], entries2[1:]
+		default:
+			e1, entries1 = entries1[0], entries1[1:]
+			e2, entries2 = entries2[0], entries2[1:]
+		}
+
+		if p := newEntryPair(d.path+"/"+nameOf(e1, e2), e1, e2); p != nil {
+			result = append(result, p)
+		}
+	}
+
+	return result, nil
+}
+
+// readdirOrNil returns sorted copy of directory entries or nil if the entry is not a directory.
+func readdirOrNil(ctx context.Context, e fs.Entry) (fs.Entries, error) {
+	dir, ok := e.(fs.Directory)
+	if !ok {
+		return nil, nil
+	}
+
+	entries, err := dir.Readdir(ctx)
+	if err != nil {
+		// nolint:wrapcheck
+		return nil, err
+	}
+
+	// sort a copy, since readers may cache returned entries.
+	entries = append(fs.Entries(nil), entries...)
+	entries.Sort()
+
+	return entries, nil
+}
+
+// pathLess orders paths as they are visited by a depth-first walk with entries sorted by name.
+func pathLess(p1, p2 string) bool {
+	c1, c2 := strings.Split(p1, "/"), strings.Split(p2, "/")
+
+	for i := 0; i < len(c1) && i < len(c2); i++ {
+		if c1[i] != c2[i] {
+			return c1[i] < c2[i]
+		}
+	}
+
+	return len(c1) < len(c2)
+}
+
+func nameOf(e1, e2 fs.Entry) string {
+	if e1 != nil {
+		return e1.Name()
+	}
+
+	return e2.Name()
+}
+
+func objectIDOf(e fs.Entry) object.ID {
+	if h, ok := e.(object.HasObjectID); ok {
+		return h.ObjectID()
+	}
+
+	return ""
+}
@@ -0,0 +1,117 @@
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/diff"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestContentChanges(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	upload := func(dir *mockfs.Directory) fs.Entry {
+		t.Helper()
+
+		man, err := snapshotfs.NewUploader(env.RepositoryWriter).Upload(ctx, dir, policy.BuildTree(nil, policy.DefaultPolicy), snapshot.SourceInfo{})
+		require.NoError(t, err)
+
+		root, err := snapshotfs.SnapshotRoot(env.RepositoryWriter, man)
+		require.NoError(t, err)
+
+		return root
+	}
+
+	dir1 := mockfs.NewDirectory()
+	dir1.AddFile("same", []byte("same"), 0o644)
+	dir1.AddFile("touched", []byte("touched"), 0o644)
+	dir1.AddFile("modified", []byte("old contents"), 0o644)
+	dir1.AddFile("removed", []byte("removed"), 0o644)
+	dir1.AddFile("file-to-dir", []byte("file"), 0o644)
+	dir1.AddDir("unchanged-dir", 0o755).AddFile("f", []byte("f"), 0o644)
+	dir1.AddDir("removed-dir", 0o755).AddFile("f", []byte("f"), 0o644)
+	dir1.AddDir("nested", 0o755).AddDir("sub", 0o755).AddFile("f", []byte("old f"), 0o644)
+
+	dir2 := mockfs.NewDirectory()
+	dir2.AddFile("same", []byte("same"), 0o644)
+	dir2.AddFile("touched", []byte("touched"), 0o600).SetModTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	dir2.AddFile("modified", []byte("new contents!"), 0o644)
+	dir2.AddFile("added", []byte("added"), 0o644)
+	dir2.AddDir("file-to-dir", 0o755)
+	dir2.AddDir("unchanged-dir", 0o755).AddFile("f", []byte("f"), 0o644)
+	dir2.AddDir("added-dir", 0o755).AddFile("g", []byte("g"), 0o644)
+	dir2.AddDir("nested", 0o755).AddDir("sub", 0o755).AddFile("f", []byte("new f"), 0o644)
+
+	root1 := upload(dir1)
+	root2 := upload(dir2)
+
+	changes, err := diff.ContentChanges(ctx, root1, root2)
+	require.NoError(t, err)
+
+	type summary struct {
+		path  string
+		typ   diff.ChangeType
+		isDir bool
+	}
+
+	var got []summary
+
+	for _, c := range changes {
+		got = append(got, summary{c.Path, c.Type, c.IsDir})
+
+		switch c.Type {
+		case diff.ChangeAdded:
+			require.Empty(t, c.OldObjectID, c.Path)
+			require.NotEmpty(t, c.NewObjectID, c.Path)
+		case diff.ChangeRemoved:
+			require.NotEmpty(t, c.OldObjectID, c.Path)
+			require.Empty(t, c.NewObjectID, c.Path)
+		default:
+			require.NotEmpty(t, c.OldObjectID, c.Path)
+			require.NotEmpty(t, c.NewObjectID, c.Path)
+			require.NotEqual(t, c.OldObjectID, c.NewObjectID, c.Path)
+		}
+	}
+
+	require.Equal(t, []summary{
+		{"./added", diff.ChangeAdded, false},
+		{"./added-dir", diff.ChangeAdded, true},
+		{"./added-dir/g", diff.ChangeAdded, false},
+		{"./file-to-dir", diff.ChangeTypeChanged, false},
+		{"./modified", diff.ChangeModified, false},
+		{"./nested/sub/f", diff.ChangeModified, false},
+		{"./removed", diff.ChangeRemoved, false},
+		{"./removed-dir", diff.ChangeRemoved, true},
+		{"./removed-dir/f", diff.ChangeRemoved, false},
+	}, got)
+
+	// comparing a tree against itself yields no changes.
+	changes, err = diff.ContentChanges(ctx, root1, root1)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestContentChangesWithoutObjectIDs(t *testing.T) {
+	dir1 := mockfs.NewDirectory()
+	dir1.AddFile("same", []byte("same"), 0o644)
+	dir1.AddFile("resized", []byte("abc"), 0o644)
+
+	dir2 := mockfs.NewDirectory()
+	dir2.AddFile("same", []byte("same"), 0o600)
+	dir2.AddFile("resized", []byte("abcd"), 0o644)
+
+	changes, err := diff.ContentChanges(testlogging.Context(t), dir1, dir2)
+	require.NoError(t, err)
+	require.Equal(t, []diff.Change{
+		{Path: "./resized", Type: diff.ChangeModified, OldSize: 3, NewSize: 4},
+	}, changes)
+}
@@ -4,6 +4,7 @@
 
 import (
 	"bytes"
+	"hash"
 	"io"
 )
 
@@ -124,6 +125,56 @@ func (b Bytes) WriteTo(w io.Writer) (int64, error) {
 	return totalN, nil
 }
 
+// Equal returns true if both Bytes contain the same data, regardless of how it is split into slices.
+func (b Bytes) Equal(other Bytes) bool {
+	if b.Length() != other.Length() {
+		return false
+	}
+
+	var (
+		s1, s2 []byte
+		i1, i2 int
+	)
+
+	for {
+		// advance to next non-empty slices.
+		for len(s1) == 0 && i1 < len(b.Slices) {
+			s1 = b.Slices[i1]
+			i1++
+		}
+
+		for len(s2) == 0 && i2 < len(other.Slices) {
+			s2 = other.Slices[i2]
+			i2++
+		}
+
+		if len(s1) == 0 || len(s2) == 0 {
+			// lengths are equal so both are exhausted at the same time.
+			return true
+		}
+
+		l := len(s1)
+		if l > len(s2) {
+			l = len(s2)
+		}
+
+		if !bytes.Equal(s1[0:l], s2[0:l]) {
+			return false
+		}
+
+		s1 = s1[l:]
+		s2 = s2[l:]
+	}
+}
+
+// HashTo writes all slices to the provided hash.
+func (b Bytes) HashTo(h hash.Hash) {
+	for _, v := range b.Slices {
+		// hash.Hash.Write never returns an error.
+		h.Write(v) //nolint:errcheck
+	}
+}
+
 // FromSlice creates Bytes from the specified slice.
 func FromSlice(b []byte) Bytes {
 	var r Bytes
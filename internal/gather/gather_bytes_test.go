@@ -2,18 +2,21 @@
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"io/ioutil"
 	"testing"
 )
 
 var sample1 = []byte("hello! how are you? nice to meet you.")
 
-func TestGatherBytes(t *testing.T) {
-	// split the 'whole' into equivalent Bytes slicings in some interesting ways
-	cases := []struct {
-		whole  []byte
-		sliced Bytes
-	}{
+type gatherBytesTestCase struct {
+	whole  []byte
+	sliced Bytes
+}
+
+// gatherBytesTestCases splits the 'whole' into equivalent Bytes slicings in some interesting ways.
+func gatherBytesTestCases() []gatherBytesTestCase {
+	return []gatherBytesTestCase{
 		{
 			whole:  nil,
 			sliced: Bytes{},
@@ -71,8 +74,10 @@ func TestGatherBytes(t *testing.T) {
 			}},
 		},
 	}
+}
 
-	for _, tc := range cases {
+func TestGatherBytes(t *testing.T) {
+	for _, tc := range gatherBytesTestCases() {
 		b := tc.sliced
 
 		// length
@@ -107,3 +112,60 @@ func TestGatherBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestGatherBytesEqual(t *testing.T) {
+	cases := gatherBytesTestCases()
+
+	for i, tc1 := range cases {
+		for j, tc2 := range cases {
+			if got, want := tc1.sliced.Equal(tc2.sliced), bytes.Equal(tc1.whole, tc2.whole); got != want {
+				t.Errorf("unexpected Equal() result for cases %v and %v: %v, want %v", i, j, got, want)
+			}
+		}
+	}
+
+	// same length, differing only in the last byte or in the first byte of the second slice.
+	modified := append([]byte(nil), sample1...)
+	modified[len(modified)-1]++
+
+	modified2 := append([]byte(nil), sample1...)
+	modified2[20]++
+
+	for _, tc := range cases {
+		if len(tc.whole) == 0 {
+			continue
+		}
+
+		for _, m := range [][]byte{modified, modified2} {
+			if tc.sliced.Equal(Bytes{Slices: [][]byte{m[0:5], m[5:]}}) {
+				t.Errorf("unexpected equality of %v and %v", string(tc.whole), string(m))
+			}
+		}
+	}
+}
+
+func TestGatherBytesHashTo(t *testing.T) {
+	for _, tc := range gatherBytesTestCases() {
+		h := sha256.New()
+		tc.sliced.HashTo(h)
+
+		want := sha256.Sum256(tc.whole)
+
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("unexpected hash %x, want %x", got, want)
+		}
+	}
+}
+
+func BenchmarkGatherBytesEqual(b *testing.B) {
+	b1 := Bytes{Slices: [][]byte{sample1[0:10], sample1[10:25], sample1[25:]}}
+	b2 := Bytes{Slices: [][]byte{sample1[0:20], sample1[20:]}}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if !b1.Equal(b2) {
+			b.Fatal("not equal")
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package gather
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
 
 // WriteBuffer is a write buffer for content of unknown size that manages
 // data in a series of byte slices of uniform size.
 type WriteBuffer struct {
 	mu    sync.Mutex
 	inner Bytes
+
+	// set when the buffer has been returned to the pool using PutWriteBuffer().
+	released bool
 }
 
 // Close releases all memory allocated by this buffer.
@@ -14,6 +21,8 @@ func (b *WriteBuffer) Close() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	for _, s := range b.inner.Slices {
 		releaseChunk(s)
 	}
@@ -26,6 +35,8 @@ func (b *WriteBuffer) Reset() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	for _, s := range b.inner.Slices {
 		releaseChunk(s)
 	}
@@ -33,17 +44,56 @@ func (b *WriteBuffer) Reset() {
 	b.inner.Slices = nil
 }
 
+func (b *WriteBuffer) assertValidLocked() {
+	if b.released {
+		panic("use of WriteBuffer after it has been returned to the pool")
+	}
+}
+
 // Write implements io.Writer for appending to the buffer.
 func (b *WriteBuffer) Write(data []byte) (n int, err error) {
 	b.Append(data)
 	return len(data), nil
 }
 
+// WriteAt implements io.WriterAt by overwriting bytes within the already-written region of the buffer.
+// It returns an error without writing anything if the range extends past the current length.
+func (b *WriteBuffer) WriteAt(p []byte, off int64) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.assertValidLocked()
+
+	if l := int64(b.inner.Length()); off < 0 || off+int64(len(p)) > l {
+		return 0, errors.Errorf("invalid write range %v..%v, buffer length %v", off, off+int64(len(p)), l)
+	}
+
+	for _, s := range b.inner.Slices {
+		if len(p) == 0 {
+			break
+		}
+
+		if off >= int64(len(s)) {
+			off -= int64(len(s))
+			continue
+		}
+
+		c := copy(s[off:], p)
+		p = p[c:]
+		n += c
+		off = 0
+	}
+
+	return n, nil
+}
+
 // AppendSectionTo appends the section of the buffer to the provided slice and returns it.
 func (b *WriteBuffer) AppendSectionTo(output []byte, offset, size int) []byte {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	return b.inner.AppendSectionTo(output, offset, size)
 }
 
@@ -52,6 +102,8 @@ func (b *WriteBuffer) Length() int {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	return b.inner.Length()
 }
 
@@ -60,6 +112,8 @@ func (b *WriteBuffer) GetBytes(output []byte) []byte {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	return b.inner.GetBytes(output)
 }
 
@@ -68,6 +122,8 @@ func (b *WriteBuffer) Bytes() Bytes {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	return b.inner
 }
 
@@ -76,6 +132,8 @@ func (b *WriteBuffer) Append(data []byte) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	b.assertValidLocked()
+
 	if len(b.inner.Slices) == 0 {
 		b.inner.sliceBuf[0] = allocChunk()
 		b.inner.Slices = b.inner.sliceBuf[0:1]
@@ -101,6 +159,46 @@ func (b *WriteBuffer) Append(data []byte) {
 	}
 }
 
+// Truncate shrinks the buffer to the provided length, releasing chunks that are no longer used.
+// It returns an error if the buffer is shorter than the provided length.
+func (b *WriteBuffer) Truncate(n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.assertValidLocked()
+
+	if l := b.inner.Length(); n < 0 || n > l {
+		return errors.Errorf("invalid truncation length %v, buffer length %v", n, l)
+	}
+
+	// find the number of slices to keep, the last one of which is possibly shortened.
+	keep := 0
+
+	for remaining := n; remaining > 0; keep++ {
+		s := b.inner.Slices[keep]
+		if remaining < len(s) {
+			b.inner.Slices[keep] = s[0:remaining]
+		}
+
+		remaining -= len(b.inner.Slices[keep])
+	}
+
+	for i := keep; i < len(b.inner.Slices); i++ {
+		releaseChunk(b.inner.Slices[i])
+
+		// do not retain references to released chunks, which may be reused by other buffers.
+		b.inner.Slices[i] = nil
+	}
+
+	if keep == 0 {
+		b.inner.Slices = nil
+	} else {
+		b.inner.Slices = b.inner.Slices[0:keep]
+	}
+
+	return nil
+}
+
 // NewWriteBuffer creates new write buffer.
 func NewWriteBuffer() *WriteBuffer {
 	return &WriteBuffer{}
@@ -0,0 +1,45 @@
+package gather
+
+import "sync"
+
+var writeBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &WriteBuffer{}
+	},
+}
+
+// GetWriteBuffer returns an empty write buffer from the pool.
+// The buffer should be returned to the pool using PutWriteBuffer() when no longer needed.
+func GetWriteBuffer() *WriteBuffer {
+	// nolint:forcetypeassert
+	b := writeBufferPool.Get().(*WriteBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.released = false
+
+	return b
+}
+
+// PutWriteBuffer resets the provided write buffer and returns it to the pool.
+// The buffer must not be used after it has been returned, doing so (including returning
+// it twice) panics until it is handed out again by GetWriteBuffer().
+func PutWriteBuffer(b *WriteBuffer) {
+	b.release()
+	writeBufferPool.Put(b)
+}
+
+func (b *WriteBuffer) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.assertValidLocked()
+
+	for _, s := range b.inner.Slices {
+		releaseChunk(s)
+	}
+
+	b.inner.Slices = nil
+	b.released = true
+}
@@ -0,0 +1,56 @@
+package gather
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBufferPool(t *testing.T) {
+	w := GetWriteBuffer()
+	require.Equal(t, 0, w.Length())
+
+	w.Append([]byte("hello"))
+	require.Equal(t, []byte("hello"), w.GetBytes(nil))
+
+	PutWriteBuffer(w)
+
+	// use after put and double put are detected.
+	require.Panics(t, func() { w.Append([]byte("x")) })
+	require.Panics(t, func() { w.Length() })
+	require.Panics(t, func() { PutWriteBuffer(w) })
+
+	// buffers obtained from the pool are always empty and usable.
+	for i := 0; i < 10; i++ {
+		w2 := GetWriteBuffer()
+		require.Equal(t, 0, w2.Length())
+
+		w2.Append([]byte("world"))
+		require.Equal(t, []byte("world"), w2.GetBytes(nil))
+
+		PutWriteBuffer(w2)
+	}
+}
+
+var benchData = bytes.Repeat([]byte{1}, 1000)
+
+func BenchmarkNewWriteBuffer(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		w := NewWriteBuffer()
+		w.Append(benchData)
+		w.Close()
+	}
+}
+
+func BenchmarkPooledWriteBuffer(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		w := GetWriteBuffer()
+		w.Append(benchData)
+		PutWriteBuffer(w)
+	}
+}
@@ -65,3 +65,110 @@ func TestGatherDefaultWriteBuffer(t *testing.T) {
 		t.Errorf("invalid number of slices %v, want %v", got, want)
 	}
 }
+
+func TestGatherWriteBufferWriteAt(t *testing.T) {
+	w := NewWriteBuffer()
+	defer w.Close()
+
+	// length prefix placeholder followed by a body spanning two chunks.
+	w.Append([]byte{0, 0, 0, 0})
+	w.Append(bytes.Repeat([]byte("x"), chunkSize))
+
+	want := w.GetBytes(nil)
+
+	n, err := w.WriteAt([]byte{1, 2, 3, 4}, 0)
+	if err != nil || n != 4 {
+		t.Fatalf("unexpected result of WriteAt: %v, %v", n, err)
+	}
+
+	copy(want, []byte{1, 2, 3, 4})
+
+	// overwrite across the chunk boundary.
+	n, err = w.WriteAt([]byte("abcdef"), chunkSize-3)
+	if err != nil || n != 6 {
+		t.Fatalf("unexpected result of WriteAt: %v, %v", n, err)
+	}
+
+	copy(want[chunkSize-3:], "abcdef")
+
+	if got := w.GetBytes(nil); !bytes.Equal(got, want) {
+		t.Errorf("unexpected buffer contents after WriteAt")
+	}
+
+	// chunk allocation is not affected.
+	if got, want := len(w.inner.Slices), 2; got != want {
+		t.Errorf("invalid number of slices %v, want %v", got, want)
+	}
+
+	if got, want := w.Length(), chunkSize+4; got != want {
+		t.Errorf("invalid length: %v, want %v", got, want)
+	}
+
+	// writes past the end of the buffer fail and don't modify anything.
+	if _, err := w.WriteAt([]byte("zz"), int64(chunkSize+3)); err == nil {
+		t.Errorf("expected error writing past the end")
+	}
+
+	if _, err := w.WriteAt([]byte("zz"), -1); err == nil {
+		t.Errorf("expected error writing at negative offset")
+	}
+
+	if got := w.GetBytes(nil); !bytes.Equal(got, want) {
+		t.Errorf("buffer modified by failed WriteAt")
+	}
+}
+
+func TestGatherWriteBufferTruncate(t *testing.T) {
+	cases := []int{0, 1, 100, chunkSize - 1, chunkSize, chunkSize + 1, 2 * chunkSize, 2*chunkSize + 50, 2*chunkSize + 100}
+
+	for _, n := range cases {
+		n := n
+
+		t.Run(fmt.Sprintf("%v", n), func(t *testing.T) {
+			w := NewWriteBuffer()
+			defer w.Close()
+
+			var want []byte
+
+			for i := 0; i < 2*chunkSize+100; i++ {
+				want = append(want, byte(i%251))
+			}
+
+			w.Append(want)
+
+			if err := w.Truncate(len(want) + 1); err == nil {
+				t.Fatalf("expected error when truncating past the end")
+			}
+
+			if err := w.Truncate(n); err != nil {
+				t.Fatalf("unable to truncate: %v", err)
+			}
+
+			want = want[0:n]
+
+			if got := w.GetBytes(nil); !bytes.Equal(got, want) {
+				t.Fatalf("invalid data after truncation")
+			}
+
+			if got, want := len(w.inner.Slices), (n+chunkSize-1)/chunkSize; got != want {
+				t.Errorf("invalid number of slices %v, want %v", got, want)
+			}
+
+			// appending after truncation continues where the data ends.
+			more := bytes.Repeat([]byte("y"), chunkSize+7)
+			w.Append(more)
+
+			want = append(want, more...)
+
+			if got := w.GetBytes(nil); !bytes.Equal(got, want) {
+				t.Fatalf("invalid data after append")
+			}
+
+			for i, s := range w.inner.Slices[0 : len(w.inner.Slices)-1] {
+				if len(s) != chunkSize {
+					t.Errorf("slice %v is not full: %v", i, len(s))
+				}
+			}
+		})
+	}
+}
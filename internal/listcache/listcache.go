@@ -5,7 +5,10 @@
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -19,50 +22,114 @@
 
 var log = logging.GetContextLoggerFunc("listcache")
 
+// DefaultBatchSize is the default maximum number of blobs stored in a single list cache entry.
+const DefaultBatchSize = 1000
+
+// DefaultMaxCachedBlobsPerPrefix is the default maximum number of blobs in a list that will be cached.
+const DefaultMaxCachedBlobsPerPrefix = 100000
+
+// Stats contains list cache statistics.
+type Stats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// Invalidator is implemented by the wrapper and allows callers that know that blobs have changed
+// outside of the wrapper to force the next ListBlobs() to go to the underlying storage.
+type Invalidator interface {
+	InvalidateLists(ctx context.Context, prefix blob.ID)
+}
+
+// StatsProvider is implemented by storage wrappers that keep list cache statistics.
+type StatsProvider interface {
+	Stats() Stats
+}
+
 type listCacheStorage struct {
+	// Keep int64 fields first to ensure they get aligned to at least 64-bit
+	// boundaries, which is required for atomic access on ARM and x86-32.
+	hits          int64
+	misses        int64
+	invalidations int64
+
 	blob.Storage
 	cacheStorage  blob.Storage
 	cacheDuration time.Duration
 	cacheTimeFunc func() time.Time
 	hmacSecret    []byte
 	prefixes      []blob.ID
+	batchSize     int
+	maxCached     int
+
+	mu              sync.Mutex
+	metadataIndexes map[blob.ID]*metadataIndex
+}
+
+// metadataIndex holds decoded metadata of all blobs in a cached list, which is used by GetMetadata
+// to avoid reading and decoding all batches on each call. It's only valid as long as the cached list
+// has the same ExpireAfter.
+type metadataIndex struct {
+	expireAfter time.Time
+	blobs       map[blob.ID]blob.Metadata
 }
 
+// cachedList is stored in the cache under the prefix and holds the first batch of list results.
+// Remaining results are stored in ExtraBatches additional entries, each with the same ExpireAfter,
+// which is also a part of their IDs. Extra batches are written before the cachedList itself so that
+// partial results are never visible.
 type cachedList struct {
-	ExpireAfter time.Time       `json:"expireAfter"`
-	Blobs       []blob.Metadata `json:"blobs"`
+	ExpireAfter  time.Time       `json:"expireAfter"`
+	Blobs        []blob.Metadata `json:"blobs"`
+	ExtraBatches int             `json:"extraBatches,omitempty"`
+}
+
+func batchBlobID(prefix blob.ID, n int, expireAfter time.Time) blob.ID {
+	return blob.ID(fmt.Sprintf("%v.%v.%v", prefix, n, expireAfter.UnixNano()))
 }
 
-func (s *listCacheStorage) saveListToCache(ctx context.Context, prefix blob.ID, cl *cachedList) {
+func (s *listCacheStorage) saveListToCache(ctx context.Context, id blob.ID, cl *cachedList) bool {
 	data, err := json.Marshal(cl)
 	if err != nil {
 		log(ctx).Debugf("unable to marshal list cache entry: %v", err)
-		return
+		return false
 	}
 
 	b := hmac.Append(data, s.hmacSecret)
 
-	if err := s.cacheStorage.PutBlob(ctx, prefix, gather.FromSlice(b)); err != nil {
+	if err := s.cacheStorage.PutBlob(ctx, id, gather.FromSlice(b)); err != nil {
 		log(ctx).Debugf("unable to persist list cache entry: %v", err)
+		return false
 	}
+
+	return true
 }
 
-func (s *listCacheStorage) readBlobsFromCache(ctx context.Context, prefix blob.ID) *cachedList {
+func (s *listCacheStorage) readListFromCache(ctx context.Context, id blob.ID) *cachedList {
 	cl := &cachedList{}
 
-	data, err := s.cacheStorage.GetBlob(ctx, prefix, 0, -1)
+	data, err := s.cacheStorage.GetBlob(ctx, id, 0, -1)
 	if err != nil {
 		return nil
 	}
 
 	data, err = hmac.VerifyAndStrip(data, s.hmacSecret)
 	if err != nil {
-		log(ctx).Debugf("warning: invalid list cache HMAC for %v, ignoring", prefix)
+		log(ctx).Debugf("warning: invalid list cache HMAC for %v, ignoring", id)
 		return nil
 	}
 
 	if err := json.Unmarshal(data, &cl); err != nil {
-		log(ctx).Debugf("warning: cant't unmarshal cached list results for %v, ignoring", prefix)
+		log(ctx).Debugf("warning: cant't unmarshal cached list results for %v, ignoring", id)
+		return nil
+	}
+
+	return cl
+}
+
+func (s *listCacheStorage) readBlobsFromCache(ctx context.Context, prefix blob.ID) *cachedList {
+	cl := s.readListFromCache(ctx, prefix)
+	if cl == nil {
 		return nil
 	}
 
@@ -82,30 +149,270 @@ func (s *listCacheStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb fun
 	}
 
 	cached := s.readBlobsFromCache(ctx, prefix)
-	if cached == nil {
-		all, err := blob.ListAllBlobs(ctx, s.Storage, prefix)
-		if err != nil {
-			// nolint:wrapcheck
+	if cached == nil || !s.extraBatchesValid(ctx, prefix, cached) {
+		atomic.AddInt64(&s.misses, 1)
+
+		return s.listAndSaveToCache(ctx, prefix, cb)
+	}
+
+	atomic.AddInt64(&s.hits, 1)
+
+	for _, v := range cached.Blobs {
+		if err := cb(v); err != nil {
 			return err
 		}
+	}
+
+	for i := 1; i <= cached.ExtraBatches; i++ {
+		batch := s.readListFromCache(ctx, batchBlobID(prefix, i, cached.ExpireAfter))
+		if batch == nil || !batch.ExpireAfter.Equal(cached.ExpireAfter) {
+			// presence of batches was verified above, so this only happens when the batch is corrupted or
+			// the cache is modified concurrently. some results have already been reported, so we can't fall back
+			// to listing the underlying storage, invalidate the cache so that the next list succeeds.
+			s.invalidatePrefix(ctx, prefix)
+
+			return errors.Errorf("list cache batch %v of %v is missing or invalid", i, prefix)
+		}
 
-		cached = &cachedList{
-			ExpireAfter: s.cacheTimeFunc().Add(s.cacheDuration),
-			Blobs:       all,
+		for _, v := range batch.Blobs {
+			if err := cb(v); err != nil {
+				return err
+			}
 		}
+	}
+
+	return nil
+}
 
-		s.saveListToCache(ctx, prefix, cached)
+// extraBatchesValid determines whether all extra batches of the cached list are present and belong to it.
+// Batches are verified before any results are reported, which allows falling back to listing the underlying
+// storage without holding the entire list in memory. To avoid reading each batch twice, only their IDs
+// are verified using a single listing of the cache storage.
+func (s *listCacheStorage) extraBatchesValid(ctx context.Context, prefix blob.ID, cached *cachedList) bool {
+	if cached.ExtraBatches == 0 {
+		return true
 	}
 
-	for _, v := range cached.Blobs {
-		if err := cb(v); err != nil {
-			return err
+	present := map[blob.ID]bool{}
+
+	if err := s.cacheStorage.ListBlobs(ctx, prefix+".", func(bm blob.Metadata) error {
+		present[bm.BlobID] = true
+		return nil
+	}); err != nil {
+		log(ctx).Debugf("unable to list list cache batches: %v", err)
+		return false
+	}
+
+	for i := 1; i <= cached.ExtraBatches; i++ {
+		if !present[batchBlobID(prefix, i, cached.ExpireAfter)] {
+			log(ctx).Debugf("list cache batch %v of %v is missing, listing storage", i, prefix)
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetMetadata implements blob.Storage and returns metadata from a fresh cached list containing
+// the blob, falling back to the underlying storage otherwise.
+func (s *listCacheStorage) GetMetadata(ctx context.Context, blobID blob.ID) (blob.Metadata, error) {
+	for _, p := range s.prefixes {
+		if !strings.HasPrefix(string(blobID), string(p)) {
+			continue
+		}
+
+		if bm, ok := s.findInCachedList(ctx, p, blobID); ok {
+			return bm, nil
+		}
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.GetMetadata(ctx, blobID)
+}
+
+// findInCachedList looks for the blob in a fresh cached list for the given prefix.
+func (s *listCacheStorage) findInCachedList(ctx context.Context, prefix, blobID blob.ID) (blob.Metadata, bool) {
+	cached := s.readBlobsFromCache(ctx, prefix)
+	if cached == nil {
+		s.dropMetadataIndex(prefix)
+		return blob.Metadata{}, false
+	}
+
+	idx := s.metadataIndexForList(ctx, prefix, cached)
+	if idx == nil {
+		return blob.Metadata{}, false
+	}
+
+	bm, ok := idx.blobs[blobID]
+
+	return bm, ok
+}
+
+// metadataIndexForList returns the in-memory index of the provided cached list, building it if needed.
+// Returns nil when any batch is invalid or when the list has more blobs than can be held in memory.
+func (s *listCacheStorage) metadataIndexForList(ctx context.Context, prefix blob.ID, cached *cachedList) *metadataIndex {
+	s.mu.Lock()
+	idx := s.metadataIndexes[prefix]
+	s.mu.Unlock()
+
+	if idx != nil && idx.expireAfter.Equal(cached.ExpireAfter) {
+		return idx
+	}
+
+	idx = s.buildMetadataIndex(ctx, prefix, cached)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if idx == nil {
+		delete(s.metadataIndexes, prefix)
+	} else {
+		s.metadataIndexes[prefix] = idx
+	}
+
+	return idx
+}
+
+func (s *listCacheStorage) buildMetadataIndex(ctx context.Context, prefix blob.ID, cached *cachedList) *metadataIndex {
+	maxBlobs := s.maxCached
+	if maxBlobs <= 0 {
+		maxBlobs = DefaultMaxCachedBlobsPerPrefix
+	}
+
+	idx := &metadataIndex{
+		expireAfter: cached.ExpireAfter,
+		blobs:       map[blob.ID]blob.Metadata{},
+	}
+
+	for i := 0; i <= cached.ExtraBatches; i++ {
+		batch := cached
+
+		if i > 0 {
+			batch = s.readListFromCache(ctx, batchBlobID(prefix, i, cached.ExpireAfter))
+			if batch == nil || !batch.ExpireAfter.Equal(cached.ExpireAfter) {
+				return nil
+			}
+		}
+
+		if len(idx.blobs)+len(batch.Blobs) > maxBlobs {
+			log(ctx).Debugf("list of %v exceeds %v blobs, not indexing", prefix, maxBlobs)
+			return nil
+		}
+
+		for _, bm := range batch.Blobs {
+			idx.blobs[bm.BlobID] = bm
 		}
 	}
 
+	return idx
+}
+
+func (s *listCacheStorage) dropMetadataIndex(prefix blob.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.metadataIndexes, prefix)
+}
+
+// listAndSaveToCache lists the underlying storage and incrementally saves results to the cache
+// in batches of up to batchSize blobs, without holding all results in memory.
+func (s *listCacheStorage) listAndSaveToCache(ctx context.Context, prefix blob.ID, cb func(blob.Metadata) error) error {
+	cached := &cachedList{
+		ExpireAfter: s.cacheTimeFunc().Add(s.cacheDuration),
+	}
+
+	var batch []blob.Metadata
+
+	cacheOK := true
+	count := 0
+
+	flushBatch := func() {
+		cached.ExtraBatches++
+
+		cacheOK = cacheOK && s.saveListToCache(ctx, batchBlobID(prefix, cached.ExtraBatches, cached.ExpireAfter), &cachedList{
+			ExpireAfter: cached.ExpireAfter,
+			Blobs:       batch,
+		})
+
+		batch = batch[:0]
+	}
+
+	if err := s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		count++
+
+		switch {
+		case !cacheOK:
+			// too many blobs or failed to save, stop caching and only report results.
+
+		case s.maxCached > 0 && count > s.maxCached:
+			log(ctx).Debugf("list of %v exceeds %v blobs, not caching", prefix, s.maxCached)
+
+			cacheOK = false
+			cached.Blobs = nil
+			batch = nil
+
+		case len(cached.Blobs) < s.batchSize:
+			cached.Blobs = append(cached.Blobs, bm)
+
+		default:
+			batch = append(batch, bm)
+
+			if len(batch) >= s.batchSize {
+				flushBatch()
+			}
+		}
+
+		return cb(bm)
+	}); err != nil {
+		// nolint:wrapcheck
+		return err
+	}
+
+	if cacheOK && len(batch) > 0 {
+		flushBatch()
+	}
+
+	if cacheOK && s.saveListToCache(ctx, prefix, cached) {
+		s.deleteStaleBatches(ctx, prefix, cached)
+	} else {
+		s.deleteStaleBatches(ctx, prefix, nil)
+	}
+
 	return nil
 }
 
+// deleteStaleBatches removes extra batches of the prefix which don't belong to the provided cached list,
+// which are left behind when a cached list is replaced or caching is abandoned. When the provided list is nil,
+// all batches are removed.
+func (s *listCacheStorage) deleteStaleBatches(ctx context.Context, prefix blob.ID, keep *cachedList) {
+	valid := map[blob.ID]bool{}
+
+	if keep != nil {
+		for i := 1; i <= keep.ExtraBatches; i++ {
+			valid[batchBlobID(prefix, i, keep.ExpireAfter)] = true
+		}
+	}
+
+	var stale []blob.ID
+
+	if err := s.cacheStorage.ListBlobs(ctx, prefix+".", func(bm blob.Metadata) error {
+		if !valid[bm.BlobID] {
+			stale = append(stale, bm.BlobID)
+		}
+
+		return nil
+	}); err != nil {
+		log(ctx).Debugf("unable to list list cache batches: %v", err)
+		return
+	}
+
+	for _, id := range stale {
+		if err := s.cacheStorage.DeleteBlob(ctx, id); err != nil {
+			log(ctx).Debugf("unable to delete list cache batch: %v", err)
+		}
+	}
+}
+
 // PutBlob implements blob.Storage and writes markers into local cache for all successful writes.
 func (s *listCacheStorage) PutBlob(ctx context.Context, blobID blob.ID, data blob.Bytes) error {
 	err := s.Storage.PutBlob(ctx, blobID, data)
@@ -115,12 +422,30 @@ func (s *listCacheStorage) PutBlob(ctx context.Context, blobID blob.ID, data blo
 	return err
 }
 
+// SetTime implements blob.Storage and invalidates cached lists, which include modification times.
+func (s *listCacheStorage) SetTime(ctx context.Context, blobID blob.ID, t time.Time) error {
+	err := s.Storage.SetTime(ctx, blobID, t)
+	s.invalidateAfterUpdate(ctx, blobID)
+
+	// nolint:wrapcheck
+	return err
+}
+
 func (s *listCacheStorage) FlushCaches(ctx context.Context) error {
 	if err := s.Storage.FlushCaches(ctx); err != nil {
 		return errors.Wrap(err, "error flushing caches")
 	}
 
-	return errors.Wrap(blob.DeleteMultiple(ctx, s.cacheStorage, s.prefixes, len(s.prefixes)), "error deleting cached lists")
+	if err := blob.DeleteMultiple(ctx, s.cacheStorage, s.prefixes, len(s.prefixes)); err != nil {
+		return errors.Wrap(err, "error deleting cached lists")
+	}
+
+	for _, p := range s.prefixes {
+		s.dropMetadataIndex(p)
+		s.deleteStaleBatches(ctx, p, nil)
+	}
+
+	return nil
 }
 
 // DeleteBlob implements blob.Storage and writes markers into local cache for all successful deletes.
@@ -132,6 +457,27 @@ func (s *listCacheStorage) DeleteBlob(ctx context.Context, blobID blob.ID) error
 	return err
 }
 
+// DeleteBlobs implements blob.BatchDeleter and invalidates cached lists once for the whole batch.
+func (s *listCacheStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	errs := blob.DeleteBlobs(ctx, s.Storage, ids)
+
+	for _, p := range s.prefixes {
+		for _, id := range ids {
+			if strings.HasPrefix(string(id), string(p)) {
+				s.invalidatePrefix(ctx, p)
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *listCacheStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.Storage)
+}
+
 func (s *listCacheStorage) isCachedPrefix(prefix blob.ID) bool {
 	for _, p := range s.prefixes {
 		if prefix == p {
@@ -145,28 +491,83 @@ func (s *listCacheStorage) isCachedPrefix(prefix blob.ID) bool {
 func (s *listCacheStorage) invalidateAfterUpdate(ctx context.Context, blobID blob.ID) {
 	for _, p := range s.prefixes {
 		if strings.HasPrefix(string(blobID), string(p)) {
-			if err := s.cacheStorage.DeleteBlob(ctx, p); err != nil {
-				log(ctx).Debugf("unable to delete cached list: %v", err)
-			}
+			s.invalidatePrefix(ctx, p)
+		}
+	}
+}
+
+// InvalidateLists implements Invalidator and discards cached lists of all prefixes that may contain
+// blobs with the provided prefix.
+func (s *listCacheStorage) InvalidateLists(ctx context.Context, prefix blob.ID) {
+	for _, p := range s.prefixes {
+		if strings.HasPrefix(string(p), string(prefix)) || strings.HasPrefix(string(prefix), string(p)) {
+			s.invalidatePrefix(ctx, p)
 		}
 	}
 }
 
+func (s *listCacheStorage) invalidatePrefix(ctx context.Context, prefix blob.ID) {
+	atomic.AddInt64(&s.invalidations, 1)
+
+	s.dropMetadataIndex(prefix)
+
+	// batches are only reachable through the entry for the prefix, so it's enough to delete it.
+	if err := s.cacheStorage.DeleteBlob(ctx, prefix); err != nil {
+		log(ctx).Debugf("unable to delete cached list: %v", err)
+	}
+}
+
+// Stats returns list cache statistics.
+func (s *listCacheStorage) Stats() Stats {
+	return Stats{
+		Hits:          atomic.LoadInt64(&s.hits),
+		Misses:        atomic.LoadInt64(&s.misses),
+		Invalidations: atomic.LoadInt64(&s.invalidations),
+	}
+}
+
+// Options provides options for the list cache wrapper.
+type Options struct {
+	Prefixes      []blob.ID     // blob prefixes whose lists are cached
+	HMACSecret    []byte        // secret used to protect cached lists
+	CacheDuration time.Duration // how long cached lists remain valid
+
+	// BatchSize is the maximum number of blobs stored in a single cache entry, DefaultBatchSize if not positive.
+	BatchSize int
+
+	// MaxCachedBlobsPerPrefix is the number of blobs above which lists are not cached at all, 0 means no limit.
+	MaxCachedBlobsPerPrefix int
+}
+
 // NewWrapper returns new wrapper that ensures list consistency with local writes for the given set of blob prefixes.
 // It leverages the provided local cache storage to maintain markers keeping track of recently created and deleted blobs.
-func NewWrapper(st, cacheStorage blob.Storage, prefixes []blob.ID, hmacSecret []byte, duration time.Duration) blob.Storage {
+func NewWrapper(st, cacheStorage blob.Storage, opt Options) blob.Storage {
 	if cacheStorage == nil {
 		return st
 	}
 
+	batchSize := opt.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
 	return &listCacheStorage{
 		Storage:       st,
 		cacheStorage:  cacheStorage,
-		prefixes:      prefixes,
+		prefixes:      opt.Prefixes,
 		cacheTimeFunc: clock.Now,
-		hmacSecret:    hmacSecret,
-		cacheDuration: duration,
+		hmacSecret:    opt.HMACSecret,
+		cacheDuration: opt.CacheDuration,
+		batchSize:     batchSize,
+		maxCached:     opt.MaxCachedBlobsPerPrefix,
+
+		metadataIndexes: map[blob.ID]*metadataIndex{},
 	}
 }
 
-var _ blob.Storage = (*listCacheStorage)(nil)
+var (
+	_ blob.Storage      = (*listCacheStorage)(nil)
+	_ blob.BatchDeleter = (*listCacheStorage)(nil)
+	_ Invalidator       = (*listCacheStorage)(nil)
+	_ StatsProvider     = (*listCacheStorage)(nil)
+)
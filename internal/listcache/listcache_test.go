@@ -1,7 +1,11 @@
 package listcache
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,15 +26,19 @@ func TestListCache(t *testing.T) {
 	cacheTime := faketime.NewTimeAdvance(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), 0)
 	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, cacheTime.NowFunc())
 
-	lc := NewWrapper(realStorage, cachest, []blob.ID{"n", "xe", "xb"}, []byte("hmac-secret"), 1*time.Minute).(*listCacheStorage)
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:      []blob.ID{"n", "xe", "xb"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+	}).(*listCacheStorage)
 	lc.cacheTimeFunc = cacheTime.NowFunc()
 
 	ctx := testlogging.Context(t)
 
-	blobtesting.AssertListResultsIDs(ctx, t, cachest, "")
+	assertCacheEntries(ctx, t, cachest, "")
 	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
 	// cached blob gets written
-	blobtesting.AssertListResultsIDs(ctx, t, cachest, "", "n")
+	assertCacheEntries(ctx, t, cachest, "", "n")
 	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
 
 	// modify underlying storage without going through cache layer
@@ -90,3 +98,551 @@ func TestListCache(t *testing.T) {
 		return errFake
 	}), errFake)
 }
+
+// syntheticListStorage generates a large list of blobs on the fly without keeping them in memory.
+type syntheticListStorage struct {
+	blob.Storage
+
+	count     int
+	listCalls int
+}
+
+func (s *syntheticListStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb func(blob.Metadata) error) error {
+	s.listCalls++
+
+	for i := 0; i < s.count; i++ {
+		if err := cb(blob.Metadata{
+			BlobID: blob.ID(fmt.Sprintf("%v%08d", prefix, i)),
+			Length: int64(i),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxPutStorage records the size of the largest blob written and counts blob reads.
+type maxPutStorage struct {
+	blob.Storage
+
+	maxPutLength int
+	getBlobCalls int
+}
+
+func (s *maxPutStorage) GetBlob(ctx context.Context, blobID blob.ID, offset, length int64) ([]byte, error) {
+	s.getBlobCalls++
+
+	// nolint:wrapcheck
+	return s.Storage.GetBlob(ctx, blobID, offset, length)
+}
+
+func (s *maxPutStorage) PutBlob(ctx context.Context, blobID blob.ID, data blob.Bytes) error {
+	if l := data.Length(); l > s.maxPutLength {
+		s.maxPutLength = l
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.PutBlob(ctx, blobID, data)
+}
+
+func TestListCacheBatching(t *testing.T) {
+	const (
+		blobCount = 100000
+		batchSize = 1000
+	)
+
+	ctx := testlogging.Context(t)
+
+	realStorage := &syntheticListStorage{
+		Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
+		count:   blobCount,
+	}
+	cachest := &maxPutStorage{Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)}
+
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:      []blob.ID{"n"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+		BatchSize:     batchSize,
+	})
+
+	verifyList := func() {
+		t.Helper()
+
+		cnt := 0
+
+		require.NoError(t, lc.ListBlobs(ctx, "n", func(bm blob.Metadata) error {
+			require.Equal(t, blob.ID(fmt.Sprintf("n%08d", cnt)), bm.BlobID)
+			require.Equal(t, int64(cnt), bm.Length)
+			cnt++
+
+			return nil
+		}))
+
+		require.Equal(t, blobCount, cnt)
+	}
+
+	verifyList()
+	require.Equal(t, 1, realStorage.listCalls)
+
+	// each cache entry holds at most one batch, so the largest entry (and the memory needed to
+	// build or read it) is bounded by the batch size and not by the size of the list.
+	cacheEntries := blobCount / batchSize
+	assertCacheEntries(ctx, t, cachest, "n.", cacheEntryIDs(cacheEntries)...)
+	require.Less(t, cachest.maxPutLength, 200*batchSize)
+
+	// second list is served from the cache, reading each cache entry once.
+	cachest.getBlobCalls = 0
+
+	verifyList()
+	require.Equal(t, 1, realStorage.listCalls)
+	require.Equal(t, cacheEntries, cachest.getBlobCalls)
+
+	// callback errors are propagated.
+	require.ErrorIs(t, lc.ListBlobs(ctx, "n", func(m blob.Metadata) error {
+		return errFake
+	}), errFake)
+
+	// corrupted batch is detected after some results have been reported, so listing fails
+	// and the cache is invalidated, next listing falls back to the underlying storage and rebuilds the cache.
+	require.NoError(t, cachest.PutBlob(ctx, cachedBatchID(ctx, t, cachest, "n", 7), gather.FromSlice([]byte{1, 2, 3})))
+	require.Error(t, lc.ListBlobs(ctx, "n", func(bm blob.Metadata) error { return nil }))
+	require.Equal(t, 1, realStorage.listCalls)
+
+	verifyList()
+	require.Equal(t, 2, realStorage.listCalls)
+
+	verifyList()
+	require.Equal(t, 2, realStorage.listCalls)
+
+	// missing batch falls back to the underlying storage before reporting any results.
+	require.NoError(t, cachest.DeleteBlob(ctx, cachedBatchID(ctx, t, cachest, "n", 42)))
+	verifyList()
+	require.Equal(t, 3, realStorage.listCalls)
+	assertCacheEntries(ctx, t, cachest, "n.", cacheEntryIDs(cacheEntries)...)
+}
+
+func TestListCacheDeletesStaleBatches(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := &syntheticListStorage{
+		Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
+		count:   10,
+	}
+	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:                []blob.ID{"n"},
+		HMACSecret:              []byte("hmac-secret"),
+		CacheDuration:           1 * time.Minute,
+		BatchSize:               2,
+		MaxCachedBlobsPerPrefix: 10,
+	})
+
+	list := func() {
+		t.Helper()
+
+		require.NoError(t, lc.ListBlobs(ctx, "n", func(bm blob.Metadata) error {
+			return nil
+		}))
+	}
+
+	list()
+	assertCacheEntries(ctx, t, cachest, "n", "n", "n.1", "n.2", "n.3", "n.4")
+
+	// shorter list replaces the cached one, batches that are no longer used are removed.
+	realStorage.count = 5
+
+	require.NoError(t, lc.DeleteBlob(ctx, "n-nonexistent"))
+	list()
+	assertCacheEntries(ctx, t, cachest, "n", "n", "n.1", "n.2")
+
+	// abandoning caching removes all batches.
+	realStorage.count = 11
+
+	require.NoError(t, lc.DeleteBlob(ctx, "n-nonexistent"))
+	list()
+	assertCacheEntries(ctx, t, cachest, "n")
+
+	// flushing caches removes all batches.
+	realStorage.count = 5
+
+	list()
+	assertCacheEntries(ctx, t, cachest, "n", "n", "n.1", "n.2")
+	require.NoError(t, lc.FlushCaches(ctx))
+	assertCacheEntries(ctx, t, cachest, "n")
+}
+
+// assertCacheEntries verifies IDs of cache entries with the provided prefix, where IDs of extra batches
+// are compared without the expiration time.
+func assertCacheEntries(ctx context.Context, t *testing.T, st blob.Storage, prefix blob.ID, want ...blob.ID) {
+	t.Helper()
+
+	var got []blob.ID
+
+	require.NoError(t, st.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		id := string(bm.BlobID)
+
+		if strings.Count(id, ".") == 2 {
+			id = id[0:strings.LastIndex(id, ".")]
+		}
+
+		got = append(got, blob.ID(id))
+
+		return nil
+	}))
+
+	require.ElementsMatch(t, want, got)
+}
+
+// cachedBatchID returns the ID of the n-th extra batch of the cached list for the prefix.
+func cachedBatchID(ctx context.Context, t *testing.T, st blob.Storage, prefix blob.ID, n int) blob.ID {
+	t.Helper()
+
+	var result blob.ID
+
+	require.NoError(t, st.ListBlobs(ctx, blob.ID(fmt.Sprintf("%v.%v.", prefix, n)), func(bm blob.Metadata) error {
+		result = bm.BlobID
+		return nil
+	}))
+
+	require.NotEmpty(t, result)
+
+	return result
+}
+
+func cacheEntryIDs(n int) []blob.ID {
+	var result []blob.ID
+
+	// the first batch is stored in the entry for the prefix itself.
+	for i := 1; i < n; i++ {
+		result = append(result, blob.ID(fmt.Sprintf("n.%v", i)))
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+
+	return result
+}
+
+// getMetadataCountingStorage counts GetMetadata() calls.
+type getMetadataCountingStorage struct {
+	blob.Storage
+
+	getMetadataCalls int
+}
+
+func (s *getMetadataCountingStorage) GetMetadata(ctx context.Context, blobID blob.ID) (blob.Metadata, error) {
+	s.getMetadataCalls++
+
+	// nolint:wrapcheck
+	return s.Storage.GetMetadata(ctx, blobID)
+}
+
+func TestListCacheGetMetadata(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := &getMetadataCountingStorage{Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)}
+	cacheTime := faketime.NewTimeAdvance(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), 0)
+	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, cacheTime.NowFunc())
+
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:      []blob.ID{"n"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+		BatchSize:     2,
+	}).(*listCacheStorage)
+	lc.cacheTimeFunc = cacheTime.NowFunc()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, realStorage.PutBlob(ctx, blob.ID(fmt.Sprintf("n%v", i)), gather.FromSlice(make([]byte, i+1))))
+	}
+
+	require.NoError(t, realStorage.PutBlob(ctx, "x1", gather.FromSlice([]byte{1})))
+
+	// before the list is cached, metadata comes from the underlying storage.
+	bm, err := lc.GetMetadata(ctx, "n1")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), bm.Length)
+	require.Equal(t, 1, realStorage.getMetadataCalls)
+
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n0", "n1", "n2", "n3", "n4")
+
+	// blobs from the first and subsequent batches are served from the cache.
+	for _, id := range []blob.ID{"n0", "n1", "n4"} {
+		bm, err = lc.GetMetadata(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, id, bm.BlobID)
+	}
+
+	require.Equal(t, 1, realStorage.getMetadataCalls)
+
+	// blobs not in the cached list or not in cached prefixes go to the underlying storage.
+	_, err = lc.GetMetadata(ctx, "n5")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	require.Equal(t, 2, realStorage.getMetadataCalls)
+
+	_, err = lc.GetMetadata(ctx, "x1")
+	require.NoError(t, err)
+	require.Equal(t, 3, realStorage.getMetadataCalls)
+
+	// writing through the cache invalidates cached metadata.
+	require.NoError(t, lc.PutBlob(ctx, "n1", gather.FromSlice([]byte{1, 2, 3, 4, 5, 6, 7})))
+
+	bm, err = lc.GetMetadata(ctx, "n1")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), bm.Length)
+	require.Equal(t, 4, realStorage.getMetadataCalls)
+
+	// re-populate the cache, then delete through the cache.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n0", "n1", "n2", "n3", "n4")
+
+	_, err = lc.GetMetadata(ctx, "n2")
+	require.NoError(t, err)
+	require.Equal(t, 4, realStorage.getMetadataCalls)
+
+	require.NoError(t, lc.DeleteBlob(ctx, "n2"))
+
+	_, err = lc.GetMetadata(ctx, "n2")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	require.Equal(t, 5, realStorage.getMetadataCalls)
+
+	// re-populate the cache, then change modification time through the cache.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n0", "n1", "n3", "n4")
+
+	newTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, lc.SetTime(ctx, "n3", newTime))
+
+	bm, err = lc.GetMetadata(ctx, "n3")
+	require.NoError(t, err)
+	require.True(t, bm.Timestamp.Equal(newTime), "unexpected timestamp: %v", bm.Timestamp)
+	require.Equal(t, 6, realStorage.getMetadataCalls)
+
+	// after the cache expires, metadata is fetched again.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n0", "n1", "n3", "n4")
+	cacheTime.Advance(lc.cacheDuration)
+
+	_, err = lc.GetMetadata(ctx, "n3")
+	require.NoError(t, err)
+	require.Equal(t, 7, realStorage.getMetadataCalls)
+}
+
+// getBlobCountingStorage counts GetBlob() calls.
+type getBlobCountingStorage struct {
+	blob.Storage
+
+	getBlobCalls int
+}
+
+func (s *getBlobCountingStorage) GetBlob(ctx context.Context, blobID blob.ID, offset, length int64) ([]byte, error) {
+	s.getBlobCalls++
+
+	// nolint:wrapcheck
+	return s.Storage.GetBlob(ctx, blobID, offset, length)
+}
+
+func TestListCacheGetMetadataIndex(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := &getMetadataCountingStorage{Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)}
+	cacheTime := faketime.NewTimeAdvance(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), 0)
+	cachest := &getBlobCountingStorage{Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, cacheTime.NowFunc())}
+
+	opt := Options{
+		Prefixes:      []blob.ID{"n"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+		BatchSize:     2,
+	}
+
+	lc := NewWrapper(realStorage, cachest, opt).(*listCacheStorage)
+	lc.cacheTimeFunc = cacheTime.NowFunc()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, realStorage.PutBlob(ctx, blob.ID(fmt.Sprintf("n%v", i)), gather.FromSlice(make([]byte, i+1))))
+	}
+
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n0", "n1", "n2", "n3", "n4")
+
+	// the first lookup reads all 3 batches, subsequent ones only verify the first one.
+	cachest.getBlobCalls = 0
+
+	for _, id := range []blob.ID{"n4", "n0", "n3", "n4"} {
+		bm, err := lc.GetMetadata(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, id, bm.BlobID)
+	}
+
+	require.Equal(t, 3+3, cachest.getBlobCalls)
+	require.Equal(t, 0, realStorage.getMetadataCalls)
+
+	// list cached by another wrapper with a different expiration time replaces the index.
+	require.NoError(t, realStorage.PutBlob(ctx, "n5", gather.FromSlice([]byte{1})))
+	cacheTime.Advance(time.Second)
+
+	lc2 := NewWrapper(realStorage, cachest, opt).(*listCacheStorage)
+	lc2.cacheTimeFunc = cacheTime.NowFunc()
+	require.NoError(t, lc2.FlushCaches(ctx))
+	blobtesting.AssertListResultsIDs(ctx, t, lc2, "n", "n0", "n1", "n2", "n3", "n4", "n5")
+
+	_, err := lc.GetMetadata(ctx, "n5")
+	require.NoError(t, err)
+	require.Equal(t, 0, realStorage.getMetadataCalls)
+
+	// invalidation drops the index.
+	require.NoError(t, lc.DeleteBlob(ctx, "n5"))
+	require.Empty(t, lc.metadataIndexes)
+
+	_, err = lc.GetMetadata(ctx, "n5")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	require.Equal(t, 1, realStorage.getMetadataCalls)
+}
+
+func TestListCacheMaxCachedBlobsPerPrefix(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := &syntheticListStorage{
+		Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
+		count:   5,
+	}
+	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:                []blob.ID{"n", "x"},
+		HMACSecret:              []byte("hmac-secret"),
+		CacheDuration:           1 * time.Minute,
+		BatchSize:               2,
+		MaxCachedBlobsPerPrefix: 5,
+	})
+
+	listCount := func(prefix blob.ID) int {
+		t.Helper()
+
+		cnt := 0
+
+		require.NoError(t, lc.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			cnt++
+			return nil
+		}))
+
+		return cnt
+	}
+
+	// small listing is cached.
+	require.Equal(t, 5, listCount("n"))
+	require.Equal(t, 5, listCount("n"))
+	require.Equal(t, 1, realStorage.listCalls)
+	assertCacheEntries(ctx, t, cachest, "n", "n", "n.1", "n.2")
+
+	// oversized listing is returned in full but not cached.
+	realStorage.count = 6
+
+	require.Equal(t, 6, listCount("x"))
+	require.Equal(t, 6, listCount("x"))
+	require.Equal(t, 3, realStorage.listCalls)
+	assertCacheEntries(ctx, t, cachest, "x")
+
+	// cached prefix is unaffected.
+	require.Equal(t, 5, listCount("n"))
+	require.Equal(t, 3, realStorage.listCalls)
+}
+
+func TestListCacheStats(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:      []blob.ID{"n", "x"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+	})
+
+	sp, ok := lc.(StatsProvider)
+	require.True(t, ok)
+	require.Equal(t, Stats{}, sp.Stats())
+
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	require.Equal(t, Stats{Hits: 2, Misses: 1}, sp.Stats())
+
+	// put and delete invalidate the matching prefix.
+	require.NoError(t, lc.PutBlob(ctx, "n1", gather.FromSlice([]byte{1})))
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1")
+	require.NoError(t, lc.DeleteBlob(ctx, "n1"))
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	require.Equal(t, Stats{Hits: 3, Misses: 3, Invalidations: 2}, sp.Stats())
+
+	// non-cached prefixes are not counted.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "z")
+	require.NoError(t, lc.PutBlob(ctx, "z1", gather.FromSlice([]byte{1})))
+	require.Equal(t, Stats{Hits: 3, Misses: 3, Invalidations: 2}, sp.Stats())
+}
+
+func TestListCacheInvalidateLists(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	lc := NewWrapper(realStorage, cachest, Options{
+		Prefixes:      []blob.ID{"n", "xe", "xb"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+	})
+
+	inv, ok := lc.(Invalidator)
+	require.True(t, ok)
+
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "xe")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "xb")
+
+	// blobs written directly to the underlying storage are not visible until lists are invalidated.
+	for _, id := range []blob.ID{"n1", "xe1", "xb1"} {
+		require.NoError(t, realStorage.PutBlob(ctx, id, gather.FromSlice([]byte{1})))
+	}
+
+	inv.InvalidateLists(ctx, "x")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "xe", "xe1")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "xb", "xb1")
+
+	// invalidating a longer prefix invalidates the cached prefix containing it.
+	inv.InvalidateLists(ctx, "n1")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1")
+}
+
+func TestListCacheDeleteBlobsBatch(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	base := &blobtesting.BatchDeletingStorage{Storage: realStorage}
+	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	lc := NewWrapper(base, cachest, Options{
+		Prefixes:      []blob.ID{"n", "x"},
+		HMACSecret:    []byte("hmac-secret"),
+		CacheDuration: 1 * time.Minute,
+	})
+
+	for _, id := range []blob.ID{"n1", "n2", "x1"} {
+		require.NoError(t, lc.PutBlob(ctx, id, gather.FromSlice([]byte{1})))
+	}
+
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1", "n2")
+
+	sp := lc.(StatsProvider)
+	before := sp.Stats()
+
+	require.Equal(t, []error{nil, nil}, blob.DeleteBlobs(ctx, lc, []blob.ID{"n1", "n2"}))
+	require.Equal(t, []int{2}, base.BatchSizes())
+
+	// the prefix is invalidated once for the whole batch.
+	require.Equal(t, before.Invalidations+1, sp.Stats().Invalidations)
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "x", "x1")
+}
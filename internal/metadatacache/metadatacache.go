@@ -0,0 +1,211 @@
+// Package metadatacache defines a blob.Storage wrapper that caches results of GetMetadata calls
+// in memory for short duration of time.
+package metadatacache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// maxCachedEntries is the number of cached entries above which expired entries are evicted.
+const maxCachedEntries = 10000
+
+// Invalidator is implemented by the wrapper and allows callers that know that a blob has changed
+// outside of the wrapper to force the next GetMetadata() to go to the underlying storage.
+type Invalidator interface {
+	Invalidate(blobID blob.ID)
+	InvalidatePrefix(prefix blob.ID)
+}
+
+type cachedMetadata struct {
+	md          blob.Metadata
+	expireAfter time.Time
+}
+
+type metadataCacheStorage struct {
+	blob.Storage
+	cacheDuration  time.Duration
+	cacheTimeFunc  func() time.Time
+	cachedPrefixes []blob.ID
+
+	mu      sync.Mutex
+	entries map[blob.ID]cachedMetadata
+}
+
+// GetMetadata implements blob.Storage and returns cached metadata for the blob, if available.
+func (s *metadataCacheStorage) GetMetadata(ctx context.Context, blobID blob.ID) (blob.Metadata, error) {
+	if !s.isCacheable(blobID) {
+		// nolint:wrapcheck
+		return s.Storage.GetMetadata(ctx, blobID)
+	}
+
+	if md, ok := s.getCached(blobID); ok {
+		return md, nil
+	}
+
+	md, err := s.Storage.GetMetadata(ctx, blobID)
+	if err != nil {
+		// nolint:wrapcheck
+		return md, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) >= maxCachedEntries {
+		s.evictExpiredLocked()
+	}
+
+	s.entries[blobID] = cachedMetadata{md, s.cacheTimeFunc().Add(s.cacheDuration)}
+
+	return md, nil
+}
+
+// PutBlob implements blob.Storage and invalidates cached metadata of the blob.
+func (s *metadataCacheStorage) PutBlob(ctx context.Context, blobID blob.ID, data blob.Bytes) error {
+	err := s.Storage.PutBlob(ctx, blobID, data)
+	s.invalidate(blobID)
+
+	// nolint:wrapcheck
+	return err
+}
+
+// SetTime implements blob.Storage and invalidates cached metadata of the blob.
+func (s *metadataCacheStorage) SetTime(ctx context.Context, blobID blob.ID, t time.Time) error {
+	err := s.Storage.SetTime(ctx, blobID, t)
+	s.invalidate(blobID)
+
+	// nolint:wrapcheck
+	return err
+}
+
+// DeleteBlob implements blob.Storage and invalidates cached metadata of the blob.
+func (s *metadataCacheStorage) DeleteBlob(ctx context.Context, blobID blob.ID) error {
+	err := s.Storage.DeleteBlob(ctx, blobID)
+	s.invalidate(blobID)
+
+	// nolint:wrapcheck
+	return err
+}
+
+// DeleteBlobs implements blob.BatchDeleter and invalidates cached metadata of the blobs.
+func (s *metadataCacheStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	errs := blob.DeleteBlobs(ctx, s.Storage, ids)
+
+	for _, id := range ids {
+		s.invalidate(id)
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *metadataCacheStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.Storage)
+}
+
+func (s *metadataCacheStorage) FlushCaches(ctx context.Context) error {
+	s.mu.Lock()
+	s.entries = map[blob.ID]cachedMetadata{}
+	s.mu.Unlock()
+
+	return errors.Wrap(s.Storage.FlushCaches(ctx), "error flushing caches")
+}
+
+func (s *metadataCacheStorage) isCacheable(blobID blob.ID) bool {
+	for _, p := range s.cachedPrefixes {
+		if strings.HasPrefix(string(blobID), string(p)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *metadataCacheStorage) getCached(blobID blob.ID) (blob.Metadata, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[blobID]
+	if !ok {
+		return blob.Metadata{}, false
+	}
+
+	if !s.cacheTimeFunc().Before(e.expireAfter) {
+		delete(s.entries, blobID)
+		return blob.Metadata{}, false
+	}
+
+	return e.md, true
+}
+
+func (s *metadataCacheStorage) invalidate(blobID blob.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, blobID)
+}
+
+// Invalidate implements Invalidator.
+func (s *metadataCacheStorage) Invalidate(blobID blob.ID) {
+	s.invalidate(blobID)
+}
+
+// InvalidatePrefix implements Invalidator.
+func (s *metadataCacheStorage) InvalidatePrefix(prefix blob.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.entries {
+		if strings.HasPrefix(string(k), string(prefix)) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+func (s *metadataCacheStorage) evictExpiredLocked() {
+	now := s.cacheTimeFunc()
+
+	for k, e := range s.entries {
+		if !now.Before(e.expireAfter) {
+			delete(s.entries, k)
+		}
+	}
+
+	if len(s.entries) >= maxCachedEntries {
+		// all entries are still valid, start over.
+		s.entries = map[blob.ID]cachedMetadata{}
+	}
+}
+
+// NewWrapper returns new wrapper that caches results of GetMetadata() for the provided duration.
+// Only blobs with one of the provided prefixes are cached, which should be limited to blobs that
+// are never rewritten in place, because changes made by other clients are not observed until the
+// cached entry expires. Cached entries are invalidated when the blob is written, deleted or its
+// time is modified through the wrapper.
+func NewWrapper(st blob.Storage, duration time.Duration, cachedPrefixes []blob.ID) blob.Storage {
+	if duration <= 0 || len(cachedPrefixes) == 0 {
+		return st
+	}
+
+	return &metadataCacheStorage{
+		Storage:        st,
+		cacheDuration:  duration,
+		cacheTimeFunc:  clock.Now,
+		cachedPrefixes: append([]blob.ID(nil), cachedPrefixes...),
+		entries:        map[blob.ID]cachedMetadata{},
+	}
+}
+
+var (
+	_ blob.Storage      = (*metadataCacheStorage)(nil)
+	_ blob.BatchDeleter = (*metadataCacheStorage)(nil)
+	_ Invalidator       = (*metadataCacheStorage)(nil)
+)
@@ -0,0 +1,202 @@
+package metadatacache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+type getMetadataCountingStorage struct {
+	blob.Storage
+
+	getMetadataCount int
+}
+
+func (s *getMetadataCountingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	s.getMetadataCount++
+
+	return s.Storage.GetMetadata(ctx, id)
+}
+
+func TestMetadataCache(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	cacheTime := faketime.NewTimeAdvance(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), 0)
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	counting := &getMetadataCountingStorage{Storage: realStorage}
+
+	mc := NewWrapper(counting, 1*time.Minute, []blob.ID{"b"}).(*metadataCacheStorage)
+	mc.cacheTimeFunc = cacheTime.NowFunc()
+
+	require.NoError(t, mc.PutBlob(ctx, "b1", gather.FromSlice([]byte{1, 2, 3})))
+
+	md, err := mc.GetMetadata(ctx, "b1")
+	require.NoError(t, err)
+	require.EqualValues(t, 3, md.Length)
+	require.Equal(t, 1, counting.getMetadataCount)
+
+	// repeated calls within TTL are served from cache.
+	for i := 0; i < 5; i++ {
+		md2, err := mc.GetMetadata(ctx, "b1")
+		require.NoError(t, err)
+		require.Equal(t, md, md2)
+	}
+
+	require.Equal(t, 1, counting.getMetadataCount)
+
+	// writing through the wrapper invalidates cached metadata.
+	require.NoError(t, mc.PutBlob(ctx, "b1", gather.FromSlice([]byte{1, 2, 3, 4})))
+
+	md, err = mc.GetMetadata(ctx, "b1")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, md.Length)
+	require.Equal(t, 2, counting.getMetadataCount)
+
+	// modifications bypassing the wrapper are not visible until cache expires.
+	require.NoError(t, realStorage.PutBlob(ctx, "b1", gather.FromSlice([]byte{1})))
+
+	md, err = mc.GetMetadata(ctx, "b1")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, md.Length)
+	require.Equal(t, 2, counting.getMetadataCount)
+
+	cacheTime.Advance(1 * time.Hour)
+
+	md, err = mc.GetMetadata(ctx, "b1")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, md.Length)
+	require.Equal(t, 3, counting.getMetadataCount)
+
+	// deleting through the wrapper invalidates cached metadata.
+	require.NoError(t, mc.DeleteBlob(ctx, "b1"))
+
+	_, err = mc.GetMetadata(ctx, "b1")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+
+	// errors are not cached.
+	_, err = mc.GetMetadata(ctx, "b1")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	require.Equal(t, 5, counting.getMetadataCount)
+
+	// flushing caches drops all entries.
+	require.NoError(t, mc.PutBlob(ctx, "b2", gather.FromSlice([]byte{1})))
+	_, err = mc.GetMetadata(ctx, "b2")
+	require.NoError(t, err)
+	require.NoError(t, mc.FlushCaches(ctx))
+	_, err = mc.GetMetadata(ctx, "b2")
+	require.NoError(t, err)
+	require.Equal(t, 7, counting.getMetadataCount)
+}
+
+func TestMetadataCacheVerifyStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	st := NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil), 1*time.Minute, []blob.ID{""})
+	blobtesting.VerifyStorage(ctx, t, st)
+}
+
+func TestMetadataCacheDisabled(t *testing.T) {
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.Equal(t, st, NewWrapper(st, 0, []blob.ID{""}))
+	require.Equal(t, st, NewWrapper(st, 1*time.Minute, nil))
+}
+
+func TestMetadataCacheInvalidate(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	counting := &getMetadataCountingStorage{Storage: realStorage}
+
+	st := NewWrapper(counting, 1*time.Hour, []blob.ID{"a", "b"})
+	inv, ok := st.(Invalidator)
+	require.True(t, ok)
+
+	for _, id := range []blob.ID{"a1", "a2", "b1"} {
+		require.NoError(t, realStorage.PutBlob(ctx, id, gather.FromSlice([]byte{1})))
+
+		_, err := st.GetMetadata(ctx, id)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 3, counting.getMetadataCount)
+
+	// blobs changed behind the wrapper's back.
+	for _, id := range []blob.ID{"a1", "a2", "b1"} {
+		require.NoError(t, realStorage.PutBlob(ctx, id, gather.FromSlice([]byte{1, 2})))
+	}
+
+	getLength := func(id blob.ID) int64 {
+		md, err := st.GetMetadata(ctx, id)
+		require.NoError(t, err)
+
+		return md.Length
+	}
+
+	inv.Invalidate("a1")
+
+	require.EqualValues(t, 2, getLength("a1"))
+	require.EqualValues(t, 1, getLength("a2"))
+	require.EqualValues(t, 1, getLength("b1"))
+	require.Equal(t, 4, counting.getMetadataCount)
+
+	inv.InvalidatePrefix("a")
+
+	require.EqualValues(t, 2, getLength("a1"))
+	require.EqualValues(t, 2, getLength("a2"))
+	require.EqualValues(t, 1, getLength("b1"))
+	require.Equal(t, 6, counting.getMetadataCount)
+}
+
+func TestMetadataCacheOnlyCachedPrefixes(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	counting := &getMetadataCountingStorage{Storage: realStorage}
+
+	st := NewWrapper(counting, 1*time.Hour, []blob.ID{"p", "n"})
+
+	for _, id := range []blob.ID{"p1", "n1", "kopia.maintenance"} {
+		require.NoError(t, st.PutBlob(ctx, id, gather.FromSlice([]byte{1})))
+
+		for i := 0; i < 3; i++ {
+			_, err := st.GetMetadata(ctx, id)
+			require.NoError(t, err)
+		}
+	}
+
+	// only blobs with cached prefixes are served from cache.
+	require.Equal(t, 1+1+3, counting.getMetadataCount)
+}
+
+func TestMetadataCacheDeleteBlobsBatch(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	realStorage := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	base := &blobtesting.BatchDeletingStorage{Storage: realStorage}
+
+	st := NewWrapper(base, 1*time.Hour, []blob.ID{"a"})
+
+	for _, id := range []blob.ID{"a1", "a2"} {
+		require.NoError(t, st.PutBlob(ctx, id, gather.FromSlice([]byte{1})))
+
+		_, err := st.GetMetadata(ctx, id)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []error{nil, nil}, blob.DeleteBlobs(ctx, st, []blob.ID{"a1", "a2"}))
+	require.Equal(t, []int{2}, base.BatchSizes())
+
+	// cached metadata of deleted blobs is not returned.
+	for _, id := range []blob.ID{"a1", "a2"} {
+		_, err := st.GetMetadata(ctx, id)
+		require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	}
+}
@@ -142,6 +142,24 @@ func (imd *Directory) AddDir(name string, permissions os.FileMode) *Directory {
 	return subdir
 }
 
+// AddSymlink adds a mock symlink with the specified name, target and permissions.
+func (imd *Directory) AddSymlink(name, target string, permissions os.FileMode) fs.Symlink {
+	imd, name = imd.resolveSubdir(name)
+
+	sl := &inmemorySymlink{
+		entry: entry{
+			name: name,
+			mode: permissions | os.ModeSymlink,
+			size: int64(len(target)),
+		},
+		target: target,
+	}
+
+	imd.addChild(sl)
+
+	return sl
+}
+
 // AddErrorEntry adds a fake directory with a given name and permissions.
 func (imd *Directory) AddErrorEntry(name string, permissions os.FileMode, err error) *ErrorEntry {
 	imd, name = imd.resolveSubdir(name)
@@ -238,6 +256,11 @@ func (imd *Directory) OnReaddir(cb func()) {
 	imd.onReaddir = cb
 }
 
+// SetModTime changes the modification time of a given directory.
+func (imd *Directory) SetModTime(t time.Time) {
+	imd.modTime = t
+}
+
 // Child gets the named child of a directory.
 func (imd *Directory) Child(ctx context.Context, name string) (fs.Entry, error) {
 	// nolint:wrapcheck
@@ -271,6 +294,11 @@ func (imf *File) SetContents(b []byte) {
 	}
 }
 
+// SetModTime changes the modification time of a given file.
+func (imf *File) SetModTime(t time.Time) {
+	imf.modTime = t
+}
+
 type fileReader struct {
 	ReaderSeekerCloser
 	entry fs.Entry
@@ -295,10 +323,12 @@ func (imf *File) Open(ctx context.Context) (fs.Reader, error) {
 
 type inmemorySymlink struct {
 	entry
+
+	target string
 }
 
 func (imsl *inmemorySymlink) Readlink(ctx context.Context) (string, error) {
-	panic("not implemented yet")
+	return imsl.target, nil
 }
 
 // NewDirectory returns new mock directory.
@@ -0,0 +1,190 @@
+// Package ratelimit implements token-bucket rate limiter for throughput-capped operations.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+)
+
+// Mode determines how tokens are consumed by readers and writers wrapped by the Limiter.
+type Mode int
+
+const (
+	// Bytes causes each byte read or written to consume one token.
+	Bytes Mode = iota
+
+	// Ops causes each Read() or Write() call to consume one token.
+	Ops
+)
+
+// Limiter is a token-bucket rate limiter. Tokens are replenished at a fixed rate up to burst size.
+// Callers may consume more tokens than currently available, in which case they wait until
+// the deficit has been replenished.
+type Limiter struct {
+	mode  Mode
+	rate  float64 // tokens per second, <=0 means unlimited
+	burst float64
+
+	timeNow func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a Limiter that allows the given number of tokens per second with provided burst size.
+// Rate <= 0 means unlimited.
+func NewLimiter(mode Mode, ratePerSecond float64, burst int) *Limiter {
+	return newLimiterWithClock(mode, ratePerSecond, burst, clock.Now, sleepWithContext)
+}
+
+func newLimiterWithClock(mode Mode, ratePerSecond float64, burst int, timeNow func() time.Time, sleep func(ctx context.Context, d time.Duration) error) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		mode:    mode,
+		rate:    ratePerSecond,
+		burst:   float64(burst),
+		timeNow: timeNow,
+		sleep:   sleep,
+		tokens:  float64(burst),
+		last:    timeNow(),
+	}
+}
+
+// Wait waits until a single token is available.
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN waits until n tokens are available or the context is canceled.
+// When the context is canceled, the tokens are returned to the bucket.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || l.rate <= 0 || n <= 0 {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "rate limiter wait canceled")
+	}
+
+	d := l.reserve(float64(n))
+	if d <= 0 {
+		return nil
+	}
+
+	if err := l.sleep(ctx, d); err != nil {
+		l.refund(float64(n))
+
+		return errors.Wrap(err, "rate limiter wait canceled")
+	}
+
+	return nil
+}
+
+// reserve consumes n tokens and returns the amount of time the caller must wait for them to be available.
+func (l *Limiter) reserve(n float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeNow()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+
+	l.last = now
+	l.tokens -= n
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) refund(n float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens += n
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+func (l *Limiter) tokensFor(n int) int {
+	if l.mode == Ops {
+		return 1
+	}
+
+	return n
+}
+
+// Reader returns io.Reader that limits the rate of reads from the provided reader.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &limitedReader{ctx, r, l}
+}
+
+// Writer returns io.Writer that limits the rate of writes to the provided writer.
+func (l *Limiter) Writer(ctx context.Context, w io.Writer) io.Writer {
+	return &limitedWriter{ctx, w, l}
+}
+
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.l.WaitN(r.ctx, r.l.tokensFor(n)); werr != nil {
+			return n, werr
+		}
+	}
+
+	// nolint:wrapcheck
+	return n, err
+}
+
+type limitedWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *Limiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if err := w.l.WaitN(w.ctx, w.l.tokensFor(len(p))); err != nil {
+		return 0, err
+	}
+
+	// nolint:wrapcheck
+	return w.w.Write(p)
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-t.C:
+		return nil
+	}
+}
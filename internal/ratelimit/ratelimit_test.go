@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/faketime"
+)
+
+func newFakeLimiter(mode Mode, rate float64, burst int) (*Limiter, *faketime.TimeAdvance) {
+	ta := faketime.NewTimeAdvance(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), 0)
+
+	return newLimiterWithClock(mode, rate, burst, ta.NowFunc(), func(ctx context.Context, d time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ta.Advance(d)
+
+		return nil
+	}), ta
+}
+
+func TestLimiterReaderBytes(t *testing.T) {
+	l, ta := newFakeLimiter(Bytes, 1000, 100)
+	start := ta.NowFunc()()
+
+	data := make([]byte, 10000)
+
+	n, err := io.Copy(ioutil.Discard, l.Reader(context.Background(), bytes.NewReader(data)))
+	require.NoError(t, err)
+	require.EqualValues(t, len(data), n)
+
+	// 10000 bytes at 1000 bytes/sec, minus initial burst of 100 bytes.
+	require.InDelta(t, 9.9, ta.NowFunc()().Sub(start).Seconds(), 0.001)
+}
+
+func TestLimiterWriterOps(t *testing.T) {
+	l, ta := newFakeLimiter(Ops, 10, 1)
+	start := ta.NowFunc()()
+
+	var buf bytes.Buffer
+
+	w := l.Writer(context.Background(), &buf)
+
+	for i := 0; i < 21; i++ {
+		_, err := w.Write(make([]byte, 1000))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 21000, buf.Len())
+
+	// 21 writes at 10 ops/sec, minus initial burst of 1.
+	require.InDelta(t, 2, ta.NowFunc()().Sub(start).Seconds(), 0.001)
+}
+
+func TestLimiterReplenishesUpToBurst(t *testing.T) {
+	l, ta := newFakeLimiter(Bytes, 100, 50)
+	ctx := context.Background()
+
+	require.NoError(t, l.WaitN(ctx, 50))
+
+	// long idle period only replenishes up to burst size.
+	ta.Advance(time.Hour)
+
+	start := ta.NowFunc()()
+
+	require.NoError(t, l.WaitN(ctx, 50))
+	require.Equal(t, time.Duration(0), ta.NowFunc()().Sub(start))
+
+	require.NoError(t, l.WaitN(ctx, 100))
+	require.InDelta(t, 1, ta.NowFunc()().Sub(start).Seconds(), 0.001)
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l, ta := newFakeLimiter(Bytes, 0, 1)
+	start := ta.NowFunc()()
+
+	require.NoError(t, l.WaitN(context.Background(), 1e9))
+	require.Equal(t, time.Duration(0), ta.NowFunc()().Sub(start))
+
+	var nilLimiter *Limiter
+
+	require.NoError(t, nilLimiter.WaitN(context.Background(), 1e9))
+}
+
+func TestLimiterCancelation(t *testing.T) {
+	t.Parallel()
+
+	// real clock, 1 token per hour.
+	l := NewLimiter(Ops, 1.0/3600, 1)
+
+	require.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	t0 := time.Now()
+
+	require.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+	require.Less(t, time.Since(t0), 10*time.Second)
+
+	// canceled context fails immediately
+	require.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+
+	_, err := l.Writer(ctx, ioutil.Discard).Write([]byte{1})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -3,6 +3,7 @@
 
 import (
 	"context"
+	"math/rand"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,6 +21,35 @@
 
 const retryExponent = 1.5
 
+// Backoff describes the schedule of retries, zero values select defaults.
+type Backoff struct {
+	Initial     time.Duration // delay before the first retry
+	Max         time.Duration // maximum delay between retries
+	Factor      float64       // multiplier applied to the delay after each retry
+	MaxAttempts int           // maximum number of attempts, including the first one
+	Jitter      float64       // fraction [0..1] of each delay that is randomized
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial == 0 {
+		b.Initial = retryInitialSleepAmount
+	}
+
+	if b.Max == 0 {
+		b.Max = retryMaxSleepAmount
+	}
+
+	if b.Factor == 0 {
+		b.Factor = retryExponent
+	}
+
+	if b.MaxAttempts == 0 {
+		b.MaxAttempts = maxAttempts
+	}
+
+	return b
+}
+
 // AttemptFunc performs an attempt and returns a value (optional, may be nil) and an error.
 type AttemptFunc func() (interface{}, error)
 
@@ -33,6 +63,14 @@ func WithExponentialBackoff(ctx context.Context, desc string, attempt AttemptFun
 	return internalRetry(ctx, desc, attempt, isRetriableError, retryInitialSleepAmount, retryMaxSleepAmount, maxAttempts, retryExponent)
 }
 
+// WithBackoff runs the provided attempt until it succeeds, retrying on all errors that are
+// deemed retriable by the provided function according to the provided schedule.
+func WithBackoff(ctx context.Context, desc string, b Backoff, attempt AttemptFunc, isRetriableError IsRetriableFunc) (interface{}, error) {
+	b = b.withDefaults()
+
+	return internalRetryWithJitter(ctx, desc, attempt, isRetriableError, b.Initial, b.Max, b.MaxAttempts, b.Factor, b.Jitter)
+}
+
 // Periodically runs the provided attempt until it succeeds, waiting given fixed amount between attempts.
 func Periodically(ctx context.Context, interval time.Duration, count int, desc string, attempt AttemptFunc, isRetriableError IsRetriableFunc) (interface{}, error) {
 	return internalRetry(ctx, desc, attempt, isRetriableError, interval, interval, count, 1)
@@ -51,8 +89,14 @@ func PeriodicallyNoValue(ctx context.Context, interval time.Duration, count int,
 // deemed retriable by the provided function. The delay between retries grows exponentially up to
 // a certain limit.
 func internalRetry(ctx context.Context, desc string, attempt AttemptFunc, isRetriableError IsRetriableFunc, initial, max time.Duration, count int, factor float64) (interface{}, error) {
+	return internalRetryWithJitter(ctx, desc, attempt, isRetriableError, initial, max, count, factor, 0)
+}
+
+func internalRetryWithJitter(ctx context.Context, desc string, attempt AttemptFunc, isRetriableError IsRetriableFunc, initial, max time.Duration, count int, factor, jitter float64) (interface{}, error) {
 	sleepAmount := initial
 
+	var lastErr error
+
 	for i := 0; i < count; i++ {
 		v, err := attempt()
 		if err == nil {
@@ -63,8 +107,22 @@ func internalRetry(ctx context.Context, desc string, attempt AttemptFunc, isRetr
 			return v, err
 		}
 
-		log(ctx).Debugf("got error %v when %v (#%v), sleeping for %v before retrying", err, desc, i, sleepAmount)
-		time.Sleep(sleepAmount)
+		lastErr = err
+
+		if i == count-1 {
+			break
+		}
+
+		d := sleepAmount - time.Duration(jitter*rand.Float64()*float64(sleepAmount)) //nolint:gosec
+
+		log(ctx).Debugf("got error %v when %v (#%v), sleeping for %v before retrying", err, desc, i, d)
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "canceled while retrying %v", desc)
+		case <-time.After(d):
+		}
+
 		sleepAmount = time.Duration(float64(sleepAmount) * factor)
 
 		if sleepAmount > max {
@@ -72,7 +130,7 @@ func internalRetry(ctx context.Context, desc string, attempt AttemptFunc, isRetr
 		}
 	}
 
-	return nil, errors.Errorf("unable to complete %v despite %v retries", desc, count)
+	return nil, errors.Wrapf(lastErr, "unable to complete %v despite %v retries", desc, count)
 }
 
 // WithExponentialBackoffNoValue is a shorthand for WithExponentialBackoff except the
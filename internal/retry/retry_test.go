@@ -1,6 +1,7 @@
 package retry
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -58,3 +59,43 @@ func TestRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryWithBackoff(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	cnt := 0
+
+	_, err := WithBackoff(ctx, "some-op", Backoff{Initial: time.Millisecond, MaxAttempts: 4, Jitter: 0.5}, func() (interface{}, error) {
+		cnt++
+		return nil, errRetriable
+	}, isRetriable)
+
+	if !errors.Is(err, errRetriable) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := cnt, 4; got != want {
+		t.Fatalf("invalid number of attempts %v, wanted %v", got, want)
+	}
+}
+
+func TestRetryCanceledWhileSleeping(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlogging.Context(t))
+
+	cnt := 0
+
+	_, err := WithBackoff(ctx, "some-op", Backoff{Initial: time.Hour, MaxAttempts: 5}, func() (interface{}, error) {
+		cnt++
+		cancel()
+
+		return nil, errRetriable
+	}, isRetriable)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := cnt, 1; got != want {
+		t.Fatalf("invalid number of attempts %v, wanted %v", got, want)
+	}
+}
@@ -32,6 +32,11 @@ func (s *Server) handleObjectGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err != nil {
+		http.Error(w, "error opening object", http.StatusInternalServerError)
+		return
+	}
+
 	if snapshotfs.IsDirectoryID(oid) {
 		w.Header().Set("Content-Type", "application/json")
 	}
@@ -50,5 +55,6 @@ func (s *Server) handleObjectGet(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// ServeContent honors Range requests, responding with 206 and the requested slice of the object.
 	http.ServeContent(w, r, fname, mtime, obj)
 }
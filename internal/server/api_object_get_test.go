@@ -0,0 +1,94 @@
+package server_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/object"
+)
+
+func TestGetObjectRange(t *testing.T) {
+	ctx := testlogging.Context(t)
+	si := startServer(ctx, t)
+
+	rep, err := repo.OpenAPIServer(ctx, si, repo.ClientOptions{
+		Username: testUsername,
+		Hostname: testHostname,
+	}, &content.CachingOptions{
+		CacheDirectory:    testutil.TempDirectory(t),
+		MaxCacheSizeBytes: maxCacheSizeBytes,
+	}, testPassword)
+	require.NoError(t, err)
+
+	defer rep.Close(ctx)
+
+	data := make([]byte, 100000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	var oid object.ID
+
+	require.NoError(t, repo.WriteSession(ctx, rep, repo.WriteSessionOptions{
+		Purpose: "write test",
+	}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		oid = mustWriteObject(ctx, t, w, data)
+		return nil
+	}))
+
+	uiUserClient, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             si.BaseURL,
+		TrustedServerCertificateFingerprint: si.TrustedServerCertificateFingerprint,
+		Username:                            testUIUsername,
+		Password:                            testUIPassword,
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		offset, length int64
+		want           []byte
+	}{
+		{0, -1, data},
+		{0, 10, data[0:10]},
+		{12345, 1000, data[12345:13345]},
+		{99990, -1, data[99990:]},
+		{99999, 1, data[99999:]},
+		{500, 0, []byte{}},
+	}
+
+	for _, tc := range cases {
+		got, err := serverapi.GetObjectRange(ctx, uiUserClient, oid.String(), tc.offset, tc.length)
+		require.NoError(t, err, "offset %v length %v", tc.offset, tc.length)
+		require.Equal(t, tc.want, got, "offset %v length %v", tc.offset, tc.length)
+	}
+
+	invalidCases := []struct {
+		offset, length int64
+	}{
+		{100000, 1},
+		{100000, -1},
+		{200000, 10},
+		{99990, 20},
+		{-1, 10},
+		{0, -2},
+	}
+
+	for _, tc := range invalidCases {
+		_, err := serverapi.GetObjectRange(ctx, uiUserClient, oid.String(), tc.offset, tc.length)
+		require.True(t, errors.Is(err, blob.ErrInvalidRange), "offset %v length %v: unexpected error %v", tc.offset, tc.length, err)
+	}
+
+	_, err = serverapi.GetObjectRange(ctx, uiUserClient, strings.Repeat("ab", 32), 0, 10)
+	require.True(t, errors.Is(err, object.ErrObjectNotFound), "unexpected error %v", err)
+}
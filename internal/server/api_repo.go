@@ -160,15 +160,15 @@ func (s *Server) handleRepoExists(ctx context.Context, r *http.Request, body []b
 
 	defer st.Close(ctx) // nolint:errcheck
 
-	_, err = st.GetBlob(ctx, repo.FormatBlobID, 0, -1)
+	exists, err := blob.Exists(ctx, st, repo.FormatBlobID)
 	if err != nil {
-		if errors.Is(err, blob.ErrBlobNotFound) {
-			return nil, requestError(serverapi.ErrorNotInitialized, "repository not initialized")
-		}
-
 		return nil, internalServerError(err)
 	}
 
+	if !exists {
+		return nil, requestError(serverapi.ErrorNotInitialized, "repository not initialized")
+	}
+
 	return serverapi.Empty{}, nil
 }
 
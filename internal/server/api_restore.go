@@ -23,6 +23,7 @@ func restoreCounters(s restore.Stats) map[string]uitask.CounterValue {
 		"Restored Symlinks":    uitask.SimpleCounter(int64(s.RestoredSymlinkCount)),
 		"Restored Bytes":       uitask.BytesCounter(s.RestoredTotalFileSize),
 		"Ignored Errors":       uitask.SimpleCounter(int64(s.IgnoredErrorCount)),
+		"Failed Entries":       uitask.ErrorCounter(int64(len(s.FailedEntries))),
 		"Skipped Files":        uitask.SimpleCounter(int64(s.SkippedCount)),
 		"Skipped Bytes":        uitask.BytesCounter(s.SkippedTotalFileSize),
 	}
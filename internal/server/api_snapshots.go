@@ -2,10 +2,15 @@
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/url"
 
+	"github.com/pkg/errors"
+
 	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
 )
@@ -45,6 +50,66 @@ func (s *Server) handleSnapshotList(ctx context.Context, r *http.Request, body [
 	return resp, nil
 }
 
+func (s *Server) handleSnapshotDelete(ctx context.Context, r *http.Request, body []byte) (interface{}, *apiError) {
+	w, ok := s.rep.(repo.RepositoryWriter)
+	if !ok {
+		return nil, repositoryNotWritableError()
+	}
+
+	var req serverapi.DeleteSnapshotsRequest
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body")
+	}
+
+	if len(req.SnapshotManifestIDs) == 0 {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "snapshot IDs not provided")
+	}
+
+	// without a source, any snapshot would match the filter.
+	if q := r.URL.Query(); q.Get("host") == "" && q.Get("userName") == "" && q.Get("path") == "" {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "source not provided")
+	}
+
+	var manifests []*snapshot.Manifest
+
+	// verify all snapshots before deleting any of them.
+	for _, id := range req.SnapshotManifestIDs {
+		m, err := snapshot.LoadSnapshot(ctx, s.rep, id)
+		if errors.Is(err, snapshot.ErrSnapshotNotFound) {
+			return nil, notFoundError("snapshot " + string(id) + " not found")
+		}
+
+		if err != nil {
+			return nil, internalServerError(err)
+		}
+
+		if !sourceMatchesURLFilter(m.Source, r.URL.Query()) {
+			return nil, requestError(serverapi.ErrorMalformedRequest, "snapshot "+string(id)+" does not belong to the requested source")
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	resp := &serverapi.DeleteSnapshotsResponse{
+		Deleted: []manifest.ID{},
+	}
+
+	for _, m := range manifests {
+		if err := w.DeleteManifest(ctx, m.ID); err != nil {
+			return nil, internalServerError(err)
+		}
+
+		resp.Deleted = append(resp.Deleted, m.ID)
+	}
+
+	if err := w.Flush(ctx); err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return resp, nil
+}
+
 func sourceMatchesURLFilter(src snapshot.SourceInfo, query url.Values) bool {
 	if v := query.Get("host"); v != "" && src.Host != v {
 		return false
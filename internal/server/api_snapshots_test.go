@@ -0,0 +1,111 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestDeleteSnapshots(t *testing.T) {
+	ctx := testlogging.Context(t)
+	si := startServer(ctx, t)
+
+	rep, err := repo.OpenAPIServer(ctx, si, repo.ClientOptions{
+		Username: testUsername,
+		Hostname: testHostname,
+	}, &content.CachingOptions{
+		CacheDirectory:    testutil.TempDirectory(t),
+		MaxCacheSizeBytes: maxCacheSizeBytes,
+	}, testPassword)
+	require.NoError(t, err)
+
+	defer rep.Close(ctx)
+
+	srcInfo := snapshot.SourceInfo{
+		Host:     testHostname,
+		UserName: testUsername,
+		Path:     testPathname,
+	}
+
+	var ids []manifest.ID
+
+	require.NoError(t, repo.WriteSession(ctx, rep, repo.WriteSessionOptions{
+		Purpose: "write test",
+	}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		for i := 0; i < 3; i++ {
+			id, err := snapshot.SaveSnapshot(ctx, w, &snapshot.Manifest{
+				Source:    srcInfo,
+				StartTime: clock.Now(),
+				EndTime:   clock.Now(),
+			})
+			require.NoError(t, err)
+
+			ids = append(ids, id)
+		}
+
+		return nil
+	}))
+
+	uiUserClient, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             si.BaseURL,
+		TrustedServerCertificateFingerprint: si.TrustedServerCertificateFingerprint,
+		Username:                            testUIUsername,
+		Password:                            testUIPassword,
+	})
+	require.NoError(t, err)
+
+	var hse apiclient.HTTPStatusError
+
+	// snapshots not belonging to the requested source are rejected.
+	_, err = serverapi.DeleteSnapshots(ctx, uiUserClient, &serverapi.DeleteSnapshotsRequest{
+		Source:              snapshot.SourceInfo{Host: "other-host"},
+		SnapshotManifestIDs: ids[0:1],
+	})
+	require.True(t, errors.As(err, &hse) && hse.HTTPStatusCode == http.StatusBadRequest, "unexpected error: %v", err)
+
+	_, err = serverapi.DeleteSnapshots(ctx, uiUserClient, &serverapi.DeleteSnapshotsRequest{
+		Source:              snapshot.SourceInfo{Host: testHostname, UserName: "other-user", Path: testPathname},
+		SnapshotManifestIDs: ids[0:1],
+	})
+	require.True(t, errors.As(err, &hse) && hse.HTTPStatusCode == http.StatusBadRequest, "unexpected error: %v", err)
+
+	// requests without a source are rejected by the server.
+	var resp serverapi.DeleteSnapshotsResponse
+
+	err = uiUserClient.Post(ctx, "snapshots/delete", &serverapi.DeleteSnapshotsRequest{
+		SnapshotManifestIDs: ids[0:1],
+	}, &resp)
+	require.True(t, errors.As(err, &hse) && hse.HTTPStatusCode == http.StatusBadRequest, "unexpected error: %v", err)
+
+	// unknown snapshots are rejected, without deleting any snapshots.
+	_, err = serverapi.DeleteSnapshots(ctx, uiUserClient, &serverapi.DeleteSnapshotsRequest{
+		Source:              srcInfo,
+		SnapshotManifestIDs: []manifest.ID{ids[0], "no-such-manifest"},
+	})
+	require.True(t, errors.As(err, &hse) && hse.HTTPStatusCode == http.StatusNotFound, "unexpected error: %v", err)
+
+	deleted, err := serverapi.DeleteSnapshots(ctx, uiUserClient, &serverapi.DeleteSnapshotsRequest{
+		Source:              srcInfo,
+		SnapshotManifestIDs: ids[0:2],
+	})
+	require.NoError(t, err)
+	require.Equal(t, ids[0:2], deleted.Deleted)
+
+	snaps, err := serverapi.ListSnapshots(ctx, uiUserClient, &srcInfo)
+	require.NoError(t, err)
+	require.Len(t, snaps.Snapshots, 1)
+	require.Equal(t, ids[2], snaps.Snapshots[0].ID)
+}
@@ -2,12 +2,16 @@
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/apiclient"
 	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
 )
@@ -116,6 +120,20 @@ func ListSnapshots(ctx context.Context, c *apiclient.KopiaAPIClient, match *snap
 	return resp, nil
 }
 
+// DeleteSnapshots deletes snapshots with the provided IDs, all of which must belong to the requested source.
+func DeleteSnapshots(ctx context.Context, c *apiclient.KopiaAPIClient, req *DeleteSnapshotsRequest) (*DeleteSnapshotsResponse, error) {
+	if req.Source == (snapshot.SourceInfo{}) {
+		return nil, errors.Errorf("source must be provided")
+	}
+
+	resp := &DeleteSnapshotsResponse{}
+	if err := c.Post(ctx, "snapshots/delete"+matchSourceParameters(&req.Source), req, resp); err != nil {
+		return nil, errors.Wrap(err, "DeleteSnapshots")
+	}
+
+	return resp, nil
+}
+
 // ListPolicies lists the policies managed by the server for a given target filter.
 func ListPolicies(ctx context.Context, c *apiclient.KopiaAPIClient, match *snapshot.SourceInfo) (*PoliciesResponse, error) {
 	resp := &PoliciesResponse{}
@@ -128,15 +146,65 @@ func ListPolicies(ctx context.Context, c *apiclient.KopiaAPIClient, match *snaps
 
 // GetObject returns the object payload.
 func GetObject(ctx context.Context, c *apiclient.KopiaAPIClient, objectID string) ([]byte, error) {
-	var b []byte
+	r, err := GetObjectStream(ctx, c, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close() //nolint:errcheck
 
-	if err := c.Get(ctx, "objects/"+objectID, object.ErrObjectNotFound, &b); err != nil {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
 		return nil, errors.Wrap(err, "GetObject")
 	}
 
 	return b, nil
 }
 
+// GetObjectRange returns length bytes of the object payload starting at the provided offset.
+// Length of -1 means until the end of the object. Returns blob.ErrInvalidRange if the range is out of bounds.
+func GetObjectRange(ctx context.Context, c *apiclient.KopiaAPIClient, objectID string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < -1 {
+		return nil, errors.Wrapf(blob.ErrInvalidRange, "invalid range %v+%v", offset, length)
+	}
+
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	r, err := c.GetStreamRange(ctx, "objects/"+objectID, object.ErrObjectNotFound, offset, length)
+
+	var hse apiclient.HTTPStatusError
+	if errors.As(err, &hse) && hse.HTTPStatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return nil, errors.Wrapf(blob.ErrInvalidRange, "range %v+%v of %v is not satisfiable", offset, length, objectID)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "GetObjectRange")
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetObjectRange")
+	}
+
+	// nolint:wrapcheck
+	return blob.EnsureLengthExactly(b, length)
+}
+
+// GetObjectStream returns the reader for the contents of the object with a given ID.
+// The caller must close the returned reader.
+func GetObjectStream(ctx context.Context, c *apiclient.KopiaAPIClient, objectID string) (io.ReadCloser, error) {
+	r, err := c.GetStream(ctx, "objects/"+objectID, object.ErrObjectNotFound)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetObjectStream")
+	}
+
+	return r, nil
+}
+
 func matchSourceParameters(match *snapshot.SourceInfo) string {
 	if match == nil {
 		return ""
@@ -148,7 +216,7 @@ func matchSourceParameters(match *snapshot.SourceInfo) string {
 	}
 
 	if v := match.UserName; v != "" {
-		clauses = append(clauses, "username="+v)
+		clauses = append(clauses, "userName="+v)
 	}
 
 	if v := match.Path; v != "" {
@@ -0,0 +1,164 @@
+package serverapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+const (
+	streamChunkSize  = 1 << 20
+	streamChunkCount = 64
+)
+
+func TestGetObjectStreamIsNotBuffered(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var bodyCompleted int32
+
+	proceed := make(chan struct{})
+	chunk := bytes.Repeat([]byte{0xcc}, streamChunkSize)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/objects/kabcd" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write(chunk) //nolint:errcheck
+		w.(http.Flusher).Flush()
+
+		// do not send the rest of the body until the client has consumed the first chunk.
+		select {
+		case <-proceed:
+		case <-time.After(10 * time.Second):
+			return
+		}
+
+		for i := 1; i < streamChunkCount; i++ {
+			w.Write(chunk) //nolint:errcheck
+		}
+
+		atomic.StoreInt32(&bodyCompleted, 1)
+	}))
+	defer srv.Close()
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	r, err := serverapi.GetObjectStream(ctx, cli, "kabcd")
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	first := make([]byte, streamChunkSize)
+
+	_, err = io.ReadFull(r, first)
+	require.NoError(t, err)
+	require.Equal(t, chunk, first)
+
+	// the server is still blocked, so the client could not have buffered the entire body.
+	require.Equal(t, int32(0), atomic.LoadInt32(&bodyCompleted))
+
+	close(proceed)
+
+	n, err := io.Copy(ioutil.Discard, r)
+	require.NoError(t, err)
+	require.Equal(t, int64(streamChunkSize*(streamChunkCount-1)), n)
+	require.Equal(t, int32(1), atomic.LoadInt32(&bodyCompleted))
+}
+
+func TestGetObject(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/objects/kabcd" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte("hello world")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	b, err := serverapi.GetObject(ctx, cli, "kabcd")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello world"), b)
+
+	_, err = serverapi.GetObject(ctx, cli, "kdoesnotexist")
+	require.True(t, errors.Is(err, object.ErrObjectNotFound), "unexpected error %v", err)
+
+	_, err = serverapi.GetObjectStream(ctx, cli, "kdoesnotexist")
+	require.True(t, errors.Is(err, object.ErrObjectNotFound), "unexpected error %v", err)
+}
+
+func TestDeleteSnapshots(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var (
+		gotMethod string
+		gotURL    string
+		gotBody   map[string]interface{}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotURL = r.URL.String()
+
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(`{"deleted":["m1","m2"]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	resp, err := serverapi.DeleteSnapshots(ctx, cli, &serverapi.DeleteSnapshotsRequest{
+		Source: snapshot.SourceInfo{
+			Host:     "some-host",
+			UserName: "some-user",
+			Path:     "/some/path",
+		},
+		SnapshotManifestIDs: []manifest.ID{"m1", "m2"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, http.MethodPost, gotMethod)
+	require.Equal(t, "/api/v1/snapshots/delete?host=some-host&userName=some-user&path=/some/path", gotURL)
+	require.Equal(t, map[string]interface{}{
+		"snapshotManifestIds": []interface{}{"m1", "m2"},
+	}, gotBody)
+	require.Equal(t, []manifest.ID{"m1", "m2"}, resp.Deleted)
+
+	// empty source is rejected without contacting the server.
+	gotMethod = ""
+
+	_, err = serverapi.DeleteSnapshots(ctx, cli, &serverapi.DeleteSnapshotsRequest{
+		SnapshotManifestIDs: []manifest.ID{"m1"},
+	})
+	require.Error(t, err)
+	require.Empty(t, gotMethod)
+}
@@ -165,6 +165,19 @@ type SnapshotsResponse struct {
 	Snapshots []*Snapshot `json:"snapshots"`
 }
 
+// DeleteSnapshotsRequest contains request to delete snapshots of a given source.
+type DeleteSnapshotsRequest struct {
+	// Source identifies the source the snapshots must belong to, it is sent as URL query parameters.
+	Source snapshot.SourceInfo `json:"-"`
+
+	SnapshotManifestIDs []manifest.ID `json:"snapshotManifestIds"`
+}
+
+// DeleteSnapshotsResponse contains the list of deleted snapshots.
+type DeleteSnapshotsResponse struct {
+	Deleted []manifest.ID `json:"deleted"`
+}
+
 // MountSnapshotRequest contains request to mount a snapshot.
 type MountSnapshotRequest struct {
 	Root string `json:"root"`
@@ -2,9 +2,12 @@
 package user
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -76,14 +79,89 @@ func ListUserProfiles(ctx context.Context, rep repo.Repository) ([]*Profile, err
 	return result, nil
 }
 
+// NormalizeUsername returns the canonical form of the provided username, which is trimmed and lowercase.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ListUserProfilesForHost gets the list of user profiles for a given hostname, sorted by username.
+// Only profiles of the matching users are loaded.
+func ListUserProfilesForHost(ctx context.Context, rep repo.Repository, hostname string) ([]*Profile, error) {
+	hostname = NormalizeUsername(hostname)
+
+	entries, err := rep.FindManifests(ctx, map[string]string{manifest.TypeLabelKey: ManifestType})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing user manifests")
+	}
+
+	var result []*Profile
+
+	for _, m := range manifest.DedupeEntryMetadataByLabel(entries, UsernameAtHostnameLabel) {
+		username := m.Labels[UsernameAtHostnameLabel]
+
+		if hostnameOf(username) != hostname {
+			continue
+		}
+
+		p := &Profile{}
+		if _, err := rep.GetManifest(ctx, m.ID, p); err != nil {
+			return nil, errors.Wrapf(err, "error loading user manifest %v", username)
+		}
+
+		p.ManifestID = m.ID
+
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Username < result[j].Username
+	})
+
+	return result, nil
+}
+
+// hostnameOf returns the hostname portion of username@hostname.
+func hostnameOf(usernameAtHost string) string {
+	p := strings.LastIndex(usernameAtHost, "@")
+	if p < 0 {
+		return ""
+	}
+
+	return usernameAtHost[p+1:]
+}
+
+// findProfileManifests returns the username and manifests of the profile matching the provided username.
+// The exact username is looked up first, so that profiles stored before usernames were normalized
+// remain reachable, followed by the normalized (trimmed and lowercased) username.
+func findProfileManifests(ctx context.Context, rep repo.Repository, username string) (string, []*manifest.EntryMetadata, error) {
+	candidates := []string{username}
+	if n := NormalizeUsername(username); n != username {
+		candidates = append(candidates, n)
+	}
+
+	for _, name := range candidates {
+		manifests, err := rep.FindManifests(ctx, map[string]string{
+			manifest.TypeLabelKey:   ManifestType,
+			UsernameAtHostnameLabel: name,
+		})
+		if err != nil {
+			return "", nil, errors.Wrap(err, "error looking for user profile")
+		}
+
+		if len(manifests) > 0 {
+			return name, manifests, nil
+		}
+	}
+
+	return NormalizeUsername(username), nil, nil
+}
+
 // GetUserProfile returns the user profile with a given username.
+// The exact username is looked up first, followed by its normalized (trimmed and lowercased) form.
 func GetUserProfile(ctx context.Context, r repo.Repository, username string) (*Profile, error) {
-	manifests, err := r.FindManifests(ctx, map[string]string{
-		manifest.TypeLabelKey:   ManifestType,
-		UsernameAtHostnameLabel: username,
-	})
+	username, manifests, err := findProfileManifests(ctx, r, username)
 	if err != nil {
-		return nil, errors.Wrap(err, "error looking for user profile")
+		return nil, err
 	}
 
 	if len(manifests) == 0 {
@@ -116,11 +194,61 @@ func ValidateUsername(name string) error {
 }
 
 // SetUserProfile creates or updates user profile.
+// The username is normalized (trimmed and lowercased) before being stored and it is an error to create
+// a profile whose username differs only by case from another existing one. Updating a profile stored
+// before usernames were normalized migrates it to the normalized username.
 func SetUserProfile(ctx context.Context, w repo.RepositoryWriter, p *Profile) error {
+	originalUsername := p.Username
+	p.Username = NormalizeUsername(p.Username)
+
 	if err := ValidateUsername(p.Username); err != nil {
 		return err
 	}
 
+	entries, err := w.FindManifests(ctx, map[string]string{manifest.TypeLabelKey: ManifestType})
+	if err != nil {
+		return errors.Wrap(err, "error listing user manifests")
+	}
+
+	// the profile being updated may be stored under its legacy, non-normalized username.
+	updatedUsernames := map[string]bool{originalUsername: true}
+
+	for _, m := range entries {
+		if m.ID == p.ManifestID {
+			updatedUsernames[m.Labels[UsernameAtHostnameLabel]] = true
+		}
+	}
+
+	var legacyManifests []manifest.ID
+
+	for _, m := range entries {
+		u := m.Labels[UsernameAtHostnameLabel]
+		if u == p.Username || NormalizeUsername(u) != p.Username {
+			continue
+		}
+
+		if !updatedUsernames[u] {
+			return caseConflictError(p.Username, u)
+		}
+
+		legacyManifests = append(legacyManifests, m.ID)
+	}
+
+	if err := setUserProfile(ctx, w, p); err != nil {
+		return err
+	}
+
+	for _, id := range legacyManifests {
+		if err := w.DeleteManifest(ctx, id); err != nil {
+			return errors.Wrapf(err, "error deleting legacy user profile %v", originalUsername)
+		}
+	}
+
+	return nil
+}
+
+// setUserProfile writes the validated user profile replacing any existing ones with the same username.
+func setUserProfile(ctx context.Context, w repo.RepositoryWriter, p *Profile) error {
 	manifests, err := w.FindManifests(ctx, map[string]string{
 		manifest.TypeLabelKey:   ManifestType,
 		UsernameAtHostnameLabel: p.Username,
@@ -148,18 +276,44 @@ func SetUserProfile(ctx context.Context, w repo.RepositoryWriter, p *Profile) er
 	return nil
 }
 
+// nonNormalizedUsernames returns the map of normalized usernames to usernames of existing profiles
+// that are not stored in normalized form.
+func nonNormalizedUsernames(usernames []string) map[string]string {
+	result := map[string]string{}
+
+	for _, u := range usernames {
+		if n := NormalizeUsername(u); n != u {
+			result[n] = u
+		}
+	}
+
+	return result
+}
+
+// ensureNoCaseConflict returns an error if there's an existing profile whose username differs from
+// the provided normalized one only by case, given the result of nonNormalizedUsernames().
+func ensureNoCaseConflict(nonNormalized map[string]string, username string) error {
+	if existing, ok := nonNormalized[username]; ok {
+		return caseConflictError(username, existing)
+	}
+
+	return nil
+}
+
+func caseConflictError(username, existing string) error {
+	return errors.Errorf("user %q conflicts with existing user %q which differs only by case", username, existing)
+}
+
 // DeleteUserProfile removes user profile with a given username.
+// The exact username is looked up first, followed by its normalized (trimmed and lowercased) form.
 func DeleteUserProfile(ctx context.Context, w repo.RepositoryWriter, username string) error {
-	if username == "" {
+	if NormalizeUsername(username) == "" {
 		return errors.Errorf("username is required")
 	}
 
-	manifests, err := w.FindManifests(ctx, map[string]string{
-		manifest.TypeLabelKey:   ManifestType,
-		UsernameAtHostnameLabel: username,
-	})
+	username, manifests, err := findProfileManifests(ctx, w, username)
 	if err != nil {
-		return errors.Wrap(err, "error looking for user profile")
+		return err
 	}
 
 	for _, m := range manifests {
@@ -170,3 +324,95 @@ func DeleteUserProfile(ctx context.Context, w repo.RepositoryWriter, username st
 
 	return nil
 }
+
+// ExportProfiles returns all user profiles in the repository, sorted by username.
+func ExportProfiles(ctx context.Context, rep repo.Repository) ([]*Profile, error) {
+	return ListUserProfiles(ctx, rep)
+}
+
+// ImportError reports per-user failures encountered by ImportProfiles.
+type ImportError struct {
+	Errors map[string]error // by username
+}
+
+func (e *ImportError) Error() string {
+	var names []string
+
+	for u := range e.Errors {
+		names = append(names, u)
+	}
+
+	sort.Strings(names)
+
+	var msgs []string
+
+	for _, u := range names {
+		msgs = append(msgs, fmt.Sprintf("%v: %v", u, e.Errors[u]))
+	}
+
+	return fmt.Sprintf("unable to import %v user profile(s): %v", len(names), strings.Join(msgs, "; "))
+}
+
+// ImportProfiles creates or updates the provided user profiles using the provided writer.
+// Invalid profiles are skipped and reported in the returned *ImportError while the remaining ones are imported.
+// When overwrite is false and any of the profiles conflicts with an existing, different profile,
+// the import is aborted and nothing is written. Profiles identical to the existing ones are left untouched,
+// which makes re-importing the same set of profiles a no-op.
+func ImportProfiles(ctx context.Context, w repo.RepositoryWriter, profiles []*Profile, overwrite bool) error {
+	existing, err := LoadProfileMap(ctx, w, nil)
+	if err != nil {
+		return err
+	}
+
+	var existingUsernames []string
+
+	for u := range existing {
+		existingUsernames = append(existingUsernames, u)
+	}
+
+	// conflicts are checked against the usernames that existed before import, computed once.
+	nonNormalized := nonNormalizedUsernames(existingUsernames)
+	importErrors := map[string]error{}
+
+	var toWrite []*Profile
+
+	for _, p := range profiles {
+		p.Username = NormalizeUsername(p.Username)
+
+		if err := ValidateUsername(p.Username); err != nil {
+			importErrors[p.Username] = err
+			continue
+		}
+
+		if err := ensureNoCaseConflict(nonNormalized, p.Username); err != nil {
+			importErrors[p.Username] = err
+			continue
+		}
+
+		old := existing[p.Username]
+
+		switch {
+		case old != nil && old.PasswordHashVersion == p.PasswordHashVersion && bytes.Equal(old.PasswordHash, p.PasswordHash):
+			// identical profile already exists.
+			p.ManifestID = old.ManifestID
+			continue
+
+		case old != nil && !overwrite:
+			return errors.Errorf("user profile %v already exists", p.Username)
+		}
+
+		toWrite = append(toWrite, p)
+	}
+
+	for _, p := range toWrite {
+		if err := setUserProfile(ctx, w, p); err != nil {
+			importErrors[p.Username] = err
+		}
+	}
+
+	if len(importErrors) > 0 {
+		return &ImportError{importErrors}
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@
 
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/internal/user"
+	"github.com/kopia/kopia/repo/manifest"
 )
 
 func TestUserManager(t *testing.T) {
@@ -111,3 +112,227 @@ func TestValidateUsername_Invalid(t *testing.T) {
 		}
 	}
 }
+
+func TestImportExportProfiles(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	profiles := []*user.Profile{
+		{Username: "alice@somehost", PasswordHash: []byte("alice-hash")},
+		{Username: "bad user", PasswordHash: []byte("invalid-hash")},
+		{Username: "bob@somehost", PasswordHash: []byte("bob-hash")},
+	}
+
+	// invalid profile is reported, but does not prevent others from being imported.
+	err := user.ImportProfiles(ctx, env.RepositoryWriter, profiles, false)
+
+	var ie *user.ImportError
+
+	require.True(t, errors.As(err, &ie), "unexpected error: %v", err)
+	require.Len(t, ie.Errors, 1)
+	require.Contains(t, ie.Errors, "bad user")
+
+	exported, err := user.ExportProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	require.Equal(t, "alice@somehost", exported[0].Username)
+	require.Equal(t, "bob@somehost", exported[1].Username)
+
+	// re-importing exported profiles is a no-op, regardless of overwrite.
+	require.NoError(t, user.ImportProfiles(ctx, env.RepositoryWriter, exported, false))
+	require.NoError(t, user.ImportProfiles(ctx, env.RepositoryWriter, exported, true))
+
+	exported2, err := user.ExportProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Equal(t, exported, exported2)
+
+	changed := []*user.Profile{
+		{Username: "bob@somehost", PasswordHash: []byte("new-bob-hash")},
+		{Username: "carol@somehost", PasswordHash: []byte("carol-hash")},
+	}
+
+	// conflict without overwrite aborts the whole batch.
+	require.Error(t, user.ImportProfiles(ctx, env.RepositoryWriter, changed, false))
+
+	_, err = user.GetUserProfile(ctx, env.RepositoryWriter, "carol@somehost")
+	require.True(t, errors.Is(err, user.ErrUserNotFound), "unexpected error: %v", err)
+
+	// with overwrite, existing profiles are updated.
+	require.NoError(t, user.ImportProfiles(ctx, env.RepositoryWriter, changed, true))
+
+	b, err := user.GetUserProfile(ctx, env.RepositoryWriter, "bob@somehost")
+	require.NoError(t, err)
+	require.Equal(t, "new-bob-hash", string(b.PasswordHash))
+
+	exported, err = user.ExportProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, exported, 3)
+}
+
+func TestUserManagerCaseInsensitive(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	p := &user.Profile{
+		Username:     " Alice@SomeHost ",
+		PasswordHash: []byte("hahaha"),
+	}
+
+	require.NoError(t, user.SetUserProfile(ctx, env.RepositoryWriter, p))
+	require.Equal(t, "alice@somehost", p.Username)
+
+	for _, name := range []string{"alice@somehost", "ALICE@SOMEHOST", "  Alice@somehost\t"} {
+		a, err := user.GetUserProfile(ctx, env.RepositoryWriter, name)
+		require.NoError(t, err, name)
+		require.Equal(t, "alice@somehost", a.Username)
+		require.Equal(t, "hahaha", string(a.PasswordHash))
+	}
+
+	// updating using different case modifies the same profile.
+	require.NoError(t, user.SetUserProfile(ctx, env.RepositoryWriter, &user.Profile{
+		Username:     "ALICE@somehost",
+		PasswordHash: []byte("hehehe"),
+	}))
+
+	profiles, err := user.ListUserProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	require.Equal(t, "hehehe", string(profiles[0].PasswordHash))
+
+	require.NoError(t, user.DeleteUserProfile(ctx, env.RepositoryWriter, " Alice@SomeHost"))
+
+	_, err = user.GetUserProfile(ctx, env.RepositoryWriter, "alice@somehost")
+	require.True(t, errors.Is(err, user.ErrUserNotFound), "unexpected error: %v", err)
+}
+
+func TestUserManagerRejectsCaseConflict(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	// simulate legacy profile stored with mixed-case username.
+	_, err := env.RepositoryWriter.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey:        user.ManifestType,
+		user.UsernameAtHostnameLabel: "Bob@SomeHost",
+	}, &user.Profile{Username: "Bob@SomeHost", PasswordHash: []byte("legacy")})
+	require.NoError(t, err)
+
+	err = user.SetUserProfile(ctx, env.RepositoryWriter, &user.Profile{
+		Username:     "bob@somehost",
+		PasswordHash: []byte("new"),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "differs only by case")
+
+	// import reports the conflict without writing the profile.
+	err = user.ImportProfiles(ctx, env.RepositoryWriter, []*user.Profile{
+		{Username: "bob@somehost", PasswordHash: []byte("new")},
+		{Username: "carol@somehost", PasswordHash: []byte("carol")},
+	}, false)
+
+	var ie *user.ImportError
+
+	require.True(t, errors.As(err, &ie), "unexpected error: %v", err)
+	require.Len(t, ie.Errors, 1)
+	require.Contains(t, ie.Errors["bob@somehost"].Error(), "differs only by case")
+
+	// legacy profile is reachable using its exact username.
+	p, err := user.GetUserProfile(ctx, env.RepositoryWriter, "Bob@SomeHost")
+	require.NoError(t, err)
+	require.Equal(t, "legacy", string(p.PasswordHash))
+
+	require.NoError(t, user.DeleteUserProfile(ctx, env.RepositoryWriter, "Bob@SomeHost"))
+
+	_, err = user.GetUserProfile(ctx, env.RepositoryWriter, "Bob@SomeHost")
+	require.True(t, errors.Is(err, user.ErrUserNotFound), "unexpected error: %v", err)
+
+	// once the legacy profile is gone, the normalized one can be created.
+	require.NoError(t, user.SetUserProfile(ctx, env.RepositoryWriter, &user.Profile{
+		Username:     "bob@somehost",
+		PasswordHash: []byte("new"),
+	}))
+}
+
+func TestUserManagerUpdatesLegacyProfile(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	// simulate legacy profile stored with mixed-case username.
+	_, err := env.RepositoryWriter.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey:        user.ManifestType,
+		user.UsernameAtHostnameLabel: "Bob@SomeHost",
+	}, &user.Profile{Username: "Bob@SomeHost", PasswordHash: []byte("legacy")})
+	require.NoError(t, err)
+
+	// updating the profile looked up by its exact username migrates it to the normalized username.
+	p, err := user.GetUserProfile(ctx, env.RepositoryWriter, "Bob@SomeHost")
+	require.NoError(t, err)
+
+	p.PasswordHash = []byte("updated")
+
+	require.NoError(t, user.SetUserProfile(ctx, env.RepositoryWriter, p))
+	require.Equal(t, "bob@somehost", p.Username)
+
+	profiles, err := user.ListUserProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	require.Equal(t, "bob@somehost", profiles[0].Username)
+	require.Equal(t, "updated", string(profiles[0].PasswordHash))
+
+	// legacy profile loaded from the profile map is identified by its manifest ID.
+	_, err = env.RepositoryWriter.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey:        user.ManifestType,
+		user.UsernameAtHostnameLabel: "Carol@SomeHost",
+	}, &user.Profile{Username: "carol@somehost", PasswordHash: []byte("legacy")})
+	require.NoError(t, err)
+
+	m, err := user.LoadProfileMap(ctx, env.RepositoryWriter, nil)
+	require.NoError(t, err)
+
+	p = m["Carol@SomeHost"]
+	require.NotNil(t, p)
+
+	p.PasswordHash = []byte("updated")
+
+	require.NoError(t, user.SetUserProfile(ctx, env.RepositoryWriter, p))
+
+	m, err = user.LoadProfileMap(ctx, env.RepositoryWriter, nil)
+	require.NoError(t, err)
+	require.Len(t, m, 2)
+	require.Nil(t, m["Carol@SomeHost"])
+	require.Equal(t, "updated", string(m["carol@somehost"].PasswordHash))
+}
+
+func TestListUserProfilesForHost(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	for _, u := range []string{"bob@host1", "alice@host1", "alice@host2", "carol@host3", "dave@host1.example"} {
+		require.NoError(t, user.SetUserProfile(ctx, env.RepositoryWriter, &user.Profile{
+			Username:     u,
+			PasswordHash: []byte("hash-" + u),
+		}))
+	}
+
+	usernames := func(profiles []*user.Profile) []string {
+		var result []string
+
+		for _, p := range profiles {
+			result = append(result, p.Username)
+		}
+
+		return result
+	}
+
+	cases := map[string][]string{
+		"host1":         {"alice@host1", "bob@host1"},
+		" HOST2 ":       {"alice@host2"},
+		"host3":         {"carol@host3"},
+		"host1.example": {"dave@host1.example"},
+		"no-such-host":  nil,
+	}
+
+	for host, want := range cases {
+		profiles, err := user.ListUserProfilesForHost(ctx, env.RepositoryWriter, host)
+		require.NoError(t, err)
+		require.Equal(t, want, usernames(profiles), host)
+
+		for _, p := range profiles {
+			require.Equal(t, "hash-"+p.Username, string(p.PasswordHash))
+		}
+	}
+}
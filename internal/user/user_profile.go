@@ -1,6 +1,8 @@
 package user
 
 import (
+	"github.com/pkg/errors"
+
 	"github.com/kopia/kopia/repo/manifest"
 )
 
@@ -13,9 +15,36 @@ type Profile struct {
 	PasswordHash        []byte `json:"passwordHash"`
 }
 
-// SetPassword changes the password for a user profile.
+// Supported password hashing algorithm versions.
+const (
+	// PasswordHashVersionScrypt is the legacy scrypt-based hashing supported by all servers.
+	PasswordHashVersionScrypt = hashVersion1
+
+	// PasswordHashVersionArgon2id is Argon2id hashing, which older servers can't verify.
+	PasswordHashVersionArgon2id = hashVersion2
+)
+
+// DefaultPasswordHashVersion is the password hashing algorithm version used for new passwords unless
+// another one is explicitly requested.
+const DefaultPasswordHashVersion = PasswordHashVersionScrypt
+
+// SetPassword changes the password for a user profile, hashing it using the default algorithm.
 func (p *Profile) SetPassword(password string) error {
-	return p.setPasswordV1(password)
+	return p.SetPasswordWithVersion(password, DefaultPasswordHashVersion)
+}
+
+// SetPasswordWithVersion changes the password for a user profile, hashing it using the provided algorithm version.
+func (p *Profile) SetPasswordWithVersion(password string, version int) error {
+	switch version {
+	case hashVersion1:
+		return p.setPasswordV1(password)
+
+	case hashVersion2:
+		return p.setPasswordV2(password)
+
+	default:
+		return errors.Errorf("unsupported password hash version %v", version)
+	}
 }
 
 // IsValidPassword determines whether the password is valid for a given user.
@@ -32,7 +61,30 @@ func (p *Profile) IsValidPassword(password string) bool {
 	case hashVersion1:
 		return isValidPasswordV1(password, p.PasswordHash)
 
+	case hashVersion2:
+		return isValidPasswordV2(password, p.PasswordHash)
+
 	default:
 		return false
 	}
 }
+
+// VerifyPassword determines whether the password is valid for a given user. When the password is valid
+// but was hashed using an algorithm older than upgradeToVersion, the profile is re-hashed using that version
+// and upgraded is set to true, in which case the caller should persist the profile using SetUserProfile().
+// Passing 0 as upgradeToVersion disables upgrades.
+func (p *Profile) VerifyPassword(password string, upgradeToVersion int) (valid, upgraded bool, err error) {
+	if !p.IsValidPassword(password) {
+		return false, false, nil
+	}
+
+	if upgradeToVersion == 0 || p.PasswordHashVersion >= upgradeToVersion {
+		return true, false, nil
+	}
+
+	if err := p.SetPasswordWithVersion(password, upgradeToVersion); err != nil {
+		return true, false, errors.Wrap(err, "error upgrading password hash")
+	}
+
+	return true, true, nil
+}
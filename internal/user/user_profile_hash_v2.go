@@ -0,0 +1,51 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+// parameters for v2 hashing (Argon2id).
+const (
+	hashVersion2 = 2
+
+	v2Argon2Time    = 1
+	v2Argon2Memory  = 64 * 1024 // in KiB
+	v2Argon2Threads = 4
+	v2SaltLength    = 32
+	v2KeyLength     = 32
+)
+
+func (p *Profile) setPasswordV2(password string) error {
+	salt := make([]byte, v2SaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return errors.Wrap(err, "error generating salt")
+	}
+
+	p.PasswordHashVersion = hashVersion2
+	p.PasswordHash = computePasswordHashV2(password, salt)
+
+	return nil
+}
+
+func computePasswordHashV2(password string, salt []byte) []byte {
+	key := argon2.IDKey([]byte(password), salt, v2Argon2Time, v2Argon2Memory, v2Argon2Threads, v2KeyLength)
+
+	return append(append([]byte(nil), salt...), key...)
+}
+
+func isValidPasswordV2(password string, hashedPassword []byte) bool {
+	if len(hashedPassword) != v2SaltLength+v2KeyLength {
+		return false
+	}
+
+	salt := hashedPassword[0:v2SaltLength]
+
+	h := computePasswordHashV2(password, salt)
+
+	return subtle.ConstantTimeCompare(h, hashedPassword) != 0
+}
@@ -0,0 +1,76 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/repotesting"
+)
+
+func TestLegacyPasswordHashUpgrade(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t)
+
+	p := &Profile{Username: "alice@somehost"}
+	require.NoError(t, p.SetPasswordWithVersion("foo", hashVersion1))
+	require.Equal(t, hashVersion1, p.PasswordHashVersion)
+	require.NoError(t, SetUserProfile(ctx, env.RepositoryWriter, p))
+
+	p, err := GetUserProfile(ctx, env.RepositoryWriter, "alice@somehost")
+	require.NoError(t, err)
+
+	// legacy hash still authenticates.
+	require.True(t, p.IsValidPassword("foo"))
+	require.False(t, p.IsValidPassword("bar"))
+
+	// upgrades are disabled by default.
+	valid, upgraded, err := p.VerifyPassword("foo", 0)
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.False(t, upgraded)
+	require.Equal(t, hashVersion1, p.PasswordHashVersion)
+
+	// invalid password does not upgrade the hash.
+	valid, upgraded, err = p.VerifyPassword("bar", hashVersion2)
+	require.NoError(t, err)
+	require.False(t, valid)
+	require.False(t, upgraded)
+	require.Equal(t, hashVersion1, p.PasswordHashVersion)
+
+	valid, upgraded, err = p.VerifyPassword("foo", hashVersion2)
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.True(t, upgraded)
+	require.Equal(t, hashVersion2, p.PasswordHashVersion)
+
+	require.NoError(t, SetUserProfile(ctx, env.RepositoryWriter, p))
+
+	p, err = GetUserProfile(ctx, env.RepositoryWriter, "alice@somehost")
+	require.NoError(t, err)
+	require.Equal(t, hashVersion2, p.PasswordHashVersion)
+	require.True(t, p.IsValidPassword("foo"))
+	require.False(t, p.IsValidPassword("bar"))
+
+	// already using requested version, no further upgrade.
+	valid, upgraded, err = p.VerifyPassword("foo", hashVersion2)
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.False(t, upgraded)
+}
+
+func TestUnsupportedPasswordHashVersion(t *testing.T) {
+	p := &Profile{}
+	require.Error(t, p.SetPasswordWithVersion("foo", 999))
+
+	p.PasswordHashVersion = 999
+	p.PasswordHash = make([]byte, v2SaltLength+v2KeyLength)
+	require.False(t, p.IsValidPassword("foo"))
+}
+
+func TestDefaultPasswordHashVersion(t *testing.T) {
+	p := &Profile{}
+	require.NoError(t, p.SetPassword("foo"))
+
+	// new passwords use the legacy algorithm unless another one is explicitly requested.
+	require.Equal(t, hashVersion1, p.PasswordHashVersion)
+}
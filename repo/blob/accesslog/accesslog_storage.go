@@ -0,0 +1,136 @@
+// Package accesslog implements wrapper around blob.Storage that records all blob operations
+// in JSON Lines format, which is useful for security audits.
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// Record describes a single blob operation.
+type Record struct {
+	Time     time.Time     `json:"time"`
+	Op       string        `json:"op"`
+	BlobID   blob.ID       `json:"blobID,omitempty"`
+	Prefix   blob.ID       `json:"prefix,omitempty"`
+	Offset   int64         `json:"offset,omitempty"`
+	Length   int64         `json:"length,omitempty"` // requested length of GetBlob, -1 means until the end
+	Bytes    int64         `json:"bytes"`            // number of bytes read or written
+	Count    int           `json:"count,omitempty"`  // number of blobs returned by ListBlobs
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type accessLogStorage struct {
+	blob.Storage
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *accessLogStorage) record(r Record, t0 time.Time, err error) {
+	r.Time = t0
+	r.Duration = clock.Since(t0)
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// failure to write the log must not affect the operation.
+	_ = s.enc.Encode(r)
+}
+
+func (s *accessLogStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64) ([]byte, error) {
+	t0 := clock.Now()
+	result, err := s.Storage.GetBlob(ctx, id, offset, length)
+	s.record(Record{Op: "GetBlob", BlobID: id, Offset: offset, Length: length, Bytes: int64(len(result))}, t0, err)
+
+	// nolint:wrapcheck
+	return result, err
+}
+
+func (s *accessLogStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	t0 := clock.Now()
+	result, err := s.Storage.GetMetadata(ctx, id)
+	s.record(Record{Op: "GetMetadata", BlobID: id}, t0, err)
+
+	// nolint:wrapcheck
+	return result, err
+}
+
+func (s *accessLogStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes) error {
+	t0 := clock.Now()
+	err := s.Storage.PutBlob(ctx, id, data)
+	s.record(Record{Op: "PutBlob", BlobID: id, Bytes: int64(data.Length())}, t0, err)
+
+	// nolint:wrapcheck
+	return err
+}
+
+func (s *accessLogStorage) SetTime(ctx context.Context, id blob.ID, t time.Time) error {
+	t0 := clock.Now()
+	err := s.Storage.SetTime(ctx, id, t)
+	s.record(Record{Op: "SetTime", BlobID: id}, t0, err)
+
+	// nolint:wrapcheck
+	return err
+}
+
+func (s *accessLogStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	t0 := clock.Now()
+	err := s.Storage.DeleteBlob(ctx, id)
+	s.record(Record{Op: "DeleteBlob", BlobID: id}, t0, err)
+
+	// nolint:wrapcheck
+	return err
+}
+
+// DeleteBlobs implements blob.BatchDeleter and records each deleted blob.
+func (s *accessLogStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	t0 := clock.Now()
+	errs := blob.DeleteBlobs(ctx, s.Storage, ids)
+
+	for i, id := range ids {
+		s.record(Record{Op: "DeleteBlob", BlobID: id}, t0, errs[i])
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *accessLogStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.Storage)
+}
+
+func (s *accessLogStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	t0 := clock.Now()
+	cnt := 0
+
+	err := s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		cnt++
+		return callback(bm)
+	})
+	s.record(Record{Op: "ListBlobs", Prefix: prefix, Count: cnt}, t0, err)
+
+	// nolint:wrapcheck
+	return err
+}
+
+// NewWrapper returns a Storage wrapper that writes a Record for each blob operation to the provided writer.
+// When the writer is nil, the wrapped storage is returned unchanged.
+func NewWrapper(wrapped blob.Storage, w io.Writer) blob.Storage {
+	if w == nil {
+		return wrapped
+	}
+
+	return &accessLogStorage{Storage: wrapped, enc: json.NewEncoder(w)}
+}
@@ -0,0 +1,125 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/accesslog"
+)
+
+func TestAccessLog(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var buf bytes.Buffer
+
+	base := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	st := accesslog.NewWrapper(base, &buf)
+
+	nextRecord := func() accesslog.Record {
+		t.Helper()
+
+		var r accesslog.Record
+
+		dec := json.NewDecoder(&buf)
+		require.NoError(t, dec.Decode(&r))
+		require.False(t, dec.More(), "expected exactly one record")
+		require.Zero(t, buf.Len())
+
+		return r
+	}
+
+	require.NoError(t, st.PutBlob(ctx, "blob1", gather.FromSlice([]byte{1, 2, 3, 4, 5})))
+
+	r := nextRecord()
+	require.Equal(t, "PutBlob", r.Op)
+	require.Equal(t, blob.ID("blob1"), r.BlobID)
+	require.Equal(t, int64(5), r.Bytes)
+	require.Empty(t, r.Error)
+	require.False(t, r.Time.IsZero())
+
+	_, err := st.GetBlob(ctx, "blob1", 0, -1)
+	require.NoError(t, err)
+
+	r = nextRecord()
+	require.Equal(t, "GetBlob", r.Op)
+	require.Equal(t, int64(5), r.Bytes)
+	require.Equal(t, int64(-1), r.Length)
+
+	// range read.
+	_, err = st.GetBlob(ctx, "blob1", 1, 3)
+	require.NoError(t, err)
+
+	r = nextRecord()
+	require.Equal(t, "GetBlob", r.Op)
+	require.Equal(t, int64(1), r.Offset)
+	require.Equal(t, int64(3), r.Length)
+	require.Equal(t, int64(3), r.Bytes)
+
+	_, err = st.GetMetadata(ctx, "blob1")
+	require.NoError(t, err)
+	require.Equal(t, "GetMetadata", nextRecord().Op)
+
+	require.NoError(t, st.SetTime(ctx, "blob1", clock.Now()))
+	require.Equal(t, "SetTime", nextRecord().Op)
+
+	require.NoError(t, st.ListBlobs(ctx, "blob", func(bm blob.Metadata) error { return nil }))
+
+	r = nextRecord()
+	require.Equal(t, "ListBlobs", r.Op)
+	require.Equal(t, blob.ID("blob"), r.Prefix)
+	require.Equal(t, 1, r.Count)
+
+	require.NoError(t, st.DeleteBlob(ctx, "blob1"))
+	require.Equal(t, "DeleteBlob", nextRecord().Op)
+
+	// errors are recorded.
+	_, err = st.GetBlob(ctx, "blob1", 0, -1)
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+
+	r = nextRecord()
+	require.Equal(t, "GetBlob", r.Op)
+	require.Contains(t, r.Error, blob.ErrBlobNotFound.Error())
+}
+
+func TestAccessLogNilWriter(t *testing.T) {
+	base := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.Equal(t, base, accesslog.NewWrapper(base, nil))
+}
+
+func TestAccessLogDeleteBlobsBatch(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var buf bytes.Buffer
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.NoError(t, ms.PutBlob(ctx, "a1", gather.FromSlice([]byte{1})))
+	require.NoError(t, ms.PutBlob(ctx, "a2", gather.FromSlice([]byte{2})))
+
+	base := &blobtesting.BatchDeletingStorage{Storage: ms}
+	st := accesslog.NewWrapper(base, &buf)
+
+	require.Equal(t, []error{nil, nil}, blob.DeleteBlobs(ctx, st, []blob.ID{"a1", "a2"}))
+	require.Equal(t, []int{2}, base.BatchSizes())
+
+	// each deleted blob is recorded.
+	dec := json.NewDecoder(&buf)
+
+	for _, id := range []blob.ID{"a1", "a2"} {
+		var r accesslog.Record
+
+		require.NoError(t, dec.Decode(&r))
+		require.Equal(t, "DeleteBlob", r.Op)
+		require.Equal(t, id, r.BlobID)
+		require.Empty(t, r.Error)
+	}
+
+	require.False(t, dec.More())
+}
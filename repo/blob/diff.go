@@ -0,0 +1,84 @@
+package blob
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DiffResult describes differences between sets of blobs in two storages.
+type DiffResult struct {
+	// Missing contains metadata of blobs present in the source but not in the destination.
+	Missing []Metadata `json:"missing"`
+
+	// Extra contains metadata of blobs present in the destination but not in the source.
+	Extra []Metadata `json:"extra"`
+
+	// Mismatched contains source metadata of blobs present in both storages but with different lengths.
+	Mismatched []Metadata `json:"mismatched"`
+
+	// Matching is the number of blobs present in both storages with identical lengths.
+	Matching int `json:"matching"`
+}
+
+// Empty returns true if the compared storages had no differences.
+func (r *DiffResult) Empty() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+// Diff compares blobs with the provided prefix in two storages and returns the differences.
+// When filter is not nil, only blobs for which it returns true are compared.
+func Diff(ctx context.Context, src, dst Reader, prefix ID, filter func(id ID) bool) (*DiffResult, error) {
+	dstMetadata := map[ID]Metadata{}
+
+	if err := dst.ListBlobs(ctx, prefix, func(bm Metadata) error {
+		if filter == nil || filter(bm.BlobID) {
+			dstMetadata[bm.BlobID] = bm
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error listing destination blobs")
+	}
+
+	result := &DiffResult{}
+
+	if err := src.ListBlobs(ctx, prefix, func(bm Metadata) error {
+		if filter != nil && !filter(bm.BlobID) {
+			return nil
+		}
+
+		dstmd, ok := dstMetadata[bm.BlobID]
+		delete(dstMetadata, bm.BlobID)
+
+		switch {
+		case !ok:
+			result.Missing = append(result.Missing, bm)
+		case dstmd.Length != bm.Length:
+			result.Mismatched = append(result.Mismatched, bm)
+		default:
+			result.Matching++
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error listing source blobs")
+	}
+
+	for _, bm := range dstMetadata {
+		result.Extra = append(result.Extra, bm)
+	}
+
+	sortByID(result.Missing)
+	sortByID(result.Extra)
+	sortByID(result.Mismatched)
+
+	return result, nil
+}
+
+func sortByID(mds []Metadata) {
+	sort.Slice(mds, func(i, j int) bool {
+		return mds[i].BlobID < mds[j].BlobID
+	})
+}
@@ -0,0 +1,49 @@
+package blob_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+
+	src := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	dst := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	require.NoError(t, src.PutBlob(ctx, "same", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, dst.PutBlob(ctx, "same", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, src.PutBlob(ctx, "missing", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, dst.PutBlob(ctx, "extra", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, src.PutBlob(ctx, "mismatched", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, dst.PutBlob(ctx, "mismatched", gather.FromSlice([]byte{1, 2})))
+
+	r, err := blob.Diff(ctx, src, dst, "", nil)
+	require.NoError(t, err)
+	require.False(t, r.Empty())
+	require.Equal(t, 1, r.Matching)
+	require.Equal(t, []blob.ID{"missing"}, blob.IDsFromMetadata(r.Missing))
+	require.Equal(t, []blob.ID{"extra"}, blob.IDsFromMetadata(r.Extra))
+	require.Equal(t, []blob.ID{"mismatched"}, blob.IDsFromMetadata(r.Mismatched))
+
+	// compare with a filter that only accepts blobs starting with 's'
+	r, err = blob.Diff(ctx, src, dst, "", func(id blob.ID) bool {
+		return strings.HasPrefix(string(id), "s")
+	})
+	require.NoError(t, err)
+	require.True(t, r.Empty())
+	require.Equal(t, 1, r.Matching)
+
+	// compare storage to itself
+	r, err = blob.Diff(ctx, src, src, "", nil)
+	require.NoError(t, err)
+	require.True(t, r.Empty())
+	require.Equal(t, 3, r.Matching)
+}
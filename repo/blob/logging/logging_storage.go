@@ -73,6 +73,29 @@ func (s *loggingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
 	return err
 }
 
+func (s *loggingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	t0 := clock.Now()
+	errs := blob.DeleteBlobs(ctx, s.base, ids)
+	dt := clock.Since(t0)
+
+	failed := 0
+
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	s.printf(s.prefix+"DeleteBlobs(len=%v) failed %v took %v", len(ids), failed, dt)
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *loggingStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.base)
+}
+
 func (s *loggingStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
 	t0 := clock.Now()
 	cnt := 0
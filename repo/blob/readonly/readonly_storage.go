@@ -40,6 +40,21 @@ func (s readonlyStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
 	return ErrReadonly
 }
 
+func (s readonlyStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	errs := make([]error, len(ids))
+
+	for i := range errs {
+		errs[i] = ErrReadonly
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter, blobs are never deleted from read-only storage.
+func (s readonlyStorage) SupportsBatchDelete() bool {
+	return false
+}
+
 func (s readonlyStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
 	// nolint:wrapcheck
 	return s.base.ListBlobs(ctx, prefix, callback)
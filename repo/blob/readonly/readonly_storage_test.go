@@ -0,0 +1,64 @@
+package readonly_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/readonly"
+)
+
+func TestReadonlyStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{}
+	base := blobtesting.NewMapStorage(data, nil, nil)
+
+	require.NoError(t, base.PutBlob(ctx, "blob1", gather.FromSlice([]byte{1, 2, 3})))
+
+	st := readonly.NewWrapper(base)
+
+	// reads pass through.
+	v, err := st.GetBlob(ctx, "blob1", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, v)
+
+	blobtesting.AssertGetBlobNotFound(ctx, t, st, "blob2")
+	blobtesting.AssertListResults(ctx, t, st, "", "blob1")
+
+	bm, err := st.GetMetadata(ctx, "blob1")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), bm.Length)
+
+	require.Equal(t, base.ConnectionInfo(), st.ConnectionInfo())
+	require.Equal(t, base.DisplayName(), st.DisplayName())
+	require.NoError(t, st.FlushCaches(ctx))
+
+	// all mutations are rejected, including those that would fail in the underlying storage anyway.
+	for _, id := range []blob.ID{"blob1", "blob2"} {
+		require.ErrorIs(t, st.PutBlob(ctx, id, gather.FromSlice([]byte{4, 5, 6})), readonly.ErrReadonly)
+		require.ErrorIs(t, st.SetTime(ctx, id, clock.Now()), readonly.ErrReadonly)
+		require.ErrorIs(t, st.DeleteBlob(ctx, id), readonly.ErrReadonly)
+	}
+
+	bd, ok := st.(blob.BatchDeleter)
+	require.True(t, ok)
+
+	for _, err := range bd.DeleteBlobs(ctx, []blob.ID{"blob1", "blob2"}) {
+		require.ErrorIs(t, err, readonly.ErrReadonly)
+	}
+
+	require.False(t, errors.Is(readonly.ErrReadonly, blob.ErrBlobNotFound))
+
+	// underlying storage is unchanged.
+	require.Len(t, data, 1)
+	require.Equal(t, []byte{1, 2, 3}, data["blob1"])
+
+	require.NoError(t, st.Close(ctx))
+}
@@ -5,22 +5,58 @@
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/kopia/kopia/internal/retry"
 	"github.com/kopia/kopia/repo/blob"
 )
 
+// Options controls the retry behavior of the wrapper, zero values select defaults.
+type Options struct {
+	MaxAttempts    int           // maximum number of attempts of each operation, including the first one
+	InitialBackoff time.Duration // delay before the first retry
+	MaxBackoff     time.Duration // maximum delay between retries
+	Jitter         float64       // fraction [0..1] of each delay that is randomized
+
+	// IsRetriable determines whether the given error should be retried, nil means retry all errors.
+	// Errors such as blob.ErrBlobNotFound or blob.ErrInvalidRange are never retried.
+	IsRetriable func(err error) bool
+}
+
 // retryingStorage adds retry loop around all operations of the underlying storage.
 type retryingStorage struct {
 	blob.Storage
+
+	opt Options
+}
+
+func (s retryingStorage) retry(ctx context.Context, desc string, attempt retry.AttemptFunc) (interface{}, error) {
+	return retry.WithBackoff(ctx, desc, retry.Backoff{
+		Initial:     s.opt.InitialBackoff,
+		Max:         s.opt.MaxBackoff,
+		MaxAttempts: s.opt.MaxAttempts,
+		Jitter:      s.opt.Jitter,
+	}, attempt, s.isRetriable)
+}
+
+func (s retryingStorage) isRetriable(err error) bool {
+	if !isRetriable(err) {
+		return false
+	}
+
+	if s.opt.IsRetriable == nil {
+		return true
+	}
+
+	return s.opt.IsRetriable(err)
 }
 
 func (s retryingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64) ([]byte, error) {
-	v, err := retry.WithExponentialBackoff(ctx, fmt.Sprintf("GetBlob(%v,%v,%v)", id, offset, length), func() (interface{}, error) {
+	v, err := s.retry(ctx, fmt.Sprintf("GetBlob(%v,%v,%v)", id, offset, length), func() (interface{}, error) {
 		// nolint:wrapcheck
 		return s.Storage.GetBlob(ctx, id, offset, length)
-	}, isRetriable)
+	})
 	if err != nil {
 		return nil, err // nolint:wrapcheck
 	}
@@ -29,10 +65,10 @@ func (s retryingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length
 }
 
 func (s retryingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
-	v, err := retry.WithExponentialBackoff(ctx, "GetMetadata("+string(id)+")", func() (interface{}, error) {
+	v, err := s.retry(ctx, "GetMetadata("+string(id)+")", func() (interface{}, error) {
 		// nolint:wrapcheck
 		return s.Storage.GetMetadata(ctx, id)
-	}, isRetriable)
+	})
 	if err != nil {
 		return blob.Metadata{}, err // nolint:wrapcheck
 	}
@@ -41,39 +77,108 @@ func (s retryingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Meta
 }
 
 func (s retryingStorage) SetTime(ctx context.Context, id blob.ID, t time.Time) error {
-	_, err := retry.WithExponentialBackoff(ctx, "GetMetadata("+string(id)+")", func() (interface{}, error) {
+	_, err := s.retry(ctx, "SetTime("+string(id)+")", func() (interface{}, error) {
 		// nolint:wrapcheck
 		return true, s.Storage.SetTime(ctx, id, t)
-	}, isRetriable)
+	})
 
 	return err // nolint:wrapcheck
 }
 
 func (s retryingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes) error {
-	_, err := retry.WithExponentialBackoff(ctx, "PutBlob("+string(id)+")", func() (interface{}, error) {
+	_, err := s.retry(ctx, "PutBlob("+string(id)+")", func() (interface{}, error) {
 		// nolint:wrapcheck
 		return true, s.Storage.PutBlob(ctx, id, data)
-	}, isRetriable)
+	})
 
 	return err // nolint:wrapcheck
 }
 
 func (s retryingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
-	_, err := retry.WithExponentialBackoff(ctx, "DeleteBlob("+string(id)+")", func() (interface{}, error) {
+	_, err := s.retry(ctx, "DeleteBlob("+string(id)+")", func() (interface{}, error) {
 		// nolint:wrapcheck
 		return true, s.Storage.DeleteBlob(ctx, id)
-	}, isRetriable)
+	})
+
+	return err // nolint:wrapcheck
+}
+
+// DeleteBlobs deletes the provided blobs in a batch if supported by the underlying storage
+// and retries failed deletions one by one.
+func (s retryingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	errs := blob.DeleteBlobs(ctx, s.Storage, ids)
+
+	for i, err := range errs {
+		if err != nil && s.isRetriable(err) {
+			errs[i] = s.DeleteBlob(ctx, ids[i])
+		}
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s retryingStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.Storage)
+}
+
+// ListBlobs retries listing only until the first blob has been delivered to the callback,
+// since retrying afterwards would deliver the same blobs again.
+func (s retryingStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	var delivered int32
+
+	_, err := s.retry(ctx, "ListBlobs("+string(prefix)+")", func() (interface{}, error) {
+		err := s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			atomic.StoreInt32(&delivered, 1)
+			return callback(bm)
+		})
+
+		if err != nil && atomic.LoadInt32(&delivered) != 0 {
+			return true, notRetriableError{err}
+		}
+
+		// nolint:wrapcheck
+		return true, err
+	})
+
+	var nre notRetriableError
+	if errors.As(err, &nre) {
+		return nre.error
+	}
 
 	return err // nolint:wrapcheck
 }
 
+// notRetriableError marks an error that must be returned to the caller without retrying.
+type notRetriableError struct {
+	error
+}
+
+func (e notRetriableError) Unwrap() error {
+	return e.error
+}
+
 // NewWrapper returns a Storage wrapper that adds retry loop around all operations of the underlying storage.
 func NewWrapper(wrapped blob.Storage) blob.Storage {
-	return &retryingStorage{Storage: wrapped}
+	return NewWrapperWithOptions(wrapped, Options{})
+}
+
+// NewWrapperWithOptions returns a Storage wrapper that retries operations of the underlying storage
+// according to the provided options.
+func NewWrapperWithOptions(wrapped blob.Storage, opt Options) blob.Storage {
+	return &retryingStorage{Storage: wrapped, opt: opt}
 }
 
 func isRetriable(err error) bool {
+	var nre notRetriableError
+
 	switch {
+	case errors.As(err, &nre):
+		return false
+
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+
 	case errors.Is(err, blob.ErrBlobNotFound):
 		return false
 
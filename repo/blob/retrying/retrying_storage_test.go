@@ -1,7 +1,9 @@
 package retrying_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
@@ -84,3 +86,184 @@ func TestRetrying(t *testing.T) {
 
 	fs.VerifyAllFaultsExercised(t)
 }
+
+func TestRetryingWithOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	someError := errors.New("some error")
+	permanentError := errors.New("permanent error")
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.NoError(t, ms.PutBlob(ctx, "a1", gather.FromSlice([]byte{1})))
+	require.NoError(t, ms.PutBlob(ctx, "a2", gather.FromSlice([]byte{2})))
+
+	var attempts int
+
+	countAttempts := func(err error) func() error {
+		return func() error {
+			attempts++
+			return err
+		}
+	}
+
+	fs := &blobtesting.FaultyStorage{Base: ms}
+
+	rs := retrying.NewWrapperWithOptions(fs, retrying.Options{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		Jitter:         0.5,
+		IsRetriable: func(err error) bool {
+			return !errors.Is(err, permanentError)
+		},
+	})
+
+	// retriable errors are retried until they succeed.
+	attempts = 0
+	fs.Faults = map[string][]*blobtesting.Fault{
+		"GetBlob": {{Repeat: 1, ErrCallback: countAttempts(someError)}},
+	}
+
+	v, err := rs.GetBlob(ctx, "a1", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, v)
+	require.Equal(t, 2, attempts)
+	fs.VerifyAllFaultsExercised(t)
+
+	// attempts are capped and the last error is returned.
+	attempts = 0
+	fs.Faults = map[string][]*blobtesting.Fault{
+		"PutBlob": {{Repeat: 10, ErrCallback: countAttempts(someError)}},
+	}
+
+	require.ErrorIs(t, rs.PutBlob(ctx, "a3", gather.FromSlice([]byte{3})), someError)
+	require.Equal(t, 3, attempts)
+
+	// errors rejected by IsRetriable are not retried.
+	attempts = 0
+	fs.Faults = map[string][]*blobtesting.Fault{
+		"DeleteBlob": {{Repeat: 10, ErrCallback: countAttempts(permanentError)}},
+	}
+
+	require.ErrorIs(t, rs.DeleteBlob(ctx, "a1"), permanentError)
+	require.Equal(t, 1, attempts)
+
+	// not found is never retried.
+	attempts = 0
+	fs.Faults = map[string][]*blobtesting.Fault{
+		"GetBlob": {{Repeat: 10, ErrCallback: countAttempts(blob.ErrBlobNotFound)}},
+	}
+
+	_, err = rs.GetBlob(ctx, "a1", 0, -1)
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetryingListBlobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	someError := errors.New("some error")
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.NoError(t, ms.PutBlob(ctx, "a1", gather.FromSlice([]byte{1})))
+	require.NoError(t, ms.PutBlob(ctx, "a2", gather.FromSlice([]byte{2})))
+
+	fs := &blobtesting.FaultyStorage{Base: ms}
+	rs := retrying.NewWrapperWithOptions(fs, retrying.Options{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+
+	// errors before the first blob is delivered are retried.
+	fs.Faults = map[string][]*blobtesting.Fault{
+		"ListBlobs": {{Err: someError}},
+	}
+
+	blobtesting.AssertListResults(ctx, t, rs, "a", "a1", "a2")
+	fs.VerifyAllFaultsExercised(t)
+
+	// errors after the first blob is delivered are not, to avoid delivering the same blobs twice.
+	fs.Faults = map[string][]*blobtesting.Fault{
+		"ListBlobsItem": {{}, {Err: someError}},
+	}
+
+	var got []blob.ID
+
+	err := rs.ListBlobs(ctx, "a", func(bm blob.Metadata) error {
+		got = append(got, bm.BlobID)
+		return nil
+	})
+
+	require.ErrorIs(t, err, someError)
+	require.Len(t, got, 1)
+	fs.VerifyAllFaultsExercised(t)
+}
+
+func TestRetryingCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(testlogging.Context(t))
+
+	var attempts int
+
+	fs := &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"GetMetadata": {{Repeat: 10, ErrCallback: func() error {
+				attempts++
+				cancel()
+
+				return errors.New("some error")
+			}}},
+		},
+	}
+
+	rs := retrying.NewWrapperWithOptions(fs, retrying.Options{
+		MaxAttempts:    5,
+		InitialBackoff: time.Hour,
+	})
+
+	_, err := rs.GetMetadata(ctx, "a1")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetryingDeleteBlobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	someError := errors.New("some error")
+	permanentError := errors.New("permanent error")
+
+	data := blobtesting.DataMap{"a": {1}, "b": {2}, "c": {3}}
+
+	fs := &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(data, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"DeleteBlob": {{Err: someError}, {}, {Err: permanentError}},
+		},
+	}
+
+	rs := retrying.NewWrapperWithOptions(fs, retrying.Options{
+		InitialBackoff: time.Millisecond,
+		IsRetriable: func(err error) bool {
+			return !errors.Is(err, permanentError)
+		},
+	})
+
+	bd, ok := rs.(blob.BatchDeleter)
+	require.True(t, ok)
+
+	// failed deletions are retried individually unless the error is not retriable.
+	errs := bd.DeleteBlobs(ctx, []blob.ID{"a", "b", "c"})
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.ErrorIs(t, errs[2], permanentError)
+	require.Equal(t, blobtesting.DataMap{"c": {3}}, data)
+}
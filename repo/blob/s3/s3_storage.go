@@ -154,6 +154,48 @@ func (s *s3Storage) DeleteBlob(ctx context.Context, b blob.ID) error {
 	return err
 }
 
+// DeleteBlobs deletes the provided blobs using S3 multi-object delete requests.
+func (s *s3Storage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	errs := make([]error, len(ids))
+	indexByName := map[string]int{}
+
+	objectsCh := make(chan minio.ObjectInfo, len(ids))
+
+	for i, id := range ids {
+		name := s.getObjectNameString(id)
+		indexByName[name] = i
+		objectsCh <- minio.ObjectInfo{Key: name}
+	}
+
+	close(objectsCh)
+
+	for re := range s.cli.RemoveObjects(ctx, s.BucketName, objectsCh, minio.RemoveObjectsOptions{}) {
+		err := translateError(re.Err)
+		if err == nil || errors.Is(err, blob.ErrBlobNotFound) {
+			continue
+		}
+
+		i, ok := indexByName[re.ObjectName]
+		if !ok {
+			// error not associated with a particular object applies to all of them.
+			for j := range errs {
+				errs[j] = err
+			}
+
+			continue
+		}
+
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *s3Storage) SupportsBatchDelete() bool {
+	return true
+}
+
 func (s *s3Storage) getObjectNameString(b blob.ID) string {
 	return s.Prefix + string(b)
 }
@@ -0,0 +1,100 @@
+package sftp_test
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob/sftp"
+)
+
+func TestSFTPStorageJumpHost(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	hostKey, _ := mustGenerateRSAKey(t)
+	clientKey, clientKeyPEM := mustGenerateRSAKey(t)
+
+	target := startStubSSHServer(t, hostKey, "target-user", clientKey.PublicKey())
+	defer target.close()
+
+	jump := startStubSSHServer(t, hostKey, "jump-user", clientKey.PublicKey())
+	defer jump.close()
+
+	targetHost, targetPort := target.hostPort()
+	jumpHost, jumpPort := jump.hostPort()
+
+	knownHosts := knownhosts.Line([]string{knownhosts.Normalize(target.addr()), knownhosts.Normalize(jump.addr())}, hostKey.PublicKey())
+
+	opt := &sftp.Options{
+		Path:           t.TempDir(),
+		Host:           targetHost,
+		Port:           targetPort,
+		Username:       "target-user",
+		KeyData:        string(clientKeyPEM),
+		KnownHostsData: knownHosts,
+		JumpHost:       jumpHost,
+		JumpHostPort:   jumpPort,
+		JumpHostUser:   "jump-user",
+	}
+
+	st, err := sftp.New(ctx, opt)
+	require.NoError(t, err)
+
+	blobtesting.VerifyStorage(ctx, t, st)
+	blobtesting.AssertConnectionInfoRoundTrips(ctx, t, st)
+	require.NoError(t, st.Close(ctx))
+
+	require.NotZero(t, atomic.LoadInt32(&jump.forwardedConnections), "connection was not tunneled through jump host")
+	require.Zero(t, atomic.LoadInt32(&target.forwardedConnections))
+
+	// jump host key must be verified too.
+	opt.KnownHostsData = knownhosts.Line([]string{knownhosts.Normalize(target.addr())}, hostKey.PublicKey())
+
+	_, err = sftp.New(ctx, opt)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to dial jump host")
+
+	// wrong jump host user.
+	opt.KnownHostsData = knownHosts
+	opt.JumpHostUser = "target-user"
+
+	_, err = sftp.New(ctx, opt)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to dial jump host")
+}
+
+func TestSFTPStorageJumpHostOptionValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		opt     sftp.Options
+		wantErr string
+	}{
+		{sftp.Options{JumpHostPort: 22}, "jump host port or user specified without jump host"},
+		{sftp.Options{JumpHostUser: "foo"}, "jump host port or user specified without jump host"},
+		{sftp.Options{JumpHost: "bastion", ExternalSSH: true}, "jump host is not supported with external SSH"},
+		{sftp.Options{JumpHost: "bastion", JumpHostPort: -1}, "invalid jump host port"},
+		{sftp.Options{JumpHost: "bastion", JumpHostPort: 70000}, "invalid jump host port"},
+	}
+
+	for i, tc := range cases {
+		tc := tc
+
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			tc.opt.Path = "/upload"
+			tc.opt.Host = "some-host"
+			tc.opt.Username = sftpUsername
+
+			_, err := sftp.New(testlogging.Context(t), &tc.opt)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
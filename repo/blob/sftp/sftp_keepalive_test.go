@@ -0,0 +1,140 @@
+package sftp_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob/sftp"
+)
+
+func newStubServerOptions(t *testing.T, dropAfterBytes int64) (*stubSSHServer, *sftp.Options) {
+	t.Helper()
+
+	hostKey, _ := mustGenerateRSAKey(t)
+	clientKey, clientKeyPEM := mustGenerateRSAKey(t)
+
+	srv := startStubSSHServer(t, hostKey, sftpUsername, clientKey.PublicKey())
+	t.Cleanup(srv.close)
+
+	atomic.StoreInt64(&srv.dropFirstConnectionAfterBytes, dropAfterBytes)
+
+	host, port := srv.hostPort()
+
+	return srv, &sftp.Options{
+		Path:           t.TempDir(),
+		Host:           host,
+		Port:           port,
+		Username:       sftpUsername,
+		KeyData:        string(clientKeyPEM),
+		KnownHostsData: knownhosts.Line([]string{knownhosts.Normalize(srv.addr())}, hostKey.PublicKey()),
+	}
+}
+
+func TestSFTPStorageKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	srv, opt := newStubServerOptions(t, 0)
+
+	// keepalives are driven by the fake ticker below, the interval only limits how long to wait for a reply.
+	opt.KeepAliveInterval = time.Hour
+
+	ticks := make(chan time.Time)
+	tickersStopped := make(chan struct{}, maxStubConnections)
+
+	var tickersStarted int32
+
+	opt.NewKeepAliveTicker = func(interval time.Duration) (<-chan time.Time, func()) {
+		atomic.AddInt32(&tickersStarted, 1)
+
+		return ticks, func() { tickersStopped <- struct{}{} }
+	}
+
+	st, err := sftp.New(ctx, opt)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		sendTick(t, ticks)
+		waitFor(t, srv.keepAlivesReceived, "keepalive request")
+	}
+
+	require.NoError(t, st.Close(ctx))
+
+	// keepalives stop when the connection is closed.
+	for i := int32(0); i < atomic.LoadInt32(&tickersStarted); i++ {
+		waitFor(t, tickersStopped, "keepalive ticker to stop")
+	}
+
+	select {
+	case ticks <- time.Time{}:
+		t.Fatalf("keepalive tick was consumed after close")
+	default:
+	}
+}
+
+func sendTick(t *testing.T, ticks chan<- time.Time) {
+	t.Helper()
+
+	select {
+	case ticks <- time.Time{}:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out sending keepalive tick")
+	}
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for %v", what)
+	}
+}
+
+func TestSFTPStorageReconnectsAfterConnectionDrop(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	// the first connection will be dropped in the middle of the upload below.
+	srv, opt := newStubServerOptions(t, 256<<10)
+
+	st, err := sftp.New(ctx, opt)
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+
+	require.NoError(t, st.PutBlob(ctx, "someblob", gather.FromSlice(data)))
+
+	got, err := st.GetBlob(ctx, "someblob", 0, -1)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(got, data), "invalid data after reconnect")
+
+	require.EqualValues(t, 2, atomic.LoadInt32(&srv.acceptedConnections))
+}
+
+func TestSFTPStorageInvalidKeepAliveInterval(t *testing.T) {
+	t.Parallel()
+
+	_, err := sftp.New(testlogging.Context(t), &sftp.Options{
+		Path:              "/upload",
+		Host:              "some-host",
+		Username:          sftpUsername,
+		KeepAliveInterval: -time.Second,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid keepalive interval")
+}
@@ -1,8 +1,17 @@
 package sftp
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSSHPort = 22
+	maxPort        = 65535
 )
 
 // Options defines options for sftp-backed storage.
@@ -14,10 +23,22 @@ type Options struct {
 	Username       string `json:"username"`
 	Keyfile        string `json:"keyfile,omitempty"`
 	KeyData        string `json:"keyData,omitempty" kopia:"sensitive"`
+	UseSSHAgent    bool   `json:"useSSHAgent,omitempty"` // authenticate using ssh-agent listening on SSH_AUTH_SOCK
 	KnownHostsFile string `json:"knownHostsFile,omitempty"`
 	KnownHostsData string `json:"knownHostsData,omitempty"`
 	MaxConnections int    `json:"maxConnections"`
 
+	// KeepAliveInterval specifies how often to send SSH keepalive requests, 0 disables them.
+	KeepAliveInterval time.Duration `json:"keepAliveInterval,omitempty"`
+
+	// NewKeepAliveTicker returns a channel delivering keepalive ticks and a function to stop it,
+	// defaults to time.NewTicker. Used in tests.
+	NewKeepAliveTicker func(interval time.Duration) (<-chan time.Time, func()) `json:"-"`
+
+	JumpHost     string `json:"jumpHost,omitempty"`
+	JumpHostPort int    `json:"jumpHostPort,omitempty"`
+	JumpHostUser string `json:"jumpHostUser,omitempty"` // defaults to Username
+
 	ExternalSSH  bool   `json:"externalSSH"`
 	SSHCommand   string `json:"sshCommand,omitempty"` // default "ssh"
 	SSHArguments string `json:"sshArguments,omitempty"`
@@ -44,6 +65,47 @@ func (sftpo *Options) knownHostsFile() string {
 	return sftpo.KnownHostsFile
 }
 
+func (sftpo *Options) jumpHostAddress() string {
+	port := sftpo.JumpHostPort
+	if port == 0 {
+		port = defaultSSHPort
+	}
+
+	return fmt.Sprintf("%s:%d", sftpo.JumpHost, port)
+}
+
+func (sftpo *Options) jumpHostUser() string {
+	if sftpo.JumpHostUser == "" {
+		return sftpo.Username
+	}
+
+	return sftpo.JumpHostUser
+}
+
+func (sftpo *Options) validate() error {
+	if sftpo.KeepAliveInterval < 0 {
+		return errors.Errorf("invalid keepalive interval: %v", sftpo.KeepAliveInterval)
+	}
+
+	if sftpo.JumpHost == "" {
+		if sftpo.JumpHostPort != 0 || sftpo.JumpHostUser != "" {
+			return errors.Errorf("jump host port or user specified without jump host")
+		}
+
+		return nil
+	}
+
+	if sftpo.ExternalSSH {
+		return errors.Errorf("jump host is not supported with external SSH, use SSH arguments instead")
+	}
+
+	if sftpo.JumpHostPort < 0 || sftpo.JumpHostPort > maxPort {
+		return errors.Errorf("invalid jump host port: %v", sftpo.JumpHostPort)
+	}
+
+	return nil
+}
+
 func (sftpo *Options) maxConnections() int {
 	if sftpo.MaxConnections <= 0 {
 		return 1
@@ -51,3 +113,13 @@ func (sftpo *Options) maxConnections() int {
 
 	return sftpo.MaxConnections
 }
+
+func (sftpo *Options) newKeepAliveTicker(interval time.Duration) (<-chan time.Time, func()) {
+	if sftpo.NewKeepAliveTicker != nil {
+		return sftpo.NewKeepAliveTicker(interval)
+	}
+
+	t := time.NewTicker(interval)
+
+	return t.C, t.Stop
+}
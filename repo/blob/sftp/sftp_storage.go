@@ -7,6 +7,7 @@
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -18,6 +19,7 @@
 	"github.com/pkg/errors"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
 
 	"github.com/kopia/kopia/internal/retry"
@@ -35,6 +37,10 @@
 	tempFileRandomSuffixLen = 8
 
 	packetSize = 1 << 15
+
+	sshAuthSockEnvVar = "SSH_AUTH_SOCK"
+
+	keepAliveRequestType = "keepalive@openssh.com"
 )
 
 var sftpDefaultShards = []int{3, 3}
@@ -49,6 +55,7 @@ type sftpImpl struct {
 
 	cond          sync.Cond
 	connectionID  int
+	everConnected bool // set after the first successful connection, enables retrying of reconnects
 	availableConn []*sftpConnection
 	allConn       []*sftpConnection
 }
@@ -93,10 +100,15 @@ func (s *sftpImpl) getPooledConnection(ctx context.Context) (*sftpConnection, er
 
 			conn, err := getSFTPClient(ctx, &s.Options)
 			if err != nil {
+				if s.everConnected {
+					return nil, reconnectError{errors.Wrap(err, "error re-establishing SFTP connection")}
+				}
+
 				return nil, errors.Wrap(err, "error establishing SFTP connecting")
 			}
 
 			conn.id = s.connectionID
+			s.everConnected = true
 
 			s.allConn = append(s.allConn, conn)
 
@@ -141,6 +153,22 @@ func removeConn(s []*sftpConnection, v *sftpConnection) []*sftpConnection {
 	return result
 }
 
+// reconnectError wraps errors encountered when re-establishing a connection after the
+// initial one succeeded, those are assumed to be transient and are retried.
+type reconnectError struct {
+	error
+}
+
+func (e reconnectError) Unwrap() error {
+	return e.error
+}
+
+func isRetriableConnectionError(err error) bool {
+	var re reconnectError
+
+	return errors.As(err, &re) || isConnectionClosedError(err)
+}
+
 func isConnectionClosedError(err error) bool {
 	if errors.Is(err, sftp.ErrSshFxConnectionLost) {
 		return true
@@ -162,7 +190,7 @@ func (s *sftpImpl) usingClient(ctx context.Context, desc string, cb func(cli *sf
 	return retry.WithExponentialBackoff(ctx, desc, func() (interface{}, error) {
 		conn, err := s.getPooledConnection(ctx)
 		if err != nil {
-			if isConnectionClosedError(err) {
+			if isRetriableConnectionError(err) {
 				log(ctx).Errorf("SFTP connection failed: %v, will retry", err)
 			}
 
@@ -180,7 +208,7 @@ func (s *sftpImpl) usingClient(ctx context.Context, desc string, cb func(cli *sf
 		}
 
 		return v, err
-	}, isConnectionClosedError)
+	}, isRetriableConnectionError)
 }
 
 func (s *sftpImpl) usingClientNoResult(ctx context.Context, desc string, cb func(cli *sftp.Client) error) error {
@@ -200,6 +228,7 @@ func (s *sftpImpl) closeAllConnections(ctx context.Context) {
 	}
 
 	s.allConn = nil
+	s.availableConn = nil
 }
 
 func (s *sftpImpl) GetBlobFromPath(ctx context.Context, dirPath, fullPath string, offset, length int64) ([]byte, error) {
@@ -461,26 +490,82 @@ func getSigner(opt *Options) (ssh.Signer, error) {
 	return key, nil
 }
 
-func createSSHConfig(ctx context.Context, opt *Options) (*ssh.ClientConfig, error) {
+// getSSHAgentAuth returns authentication method backed by the SSH agent listening on SSH_AUTH_SOCK
+// and a function that closes the connection to the agent.
+func getSSHAgentAuth() (ssh.AuthMethod, func() error, error) {
+	sock := os.Getenv(sshAuthSockEnvVar)
+	if sock == "" {
+		return nil, nil, errors.Errorf("%v is not set", sshAuthSockEnvVar)
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to connect to ssh-agent")
+	}
+
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), conn.Close, nil
+}
+
+// getAuthMethods returns SSH authentication methods based on the provided options and a function
+// that releases resources associated with them after the SSH handshake completes.
+func getAuthMethods(ctx context.Context, opt *Options) ([]ssh.AuthMethod, func(), error) {
+	var (
+		methods []ssh.AuthMethod
+		closers []func() error
+	)
+
+	if opt.UseSSHAgent {
+		m, closeFunc, err := getSSHAgentAuth()
+		if err == nil {
+			methods = append(methods, m)
+			closers = append(closers, closeFunc)
+		} else {
+			log(ctx).Debugf("ssh-agent is not available: %v", err)
+
+			if opt.Keyfile == "" && opt.KeyData == "" {
+				return nil, nil, errors.Wrap(err, "ssh-agent is unavailable and no private key was specified")
+			}
+		}
+	}
+
+	if !opt.UseSSHAgent || opt.Keyfile != "" || opt.KeyData != "" {
+		signer, err := getSigner(opt)
+		if err != nil {
+			for _, c := range closers {
+				c() // nolint:errcheck
+			}
+
+			return nil, nil, errors.Wrapf(err, "unable to getSigner")
+		}
+
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	return methods, func() {
+		for _, c := range closers {
+			c() // nolint:errcheck
+		}
+	}, nil
+}
+
+func createSSHConfig(ctx context.Context, opt *Options) (*ssh.ClientConfig, func(), error) {
 	log(ctx).Debugf("using internal SSH client")
 
 	hostKeyCallback, err := getHostKeyCallback(opt)
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to getHostKey: %s", opt.Host)
+		return nil, nil, errors.Wrapf(err, "unable to getHostKey: %s", opt.Host)
 	}
 
-	signer, err := getSigner(opt)
+	auth, closeFunc, err := getAuthMethods(ctx, opt)
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to getSigner")
+		return nil, nil, err
 	}
 
 	return &ssh.ClientConfig{
-		User: opt.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
+		User:            opt.Username,
+		Auth:            auth,
 		HostKeyCallback: hostKeyCallback,
-	}, nil
+	}, closeFunc, nil
 }
 
 func getSFTPClientExternal(ctx context.Context, opt *Options) (*sftpConnection, error) {
@@ -551,16 +636,20 @@ func getSFTPClient(ctx context.Context, opt *Options) (*sftpConnection, error) {
 		return getSFTPClientExternal(ctx, opt)
 	}
 
-	config, err := createSSHConfig(ctx, opt)
+	config, closeAuth, err := createSSHConfig(ctx, opt)
 	if err != nil {
 		return nil, err
 	}
 
 	addr := fmt.Sprintf("%s:%d", opt.Host, opt.Port)
 
-	conn, err := ssh.Dial("tcp", addr, config)
+	conn, closeConn, err := dialSSH(opt, addr, config)
+
+	// authentication is complete after the handshake.
+	closeAuth()
+
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to dial [%s]: %#v", addr, config)
+		return nil, err
 	}
 
 	c, err := sftp.NewClient(conn,
@@ -569,18 +658,131 @@ func getSFTPClient(ctx context.Context, opt *Options) (*sftpConnection, error) {
 		sftp.UseConcurrentReads(true),
 	)
 	if err != nil {
-		conn.Close() // nolint:errcheck
+		closeConn() // nolint:errcheck
 		return nil, errors.Wrapf(err, "unable to create sftp client")
 	}
 
+	if opt.KeepAliveInterval > 0 {
+		stop := make(chan struct{})
+
+		var stopOnce sync.Once
+
+		ticks, stopTicker := opt.newKeepAliveTicker(opt.KeepAliveInterval)
+
+		go sendKeepAlives(ctx, conn, opt.KeepAliveInterval, ticks, stopTicker, stop)
+
+		closeConnNoKeepAlive := closeConn
+		closeConn = func() error {
+			// the connection may be closed more than once, for example after a connection error.
+			stopOnce.Do(func() { close(stop) })
+			return closeConnNoKeepAlive()
+		}
+	}
+
 	return &sftpConnection{
 		currentClient: c,
-		closeFunc:     conn.Close,
+		closeFunc:     closeConn,
+	}, nil
+}
+
+// sendKeepAlives periodically sends keepalive requests over the provided SSH connection until stopped.
+// When the server does not respond in time, the connection is closed, which causes pending and future
+// operations to fail with connection lost errors and the connection to be re-established.
+func sendKeepAlives(ctx context.Context, conn *ssh.Client, interval time.Duration, ticks <-chan time.Time, stopTicker func(), stop <-chan struct{}) {
+	defer stopTicker()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticks:
+		}
+
+		replied := make(chan error, 1)
+
+		go func() {
+			_, _, err := conn.SendRequest(keepAliveRequestType, true, nil)
+			replied <- err
+		}()
+
+		select {
+		case <-stop:
+			return
+
+		case err := <-replied:
+			if err == nil {
+				continue
+			}
+
+			log(ctx).Errorf("SSH keepalive failed: %v, closing connection", err)
+
+		case <-time.After(interval):
+			log(ctx).Errorf("SSH keepalive timed out after %v, closing connection", interval)
+		}
+
+		conn.Close() // nolint:errcheck
+
+		return
+	}
+}
+
+// dialSSH establishes SSH connection to the provided address, tunneling it through the jump host if one is
+// specified. The same authentication and host key verification is used for both hops.
+func dialSSH(opt *Options, addr string, config *ssh.ClientConfig) (*ssh.Client, func() error, error) {
+	if opt.JumpHost == "" {
+		conn, err := ssh.Dial("tcp", addr, config)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to dial [%s]: %#v", addr, config)
+		}
+
+		return conn, conn.Close, nil
+	}
+
+	jumpConfig := *config
+	jumpConfig.User = opt.jumpHostUser()
+
+	jumpAddr := opt.jumpHostAddress()
+
+	jump, err := ssh.Dial("tcp", jumpAddr, &jumpConfig)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to dial jump host [%s]", jumpAddr)
+	}
+
+	tunnel, err := jump.Dial("tcp", addr)
+	if err != nil {
+		jump.Close() // nolint:errcheck
+		return nil, nil, errors.Wrapf(err, "unable to dial [%s] through jump host [%s]", addr, jumpAddr)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(tunnel, addr, config)
+	if err != nil {
+		tunnel.Close() // nolint:errcheck
+		jump.Close()   // nolint:errcheck
+
+		return nil, nil, errors.Wrapf(err, "unable to establish SSH connection to [%s] through jump host [%s]", addr, jumpAddr)
+	}
+
+	conn := ssh.NewClient(c, chans, reqs)
+
+	return conn, func() error {
+		err := conn.Close()
+
+		if jerr := jump.Close(); err == nil {
+			err = jerr
+		}
+
+		// nolint:wrapcheck
+		return err
 	}, nil
 }
 
 // New creates new ssh-backed storage in a specified host.
 func New(ctx context.Context, opts *Options) (blob.Storage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid options")
+	}
+
 	impl := &sftpImpl{
 		Options: *opts,
 
@@ -11,14 +11,19 @@
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/kopia/kopia/internal/blobtesting"
 	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo/blob"
@@ -198,6 +203,131 @@ func TestSFTPStorageValid(t *testing.T) {
 	}
 }
 
+func TestSFTPStorageConcurrentConnections(t *testing.T) {
+	t.Parallel()
+
+	testutil.TestSkipOnCIUnlessLinuxAMD64(t)
+
+	tmpDir := mustGetLocalTmpDir(t)
+	idRSA := filepath.Join(tmpDir, "id_rsa")
+
+	mustRunCommand(t, "ssh-keygen", "-t", "rsa", "-P", "", "-f", idRSA)
+
+	host, port, knownHostsFile := startDockerSFTPServerOrSkip(t, idRSA)
+
+	ctx := testlogging.Context(t)
+
+	st, err := sftp.New(ctx, &sftp.Options{
+		Path:           "/upload",
+		Host:           host,
+		Username:       sftpUsername,
+		Port:           port,
+		Keyfile:        idRSA,
+		KnownHostsFile: knownHostsFile,
+		MaxConnections: 4,
+	})
+	require.NoError(t, err)
+
+	deleteBlobs(ctx, t, st)
+
+	const (
+		numWorkers     = 10
+		blobsPerWorker = 10
+	)
+
+	var eg errgroup.Group
+
+	for i := 0; i < numWorkers; i++ {
+		i := i
+
+		eg.Go(func() error {
+			for j := 0; j < blobsPerWorker; j++ {
+				id := blob.ID(fmt.Sprintf("blob-%v-%v", i, j))
+				data := []byte(id)
+
+				if err := st.PutBlob(ctx, id, gather.FromSlice(data)); err != nil {
+					return err
+				}
+
+				got, err := st.GetBlob(ctx, id, 0, -1)
+				if err != nil {
+					return err
+				}
+
+				if !bytes.Equal(got, data) {
+					return errors.Errorf("invalid data for %v: %x", id, got)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	require.NoError(t, eg.Wait())
+
+	all, err := blob.ListAllBlobs(ctx, st, "blob-")
+	require.NoError(t, err)
+	require.Len(t, all, numWorkers*blobsPerWorker)
+
+	deleteBlobs(ctx, t, st)
+	require.NoError(t, st.Close(ctx))
+}
+
+func TestSFTPStorageClosesPooledConnections(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	srv, opt := newStubServerOptions(t, 0)
+	opt.MaxConnections = 3
+
+	st, err := sftp.New(ctx, opt)
+	require.NoError(t, err)
+
+	const (
+		numWorkers     = 10
+		blobsPerWorker = 5
+	)
+
+	var eg errgroup.Group
+
+	for i := 0; i < numWorkers; i++ {
+		i := i
+
+		eg.Go(func() error {
+			for j := 0; j < blobsPerWorker; j++ {
+				id := blob.ID(fmt.Sprintf("blob-%v-%v", i, j))
+
+				if err := st.PutBlob(ctx, id, gather.FromSlice([]byte(id))); err != nil {
+					return err
+				}
+
+				if _, err := st.GetBlob(ctx, id, 0, -1); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	require.NoError(t, eg.Wait())
+
+	accepted := int(atomic.LoadInt32(&srv.acceptedConnections))
+	require.LessOrEqual(t, accepted, opt.MaxConnections)
+
+	require.NoError(t, st.Close(ctx))
+
+	// every pooled connection must be closed, whether it was idle or in use.
+	for i := 0; i < accepted; i++ {
+		select {
+		case <-srv.closedConnections:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("only %v out of %v connections were closed", i, accepted)
+		}
+	}
+}
+
 func TestInvalidServerFailsFast(t *testing.T) {
 	t.Parallel()
 
@@ -258,6 +388,87 @@ func TestSFTPStorageRelativeKnownHostsFile(t *testing.T) {
 	require.Contains(t, err.Error(), "known hosts path must be absolute")
 }
 
+func TestSFTPStorageSSHAgentUnavailable(t *testing.T) {
+	kh := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, ioutil.WriteFile(kh, []byte{}, 0600))
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", filepath.Join(t.TempDir(), "no-such-socket"))
+
+	opt := &sftp.Options{
+		Path:           "/upload",
+		Host:           "some-host",
+		Username:       sftpUsername,
+		Port:           22,
+		KnownHostsFile: kh,
+		UseSSHAgent:    true,
+	}
+
+	_, err := sftp.New(testlogging.Context(t), opt)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ssh-agent is unavailable and no private key was specified")
+}
+
+func TestSFTPStorageWithSSHAgent(t *testing.T) {
+	testutil.TestSkipOnCIUnlessLinuxAMD64(t)
+
+	tmpDir := mustGetLocalTmpDir(t)
+	idRSA := filepath.Join(tmpDir, "id_rsa")
+
+	mustRunCommand(t, "ssh-keygen", "-t", "rsa", "-P", "", "-f", idRSA)
+
+	host, port, knownHostsFile := startDockerSFTPServerOrSkip(t, idRSA)
+
+	// serve in-process SSH agent holding the private key.
+	keyData, err := ioutil.ReadFile(idRSA)
+	require.NoError(t, err)
+
+	key, err := ssh.ParseRawPrivateKey(keyData)
+	require.NoError(t, err)
+
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+
+	l, err := net.Listen("unix", filepath.Join(tmpDir, "agent.sock"))
+	if err != nil {
+		t.Skipf("unable to start SSH agent: %v", err)
+	}
+
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go agent.ServeAgent(keyring, conn) // nolint:errcheck
+		}
+	}()
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", l.Addr().String())
+
+	ctx := testlogging.Context(t)
+
+	st, err := sftp.New(ctx, &sftp.Options{
+		Path:           "/upload",
+		Host:           host,
+		Username:       sftpUsername,
+		Port:           port,
+		KnownHostsFile: knownHostsFile,
+		UseSSHAgent:    true,
+	})
+	require.NoError(t, err)
+
+	deleteBlobs(ctx, t, st)
+	blobtesting.VerifyStorage(ctx, t, st)
+	deleteBlobs(ctx, t, st)
+
+	require.NoError(t, st.Close(ctx))
+}
+
 func deleteBlobs(ctx context.Context, t *testing.T, st blob.Storage) {
 	t.Helper()
 
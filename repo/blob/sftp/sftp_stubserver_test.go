@@ -0,0 +1,268 @@
+package sftp_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	pkgsftp "github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	stubKeyBits = 2048
+
+	// maxStubConnections is the maximum number of connections a test may open to the stub server.
+	maxStubConnections = 100
+)
+
+// stubSSHServer is a minimal in-process SSH server supporting the sftp subsystem
+// and direct-tcpip port forwarding, which is used by jump hosts.
+type stubSSHServer struct {
+	t        *testing.T
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	forwardedConnections int32
+	acceptedConnections  int32
+
+	// receives a value each time the server finishes handling a connection.
+	closedConnections chan struct{}
+
+	// receives a value each time the server receives a keepalive request.
+	keepAlivesReceived chan struct{}
+
+	// when non-zero, the first accepted connection is dropped after reading that many bytes from the client.
+	dropFirstConnectionAfterBytes int64
+
+	wg sync.WaitGroup
+}
+
+// droppingConn is a net.Conn that abruptly closes itself after reading a given number of bytes.
+type droppingConn struct {
+	net.Conn
+
+	remaining int64
+}
+
+func (c *droppingConn) Read(b []byte) (int, error) {
+	if c.remaining <= 0 {
+		c.Conn.Close() // nolint:errcheck
+		return 0, io.EOF
+	}
+
+	if int64(len(b)) > c.remaining {
+		b = b[0:c.remaining]
+	}
+
+	n, err := c.Conn.Read(b)
+	c.remaining -= int64(n)
+
+	// nolint:wrapcheck
+	return n, err
+}
+
+func (s *stubSSHServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *stubSSHServer) hostPort() (string, int) {
+	host, port, err := net.SplitHostPort(s.addr())
+	require.NoError(s.t, err)
+
+	p, err := strconv.Atoi(port)
+	require.NoError(s.t, err)
+
+	return host, p
+}
+
+func (s *stubSSHServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if n := atomic.LoadInt64(&s.dropFirstConnectionAfterBytes); atomic.AddInt32(&s.acceptedConnections, 1) == 1 && n > 0 {
+			conn = &droppingConn{conn, n}
+		}
+
+		s.wg.Add(1)
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *stubSSHServer) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+
+	defer func() {
+		s.closedConnections <- struct{}{}
+	}()
+
+	sc, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+
+	defer sc.Close() // nolint:errcheck
+
+	go s.handleGlobalRequests(reqs)
+
+	for nc := range chans {
+		switch nc.ChannelType() {
+		case "session":
+			go s.handleSession(nc)
+
+		case "direct-tcpip":
+			go s.handleDirectTCPIP(nc)
+
+		default:
+			nc.Reject(ssh.UnknownChannelType, "unsupported channel type") // nolint:errcheck
+		}
+	}
+}
+
+func (s *stubSSHServer) handleGlobalRequests(reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		if req.Type == "keepalive@openssh.com" {
+			select {
+			case s.keepAlivesReceived <- struct{}{}:
+			default:
+			}
+		}
+
+		if req.WantReply {
+			req.Reply(false, nil) // nolint:errcheck
+		}
+	}
+}
+
+func (s *stubSSHServer) handleSession(nc ssh.NewChannel) {
+	ch, reqs, err := nc.Accept()
+	if err != nil {
+		return
+	}
+
+	defer ch.Close() // nolint:errcheck
+
+	for req := range reqs {
+		// subsystem request payload is a length-prefixed subsystem name.
+		if req.Type != "subsystem" || len(req.Payload) < 4 || string(req.Payload[4:]) != "sftp" {
+			req.Reply(false, nil) // nolint:errcheck
+			continue
+		}
+
+		req.Reply(true, nil) // nolint:errcheck
+
+		srv, err := pkgsftp.NewServer(ch)
+		if err != nil {
+			return
+		}
+
+		srv.Serve() // nolint:errcheck
+
+		return
+	}
+}
+
+func (s *stubSSHServer) handleDirectTCPIP(nc ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+
+	if err := ssh.Unmarshal(nc.ExtraData(), &payload); err != nil {
+		nc.Reject(ssh.ConnectionFailed, "invalid payload") // nolint:errcheck
+		return
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		nc.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+		return
+	}
+
+	ch, reqs, err := nc.Accept()
+	if err != nil {
+		target.Close() // nolint:errcheck
+		return
+	}
+
+	atomic.AddInt32(&s.forwardedConnections, 1)
+
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		io.Copy(ch, target) // nolint:errcheck
+		ch.CloseWrite()     // nolint:errcheck
+	}()
+
+	io.Copy(target, ch) // nolint:errcheck
+	target.Close()      // nolint:errcheck
+}
+
+func (s *stubSSHServer) close() {
+	s.listener.Close()
+}
+
+func startStubSSHServer(t *testing.T, hostKey ssh.Signer, allowedUser string, clientKey ssh.PublicKey) *stubSSHServer {
+	t.Helper()
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() != allowedUser || string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, errors.Errorf("access denied for %v", conn.User())
+			}
+
+			return nil, nil
+		},
+	}
+
+	config.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &stubSSHServer{
+		t:                  t,
+		listener:           l,
+		config:             config,
+		closedConnections:  make(chan struct{}, maxStubConnections),
+		keepAlivesReceived: make(chan struct{}, maxStubConnections),
+	}
+
+	s.wg.Add(1)
+
+	go s.serve()
+
+	return s
+}
+
+func mustGenerateRSAKey(t *testing.T) (ssh.Signer, []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, stubKeyBits)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	return signer, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
@@ -80,6 +80,17 @@ type Storage interface {
 	FlushCaches(ctx context.Context) error
 }
 
+// BatchDeleter is implemented by storage backends that can delete multiple blobs in a single operation.
+type BatchDeleter interface {
+	// DeleteBlobs removes the provided blobs from storage and returns a slice of errors with one entry
+	// (nil on success) for each provided ID.
+	DeleteBlobs(ctx context.Context, blobIDs []ID) []error
+
+	// SupportsBatchDelete returns true if the innermost storage deletes multiple blobs in a single operation,
+	// as opposed to wrappers that forward batches to a storage deleting blobs one by one.
+	SupportsBatchDelete() bool
+}
+
 // ID is a string that represents blob identifier.
 type ID string
 
@@ -98,6 +109,22 @@ func (m *Metadata) String() string {
 // ErrBlobNotFound is returned when a BLOB cannot be found in storage.
 var ErrBlobNotFound = errors.New("BLOB not found")
 
+// Exists returns true if the blob with the provided ID exists in the storage.
+// The check is performed using GetMetadata(), which all providers implement without
+// fetching blob contents (e.g. using HEAD or stat), so it is cheaper than GetBlob().
+func Exists(ctx context.Context, st Reader, id ID) (bool, error) {
+	_, err := st.GetMetadata(ctx, id)
+
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrBlobNotFound):
+		return false, nil
+	default:
+		return false, errors.Wrapf(err, "error checking existence of %v", id)
+	}
+}
+
 // ListAllBlobs returns Metadata for all blobs in a given storage that have the provided name prefix.
 func ListAllBlobs(ctx context.Context, st Storage, prefix ID) ([]Metadata, error) {
 	var result []Metadata
@@ -253,3 +280,27 @@ func DeleteMultiple(ctx context.Context, st Storage, ids []ID, parallelism int)
 
 	return errors.Wrap(eg.Wait(), "error deleting blobs")
 }
+
+// SupportsBatchDelete returns true if the storage natively deletes multiple blobs in a single operation,
+// in which case callers should delete blobs in batches.
+func SupportsBatchDelete(st Storage) bool {
+	bd, ok := st.(BatchDeleter)
+
+	return ok && bd.SupportsBatchDelete()
+}
+
+// DeleteBlobs deletes the provided blobs using a single batch operation when the storage implements
+// BatchDeleter, or one by one otherwise. It returns one error (nil on success) for each provided ID.
+func DeleteBlobs(ctx context.Context, st Storage, ids []ID) []error {
+	if bd, ok := st.(BatchDeleter); ok {
+		return bd.DeleteBlobs(ctx, ids)
+	}
+
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		errs[i] = st.DeleteBlob(ctx, id)
+	}
+
+	return errs
+}
@@ -12,6 +12,8 @@
 	"github.com/kopia/kopia/internal/blobtesting"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/logging"
+	"github.com/kopia/kopia/repo/blob/retrying"
 )
 
 func TestListAllBlobs(t *testing.T) {
@@ -205,6 +207,94 @@ func TestDeleteMultiple(t *testing.T) {
 	}, data)
 }
 
+// batchDeletingStorage implements BatchDeleter by recording batches and deleting from the underlying storage.
+type batchDeletingStorage struct {
+	blob.Storage
+
+	batches [][]blob.ID
+}
+
+func (s *batchDeletingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	s.batches = append(s.batches, ids)
+
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		if id == "bad" {
+			errs[i] = errors.New("some error")
+			continue
+		}
+
+		errs[i] = s.Storage.DeleteBlob(ctx, id)
+	}
+
+	return errs
+}
+
+func (s *batchDeletingStorage) SupportsBatchDelete() bool {
+	return true
+}
+
+func (s *batchDeletingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	return errors.New("unexpected call to DeleteBlob")
+}
+
+func TestSupportsBatchDelete(t *testing.T) {
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	bs := &batchDeletingStorage{Storage: st}
+
+	require.False(t, blob.SupportsBatchDelete(st))
+	require.True(t, blob.SupportsBatchDelete(bs))
+
+	// wrappers forward batches, but only support batch deletion if the wrapped storage does.
+	require.False(t, blob.SupportsBatchDelete(retrying.NewWrapper(st)))
+	require.True(t, blob.SupportsBatchDelete(retrying.NewWrapper(bs)))
+	require.False(t, blob.SupportsBatchDelete(logging.NewWrapper(retrying.NewWrapper(st), nil, "")))
+	require.True(t, blob.SupportsBatchDelete(logging.NewWrapper(retrying.NewWrapper(bs), nil, "")))
+}
+
+func TestDeleteBlobs(t *testing.T) {
+	ctx := context.Background()
+	someErr := errors.New("some error")
+
+	data := blobtesting.DataMap{
+		"foo": []byte{1, 2, 3},
+		"bar": []byte{1, 2, 4},
+		"baz": []byte{1, 2, 5},
+		"qux": []byte{1, 2, 6},
+	}
+
+	// storage without batch support falls back to deleting blobs one by one.
+	fs := &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(data, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"DeleteBlob": {{}, {Err: someErr}},
+		},
+	}
+
+	errs := blob.DeleteBlobs(ctx, fs, []blob.ID{"bar", "baz", "qux"})
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], someErr)
+	require.NoError(t, errs[2])
+
+	require.Equal(t, blobtesting.DataMap{
+		"foo": []byte{1, 2, 3},
+		"baz": []byte{1, 2, 5},
+	}, data)
+
+	// storage with batch support deletes all blobs in a single call.
+	bs := &batchDeletingStorage{Storage: blobtesting.NewMapStorage(data, nil, nil)}
+
+	errs = blob.DeleteBlobs(ctx, bs, []blob.ID{"foo", "bad", "baz"})
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	require.Error(t, errs[1])
+	require.NoError(t, errs[2])
+	require.Equal(t, [][]blob.ID{{"foo", "bad", "baz"}}, bs.batches)
+	require.Empty(t, data)
+}
+
 func TestMetataJSONString(t *testing.T) {
 	bm := blob.Metadata{
 		BlobID:    "foo",
@@ -214,3 +304,45 @@ func TestMetataJSONString(t *testing.T) {
 
 	require.Equal(t, `{"id":"foo","length":12345,"timestamp":"2000-01-02T03:04:05.000000006Z"}`, bm.String())
 }
+
+// getBlobCountingStorage counts the number of GetBlob() calls.
+type getBlobCountingStorage struct {
+	blob.Storage
+
+	getBlobCount int
+}
+
+func (s *getBlobCountingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64) ([]byte, error) {
+	s.getBlobCount++
+
+	return s.Storage.GetBlob(ctx, id, offset, length)
+}
+
+func TestExists(t *testing.T) {
+	ctx := context.Background()
+	data := blobtesting.DataMap{}
+	st := &getBlobCountingStorage{Storage: blobtesting.NewMapStorage(data, nil, nil)}
+
+	require.NoError(t, st.PutBlob(ctx, "foo", gather.FromSlice([]byte{1, 2, 3})))
+
+	exists, err := blob.Exists(ctx, st, "foo")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	exists, err = blob.Exists(ctx, st, "bar")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	someErr := errors.Errorf("some error")
+	fs := &blobtesting.FaultyStorage{
+		Base: st,
+		Faults: map[string][]*blobtesting.Fault{
+			"GetMetadata": {{Err: someErr}},
+		},
+	}
+
+	_, err = blob.Exists(ctx, fs, "foo")
+	require.ErrorIs(t, err, someErr)
+
+	require.Zero(t, st.getBlobCount, "existence checks must not fetch blob contents")
+}
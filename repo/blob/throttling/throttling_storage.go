@@ -0,0 +1,140 @@
+// Package throttling implements wrapper around blob.Storage that limits the rate of operations
+// and transferred bytes across all concurrent callers.
+package throttling
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/kopia/kopia/internal/ratelimit"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// Limits specifies the maximum rates of storage operations, zero values mean unlimited.
+type Limits struct {
+	ReadOpsPerSecond    float64 // GetBlob and GetMetadata
+	WriteOpsPerSecond   float64 // PutBlob, SetTime and DeleteBlob
+	ListOpsPerSecond    float64 // ListBlobs
+	ReadBytesPerSecond  float64
+	WriteBytesPerSecond float64
+}
+
+// throttlingStorage limits the rate of operations of the underlying storage.
+type throttlingStorage struct {
+	blob.Storage
+
+	readOps    *ratelimit.Limiter
+	writeOps   *ratelimit.Limiter
+	listOps    *ratelimit.Limiter
+	readBytes  *ratelimit.Limiter
+	writeBytes *ratelimit.Limiter
+}
+
+func (s *throttlingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64) ([]byte, error) {
+	if err := s.readOps.Wait(ctx); err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	v, err := s.Storage.GetBlob(ctx, id, offset, length)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	// the number of bytes is only known after the read, the next caller pays for it.
+	if err := s.readBytes.WaitN(ctx, len(v)); err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	return v, nil
+}
+
+func (s *throttlingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	if err := s.readOps.Wait(ctx); err != nil {
+		return blob.Metadata{}, err // nolint:wrapcheck
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.GetMetadata(ctx, id)
+}
+
+func (s *throttlingStorage) SetTime(ctx context.Context, id blob.ID, t time.Time) error {
+	if err := s.writeOps.Wait(ctx); err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.SetTime(ctx, id, t)
+}
+
+func (s *throttlingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes) error {
+	if err := s.writeOps.Wait(ctx); err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	if err := s.writeBytes.WaitN(ctx, data.Length()); err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.PutBlob(ctx, id, data)
+}
+
+func (s *throttlingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	if err := s.writeOps.Wait(ctx); err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.DeleteBlob(ctx, id)
+}
+
+// DeleteBlobs implements blob.BatchDeleter and counts each deleted blob as a write operation.
+func (s *throttlingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	if err := s.writeOps.WaitN(ctx, len(ids)); err != nil {
+		errs := make([]error, len(ids))
+		for i := range errs {
+			errs[i] = err
+		}
+
+		return errs
+	}
+
+	return blob.DeleteBlobs(ctx, s.Storage, ids)
+}
+
+// SupportsBatchDelete implements blob.BatchDeleter.
+func (s *throttlingStorage) SupportsBatchDelete() bool {
+	return blob.SupportsBatchDelete(s.Storage)
+}
+
+func (s *throttlingStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	if err := s.listOps.Wait(ctx); err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	// nolint:wrapcheck
+	return s.Storage.ListBlobs(ctx, prefix, callback)
+}
+
+// newLimiter returns a limiter for the provided rate which allows bursts of up to one second worth of tokens.
+func newLimiter(mode ratelimit.Mode, ratePerSecond float64) *ratelimit.Limiter {
+	if ratePerSecond <= 0 {
+		return nil
+	}
+
+	return ratelimit.NewLimiter(mode, ratePerSecond, int(math.Ceil(ratePerSecond)))
+}
+
+// NewWrapper returns a Storage wrapper that limits the rate of operations of the underlying storage.
+// The limits are shared by all callers of the returned storage.
+func NewWrapper(wrapped blob.Storage, limits Limits) blob.Storage {
+	return &throttlingStorage{
+		Storage:    wrapped,
+		readOps:    newLimiter(ratelimit.Ops, limits.ReadOpsPerSecond),
+		writeOps:   newLimiter(ratelimit.Ops, limits.WriteOpsPerSecond),
+		listOps:    newLimiter(ratelimit.Ops, limits.ListOpsPerSecond),
+		readBytes:  newLimiter(ratelimit.Bytes, limits.ReadBytesPerSecond),
+		writeBytes: newLimiter(ratelimit.Bytes, limits.WriteBytesPerSecond),
+	}
+}
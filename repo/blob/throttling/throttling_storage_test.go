@@ -0,0 +1,123 @@
+package throttling_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/throttling"
+)
+
+func TestThrottlingSpreadsConcurrentReads(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.NoError(t, ms.PutBlob(ctx, "a1", gather.FromSlice([]byte{1, 2, 3})))
+
+	st := throttling.NewWrapper(ms, throttling.Limits{ReadOpsPerSecond: 20})
+
+	// 20 reads are allowed immediately, the remaining 10 must wait for 0.5s worth of tokens.
+	const numReads = 30
+
+	var wg sync.WaitGroup
+
+	t0 := time.Now()
+
+	for i := 0; i < numReads; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := st.GetBlob(ctx, "a1", 0, -1)
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	require.GreaterOrEqual(t, time.Since(t0), 450*time.Millisecond)
+
+	// writes and lists are not affected by read limits.
+	t0 = time.Now()
+
+	require.NoError(t, st.PutBlob(ctx, "a2", gather.FromSlice([]byte{4})))
+	blobtesting.AssertListResults(ctx, t, st, "a", "a1", "a2")
+
+	require.Less(t, time.Since(t0), 400*time.Millisecond)
+}
+
+func TestThrottlingWriteBytes(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	st := throttling.NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil), throttling.Limits{
+		WriteBytesPerSecond: 1000,
+	})
+
+	t0 := time.Now()
+
+	// first 1000 bytes are within burst, the next 500 must wait.
+	require.NoError(t, st.PutBlob(ctx, "a1", gather.FromSlice(make([]byte, 1000))))
+	require.NoError(t, st.PutBlob(ctx, "a2", gather.FromSlice(make([]byte, 500))))
+
+	require.GreaterOrEqual(t, time.Since(t0), 450*time.Millisecond)
+}
+
+func TestThrottlingCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(testlogging.Context(t))
+
+	st := throttling.NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil), throttling.Limits{
+		ListOpsPerSecond: 0.001,
+	})
+
+	// the first list consumes the only token.
+	blobtesting.AssertListResults(ctx, t, st, "")
+
+	cancel()
+
+	require.ErrorIs(t, st.ListBlobs(ctx, "", func(blob.Metadata) error { return nil }), context.Canceled)
+}
+
+func TestThrottlingDeleteBlobsBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	// 20 deletes are allowed immediately, the remaining 10 must wait for 0.5s worth of tokens.
+	const numBlobs = 30
+
+	var ids []blob.ID
+
+	for i := 0; i < numBlobs; i++ {
+		id := blob.ID(fmt.Sprintf("a%v", i))
+		require.NoError(t, ms.PutBlob(ctx, id, gather.FromSlice([]byte{1})))
+
+		ids = append(ids, id)
+	}
+
+	base := &blobtesting.BatchDeletingStorage{Storage: ms}
+	st := throttling.NewWrapper(base, throttling.Limits{WriteOpsPerSecond: 20})
+
+	t0 := time.Now()
+
+	require.Equal(t, make([]error, numBlobs), blob.DeleteBlobs(ctx, st, ids))
+	require.GreaterOrEqual(t, time.Since(t0), 450*time.Millisecond)
+	require.Equal(t, []int{numBlobs}, base.BatchSizes())
+	blobtesting.AssertListResults(ctx, t, st, "")
+}
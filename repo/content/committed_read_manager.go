@@ -16,6 +16,7 @@
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/epoch"
 	"github.com/kopia/kopia/internal/listcache"
+	"github.com/kopia/kopia/internal/metadatacache"
 	"github.com/kopia/kopia/internal/ownwrites"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/filesystem"
@@ -30,6 +31,19 @@
 
 const ownWritesCacheDuration = 15 * time.Minute
 
+// blobMetadataCacheDuration is the amount of time for which results of GetMetadata() on pack and index blobs are cached in memory.
+const blobMetadataCacheDuration = 30 * time.Second
+
+// blobMetadataCachePrefixes are prefixes of blobs that are never rewritten in place and whose metadata can be cached.
+// Other blobs, such as format, maintenance or session blobs may be modified by other clients at any time.
+var blobMetadataCachePrefixes = append([]blob.ID{
+	IndexBlobPrefix,
+	epoch.EpochMarkerIndexBlobPrefix,
+	epoch.UncompactedIndexBlobPrefix,
+	epoch.SingleEpochCompactionBlobPrefix,
+	epoch.RangeCheckpointIndexBlobPrefix,
+}, PackBlobIDPrefixes...)
+
 var cachedIndexBlobPrefixes = []blob.ID{
 	IndexBlobPrefix,
 	compactionLogBlobPrefix,
@@ -63,6 +77,8 @@ type SharedManager struct {
 
 	contentCache      contentCache
 	metadataCache     contentCache
+	blobMetadataCache metadatacache.Invalidator // nil when blob metadata is not cached
+	blobListCache     listcache.Invalidator     // nil when blob lists are not cached
 	committedContents *committedContentIndex
 	crypter           *Crypter
 	enc               *encryptedBlobMgr
@@ -303,7 +319,13 @@ func newListCache(ctx context.Context, st blob.Storage, caching *CachingOptions)
 		return nil, errors.Wrap(err, "unable to get list cache backing storage")
 	}
 
-	return listcache.NewWrapper(st, cacheSt, cachedIndexBlobPrefixes, caching.HMACSecret, time.Duration(caching.MaxListCacheDurationSec)*time.Second), nil
+	return listcache.NewWrapper(st, cacheSt, listcache.Options{
+		Prefixes:                cachedIndexBlobPrefixes,
+		HMACSecret:              caching.HMACSecret,
+		CacheDuration:           time.Duration(caching.MaxListCacheDurationSec) * time.Second,
+		BatchSize:               listcache.DefaultBatchSize,
+		MaxCachedBlobsPerPrefix: listcache.DefaultMaxCachedBlobsPerPrefix,
+	}), nil
 }
 
 func newCacheBackingStorage(ctx context.Context, caching *CachingOptions, subdir string) (blob.Storage, error) {
@@ -357,11 +379,16 @@ func (sm *SharedManager) setupReadManagerCaches(ctx context.Context, caching *Ca
 		return errors.Wrap(err, "unable to initialize own writes cache")
 	}
 
-	cachedSt, err := newListCache(ctx, ownWritesCachingSt, caching)
+	listCachingSt, err := newListCache(ctx, ownWritesCachingSt, caching)
 	if err != nil {
 		return errors.Wrap(err, "unable to initialize list cache")
 	}
 
+	sm.blobListCache, _ = listCachingSt.(listcache.Invalidator)
+
+	cachedSt := metadatacache.NewWrapper(listCachingSt, blobMetadataCacheDuration, blobMetadataCachePrefixes)
+	sm.blobMetadataCache, _ = cachedSt.(metadatacache.Invalidator)
+
 	sm.enc = &encryptedBlobMgr{
 		st:             cachedSt,
 		crypter:        sm.crypter,
@@ -407,6 +434,18 @@ func (sm *SharedManager) setupReadManagerCaches(ctx context.Context, caching *Ca
 	return nil
 }
 
+// InvalidateBlobMetadata discards cached metadata and cached lists of blobs with the provided prefix.
+// It must be called after blobs have been written to the underlying storage directly, bypassing the content manager.
+func (sm *SharedManager) InvalidateBlobMetadata(ctx context.Context, prefix blob.ID) {
+	if sm.blobListCache != nil {
+		sm.blobListCache.InvalidateLists(ctx, prefix)
+	}
+
+	if sm.blobMetadataCache != nil {
+		sm.blobMetadataCache.InvalidatePrefix(prefix)
+	}
+}
+
 // EpochManager returns the epoch manager.
 func (sm *SharedManager) EpochManager() (*epoch.Manager, bool) {
 	ibm1, ok := sm.indexBlobManager.(*indexBlobManagerV1)
@@ -11,4 +11,10 @@
 type contentCache interface {
 	close(ctx context.Context)
 	getContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, offset, length int64) ([]byte, error)
+
+	// getCachedContent returns the cached payload of a content or nil if it's not in the cache.
+	getCachedContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, offset, length int64) []byte
+
+	// putContent adds the payload of a content which was read from the storage by the caller.
+	putContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, payload []byte)
 }
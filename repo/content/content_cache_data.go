@@ -34,6 +34,14 @@ func (c *contentCacheForData) getContent(ctx context.Context, cacheKey cacheKey,
 	})
 }
 
+func (c *contentCacheForData) getCachedContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, offset, length int64) []byte {
+	return c.pc.Get(ctx, string(adjustCacheKey(cacheKey)), 0, -1)
+}
+
+func (c *contentCacheForData) putContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, payload []byte) {
+	c.pc.Put(ctx, string(adjustCacheKey(cacheKey)), payload)
+}
+
 func (c *contentCacheForData) close(ctx context.Context) {
 	c.pc.Close(ctx)
 }
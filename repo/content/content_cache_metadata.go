@@ -106,6 +106,14 @@ func (c *contentCacheForMetadata) getContent(ctx context.Context, cacheKey cache
 	return blobData[offset : offset+length], nil
 }
 
+func (c *contentCacheForMetadata) getCachedContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, offset, length int64) []byte {
+	return c.pc.Get(ctx, string(blobID), offset, length)
+}
+
+func (c *contentCacheForMetadata) putContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, payload []byte) {
+	// metadata cache only stores entire blobs, which are loaded by getContent().
+}
+
 func (c *contentCacheForMetadata) close(ctx context.Context) {
 	c.pc.Close(ctx)
 }
@@ -17,3 +17,10 @@ func (c passthroughContentCache) getContent(ctx context.Context, cacheKey cacheK
 	// nolint:wrapcheck
 	return c.st.GetBlob(ctx, blobID, offset, length)
 }
+
+func (c passthroughContentCache) getCachedContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, offset, length int64) []byte {
+	return nil
+}
+
+func (c passthroughContentCache) putContent(ctx context.Context, cacheKey cacheKey, blobID blob.ID, payload []byte) {
+}
@@ -0,0 +1,165 @@
+package content
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// maxReadContentsBatchedSpan is the maximum length of a range of a pack read with a single request by ReadContentsBatched.
+const maxReadContentsBatchedSpan = 8 << 20
+
+// ReadContentsBatchedCallback is invoked by ReadContentsBatched for each content as it is decoded.
+type ReadContentsBatchedCallback func(id ID, data []byte) error
+
+// ReadContentsBatched reads the provided contents and invokes the callback for each of them.
+// Contents are grouped by the pack blob they are stored in and each pack is read only once,
+// which makes it much more efficient than calling GetContent() for large number of contents.
+// Contents are yielded in pack order, not in the order in which they were requested,
+// and each unique content is yielded exactly once.
+func (bm *WriteManager) ReadContentsBatched(ctx context.Context, ids []ID, cb ReadContentsBatchedCallback) error {
+	byPack := map[blob.ID][]Info{}
+	seen := map[ID]bool{}
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		pp, bi, err := bm.getContentInfo(id)
+		if err != nil {
+			return errors.Wrapf(err, "error getting content info for %v", id)
+		}
+
+		if pp != nil && pp.packBlobID == bi.GetPackBlobID() {
+			// content has not been written to storage yet, read it directly from memory.
+			data, err := bm.getContentDataUnlocked(ctx, pp, bi)
+			if err != nil {
+				return errors.Wrapf(err, "error reading pending content %v", id)
+			}
+
+			if err := cb(id, data); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		byPack[bi.GetPackBlobID()] = append(byPack[bi.GetPackBlobID()], bi)
+	}
+
+	packIDs := make([]blob.ID, 0, len(byPack))
+	for packID := range byPack {
+		packIDs = append(packIDs, packID)
+	}
+
+	sort.Slice(packIDs, func(i, j int) bool {
+		return packIDs[i] < packIDs[j]
+	})
+
+	for _, packID := range packIDs {
+		if err := bm.readContentsFromPack(ctx, packID, byPack[packID], maxReadContentsBatchedSpan, cb); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readContentsFromPack reads the provided contents of a single pack in the order of their offsets.
+// Contents found in the cache are served from it, remaining ones are read using as few requests as possible,
+// each covering a range of the pack no longer than maxSpan, and are added to the cache.
+func (bm *WriteManager) readContentsFromPack(ctx context.Context, packID blob.ID, infos []Info, maxSpan int64, cb ReadContentsBatchedCallback) error {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].GetPackOffset() < infos[j].GetPackOffset()
+	})
+
+	cc := bm.getCacheForContentID(infos[0].GetContentID())
+
+	var span []Info
+
+	for _, bi := range infos {
+		if payload := cc.getCachedContent(ctx, cacheKey(bi.GetContentID()), packID, int64(bi.GetPackOffset()), int64(bi.GetPackedLength())); payload != nil {
+			if err := bm.readContentsSpan(ctx, cc, packID, span, cb); err != nil {
+				return err
+			}
+
+			span = nil
+
+			if err := bm.decodeBatchedContent(payload, bi, cb); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if len(span) > 0 && int64(bi.GetPackOffset())+int64(bi.GetPackedLength())-int64(span[0].GetPackOffset()) > maxSpan {
+			if err := bm.readContentsSpan(ctx, cc, packID, span, cb); err != nil {
+				return err
+			}
+
+			span = nil
+		}
+
+		span = append(span, bi)
+	}
+
+	return bm.readContentsSpan(ctx, cc, packID, span, cb)
+}
+
+// readContentsSpan reads the smallest range of the pack that covers all provided contents
+// with a single request, adds them to the cache and decodes them.
+func (bm *WriteManager) readContentsSpan(ctx context.Context, cc contentCache, packID blob.ID, infos []Info, cb ReadContentsBatchedCallback) error {
+	if len(infos) == 0 {
+		return nil
+	}
+
+	start := int64(infos[0].GetPackOffset())
+	end := start
+
+	for _, bi := range infos {
+		if e := int64(bi.GetPackOffset()) + int64(bi.GetPackedLength()); e > end {
+			end = e
+		}
+	}
+
+	payload, err := bm.st.GetBlob(ctx, packID, start, end-start)
+	if err != nil {
+		return errors.Wrapf(err, "error reading pack %v", packID)
+	}
+
+	payload, err = blob.EnsureLengthExactly(payload, end-start)
+	if err != nil {
+		return errors.Wrapf(err, "invalid pack %v", packID)
+	}
+
+	for _, bi := range infos {
+		off := int64(bi.GetPackOffset()) - start
+		end := off + int64(bi.GetPackedLength())
+
+		// limit the capacity, so that appending to the content payload can't overwrite the following ones.
+		contentPayload := payload[off:end:end]
+
+		if err := bm.decodeBatchedContent(contentPayload, bi, cb); err != nil {
+			return err
+		}
+
+		cc.putContent(ctx, cacheKey(bi.GetContentID()), packID, contentPayload)
+	}
+
+	return nil
+}
+
+func (bm *WriteManager) decodeBatchedContent(payload []byte, bi Info, cb ReadContentsBatchedCallback) error {
+	data, err := bm.decryptContentAndVerify(payload, bi)
+	if err != nil {
+		return errors.Wrapf(err, "error decoding content %v", bi.GetContentID())
+	}
+
+	return cb(bi.GetContentID(), data)
+}
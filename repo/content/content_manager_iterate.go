@@ -3,6 +3,7 @@
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,6 +18,15 @@ type IterateOptions struct {
 	Range          IDRange
 	IncludeDeleted bool
 	Parallel       int
+
+	// Sorted causes contents to be delivered in ascending content ID order, each ID at most once.
+	// This requires sorting uncommitted contents in memory and disables parallel callbacks,
+	// so it is slower than unordered iteration.
+	Sorted bool
+
+	// After causes iteration to only deliver contents with IDs greater than the provided one,
+	// which allows resuming a previous iteration. Implies Sorted.
+	After ID
 }
 
 // IterateCallback is the function type used as a callback during content iteration.
@@ -111,6 +121,20 @@ func (bm *WriteManager) IterateContents(ctx context.Context, opts IterateOptions
 		opts.Range = AllIDs
 	}
 
+	if opts.After != "" {
+		// no valid content ID contains zero bytes, so this is the smallest ID greater than After.
+		if start := opts.After + "\x00"; start > opts.Range.StartID {
+			opts.Range.StartID = start
+		}
+
+		opts.Sorted = true
+	}
+
+	if opts.Sorted {
+		// parallel callbacks would not preserve the order.
+		opts.Parallel = 0
+	}
+
 	callback, cleanup := maybeParallelExecutor(opts.Parallel, callback)
 	defer cleanup() //nolint:errcheck
 
@@ -139,6 +163,10 @@ func (bm *WriteManager) IterateContents(ctx context.Context, opts IterateOptions
 		invokeCallback = callback
 	}
 
+	if opts.Sorted {
+		return listSortedContents(bm.committedContents, uncommitted, opts.Range, invokeCallback)
+	}
+
 	for _, bi := range uncommitted {
 		_ = invokeCallback(bi)
 	}
@@ -150,6 +178,88 @@ func (bm *WriteManager) IterateContents(ctx context.Context, opts IterateOptions
 	return cleanup()
 }
 
+// listSortedContents merges sorted uncommitted contents with committed contents, which are
+// already iterated in ascending order, uncommitted contents take precedence over committed ones.
+func listSortedContents(committed *committedContentIndex, uncommitted packIndexBuilder, r IDRange, cb IterateCallback) error {
+	var pending []Info
+
+	for _, i := range uncommitted {
+		if r.Contains(i.GetContentID()) {
+			pending = append(pending, i)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].GetContentID() < pending[j].GetContentID()
+	})
+
+	if err := committed.listContents(r, func(i Info) error {
+		for len(pending) > 0 && pending[0].GetContentID() <= i.GetContentID() {
+			next := pending[0]
+			pending = pending[1:]
+
+			if err := cb(next); err != nil {
+				return err
+			}
+
+			if next.GetContentID() == i.GetContentID() {
+				return nil
+			}
+		}
+
+		return cb(i)
+	}); err != nil {
+		return err
+	}
+
+	for _, i := range pending {
+		if err := cb(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// errBatchLimitReached is used to stop iteration once a batch has been delivered.
+var errBatchLimitReached = errors.New("batch limit reached")
+
+// IterateContentsBatch delivers up to the provided number of contents in ascending content ID order,
+// starting after opts.After. It returns the cursor that should be passed as IterateOptions.After to
+// resume iteration, or an empty ID when there are no more contents.
+func IterateContentsBatch(ctx context.Context, r Reader, opts IterateOptions, limit int, callback IterateCallback) (ID, error) {
+	var (
+		count int
+		last  ID
+	)
+
+	opts.Sorted = true
+
+	err := r.IterateContents(ctx, opts, func(ci Info) error {
+		if err := callback(ci); err != nil {
+			return err
+		}
+
+		count++
+		last = ci.GetContentID()
+
+		if limit > 0 && count >= limit {
+			return errBatchLimitReached
+		}
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBatchLimitReached):
+		return last, nil
+	case err != nil:
+		return "", err
+	default:
+		return "", nil
+	}
+}
+
 // IteratePackOptions are the options used to iterate over packs.
 type IteratePackOptions struct {
 	IncludePacksWithOnlyDeletedContent bool
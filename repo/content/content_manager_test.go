@@ -23,6 +23,7 @@
 	"github.com/kopia/kopia/internal/blobtesting"
 	"github.com/kopia/kopia/internal/epoch"
 	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/ownwrites"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/internal/testutil"
@@ -1601,6 +1602,20 @@ func (s *contentManagerSuite) TestIterateContents(t *testing.T) {
 				contentID3: true,
 			},
 		},
+		{
+			desc:    "sorted",
+			options: IterateOptions{Sorted: true},
+			want:    map[ID]bool{contentID1: true, contentID3: true},
+		},
+		{
+			desc:    "sorted, include deleted",
+			options: IterateOptions{Sorted: true, IncludeDeleted: true, Parallel: 10},
+			want: map[ID]bool{
+				contentID1: true,
+				contentID2: true,
+				contentID3: true,
+			},
+		},
 		{
 			desc: "prefix match",
 			options: IterateOptions{
@@ -1652,6 +1667,125 @@ func (s *contentManagerSuite) TestIterateContents(t *testing.T) {
 	}
 }
 
+func (s *contentManagerSuite) TestIterateContentsSorted(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	st := blobtesting.NewMapStorage(data, nil, nil)
+	bm := s.newTestContentManager(t, st)
+
+	var (
+		all     []ID
+		deleted ID
+	)
+
+	// spread contents across several committed indexes and pending packs.
+	for i := 0; i < 30; i++ {
+		all = append(all, writeContentAndVerify(ctx, t, bm, seededRandomData(i, 100)))
+
+		if i%10 == 9 {
+			require.NoError(t, bm.Flush(ctx))
+		}
+	}
+
+	for i := 30; i < 40; i++ {
+		all = append(all, writeContentAndVerify(ctx, t, bm, seededRandomData(i, 100)))
+	}
+
+	// committed content that has an uncommitted deletion is delivered once.
+	deleted = all[5]
+	require.NoError(t, bm.DeleteContent(ctx, deleted))
+
+	for _, includeDeleted := range []bool{false, true} {
+		var got []ID
+
+		require.NoError(t, bm.IterateContents(ctx, IterateOptions{Sorted: true, IncludeDeleted: includeDeleted}, func(ci Info) error {
+			if n := len(got); n > 0 && got[n-1] >= ci.GetContentID() {
+				t.Fatalf("content IDs out of order: %v after %v", ci.GetContentID(), got[n-1])
+			}
+
+			if ci.GetContentID() == deleted {
+				require.True(t, ci.GetDeleted())
+			}
+
+			got = append(got, ci.GetContentID())
+
+			return nil
+		}))
+
+		if includeDeleted {
+			require.Len(t, got, len(all))
+		} else {
+			require.Len(t, got, len(all)-1)
+			require.NotContains(t, got, deleted)
+		}
+	}
+}
+
+func (s *contentManagerSuite) TestIterateContentsBatch(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	st := blobtesting.NewMapStorage(data, nil, nil)
+	bm := s.newTestContentManager(t, st)
+
+	for i := 0; i < 40; i++ {
+		cid := writeContentAndVerify(ctx, t, bm, seededRandomData(i, 100))
+
+		if i%3 == 0 {
+			require.NoError(t, bm.DeleteContent(ctx, cid))
+		}
+
+		if i%15 == 14 {
+			require.NoError(t, bm.Flush(ctx))
+		}
+	}
+
+	for _, includeDeleted := range []bool{false, true} {
+		var full []ID
+
+		require.NoError(t, bm.IterateContents(ctx, IterateOptions{Sorted: true, IncludeDeleted: includeDeleted}, func(ci Info) error {
+			full = append(full, ci.GetContentID())
+			return nil
+		}))
+
+		for _, batchSize := range []int{1, 7, len(full), len(full) + 1} {
+			var (
+				got     []ID
+				cursor  ID
+				batches int
+			)
+
+			for {
+				batches++
+
+				next, err := IterateContentsBatch(ctx, bm, IterateOptions{After: cursor, IncludeDeleted: includeDeleted}, batchSize, func(ci Info) error {
+					got = append(got, ci.GetContentID())
+					return nil
+				})
+				require.NoError(t, err)
+
+				if next == "" {
+					break
+				}
+
+				require.Greater(t, next, cursor)
+				cursor = next
+			}
+
+			require.Equal(t, full, got, "batch size %v", batchSize)
+			require.LessOrEqual(t, batches, len(full)/batchSize+2)
+		}
+	}
+
+	// errors returned by the callback are passed through.
+	someErr := errors.New("some error")
+
+	next, err := IterateContentsBatch(ctx, bm, IterateOptions{}, 10, func(ci Info) error {
+		return someErr
+	})
+	require.ErrorIs(t, err, someErr)
+	require.Empty(t, next)
+}
+
 func (s *contentManagerSuite) TestFindUnreferencedBlobs(t *testing.T) {
 	ctx := testlogging.Context(t)
 	data := blobtesting.DataMap{}
@@ -1826,6 +1960,166 @@ func (s *contentManagerSuite) TestContentReadAliasing(t *testing.T) {
 	verifyContent(ctx, t, bm, id1, contentData)
 }
 
+func (s *contentManagerSuite) TestReadContentsBatched(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	keyTime := map[blob.ID]time.Time{}
+	st := &getBlobCountingStorage{Storage: blobtesting.NewMapStorage(data, keyTime, nil)}
+
+	bm := s.newTestContentManager(t, st)
+	defer bm.Close(ctx)
+
+	want := map[ID][]byte{}
+
+	var ids []ID
+
+	// write 3 packs with 10 contents each and a few pending contents.
+	for i := 0; i < 33; i++ {
+		b := seededRandomData(i, 50)
+
+		cid := writeContentAndVerify(ctx, t, bm, b)
+		want[cid] = b
+		ids = append(ids, cid)
+
+		if i%10 == 9 {
+			require.NoError(t, bm.Flush(ctx))
+		}
+	}
+
+	packs := map[blob.ID]bool{}
+
+	for _, cid := range ids {
+		if bi := getContentInfo(t, bm, cid); bi.GetPackBlobID() != "" {
+			packs[bi.GetPackBlobID()] = true
+		}
+	}
+
+	// shuffle the requested IDs so that contents from different packs are interleaved and include duplicates.
+	req := append([]ID{}, ids...)
+	rand.Shuffle(len(req), func(i, j int) { req[i], req[j] = req[j], req[i] })
+	req = append(req, ids[0], ids[15])
+
+	st.reset()
+
+	got := map[ID][]byte{}
+
+	require.NoError(t, bm.ReadContentsBatched(ctx, req, func(id ID, b []byte) error {
+		require.NotContains(t, got, id)
+		got[id] = b
+		return nil
+	}))
+
+	require.Equal(t, want, got)
+
+	counts := st.counts()
+	require.Len(t, counts, len(packs)-1, "pending pack must not be read from storage")
+
+	for packID, cnt := range counts {
+		require.True(t, packs[packID], "unexpected read of %v", packID)
+		require.Equal(t, 1, cnt, "pack %v read more than once", packID)
+	}
+
+	// missing content fails the whole batch.
+	err := bm.ReadContentsBatched(ctx, []ID{ids[0], ID(strings.Repeat("ab", 32))}, func(id ID, b []byte) error { return nil })
+	require.True(t, errors.Is(err, ErrContentNotFound), "unexpected error %v", err)
+
+	// callback errors are propagated.
+	errStop := errors.New("stop")
+	require.ErrorIs(t, bm.ReadContentsBatched(ctx, ids, func(id ID, b []byte) error { return errStop }), errStop)
+}
+
+func (s *contentManagerSuite) TestReadContentsBatchedUsesCache(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	keyTime := map[blob.ID]time.Time{}
+	st := &getBlobCountingStorage{Storage: blobtesting.NewMapStorage(data, keyTime, nil)}
+
+	bm := s.newTestContentManagerWithTweaks(t, st, &contentManagerTestTweaks{
+		CachingOptions: CachingOptions{
+			CacheDirectory:    t.TempDir(),
+			MaxCacheSizeBytes: 1e6,
+		},
+	})
+
+	var ids []ID
+
+	for i := 0; i < 10; i++ {
+		ids = append(ids, writeContentAndVerify(ctx, t, bm, seededRandomData(i, 50)))
+	}
+
+	require.NoError(t, bm.Flush(ctx))
+
+	st.reset()
+
+	// contents read in a batch are added to the cache.
+	require.NoError(t, bm.ReadContentsBatched(ctx, ids[0:5], func(id ID, b []byte) error { return nil }))
+	require.Len(t, st.counts(), 1)
+
+	st.reset()
+
+	for _, cid := range ids[0:5] {
+		_, err := bm.GetContent(ctx, cid)
+		require.NoError(t, err)
+	}
+
+	require.Empty(t, st.counts())
+
+	// only contents missing from the cache are read from the storage.
+	require.NoError(t, bm.ReadContentsBatched(ctx, ids, func(id ID, b []byte) error { return nil }))
+
+	for _, cnt := range st.counts() {
+		require.Equal(t, 1, cnt)
+	}
+
+	st.reset()
+
+	require.NoError(t, bm.ReadContentsBatched(ctx, ids, func(id ID, b []byte) error { return nil }))
+	require.Empty(t, st.counts())
+}
+
+func (s *contentManagerSuite) TestReadContentsBatchedMaxSpan(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	keyTime := map[blob.ID]time.Time{}
+	st := &getBlobCountingStorage{Storage: blobtesting.NewMapStorage(data, keyTime, nil)}
+
+	bm := s.newTestContentManager(t, st)
+
+	want := map[ID][]byte{}
+
+	var infos []Info
+
+	for i := 0; i < 10; i++ {
+		b := seededRandomData(i, 50)
+		cid := writeContentAndVerify(ctx, t, bm, b)
+		want[cid] = b
+	}
+
+	require.NoError(t, bm.Flush(ctx))
+
+	for cid := range want {
+		infos = append(infos, getContentInfo(t, bm, cid))
+	}
+
+	packID := infos[0].GetPackBlobID()
+	for _, bi := range infos {
+		require.Equal(t, packID, bi.GetPackBlobID())
+	}
+
+	st.reset()
+
+	got := map[ID][]byte{}
+
+	// each request covers at most two contents.
+	require.NoError(t, bm.readContentsFromPack(ctx, packID, infos, 2*int64(infos[0].GetPackedLength()), func(id ID, b []byte) error {
+		got[id] = b
+		return nil
+	}))
+
+	require.Equal(t, want, got)
+	require.Equal(t, map[blob.ID]int{packID: 5}, st.counts())
+}
+
 func (s *contentManagerSuite) TestVersionCompatibility(t *testing.T) {
 	for writeVer := minSupportedReadVersion; writeVer <= currentWriteVersion; writeVer++ {
 		writeVer := writeVer
@@ -2077,6 +2371,36 @@ func (s *contentManagerSuite) TestCompression_NonCompressibleData(t *testing.T)
 	verifyContent(ctx, t, bm2, cid, nonCompressibleData)
 }
 
+func (s *contentManagerSuite) TestInvalidateBlobMetadata(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	st := blobtesting.NewMapStorage(data, nil, nil)
+	bm := s.newTestContentManager(t, st)
+
+	defer bm.Close(ctx)
+
+	blobID := blob.ID(IndexBlobPrefix + "abc")
+
+	require.NoError(t, st.PutBlob(ctx, blobID, gather.FromSlice([]byte{1, 2, 3})))
+
+	md, err := bm.enc.st.GetMetadata(ctx, blobID)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, md.Length)
+
+	// blob written directly to the underlying storage is not observed until metadata is invalidated.
+	require.NoError(t, st.PutBlob(ctx, blobID, gather.FromSlice([]byte{1, 2, 3, 4, 5})))
+
+	md, err = bm.enc.st.GetMetadata(ctx, blobID)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, md.Length)
+
+	bm.InvalidateBlobMetadata(ctx, "")
+
+	md, err = bm.enc.st.GetMetadata(ctx, blobID)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, md.Length)
+}
+
 func (s *contentManagerSuite) newTestContentManager(t *testing.T, st blob.Storage) *WriteManager {
 	t.Helper()
 
@@ -2241,6 +2565,45 @@ func writeContentWithRetriesAndVerify(ctx context.Context, t *testing.T, bm *Wri
 	return contentID, retryCount
 }
 
+// getBlobCountingStorage counts GetBlob() calls for each blob.
+type getBlobCountingStorage struct {
+	blob.Storage
+
+	mu        sync.Mutex
+	getCounts map[blob.ID]int
+}
+
+func (s *getBlobCountingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64) ([]byte, error) {
+	s.mu.Lock()
+	if s.getCounts == nil {
+		s.getCounts = map[blob.ID]int{}
+	}
+	s.getCounts[id]++
+	s.mu.Unlock()
+
+	// nolint:wrapcheck
+	return s.Storage.GetBlob(ctx, id, offset, length)
+}
+
+func (s *getBlobCountingStorage) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.getCounts = nil
+}
+
+func (s *getBlobCountingStorage) counts() map[blob.ID]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := map[blob.ID]int{}
+	for k, v := range s.getCounts {
+		result[k] = v
+	}
+
+	return result
+}
+
 func seededRandomData(seed, length int) []byte {
 	b := make([]byte, length)
 	rnd := rand.New(rand.NewSource(int64(seed)))
@@ -4,6 +4,7 @@
 	"context"
 
 	"github.com/kopia/kopia/internal/epoch"
+	"github.com/kopia/kopia/repo/blob"
 )
 
 // Reader defines content read API.
@@ -11,9 +12,11 @@ type Reader interface {
 	SupportsContentCompression() bool
 	ContentFormat() FormattingOptions
 	GetContent(ctx context.Context, id ID) ([]byte, error)
+	ReadContentsBatched(ctx context.Context, ids []ID, cb ReadContentsBatchedCallback) error
 	ContentInfo(ctx context.Context, id ID) (Info, error)
 	IterateContents(ctx context.Context, opts IterateOptions, callback IterateCallback) error
 	IteratePacks(ctx context.Context, opts IteratePackOptions, callback IteratePacksCallback) error
+	IterateUnreferencedBlobs(ctx context.Context, blobPrefixes []blob.ID, parallellism int, callback func(blob.Metadata) error) error
 	ListActiveSessions(ctx context.Context) (map[SessionID]*SessionInfo, error)
 	EpochManager() (*epoch.Manager, bool)
 }
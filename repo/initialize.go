@@ -46,14 +46,13 @@ func Initialize(ctx context.Context, st blob.Storage, opt *NewRepositoryOptions,
 		opt = &NewRepositoryOptions{}
 	}
 
-	// get the blob - expect ErrNotFound
-	_, err := st.GetBlob(ctx, FormatBlobID, 0, -1)
-	if err == nil {
-		return ErrAlreadyInitialized
+	exists, err := blob.Exists(ctx, st, FormatBlobID)
+	if err != nil {
+		return errors.Wrap(err, "unexpected error when checking for format blob")
 	}
 
-	if !errors.Is(err, blob.ErrBlobNotFound) {
-		return errors.Wrap(err, "unexpected error when checking for format blob")
+	if exists {
+		return ErrAlreadyInitialized
 	}
 
 	format := formatBlobFromOptions(opt)
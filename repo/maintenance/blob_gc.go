@@ -21,13 +21,24 @@ type DeleteUnreferencedBlobsOptions struct {
 }
 
 // DeleteUnreferencedBlobs deletes old blobs that are no longer referenced by index entries.
-// nolint:gocyclo
 func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWriter, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters) (int, error) {
+	cnt, _, err := deleteUnreferencedBlobs(ctx, rep, opt, safety)
+
+	return cnt, err
+}
+
+// deleteUnreferencedBlobs deletes old blobs that are no longer referenced by index entries and returns
+// the number and total size of blobs deleted (or to be deleted in dry run mode).
+// nolint:gocyclo
+func deleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWriter, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters) (int, int64, error) {
 	if opt.Parallel == 0 {
 		opt.Parallel = 16
 	}
 
-	const deleteQueueSize = 100
+	const (
+		deleteQueueSize = 100
+		deleteBatchSize = 100
+	)
 
 	var unreferenced, deleted stats.CountSum
 
@@ -36,20 +47,33 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 	unused := make(chan blob.Metadata, deleteQueueSize)
 
 	if !opt.DryRun {
+		st := rep.BlobStorage()
+
+		// delete one blob at a time unless the storage natively deletes them in batches.
+		batchSize := 1
+		if blob.SupportsBatchDelete(st) {
+			batchSize = deleteBatchSize
+		}
+
 		// start goroutines to delete blobs as they come.
 		for i := 0; i < opt.Parallel; i++ {
 			eg.Go(func() error {
+				var batch []blob.Metadata
+
 				for bm := range unused {
-					if err := rep.BlobStorage().DeleteBlob(ctx, bm.BlobID); err != nil {
-						return errors.Wrapf(err, "unable to delete blob %q", bm.BlobID)
+					batch = append(batch, bm)
+					if len(batch) < batchSize {
+						continue
 					}
-					cnt, del := deleted.Add(bm.Length)
-					if cnt%100 == 0 {
-						log(ctx).Infof("  deleted %v unreferenced blobs (%v)", cnt, units.BytesStringBase10(del))
+
+					if err := deleteBlobBatch(ctx, st, batch, &deleted); err != nil {
+						return err
 					}
+
+					batch = nil
 				}
 
-				return nil
+				return deleteBlobBatch(ctx, st, batch, &deleted)
 			})
 		}
 	}
@@ -57,6 +81,42 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 	// iterate unreferenced blobs and count them + optionally send to the channel to be deleted
 	log(ctx).Infof("Looking for unreferenced blobs...")
 
+	if err := findUnreferencedBlobs(ctx, rep, opt, safety, func(bm blob.Metadata) error {
+		unreferenced.Add(bm.Length)
+
+		if !opt.DryRun {
+			unused <- bm
+		}
+
+		return nil
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	close(unused)
+
+	unreferencedCount, unreferencedSize := unreferenced.Approximate()
+	log(ctx).Debugf("Found %v blobs to delete (%v)", unreferencedCount, units.BytesStringBase10(unreferencedSize))
+
+	// wait for all delete workers to finish.
+	if err := eg.Wait(); err != nil {
+		return 0, 0, errors.Wrap(err, "worker error")
+	}
+
+	if opt.DryRun {
+		return int(unreferencedCount), unreferencedSize, nil
+	}
+
+	del, delBytes := deleted.Approximate()
+
+	log(ctx).Infof("Deleted total %v unreferenced blobs (%v)", del, units.BytesStringBase10(delBytes))
+
+	return int(del), delBytes, nil
+}
+
+// findUnreferencedBlobs invokes the callback for each blob that is no longer referenced by index entries
+// and is old enough to be deleted given the safety parameters.
+func findUnreferencedBlobs(ctx context.Context, rep repo.DirectRepository, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters, callback func(bm blob.Metadata) error) error {
 	var prefixes []blob.ID
 	if p := opt.Prefix; p != "" {
 		prefixes = append(prefixes, p)
@@ -64,14 +124,14 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 		prefixes = append(prefixes, content.PackBlobIDPrefixRegular, content.PackBlobIDPrefixSpecial, content.BlobIDPrefixSession)
 	}
 
-	activeSessions, err := rep.ContentManager().ListActiveSessions(ctx)
+	activeSessions, err := rep.ContentReader().ListActiveSessions(ctx)
 	if err != nil {
-		return 0, errors.Wrap(err, "unable to load active sessions")
+		return errors.Wrap(err, "unable to load active sessions")
 	}
 
 	// iterate all pack blobs + session blobs and keep ones that are too young or
 	// belong to alive sessions.
-	if err := rep.ContentManager().IterateUnreferencedBlobs(ctx, prefixes, opt.Parallel, func(bm blob.Metadata) error {
+	if err := rep.ContentReader().IterateUnreferencedBlobs(ctx, prefixes, opt.Parallel, func(bm blob.Metadata) error {
 		if age := rep.Time().Sub(bm.Timestamp); age < safety.BlobDeleteMinAge {
 			log(ctx).Debugf("  preserving %v because it's too new (age: %v<%v)", bm.BlobID, age, safety.BlobDeleteMinAge)
 			return nil
@@ -85,34 +145,36 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 			}
 		}
 
-		unreferenced.Add(bm.Length)
+		return callback(bm)
+	}); err != nil {
+		return errors.Wrap(err, "error looking for unreferenced blobs")
+	}
 
-		if !opt.DryRun {
-			unused <- bm
-		}
+	return nil
+}
 
+// deleteBlobBatch deletes the provided blobs, updates the counter of deleted blobs and returns the first error.
+func deleteBlobBatch(ctx context.Context, st blob.Storage, batch []blob.Metadata, deleted *stats.CountSum) error {
+	if len(batch) == 0 {
 		return nil
-	}); err != nil {
-		return 0, errors.Wrap(err, "error looking for unreferenced blobs")
 	}
 
-	close(unused)
+	var firstErr error
 
-	unreferencedCount, unreferencedSize := unreferenced.Approximate()
-	log(ctx).Debugf("Found %v blobs to delete (%v)", unreferencedCount, units.BytesStringBase10(unreferencedSize))
+	for i, err := range blob.DeleteBlobs(ctx, st, blob.IDsFromMetadata(batch)) {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "unable to delete blob %q", batch[i].BlobID)
+			}
 
-	// wait for all delete workers to finish.
-	if err := eg.Wait(); err != nil {
-		return 0, errors.Wrap(err, "worker error")
-	}
+			continue
+		}
 
-	if opt.DryRun {
-		return int(unreferencedCount), nil
+		cnt, del := deleted.Add(batch[i].Length)
+		if cnt%100 == 0 {
+			log(ctx).Infof("  deleted %v unreferenced blobs (%v)", cnt, units.BytesStringBase10(del))
+		}
 	}
 
-	del, cnt := deleted.Approximate()
-
-	log(ctx).Infof("Deleted total %v unreferenced blobs (%v)", del, units.BytesStringBase10(cnt))
-
-	return int(del), nil
+	return firstErr
 }
@@ -1,6 +1,7 @@
 package maintenance
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/json"
@@ -13,10 +14,12 @@
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/internal/blobtesting"
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/faketime"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/stats"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
@@ -160,6 +163,70 @@ func TestDeleteUnreferencedBlobs(t *testing.T) {
 	}
 }
 
+// batchDeletingStorage implements blob.BatchDeleter and records deleted batches.
+type batchDeletingStorage struct {
+	blob.Storage
+
+	batches [][]blob.ID
+}
+
+func (s *batchDeletingStorage) DeleteBlobs(ctx context.Context, ids []blob.ID) []error {
+	s.batches = append(s.batches, ids)
+
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		errs[i] = s.Storage.DeleteBlob(ctx, id)
+	}
+
+	return errs
+}
+
+func (s *batchDeletingStorage) SupportsBatchDelete() bool {
+	return true
+}
+
+func TestDeleteBlobBatch(t *testing.T) {
+	ctx := testlogging.Context(t)
+	someErr := errors.New("some error")
+
+	data := blobtesting.DataMap{"a": {1}, "b": {2, 3}, "c": {4, 5, 6}, "d": {7}}
+	batch := []blob.Metadata{{BlobID: "a", Length: 1}, {BlobID: "b", Length: 2}, {BlobID: "c", Length: 3}}
+
+	// per-blob fallback reports the first failure but counts all successful deletions.
+	var deleted stats.CountSum
+
+	fs := &blobtesting.FaultyStorage{
+		Base: blobtesting.NewMapStorage(data, nil, nil),
+		Faults: map[string][]*blobtesting.Fault{
+			"DeleteBlob": {{Err: someErr}},
+		},
+	}
+
+	require.ErrorIs(t, deleteBlobBatch(ctx, fs, batch, &deleted), someErr)
+
+	cnt, sum := deleted.Approximate()
+	require.Equal(t, uint32(2), cnt)
+	require.Equal(t, int64(5), sum)
+	require.Equal(t, blobtesting.DataMap{"a": {1}, "d": {7}}, data)
+
+	// batch deletion removes all blobs in a single call.
+	deleted = stats.CountSum{}
+	bs := &batchDeletingStorage{Storage: blobtesting.NewMapStorage(data, nil, nil)}
+
+	require.NoError(t, deleteBlobBatch(ctx, bs, []blob.Metadata{{BlobID: "a", Length: 1}, {BlobID: "d", Length: 1}}, &deleted))
+	require.Equal(t, [][]blob.ID{{"a", "d"}}, bs.batches)
+	require.Empty(t, data)
+
+	cnt, sum = deleted.Approximate()
+	require.Equal(t, uint32(2), cnt)
+	require.Equal(t, int64(2), sum)
+
+	// empty batch is a no-op.
+	require.NoError(t, deleteBlobBatch(ctx, bs, nil, &deleted))
+	require.Len(t, bs.batches, 1)
+}
+
 func verifyBlobExists(t *testing.T, st blob.Storage, blobID blob.ID) {
 	t.Helper()
 
@@ -0,0 +1,169 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+)
+
+// BlobRetention explains why a blob has not been deleted by maintenance yet.
+type BlobRetention struct {
+	BlobID    blob.ID       `json:"blobID"`
+	Length    int64         `json:"length"`
+	Timestamp time.Time     `json:"timestamp"`
+	Age       time.Duration `json:"age"`
+
+	LiveContentCount    int               `json:"liveContentCount"`
+	DeletedContentCount int               `json:"deletedContentCount"`
+	Contents            []RetainedContent `json:"contents,omitempty"`
+	Reasons             []string          `json:"reasons"`
+
+	// EarliestDeletion is the earliest time full maintenance could delete the blob,
+	// zero if it is referenced by contents that are still in use.
+	EarliestDeletion time.Time `json:"earliestDeletion"`
+}
+
+// RetainedContent describes a single content stored in a retained blob.
+type RetainedContent struct {
+	ContentID content.ID    `json:"contentID"`
+	Deleted   bool          `json:"deleted"`
+	Timestamp time.Time     `json:"timestamp"`
+	Age       time.Duration `json:"age"`
+
+	// SubjectToGC indicates that a live content is old enough to be deleted by snapshot GC once unreferenced.
+	SubjectToGC bool `json:"subjectToGC,omitempty"`
+
+	// DropTime is the earliest time a deleted content can be dropped from the index.
+	DropTime time.Time `json:"dropTime"`
+}
+
+// ExplainBlobRetention reports the contents referencing the provided blob and the safety thresholds
+// which prevent full maintenance with the provided safety parameters from deleting it.
+func ExplainBlobRetention(ctx context.Context, rep repo.DirectRepository, blobID blob.ID, safety SafetyParameters) (*BlobRetention, error) {
+	bm, err := rep.BlobReader().GetMetadata(ctx, blobID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get metadata of %v", blobID)
+	}
+
+	s, err := GetSchedule(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get schedule")
+	}
+
+	now := rep.Time()
+
+	r := &BlobRetention{
+		BlobID:    blobID,
+		Length:    bm.Length,
+		Timestamp: bm.Timestamp,
+		Age:       now.Sub(bm.Timestamp),
+	}
+
+	if err := rep.ContentReader().IteratePacks(ctx, content.IteratePackOptions{
+		IncludePacksWithOnlyDeletedContent: true,
+		IncludeContentInfos:                true,
+		Prefixes:                           []blob.ID{blobID},
+	}, func(pi content.PackInfo) error {
+		if pi.PackID != blobID {
+			return nil
+		}
+
+		for _, ci := range pi.ContentInfos {
+			r.Contents = append(r.Contents, RetainedContent{
+				ContentID: ci.GetContentID(),
+				Deleted:   ci.GetDeleted(),
+				Timestamp: ci.Timestamp(),
+				Age:       now.Sub(ci.Timestamp()),
+			})
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error iterating packs")
+	}
+
+	explainContentRetention(r, rep, s, safety)
+
+	if r.LiveContentCount > 0 {
+		return r, nil
+	}
+
+	r.EarliestDeletion = now
+
+	for _, rc := range r.Contents {
+		r.EarliestDeletion = laterOf(r.EarliestDeletion, rc.DropTime)
+	}
+
+	if t := bm.Timestamp.Add(safety.BlobDeleteMinAge); t.After(now) {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("blob is younger than the minimum age of %v required for deletion", safety.BlobDeleteMinAge))
+		r.EarliestDeletion = laterOf(r.EarliestDeletion, t)
+	}
+
+	if t := nextBlobDeleteTime(s, safety); t.After(now) {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("blob deletion is paused for %v after content rewrite", safety.MinRewriteToOrphanDeletionDelay))
+		r.EarliestDeletion = laterOf(r.EarliestDeletion, t)
+	}
+
+	if len(r.Reasons) == 0 {
+		r.Reasons = append(r.Reasons, "blob can be deleted by the next full maintenance")
+	}
+
+	return r, nil
+}
+
+// explainContentRetention computes the status of contents stored in the blob.
+func explainContentRetention(r *BlobRetention, rep repo.DirectRepository, s *Schedule, safety SafetyParameters) {
+	now := rep.Time()
+	safeDropTime := findSafeDropTimeForSchedule(rep, s, safety)
+
+	var notDroppable int
+
+	for i := range r.Contents {
+		rc := &r.Contents[i]
+
+		if !rc.Deleted {
+			r.LiveContentCount++
+			rc.SubjectToGC = rc.Age >= safety.MinContentAgeSubjectToGC
+
+			continue
+		}
+
+		r.DeletedContentCount++
+
+		if rc.Timestamp.Before(safeDropTime) {
+			rc.DropTime = now
+			continue
+		}
+
+		// the content can only be dropped once a snapshot GC that started after it was deleted
+		// (plus extra margin) is followed by another one at least MarginBetweenSnapshotGC later.
+		rc.DropTime = laterOf(now, rc.Timestamp.Add(safety.DropContentFromIndexExtraMargin+safety.MarginBetweenSnapshotGC))
+		notDroppable++
+	}
+
+	if r.LiveContentCount > 0 {
+		r.Reasons = append(r.Reasons, fmt.Sprintf(
+			"%v contents are still in use and must be deleted by snapshot GC (only contents older than %v are subject to it) or rewritten elsewhere",
+			r.LiveContentCount, safety.MinContentAgeSubjectToGC))
+	}
+
+	if notDroppable > 0 {
+		r.Reasons = append(r.Reasons, fmt.Sprintf(
+			"%v deleted contents can't be dropped from the index until two successful snapshot GC cycles at least %v apart complete after their deletion",
+			notDroppable, safety.MarginBetweenSnapshotGC))
+	}
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+
+	return a
+}
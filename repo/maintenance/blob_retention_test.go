@@ -0,0 +1,117 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+)
+
+func TestExplainBlobRetention(t *testing.T) {
+	// blob timestamps come from the filesystem, so the clock must start at current time.
+	// the clock advances on each call so that deletions get later timestamps than writes.
+	ft := faketime.NewTimeAdvance(clock.Now(), time.Second)
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.Options{
+		OpenOptions: func(o *repo.Options) {
+			o.TimeNowFunc = ft.NowFunc()
+		},
+	})
+
+	cm := env.RepositoryWriter.ContentManager()
+
+	// pack #1 has one live and one deleted content.
+	live, err := cm.WriteContent(ctx, []byte{1, 2, 3, 1}, "", content.NoCompression)
+	require.NoError(t, err)
+
+	deleted1, err := cm.WriteContent(ctx, []byte{1, 2, 3, 2}, "", content.NoCompression)
+	require.NoError(t, err)
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+	require.NoError(t, cm.DeleteContent(ctx, deleted1))
+
+	// pack #2 has a single deleted content.
+	deleted2, err := cm.WriteContent(ctx, []byte{1, 2, 3, 3}, "", content.NoCompression)
+	require.NoError(t, err)
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+	require.NoError(t, cm.DeleteContent(ctx, deleted2))
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	liveInfo, err := cm.ContentInfo(ctx, live)
+	require.NoError(t, err)
+
+	deleted2Info, err := cm.ContentInfo(ctx, deleted2)
+	require.NoError(t, err)
+
+	pack1, pack2 := liveInfo.GetPackBlobID(), deleted2Info.GetPackBlobID()
+	require.NotEqual(t, pack1, pack2)
+
+	// blob with live contents can't be deleted until they are.
+	r, err := ExplainBlobRetention(ctx, env.RepositoryWriter, pack1, SafetyFull)
+	require.NoError(t, err)
+	require.Equal(t, 1, r.LiveContentCount)
+	require.Equal(t, 1, r.DeletedContentCount)
+	require.Len(t, r.Contents, 2)
+	require.True(t, r.EarliestDeletion.IsZero())
+	require.Contains(t, r.Reasons[0], "1 contents are still in use")
+
+	for _, rc := range r.Contents {
+		if rc.ContentID == live {
+			require.False(t, rc.Deleted)
+			require.False(t, rc.SubjectToGC)
+		}
+	}
+
+	// without any snapshot GC, the deleted content must wait for two GC cycles.
+	r, err = ExplainBlobRetention(ctx, env.RepositoryWriter, pack2, SafetyFull)
+	require.NoError(t, err)
+	require.Equal(t, 0, r.LiveContentCount)
+	require.Equal(t, 1, r.DeletedContentCount)
+	require.Len(t, r.Reasons, 2)
+	require.Contains(t, r.Reasons[0], "two successful snapshot GC cycles at least 4h0m0s apart")
+	require.Contains(t, r.Reasons[1], "younger than the minimum age of 2h0m0s")
+
+	wantDrop := deleted2Info.Timestamp().Add(SafetyFull.DropContentFromIndexExtraMargin + SafetyFull.MarginBetweenSnapshotGC)
+	require.Equal(t, wantDrop, r.Contents[0].DropTime)
+	require.Equal(t, wantDrop, r.EarliestDeletion)
+
+	// with no safety margins the blob can be deleted right away.
+	r, err = ExplainBlobRetention(ctx, env.RepositoryWriter, pack2, SafetyNone)
+	require.NoError(t, err)
+	require.Equal(t, []string{"blob can be deleted by the next full maintenance"}, r.Reasons)
+	require.WithinDuration(t, env.RepositoryWriter.Time(), r.EarliestDeletion, time.Minute)
+
+	// two snapshot GC cycles sufficiently apart, the first of which started after the deletion.
+	t0 := env.RepositoryWriter.Time()
+
+	s, err := GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+
+	s.ReportRun(TaskSnapshotGarbageCollection, RunInfo{Start: t0.Add(2 * time.Hour), End: t0.Add(2 * time.Hour), Success: true})
+	s.ReportRun(TaskSnapshotGarbageCollection, RunInfo{Start: t0.Add(7 * time.Hour), End: t0.Add(7 * time.Hour), Success: true})
+	require.NoError(t, SetSchedule(ctx, env.RepositoryWriter, s))
+
+	ft.Advance(8 * time.Hour)
+
+	r, err = ExplainBlobRetention(ctx, env.RepositoryWriter, pack2, SafetyFull)
+	require.NoError(t, err)
+	require.Equal(t, []string{"blob can be deleted by the next full maintenance"}, r.Reasons)
+	require.WithinDuration(t, env.RepositoryWriter.Time(), r.EarliestDeletion, time.Minute)
+
+	// live content is now old enough to be subject to GC.
+	ft.Advance(24 * time.Hour)
+
+	r, err = ExplainBlobRetention(ctx, env.RepositoryWriter, pack1, SafetyFull)
+	require.NoError(t, err)
+
+	for _, rc := range r.Contents {
+		require.Equal(t, rc.ContentID == live, rc.SubjectToGC)
+	}
+}
@@ -3,8 +3,10 @@
 import (
 	"context"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -25,6 +27,33 @@ type RewriteContentsOptions struct {
 	ShortPacks     bool
 	FormatVersion  int
 	DryRun         bool
+
+	// MinAge when set causes contents younger than the provided age to be skipped,
+	// in addition to the RewriteMinAge safety parameter.
+	MinAge time.Duration
+
+	// CountEmptiedPacks when set causes packs left without any contents to be reported,
+	// which requires iterating all packs after the rewrite.
+	CountEmptiedPacks bool
+}
+
+// RewriteContentsStats describes the work done (or to be done in dry-run mode) by RewriteContents.
+type RewriteContentsStats struct {
+	RewrittenContentCount int   `json:"rewrittenContentCount"`
+	RewrittenBytes        int64 `json:"rewrittenBytes"`
+	EmptiedPackCount      int   `json:"emptiedPackCount"`
+
+	// in dry-run mode, contents that would be moved and pack blobs that would be left empty, sorted by ID.
+	Contents     []RewrittenContent `json:"contents,omitempty"`
+	EmptiedPacks []blob.ID          `json:"emptiedPacks,omitempty"`
+}
+
+// RewrittenContent describes a single content selected for rewriting.
+type RewrittenContent struct {
+	ContentID    content.ID `json:"contentID"`
+	PackBlobID   blob.ID    `json:"packBlobID"`
+	PackedLength uint32     `json:"packedLength"`
+	Deleted      bool       `json:"deleted,omitempty"`
 }
 
 const shortPackThresholdPercent = 60 // blocks below 60% of max block size are considered to be 'short
@@ -36,9 +65,11 @@ type contentInfoOrError struct {
 
 // RewriteContents rewrites contents according to provided criteria and creates new
 // blobs and index entries to point at the.
-func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *RewriteContentsOptions, safety SafetyParameters) error {
+func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *RewriteContentsOptions, safety SafetyParameters) (RewriteContentsStats, error) {
+	var stats RewriteContentsStats
+
 	if opt == nil {
-		return errors.Errorf("missing options")
+		return stats, errors.Errorf("missing options")
 	}
 
 	if opt.ShortPacks {
@@ -50,15 +81,21 @@ func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *
 	cnt := getContentToRewrite(ctx, rep, opt)
 
 	var (
-		mu          sync.Mutex
-		totalBytes  int64
-		failedCount int
+		mu             sync.Mutex
+		failedCount    int
+		seen           = map[content.ID]bool{}
+		rewrittenPacks = map[blob.ID]int{}
 	)
 
 	if opt.Parallel == 0 {
 		opt.Parallel = runtime.NumCPU() * parallelContentRewritesCPUMultiplier
 	}
 
+	minAge := safety.RewriteMinAge
+	if opt.MinAge > minAge {
+		minAge = opt.MinAge
+	}
+
 	var wg sync.WaitGroup
 
 	for i := 0; i < opt.Parallel; i++ {
@@ -82,40 +119,126 @@ func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *
 				}
 
 				age := rep.Time().Sub(c.Timestamp())
-				if age < safety.RewriteMinAge {
+				if age < minAge {
 					log(ctx).Debugf("Not rewriting content %v (%v bytes) from pack %v%v %v, because it's too new.", c.GetContentID(), c.GetPackedLength(), c.GetPackBlobID(), optDeleted, age)
 					continue
 				}
 
-				log(ctx).Debugf("Rewriting content %v (%v bytes) from pack %v%v %v", c.GetContentID(), c.GetPackedLength(), c.GetPackBlobID(), optDeleted, age)
 				mu.Lock()
-				totalBytes += int64(c.GetPackedLength())
+				alreadySeen := seen[c.GetContentID()]
+				seen[c.GetContentID()] = true
 				mu.Unlock()
 
-				if opt.DryRun {
+				if alreadySeen {
 					continue
 				}
 
-				if err := rep.ContentManager().RewriteContent(ctx, c.GetContentID()); err != nil {
-					log(ctx).Infof("unable to rewrite content %q: %v", c.GetContentID(), err)
-					mu.Lock()
-					failedCount++
-					mu.Unlock()
+				log(ctx).Debugf("Rewriting content %v (%v bytes) from pack %v%v %v", c.GetContentID(), c.GetPackedLength(), c.GetPackBlobID(), optDeleted, age)
+
+				if !opt.DryRun {
+					if err := rep.ContentManager().RewriteContent(ctx, c.GetContentID()); err != nil {
+						log(ctx).Infof("unable to rewrite content %q: %v", c.GetContentID(), err)
+						mu.Lock()
+						failedCount++
+						mu.Unlock()
+
+						continue
+					}
 				}
+
+				mu.Lock()
+				stats.RewrittenContentCount++
+				stats.RewrittenBytes += int64(c.GetPackedLength())
+				rewrittenPacks[c.GetPackBlobID()]++
+
+				if opt.DryRun {
+					stats.Contents = append(stats.Contents, RewrittenContent{
+						ContentID:    c.GetContentID(),
+						PackBlobID:   c.GetPackBlobID(),
+						PackedLength: c.GetPackedLength(),
+						Deleted:      c.GetDeleted(),
+					})
+				}
+				mu.Unlock()
 			}
 		}()
 	}
 
 	wg.Wait()
 
-	log(ctx).Debugf("Total bytes rewritten %v", units.BytesStringBase10(totalBytes))
+	log(ctx).Debugf("Total bytes rewritten %v", units.BytesStringBase10(stats.RewrittenBytes))
+
+	if failedCount != 0 {
+		return stats, errors.Errorf("failed to rewrite %v contents", failedCount)
+	}
+
+	if err := rep.ContentManager().Flush(ctx); err != nil {
+		return stats, errors.Wrap(err, "error flushing")
+	}
+
+	if opt.CountEmptiedPacks {
+		// count packs after flushing, so that the index reflects rewritten contents.
+		emptied, err := findEmptiedPacks(ctx, rep, rewrittenPacks, opt.DryRun)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.EmptiedPackCount = len(emptied)
+
+		if opt.DryRun {
+			stats.EmptiedPacks = emptied
+		}
+	}
+
+	if opt.DryRun {
+		sort.Slice(stats.Contents, func(i, j int) bool {
+			return stats.Contents[i].ContentID < stats.Contents[j].ContentID
+		})
+	}
+
+	return stats, nil
+}
 
-	if failedCount == 0 {
-		// nolint:wrapcheck
-		return rep.ContentManager().Flush(ctx)
+// findEmptiedPacks returns sorted IDs of source packs which no longer contain any contents
+// after the rewrite (or would not contain any, in dry-run mode).
+func findEmptiedPacks(ctx context.Context, rep repo.DirectRepository, rewrittenPacks map[blob.ID]int, dryRun bool) ([]blob.ID, error) {
+	if len(rewrittenPacks) == 0 {
+		return nil, nil
 	}
 
-	return errors.Errorf("failed to rewrite %v contents", failedCount)
+	remaining := map[blob.ID]int{}
+
+	if err := rep.ContentReader().IteratePacks(ctx, content.IteratePackOptions{
+		IncludePacksWithOnlyDeletedContent: true,
+	}, func(pi content.PackInfo) error {
+		if _, ok := rewrittenPacks[pi.PackID]; ok {
+			remaining[pi.PackID] = pi.ContentCount
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error iterating packs")
+	}
+
+	var emptied []blob.ID
+
+	for packID, cnt := range rewrittenPacks {
+		left := remaining[packID]
+		if dryRun {
+			// nothing has been rewritten, so the pack still holds all of its contents.
+			left -= cnt
+		}
+
+		if left <= 0 {
+			emptied = append(emptied, packID)
+		}
+	}
+
+	sort.Slice(emptied, func(i, j int) bool {
+		return emptied[i] < emptied[j]
+	})
+
+	return emptied, nil
 }
 
 func getContentToRewrite(ctx context.Context, rep repo.DirectRepository, opt *RewriteContentsOptions) <-chan contentInfoOrError {
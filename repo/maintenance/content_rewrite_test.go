@@ -3,14 +3,19 @@
 import (
 	"context"
 	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/faketime"
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/object"
 )
@@ -104,7 +109,8 @@ func TestContentRewrite(t *testing.T) {
 			require.NoError(t, err)
 
 			require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
-				return maintenance.RewriteContents(ctx, w, tc.opt, maintenance.SafetyNone)
+				_, err := maintenance.RewriteContents(ctx, w, tc.opt, maintenance.SafetyNone)
+				return err
 			}))
 
 			pBlobsAfter, err := blob.ListAllBlobs(ctx, env.RepositoryWriter.BlobStorage(), "p")
@@ -118,3 +124,161 @@ func TestContentRewrite(t *testing.T) {
 		})
 	}
 }
+
+func TestContentRewriteMinAge(t *testing.T) {
+	ft := faketime.NewTimeAdvance(clock.Now(), time.Second)
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.Options{
+		OpenOptions: func(o *repo.Options) {
+			o.TimeNowFunc = ft.NowFunc()
+		},
+	})
+
+	cm := env.RepositoryWriter.ContentManager()
+
+	// write each content to its own short pack.
+	writeContent := func() content.ID {
+		cid, err := cm.WriteContent(ctx, []byte(uuid.NewString()), "", content.NoCompression)
+		require.NoError(t, err)
+		require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+		return cid
+	}
+
+	oldContents := []content.ID{writeContent(), writeContent()}
+
+	ft.Advance(time.Hour)
+
+	newContent := writeContent()
+
+	packOf := func(cid content.ID) blob.ID {
+		ci, err := cm.ContentInfo(ctx, cid)
+		require.NoError(t, err)
+
+		return ci.GetPackBlobID()
+	}
+
+	packsBefore := map[content.ID]blob.ID{}
+	for _, cid := range append(oldContents, newContent) {
+		packsBefore[cid] = packOf(cid)
+	}
+
+	_, err := maintenance.RewriteContents(ctx, env.RepositoryWriter, &maintenance.RewriteContentsOptions{
+		ShortPacks: true,
+		MinAge:     30 * time.Minute,
+	}, maintenance.SafetyNone)
+	require.NoError(t, err)
+
+	for _, cid := range oldContents {
+		require.NotEqual(t, packsBefore[cid], packOf(cid), "old content %v was not rewritten", cid)
+	}
+
+	require.Equal(t, packsBefore[newContent], packOf(newContent), "recent content was rewritten")
+}
+
+func TestContentRewriteStats(t *testing.T) {
+	// rewritten contents must have newer timestamps than originals to supersede them in the index.
+	ft := faketime.NewTimeAdvance(clock.Now(), time.Second)
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.Options{
+		OpenOptions: func(o *repo.Options) {
+			o.TimeNowFunc = ft.NowFunc()
+		},
+	})
+
+	cm := env.RepositoryWriter.ContentManager()
+
+	var (
+		contentIDs   []content.ID
+		totalBytes   int64
+		wantContents []maintenance.RewrittenContent
+		wantPacks    []blob.ID
+	)
+
+	// 3 short packs with 2 contents each.
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 2; j++ {
+			cid, err := cm.WriteContent(ctx, []byte(uuid.NewString()), "", content.NoCompression)
+			require.NoError(t, err)
+
+			contentIDs = append(contentIDs, cid)
+		}
+
+		require.NoError(t, env.RepositoryWriter.Flush(ctx))
+	}
+
+	for _, cid := range contentIDs {
+		ci, err := cm.ContentInfo(ctx, cid)
+		require.NoError(t, err)
+
+		totalBytes += int64(ci.GetPackedLength())
+
+		wantContents = append(wantContents, maintenance.RewrittenContent{
+			ContentID:    cid,
+			PackBlobID:   ci.GetPackBlobID(),
+			PackedLength: ci.GetPackedLength(),
+		})
+
+		if len(wantPacks) == 0 || wantPacks[len(wantPacks)-1] != ci.GetPackBlobID() {
+			wantPacks = append(wantPacks, ci.GetPackBlobID())
+		}
+	}
+
+	sort.Slice(wantContents, func(i, j int) bool { return wantContents[i].ContentID < wantContents[j].ContentID })
+	sort.Slice(wantPacks, func(i, j int) bool { return wantPacks[i] < wantPacks[j] })
+
+	want := maintenance.RewriteContentsStats{
+		RewrittenContentCount: 6,
+		RewrittenBytes:        totalBytes,
+		EmptiedPackCount:      3,
+	}
+
+	blobsBefore, err := blob.ListAllBlobs(ctx, env.RepositoryWriter.BlobStorage(), "")
+	require.NoError(t, err)
+
+	// dry run describes what would happen, including affected contents and packs, without writing anything.
+	st, err := maintenance.RewriteContents(ctx, env.RepositoryWriter, &maintenance.RewriteContentsOptions{
+		ShortPacks:        true,
+		DryRun:            true,
+		CountEmptiedPacks: true,
+	}, maintenance.SafetyNone)
+	require.NoError(t, err)
+
+	wantDryRun := want
+	wantDryRun.Contents = wantContents
+	wantDryRun.EmptiedPacks = wantPacks
+	require.Equal(t, wantDryRun, st)
+
+	blobsAfter, err := blob.ListAllBlobs(ctx, env.RepositoryWriter.BlobStorage(), "")
+	require.NoError(t, err)
+	require.ElementsMatch(t, blobsBefore, blobsAfter)
+
+	// rewriting a single content by ID (listed twice) does not empty its pack.
+	st, err = maintenance.RewriteContents(ctx, env.RepositoryWriter, &maintenance.RewriteContentsOptions{
+		ContentIDs:        []content.ID{contentIDs[0], contentIDs[0]},
+		DryRun:            true,
+		CountEmptiedPacks: true,
+	}, maintenance.SafetyNone)
+	require.NoError(t, err)
+	require.Equal(t, 1, st.RewrittenContentCount)
+	require.Equal(t, 0, st.EmptiedPackCount)
+	require.Empty(t, st.EmptiedPacks)
+	require.Len(t, st.Contents, 1)
+
+	// emptied packs are only reported when requested.
+	st, err = maintenance.RewriteContents(ctx, env.RepositoryWriter, &maintenance.RewriteContentsOptions{
+		ShortPacks: true,
+		DryRun:     true,
+	}, maintenance.SafetyNone)
+	require.NoError(t, err)
+	require.Equal(t, 6, st.RewrittenContentCount)
+	require.Equal(t, 0, st.EmptiedPackCount)
+	require.Empty(t, st.EmptiedPacks)
+
+	st, err = maintenance.RewriteContents(ctx, env.RepositoryWriter, &maintenance.RewriteContentsOptions{
+		ShortPacks:        true,
+		CountEmptiedPacks: true,
+	}, maintenance.SafetyNone)
+	require.NoError(t, err)
+	require.Equal(t, want, st)
+}
@@ -189,21 +189,29 @@ func RunExclusive(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mod
 	return cb(runParams)
 }
 
+// RunResult summarizes the work performed by a maintenance run.
+type RunResult struct {
+	BlobsDeleted int   `json:"blobsDeleted"`
+	BytesDeleted int64 `json:"bytesDeleted"`
+}
+
 // Run performs maintenance activities for a repository.
-func Run(ctx context.Context, runParams RunParameters, safety SafetyParameters) error {
+func Run(ctx context.Context, runParams RunParameters, safety SafetyParameters) (RunResult, error) {
+	var result RunResult
+
 	switch runParams.Mode {
 	case ModeQuick:
-		return runQuickMaintenance(ctx, runParams, safety)
+		return result, runQuickMaintenance(ctx, runParams, safety, &result)
 
 	case ModeFull:
-		return runFullMaintenance(ctx, runParams, safety)
+		return result, runFullMaintenance(ctx, runParams, safety, &result)
 
 	default:
-		return errors.Errorf("unknown mode %q", runParams.Mode)
+		return result, errors.Errorf("unknown mode %q", runParams.Mode)
 	}
 }
 
-func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety SafetyParameters) error {
+func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety SafetyParameters, result *RunResult) error {
 	s, err := GetSchedule(ctx, runParams.rep)
 	if err != nil {
 		return errors.Wrap(err, "unable to get schedule")
@@ -228,10 +236,10 @@ func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety Sa
 		// and we'd never delete blobs orphaned by full rewrite.
 		if hadRecentFullRewrite(s) {
 			log(ctx).Debugf("Had recent full rewrite - performing full blob deletion.")
-			err = runTaskDeleteOrphanedBlobsFull(ctx, runParams, s, safety)
+			err = runTaskDeleteOrphanedBlobsFull(ctx, runParams, s, safety, result)
 		} else {
 			log(ctx).Debugf("Performing quick blob deletion.")
-			err = runTaskDeleteOrphanedBlobsQuick(ctx, runParams, s, safety)
+			err = runTaskDeleteOrphanedBlobsQuick(ctx, runParams, s, safety, result)
 		}
 
 		if err != nil {
@@ -280,13 +288,7 @@ func runTaskCleanupLogs(ctx context.Context, runParams RunParameters, s *Schedul
 }
 
 func runTaskDropDeletedContentsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
-	var safeDropTime time.Time
-
-	if safety.RequireTwoGCCycles {
-		safeDropTime = findSafeDropTime(s.Runs[TaskSnapshotGarbageCollection], safety)
-	} else {
-		safeDropTime = runParams.rep.Time()
-	}
+	safeDropTime := findSafeDropTimeForSchedule(runParams.rep, s, safety)
 
 	if safeDropTime.IsZero() {
 		log(ctx).Infof("Not enough time has passed since previous successful Snapshot GC. Will try again next time.")
@@ -302,40 +304,51 @@ func runTaskDropDeletedContentsFull(ctx context.Context, runParams RunParameters
 
 func runTaskRewriteContentsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
 	return ReportRun(ctx, runParams.rep, TaskRewriteContentsQuick, s, func() error {
-		return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+		_, err := RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
 			ContentIDRange: content.AllPrefixedIDs,
 			PackPrefix:     content.PackBlobIDPrefixSpecial,
 			ShortPacks:     true,
 		}, safety)
+
+		return err
 	})
 }
 
 func runTaskRewriteContentsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
 	return ReportRun(ctx, runParams.rep, TaskRewriteContentsFull, s, func() error {
-		return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+		_, err := RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
 			ContentIDRange: content.AllIDs,
 			ShortPacks:     true,
 		}, safety)
+
+		return err
 	})
 }
 
-func runTaskDeleteOrphanedBlobsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
+func runTaskDeleteOrphanedBlobsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters, result *RunResult) error {
 	return ReportRun(ctx, runParams.rep, TaskDeleteOrphanedBlobsFull, s, func() error {
-		_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{}, safety)
+		cnt, size, err := deleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{}, safety)
+		result.add(cnt, size)
 		return err
 	})
 }
 
-func runTaskDeleteOrphanedBlobsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
+func runTaskDeleteOrphanedBlobsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters, result *RunResult) error {
 	return ReportRun(ctx, runParams.rep, TaskDeleteOrphanedBlobsQuick, s, func() error {
-		_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
+		cnt, size, err := deleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
 			Prefix: content.PackBlobIDPrefixSpecial,
 		}, safety)
+		result.add(cnt, size)
 		return err
 	})
 }
 
-func runFullMaintenance(ctx context.Context, runParams RunParameters, safety SafetyParameters) error {
+func (r *RunResult) add(blobsDeleted int, bytesDeleted int64) {
+	r.BlobsDeleted += blobsDeleted
+	r.BytesDeleted += bytesDeleted
+}
+
+func runFullMaintenance(ctx context.Context, runParams RunParameters, safety SafetyParameters, result *RunResult) error {
 	s, err := GetSchedule(ctx, runParams.rep)
 	if err != nil {
 		return errors.Wrap(err, "unable to get schedule")
@@ -359,7 +372,7 @@ func runFullMaintenance(ctx context.Context, runParams RunParameters, safety Saf
 
 	if shouldDeleteOrphanedPacks(runParams.rep.Time(), s, safety) {
 		// delete orphaned packs after some time.
-		if err := runTaskDeleteOrphanedBlobsFull(ctx, runParams, s, safety); err != nil {
+		if err := runTaskDeleteOrphanedBlobsFull(ctx, runParams, s, safety, result); err != nil {
 			return errors.Wrap(err, "error deleting unreferenced blobs")
 		}
 	} else {
@@ -441,6 +454,16 @@ func maxEndTime(taskRuns ...[]RunInfo) time.Time {
 	return result
 }
 
+// findSafeDropTimeForSchedule returns the latest timestamp for which it is safe to drop deleted content entries
+// given the schedule and safety parameters or zero time if no contents can be dropped yet.
+func findSafeDropTimeForSchedule(rep repo.DirectRepository, s *Schedule, safety SafetyParameters) time.Time {
+	if safety.RequireTwoGCCycles {
+		return findSafeDropTime(s.Runs[TaskSnapshotGarbageCollection], safety)
+	}
+
+	return rep.Time()
+}
+
 // findSafeDropTime returns the latest timestamp for which it is safe to drop content entries
 // deleted before that time, because at least two successful GC cycles have completed
 // and minimum required time between the GCs has passed.
@@ -42,6 +42,9 @@ type Schedule struct {
 	NextQuickMaintenanceTime time.Time `json:"nextQuickMaintenance"`
 
 	Runs map[TaskType][]RunInfo `json:"runs"`
+
+	// LastRunSummary is the summary of the most recent maintenance run, its format is defined by the caller.
+	LastRunSummary json.RawMessage `json:"lastRunSummary,omitempty"`
 }
 
 // ReportRun adds the provided run information to the history and discards oldest entried.
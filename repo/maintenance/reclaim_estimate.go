@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/stats"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+)
+
+// defaultEstimateParallelism is the number of parallel blob listings used when looking for orphaned blobs.
+const defaultEstimateParallelism = 16
+
+// ReclaimEstimate describes the amount of storage expected to be reclaimed by full maintenance.
+type ReclaimEstimate struct {
+	// deleted contents old enough to be dropped from the index.
+	DeletedContentCount int   `json:"deletedContentCount"`
+	DeletedContentBytes int64 `json:"deletedContentBytes"`
+
+	// pack blobs that will become unreferenced once deleted contents are dropped.
+	DroppablePackCount int   `json:"droppablePackCount"`
+	DroppablePackBytes int64 `json:"droppablePackBytes"`
+
+	// blobs that are already unreferenced and old enough to be deleted.
+	OrphanedBlobCount int   `json:"orphanedBlobCount"`
+	OrphanedBlobBytes int64 `json:"orphanedBlobBytes"`
+}
+
+// ReclaimableBlobCount returns the number of blobs expected to be deleted.
+func (e ReclaimEstimate) ReclaimableBlobCount() int {
+	return e.DroppablePackCount + e.OrphanedBlobCount
+}
+
+// ReclaimableBytes returns the number of bytes expected to be reclaimed.
+func (e ReclaimEstimate) ReclaimableBytes() int64 {
+	return e.DroppablePackBytes + e.OrphanedBlobBytes
+}
+
+// EstimateReclaimableSpace computes the amount of storage that full maintenance with the provided
+// safety parameters would reclaim, without modifying the repository.
+func EstimateReclaimableSpace(ctx context.Context, rep repo.DirectRepository, safety SafetyParameters) (ReclaimEstimate, error) {
+	var est ReclaimEstimate
+
+	s, err := GetSchedule(ctx, rep)
+	if err != nil {
+		return est, errors.Wrap(err, "unable to get schedule")
+	}
+
+	canDeleteBlobs := shouldDeleteOrphanedPacks(rep.Time(), s, safety)
+	if !canDeleteBlobs {
+		notDeletingOrphanedBlobs(ctx, s, safety)
+	}
+
+	if safeDropTime := findSafeDropTimeForSchedule(rep, s, safety); !safeDropTime.IsZero() {
+		if err := estimateDroppableContents(ctx, rep, safeDropTime, canDeleteBlobs, safety, &est); err != nil {
+			return est, errors.Wrap(err, "error estimating droppable contents")
+		}
+	} else {
+		log(ctx).Infof("Not enough time has passed since previous successful Snapshot GC, no contents can be dropped.")
+	}
+
+	if canDeleteBlobs {
+		var orphaned stats.CountSum
+
+		if err := findUnreferencedBlobs(ctx, rep, DeleteUnreferencedBlobsOptions{Parallel: defaultEstimateParallelism}, safety, func(bm blob.Metadata) error {
+			orphaned.Add(bm.Length)
+			return nil
+		}); err != nil {
+			return est, errors.Wrap(err, "error looking for orphaned blobs")
+		}
+
+		cnt, size := orphaned.Approximate()
+
+		est.OrphanedBlobCount = int(cnt)
+		est.OrphanedBlobBytes = size
+	}
+
+	return est, nil
+}
+
+// estimateDroppableContents finds deleted contents which would be dropped from the index before safeDropTime
+// and packs that would become unreferenced as a result.
+func estimateDroppableContents(ctx context.Context, rep repo.DirectRepository, safeDropTime time.Time, includePacks bool, safety SafetyParameters, est *ReclaimEstimate) error {
+	// nolint:wrapcheck
+	return rep.ContentReader().IteratePacks(ctx, content.IteratePackOptions{
+		IncludePacksWithOnlyDeletedContent: true,
+		IncludeContentInfos:                true,
+	}, func(pi content.PackInfo) error {
+		droppable := 0
+
+		for _, ci := range pi.ContentInfos {
+			if ci.GetDeleted() && ci.Timestamp().Before(safeDropTime) {
+				droppable++
+				est.DeletedContentCount++
+				est.DeletedContentBytes += int64(ci.GetPackedLength())
+			}
+		}
+
+		if !includePacks || droppable == 0 || droppable < len(pi.ContentInfos) {
+			return nil
+		}
+
+		bm, err := rep.BlobReader().GetMetadata(ctx, pi.PackID)
+		if errors.Is(err, blob.ErrBlobNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "unable to get metadata of %v", pi.PackID)
+		}
+
+		if age := rep.Time().Sub(bm.Timestamp); age < safety.BlobDeleteMinAge {
+			return nil
+		}
+
+		est.DroppablePackCount++
+		est.DroppablePackBytes += bm.Length
+
+		return nil
+	})
+}
@@ -0,0 +1,84 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+)
+
+func TestEstimateReclaimableSpace(t *testing.T) {
+	// blob timestamps come from the filesystem, so the clock must start at current time.
+	ft := faketime.NewTimeAdvance(clock.Now(), time.Second)
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.Options{
+		OpenOptions: func(o *repo.Options) {
+			o.TimeNowFunc = ft.NowFunc()
+		},
+	})
+
+	cm := env.RepositoryWriter.ContentManager()
+
+	// write a pack with contents, all of which are subsequently deleted.
+	var deletedBytes int64
+
+	for i := 0; i < 3; i++ {
+		cid, err := cm.WriteContent(ctx, []byte{1, 2, 3, byte(i)}, "", content.NoCompression)
+		require.NoError(t, err)
+
+		require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+		ci, err := cm.ContentInfo(ctx, cid)
+		require.NoError(t, err)
+
+		deletedBytes += int64(ci.GetPackedLength())
+
+		require.NoError(t, cm.DeleteContent(ctx, cid))
+	}
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	// orphaned blob that is not referenced by any index.
+	require.NoError(t, env.RepositoryWriter.BlobStorage().PutBlob(ctx, "pdeadbeef", gather.FromSlice([]byte{1, 2, 3, 4, 5})))
+
+	est, err := EstimateReclaimableSpace(ctx, env.RepositoryWriter, SafetyNone)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, est.DeletedContentCount)
+	require.Equal(t, deletedBytes, est.DeletedContentBytes)
+	require.Equal(t, 3, est.DroppablePackCount)
+	require.Equal(t, 1, est.OrphanedBlobCount)
+	require.Equal(t, int64(5), est.OrphanedBlobBytes)
+
+	// with full safety nothing can be reclaimed yet.
+	est2, err := EstimateReclaimableSpace(ctx, env.RepositoryWriter, SafetyFull)
+	require.NoError(t, err)
+	require.Zero(t, est2.ReclaimableBytes())
+
+	// estimation must not mutate the repository.
+	est3, err := EstimateReclaimableSpace(ctx, env.RepositoryWriter, SafetyNone)
+	require.NoError(t, err)
+	require.Equal(t, est, est3)
+
+	var result RunResult
+
+	require.NoError(t, RunExclusive(ctx, env.RepositoryWriter, ModeFull, true, func(runParams RunParameters) error {
+		result, err = Run(ctx, runParams, SafetyNone)
+		return err
+	}))
+
+	require.Equal(t, est.ReclaimableBlobCount(), result.BlobsDeleted)
+	require.Equal(t, est.ReclaimableBytes(), result.BytesDeleted)
+
+	// nothing left to reclaim after maintenance.
+	est4, err := EstimateReclaimableSpace(ctx, env.RepositoryWriter, SafetyNone)
+	require.NoError(t, err)
+	require.Equal(t, ReclaimEstimate{}, est4)
+}
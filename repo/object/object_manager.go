@@ -55,12 +55,13 @@ type Manager struct {
 // NewWriter creates an ObjectWriter for writing to the repository.
 func (om *Manager) NewWriter(ctx context.Context, opt WriterOptions) Writer {
 	w := &objectWriter{
-		ctx:         ctx,
-		om:          om,
-		splitter:    om.newSplitter(),
-		description: opt.Description,
-		prefix:      opt.Prefix,
-		compressor:  compression.ByName[opt.Compressor],
+		ctx:                ctx,
+		om:                 om,
+		splitter:           om.newSplitter(),
+		description:        opt.Description,
+		prefix:             opt.Prefix,
+		compressor:         compression.ByName[opt.Compressor],
+		metadataCompressor: compression.ByName[opt.MetadataCompressor],
 	}
 
 	// point the slice at the embedded array, so that we avoid allocations most of the time
@@ -191,6 +191,41 @@ func TestCompression_ContentCompressionDisabled(t *testing.T) {
 	require.True(t, isCompressed) // oid will indicate compression
 }
 
+func TestMetadataCompression(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	cases := []struct {
+		metadataCompressor compression.Name
+		wantCompressed     bool
+	}{
+		{"", false},
+		{"gzip", true},
+	}
+
+	for _, tc := range cases {
+		// this disables content compression, so compression is reflected in object IDs
+		_, om := setupTest(t, nil)
+
+		w := om.NewWriter(ctx, WriterOptions{
+			MetadataCompressor: tc.metadataCompressor,
+		})
+		w.(*objectWriter).splitter = splitter.Fixed(1000)()
+		w.Write(bytes.Repeat([]byte{1, 2, 3, 4}, 1000))
+		oid, err := w.Result()
+		require.NoError(t, err)
+
+		indexObjectID, ok := oid.IndexObjectID()
+		require.True(t, ok)
+
+		// data contents are never compressed, only the index object is.
+		_, isCompressed, ok := indexObjectID.ContentID()
+		require.True(t, ok)
+		require.Equal(t, tc.wantCompressed, isCompressed)
+
+		verifyIndirectBlock(ctx, t, om, oid)
+	}
+}
+
 func TestWriterCompleteChunkInTwoWrites(t *testing.T) {
 	ctx := testlogging.Context(t)
 	_, om := setupTest(t, nil)
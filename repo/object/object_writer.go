@@ -66,7 +66,8 @@ type objectWriter struct {
 	ctx context.Context
 	om  *Manager
 
-	compressor compression.Compressor
+	compressor         compression.Compressor
+	metadataCompressor compression.Compressor
 
 	prefix      content.ID
 	buf         buf.Buf
@@ -293,12 +294,13 @@ func (w *objectWriter) checkpointLocked() (ID, error) {
 	}
 
 	iw := &objectWriter{
-		ctx:         w.ctx,
-		om:          w.om,
-		compressor:  nil,
-		description: "LIST(" + w.description + ")",
-		splitter:    w.om.newSplitter(),
-		prefix:      w.prefix,
+		ctx:                w.ctx,
+		om:                 w.om,
+		compressor:         w.metadataCompressor,
+		metadataCompressor: w.metadataCompressor,
+		description:        "LIST(" + w.description + ")",
+		splitter:           w.om.newSplitter(),
+		prefix:             w.prefix,
 	}
 
 	if iw.prefix == "" {
@@ -341,4 +343,6 @@ type WriterOptions struct {
 	Prefix      content.ID // empty string or a single-character ('g'..'z')
 	Compressor  compression.Name
 	AsyncWrites int // allow up to N content writes to be asynchronous
+
+	MetadataCompressor compression.Name // compressor for indirect object index, empty means no compression
 }
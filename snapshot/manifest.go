@@ -80,12 +80,17 @@ func (p *Permissions) UnmarshalJSON(b []byte) error {
 }
 
 // DirEntry represents a directory entry as stored in JSON stream.
+//
+// BirthTime is only captured on Windows, where it can also be restored. Because directory objects are
+// content-addressed, the first snapshot taken there after it was introduced writes new directory objects
+// once even for unchanged files, while file contents are still deduplicated.
 type DirEntry struct {
 	Name        string               `json:"name,omitempty"`
 	Type        EntryType            `json:"type,omitempty"`
 	Permissions Permissions          `json:"mode,omitempty"`
 	FileSize    int64                `json:"size,omitempty"`
 	ModTime     time.Time            `json:"mtime,omitempty"`
+	BirthTime   *time.Time           `json:"btime,omitempty"`
 	UserID      uint32               `json:"uid,omitempty"`
 	GroupID     uint32               `json:"gid,omitempty"`
 	ObjectID    object.ID            `json:"obj,omitempty"`
@@ -140,6 +145,30 @@ func GroupBySource(manifests []*Manifest) [][]*Manifest {
 	return result
 }
 
+// GroupByRootObjectID returns a slice of slices, such that each result item contains manifests with identical
+// root object ID, sorted by time. Groups are ordered by the time of their earliest snapshot.
+func GroupByRootObjectID(manifests []*Manifest) [][]*Manifest {
+	resultMap := map[object.ID][]*Manifest{}
+	for _, m := range manifests {
+		resultMap[m.RootObjectID()] = append(resultMap[m.RootObjectID()], m)
+	}
+
+	var result [][]*Manifest
+	for _, v := range resultMap {
+		result = append(result, SortByTime(v, false))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if ti, tj := result[i][0].StartTime, result[j][0].StartTime; !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+
+		return result[i][0].RootObjectID() < result[j][0].RootObjectID()
+	})
+
+	return result
+}
+
 // SortByTime returns a slice of manifests sorted by start time.
 func SortByTime(manifests []*Manifest, reverse bool) []*Manifest {
 	result := append([]*Manifest(nil), manifests...)
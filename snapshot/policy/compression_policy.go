@@ -15,6 +15,8 @@ type CompressionPolicy struct {
 	NeverCompress  []string         `json:"neverCompress,omitempty"`
 	MinSize        int64            `json:"minSize,omitempty"`
 	MaxSize        int64            `json:"maxSize,omitempty"`
+
+	MetadataCompressorName compression.Name `json:"metadataCompressorName,omitempty"`
 }
 
 // CompressorForFile returns compression name to be used for compressing a given file according to policy, using attributes such as name or size.
@@ -45,12 +47,25 @@ func (p *CompressionPolicy) CompressorForFile(e fs.File) compression.Name {
 	return p.CompressorName
 }
 
+// MetadataCompressor returns compression name to be used for compressing snapshot metadata, such as directory listings.
+func (p *CompressionPolicy) MetadataCompressor() compression.Name {
+	if p.MetadataCompressorName == "none" {
+		return ""
+	}
+
+	return p.MetadataCompressorName
+}
+
 // Merge applies default values from the provided policy.
 func (p *CompressionPolicy) Merge(src CompressionPolicy) {
 	if p.CompressorName == "" {
 		p.CompressorName = src.CompressorName
 	}
 
+	if p.MetadataCompressorName == "" {
+		p.MetadataCompressorName = src.MetadataCompressorName
+	}
+
 	if p.MinSize == 0 {
 		p.MinSize = src.MinSize
 	}
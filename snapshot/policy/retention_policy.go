@@ -35,6 +35,7 @@ func (r *RetentionPolicy) ComputeRetentionReasons(manifests []*snapshot.Manifest
 	// compute max time across all and complete snapshots
 	var (
 		maxCompleteStartTime time.Time
+		maxCompleteEndTime   time.Time
 		maxStartTime         time.Time
 	)
 
@@ -46,6 +47,10 @@ func (r *RetentionPolicy) ComputeRetentionReasons(manifests []*snapshot.Manifest
 		if m.IncompleteReason == "" && m.StartTime.After(maxCompleteStartTime) {
 			maxCompleteStartTime = m.StartTime
 		}
+
+		if m.IncompleteReason == "" && m.EndTime.After(maxCompleteEndTime) {
+			maxCompleteEndTime = m.EndTime
+		}
 	}
 
 	maxTime := maxCompleteStartTime.Add(365 * 24 * time.Hour)
@@ -88,6 +93,12 @@ func (r *RetentionPolicy) ComputeRetentionReasons(manifests []*snapshot.Manifest
 			break
 		}
 
+		// incomplete snapshots (such as checkpoints) created before a complete snapshot
+		// has finished are superseded by it.
+		if !s.StartTime.After(maxCompleteEndTime) {
+			continue
+		}
+
 		age := maxStartTime.Sub(s.StartTime)
 		// retain incomplete snapshots below certain age and below maximum count.
 		if age < retainIncompleteSnapshotsYoungerThan || i < retainIncompleteSnapshotMinimumCount {
@@ -194,3 +194,30 @@ func TestRetentionPolicyTest(t *testing.T) {
 		})
 	}
 }
+
+func TestRetentionPolicyCheckpointsSupersededByCompleteSnapshot(t *testing.T) {
+	t0 := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+
+	complete := &snapshot.Manifest{StartTime: t0, EndTime: t0.Add(3 * time.Hour)}
+	checkpoint1 := &snapshot.Manifest{StartTime: t0.Add(1 * time.Hour), EndTime: t0.Add(1 * time.Hour), IncompleteReason: "checkpoint"}
+	checkpoint2 := &snapshot.Manifest{StartTime: t0.Add(2 * time.Hour), EndTime: t0.Add(2 * time.Hour), IncompleteReason: "checkpoint"}
+	laterIncomplete := &snapshot.Manifest{StartTime: t0.Add(4 * time.Hour), EndTime: t0.Add(5 * time.Hour), IncompleteReason: "canceled"}
+
+	(&RetentionPolicy{KeepLatest: intPtr(3)}).ComputeRetentionReasons([]*snapshot.Manifest{complete, checkpoint1, checkpoint2, laterIncomplete})
+
+	if diff := cmp.Diff(complete.RetentionReasons, []string{"latest-1"}); diff != "" {
+		t.Errorf("unexpected retention reasons for complete snapshot: %v", diff)
+	}
+
+	if diff := cmp.Diff(checkpoint1.RetentionReasons, []string{}); diff != "" {
+		t.Errorf("unexpected retention reasons for checkpoint1: %v", diff)
+	}
+
+	if diff := cmp.Diff(checkpoint2.RetentionReasons, []string{}); diff != "" {
+		t.Errorf("unexpected retention reasons for checkpoint2: %v", diff)
+	}
+
+	if diff := cmp.Diff(laterIncomplete.RetentionReasons, []string{"incomplete"}); diff != "" {
+		t.Errorf("unexpected retention reasons for later incomplete snapshot: %v", diff)
+	}
+}
@@ -2,11 +2,14 @@
 package restore
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -36,6 +39,14 @@ type FilesystemOutput struct {
 	// instead.
 	OverwriteFiles bool `json:"overwriteFiles"`
 
+	// OverwriteOnlyIfDifferent causes existing files that match the snapshot file on size and
+	// modification time to be left alone instead of being overwritten.
+	OverwriteOnlyIfDifferent bool `json:"overwriteOnlyIfDifferent,omitempty"`
+
+	// VerifyContentBeforeSkip when used with OverwriteOnlyIfDifferent additionally requires
+	// the hash of the existing file to match the snapshot file before skipping it.
+	VerifyContentBeforeSkip bool `json:"verifyContentBeforeSkip,omitempty"`
+
 	// If a symlink already exists, remove it and create a new one. When set to
 	// false, the copier does not modify existing symlinks and will return an
 	// error instead.
@@ -52,8 +63,40 @@ type FilesystemOutput struct {
 
 	// SkipTimes when set to true causes restore to skip restoring modification times.
 	SkipTimes bool `json:"skipTimes"`
+
+	// RestoreBirthTimes when set to true causes restore to also restore creation (birth) times
+	// on platforms that support it. It is silently ignored elsewhere.
+	RestoreBirthTimes bool `json:"restoreBirthTimes"`
+
+	// SnapshotProvenance when not empty is recorded on each restored file in the ProvenanceXattrName
+	// extended attribute, on filesystems that support it.
+	SnapshotProvenance string `json:"snapshotProvenance,omitempty"`
+
+	// SymlinkRewriteFrom and SymlinkRewriteTo when set cause absolute symlink targets under
+	// SymlinkRewriteFrom (typically the original snapshot root) to be re-pointed under SymlinkRewriteTo.
+	// Relative symlink targets are never rewritten.
+	SymlinkRewriteFrom string `json:"symlinkRewriteFrom,omitempty"`
+	SymlinkRewriteTo   string `json:"symlinkRewriteTo,omitempty"`
+
+	// RemoveExtraEntries when used with OverwriteDirectories causes entries of existing directories
+	// that are not present in the snapshot to be removed.
+	RemoveExtraEntries bool `json:"removeExtraEntries,omitempty"`
+
+	// StagingMode when set causes the tree to be restored into a temporary sibling directory of TargetPath,
+	// which is moved into place only when the restore completes without failures or cancelation.
+	// An existing non-empty directory is only replaced when RemoveExtraEntries is also set.
+	StagingMode bool `json:"stagingMode,omitempty"`
 }
 
+// ProvenanceXattrName is the name of the extended attribute recording snapshot origin of restored files.
+const ProvenanceXattrName = "user.kopia.snapshot"
+
+// errXattrUnsupported is returned by setXattr on platforms or filesystems that don't support extended attributes.
+var errXattrUnsupported = errors.New("extended attributes are not supported")
+
+// errBirthTimeUnsupported is returned by setBirthTime on platforms where setting birth time is not supported.
+var errBirthTimeUnsupported = errors.New("setting birth time is not supported on this platform")
+
 // Parallelizable implements restore.Output interface.
 func (o *FilesystemOutput) Parallelizable() bool {
 	return true
@@ -61,7 +104,11 @@ func (o *FilesystemOutput) Parallelizable() bool {
 
 // BeginDirectory implements restore.Output interface.
 func (o *FilesystemOutput) BeginDirectory(ctx context.Context, relativePath string, e fs.Directory) error {
-	path := filepath.Join(o.TargetPath, filepath.FromSlash(relativePath))
+	if err := o.maybePrepareStaging(relativePath, e); err != nil {
+		return err
+	}
+
+	path := o.outputPath(relativePath)
 
 	if err := o.createDirectory(ctx, path); err != nil {
 		return errors.Wrap(err, "error creating directory")
@@ -72,14 +119,58 @@ func (o *FilesystemOutput) BeginDirectory(ctx context.Context, relativePath stri
 
 // FinishDirectory implements restore.Output interface.
 func (o *FilesystemOutput) FinishDirectory(ctx context.Context, relativePath string, e fs.Directory) error {
-	path := filepath.Join(o.TargetPath, filepath.FromSlash(relativePath))
+	path := o.outputPath(relativePath)
+
+	if err := o.removeExtraEntries(ctx, path, e); err != nil {
+		return errors.Wrap(err, "error removing extra entries")
+	}
+
 	if err := o.setAttributes(path, e, os.FileMode(0)); err != nil {
 		return errors.Wrap(err, "error setting attributes")
 	}
 
+	// remove placeholder left behind by an earlier shallow restore, the directory itself is kept.
 	return SafeRemoveAll(path)
 }
 
+// removeExtraEntries removes entries of the local directory which are not present in the snapshot directory.
+// Placeholders of snapshot entries written by shallow restore are kept.
+func (o *FilesystemOutput) removeExtraEntries(ctx context.Context, path string, e fs.Directory) error {
+	if !o.RemoveExtraEntries || !o.OverwriteDirectories {
+		return nil
+	}
+
+	snapshotEntries, err := e.Readdir(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to read snapshot directory")
+	}
+
+	inSnapshot := map[string]bool{}
+	for _, se := range snapshotEntries {
+		inSnapshot[se.Name()] = true
+	}
+
+	localEntries, err := os.ReadDir(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to read local directory")
+	}
+
+	for _, le := range localEntries {
+		name := le.Name()
+		if inSnapshot[name] || inSnapshot[PathIfPlaceholder(name)] {
+			continue
+		}
+
+		log(ctx).Debugf("removing %v not present in the snapshot", filepath.Join(path, name))
+
+		if err := os.RemoveAll(filepath.Join(path, name)); err != nil {
+			return errors.Wrap(err, "unable to remove extra entry")
+		}
+	}
+
+	return nil
+}
+
 // WriteDirEntry implements restore.Output interface.
 func (o *FilesystemOutput) WriteDirEntry(ctx context.Context, relativePath string, de *snapshot.DirEntry, e fs.Directory) error {
 	return nil
@@ -87,32 +178,52 @@ func (o *FilesystemOutput) WriteDirEntry(ctx context.Context, relativePath strin
 
 // Close implements restore.Output interface.
 func (o *FilesystemOutput) Close(ctx context.Context) error {
-	return nil
+	if !o.StagingMode {
+		return nil
+	}
+
+	return o.commitStaging(ctx)
 }
 
 // WriteFile implements restore.Output interface.
 func (o *FilesystemOutput) WriteFile(ctx context.Context, relativePath string, f fs.File) error {
-	log(ctx).Debugf("WriteFile %v (%v bytes) %v, %v", filepath.Join(o.TargetPath, relativePath), f.Size(), f.Mode(), f.ModTime())
-	path := filepath.Join(o.TargetPath, filepath.FromSlash(relativePath))
+	log(ctx).Debugf("WriteFile %v (%v bytes) %v, %v", o.outputPath(relativePath), f.Size(), f.Mode(), f.ModTime())
+	if err := o.maybePrepareStaging(relativePath, f); err != nil {
+		return err
+	}
+
+	path := o.outputPath(relativePath)
 
 	if err := o.copyFileContent(ctx, path, f); err != nil {
 		return errors.Wrap(err, "error creating file")
 	}
 
+	// write provenance before setting attributes, which may make the file read-only.
+	if err := o.writeProvenance(ctx, path); err != nil {
+		return errors.Wrap(err, "error writing provenance")
+	}
+
 	if err := o.setAttributes(path, f, os.FileMode(0)); err != nil {
 		return errors.Wrap(err, "error setting attributes")
 	}
 
+	// remove placeholder left behind by an earlier shallow restore, the file itself is kept.
 	return SafeRemoveAll(path)
 }
 
 // FileExists implements restore.Output interface.
 func (o *FilesystemOutput) FileExists(ctx context.Context, relativePath string, e fs.File) bool {
-	st, err := os.Lstat(filepath.Join(o.TargetPath, relativePath))
+	st, err := os.Lstat(o.outputPath(relativePath))
 	if err != nil {
 		return false
 	}
 
+	return fileMetadataMatches(st, e)
+}
+
+// fileMetadataMatches returns true if the existing file is a regular file with the same size
+// and approximately the same modification time as the snapshot file.
+func fileMetadataMatches(st os.FileInfo, e fs.File) bool {
 	if (st.Mode() & os.ModeType) != 0 {
 		// not a file
 		return false
@@ -138,9 +249,15 @@ func (o *FilesystemOutput) CreateSymlink(ctx context.Context, relativePath strin
 		return errors.Wrap(err, "error reading link target")
 	}
 
-	log(ctx).Debugf("CreateSymlink %v => %v, time %v", filepath.Join(o.TargetPath, relativePath), targetPath, e.ModTime())
+	targetPath = o.rewriteSymlinkTarget(targetPath)
+
+	log(ctx).Debugf("CreateSymlink %v => %v, time %v", o.outputPath(relativePath), targetPath, e.ModTime())
 
-	path := filepath.Join(o.TargetPath, filepath.FromSlash(relativePath))
+	if err := o.maybePrepareStaging(relativePath, e); err != nil {
+		return err
+	}
+
+	path := o.outputPath(relativePath)
 
 	switch stat, err := os.Lstat(path); {
 	case os.IsNotExist(err): // Proceed to symlink creation
@@ -171,13 +288,30 @@ func (o *FilesystemOutput) CreateSymlink(ctx context.Context, relativePath strin
 	return nil
 }
 
+// rewriteSymlinkTarget returns the symlink target with SymlinkRewriteFrom prefix replaced with SymlinkRewriteTo.
+func (o *FilesystemOutput) rewriteSymlinkTarget(target string) string {
+	if o.SymlinkRewriteFrom == "" || !filepath.IsAbs(target) {
+		return target
+	}
+
+	from := filepath.Clean(o.SymlinkRewriteFrom)
+
+	rel, err := filepath.Rel(from, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// target is not under the rewritten prefix.
+		return target
+	}
+
+	return filepath.Join(o.SymlinkRewriteTo, rel)
+}
+
 func fileIsSymlink(stat os.FileInfo) bool {
 	return stat.Mode()&os.ModeSymlink != 0
 }
 
 // SymlinkExists implements restore.Output interface.
 func (o *FilesystemOutput) SymlinkExists(ctx context.Context, relativePath string, e fs.Symlink) bool {
-	st, err := os.Lstat(filepath.Join(o.TargetPath, relativePath))
+	st, err := os.Lstat(o.outputPath(relativePath))
 	if err != nil {
 		return false
 	}
@@ -229,14 +363,56 @@ func (o *FilesystemOutput) setAttributes(targetPath string, e fs.Entry, modclear
 		}
 	}
 
+	if bt := o.birthTimeToRestore(le, e); !bt.IsZero() {
+		if err = o.maybeIgnorePermissionError(setBirthTime(targetPath, bt, isSymlink(e))); err != nil && !errors.Is(err, errBirthTimeUnsupported) {
+			return errors.Wrap(err, "could not change birth time on "+targetPath)
+		}
+	}
+
 	return nil
 }
 
+// birthTimeToRestore returns the birth time of the remote entry if it should be restored or zero time otherwise.
+func (o *FilesystemOutput) birthTimeToRestore(local, remote fs.Entry) time.Time {
+	if !o.RestoreBirthTimes || o.SkipTimes {
+		return time.Time{}
+	}
+
+	rb, ok := remote.(fs.EntryWithBirthTime)
+	if !ok {
+		return time.Time{}
+	}
+
+	bt := rb.BirthTime()
+
+	if lb, ok := local.(fs.EntryWithBirthTime); ok && lb.BirthTime().Equal(bt) {
+		return time.Time{}
+	}
+
+	return bt
+}
+
 func isSymlink(e fs.Entry) bool {
 	_, ok := e.(fs.Symlink)
 	return ok
 }
 
+// writeProvenance records SnapshotProvenance in an extended attribute of the provided file,
+// silently skipping platforms and filesystems that don't support extended attributes.
+func (o *FilesystemOutput) writeProvenance(ctx context.Context, path string) error {
+	if o.SnapshotProvenance == "" {
+		return nil
+	}
+
+	err := setXattr(path, ProvenanceXattrName, []byte(o.SnapshotProvenance))
+	if errors.Is(err, errXattrUnsupported) {
+		log(ctx).Debugf("unable to record provenance of %v: %v", path, err)
+		return nil
+	}
+
+	return o.maybeIgnorePermissionError(err)
+}
+
 func (o *FilesystemOutput) maybeIgnorePermissionError(err error) error {
 	if o.IgnorePermissionErrors && os.IsPermission(err) {
 		return nil
@@ -300,13 +476,25 @@ func (o *FilesystemOutput) createDirectory(ctx context.Context, path string) err
 }
 
 func (o *FilesystemOutput) copyFileContent(ctx context.Context, targetPath string, f fs.File) error {
-	switch _, err := os.Stat(targetPath); {
+	switch st, err := os.Stat(targetPath); {
 	case os.IsNotExist(err): // copy file below
 	case err == nil:
 		if !o.OverwriteFiles {
 			return errors.Errorf("unable to create %q, it already exists", targetPath)
 		}
 
+		if o.OverwriteOnlyIfDifferent && fileMetadataMatches(st, f) {
+			same, err := o.existingContentMatches(ctx, targetPath, f)
+			if err != nil {
+				return err
+			}
+
+			if same {
+				log(ctx).Debugf("Not overwriting existing file which matches snapshot: %v", targetPath)
+				return nil
+			}
+		}
+
 		log(ctx).Debugf("Overwriting existing file: %v", targetPath)
 	default:
 		return errors.Wrap(err, "failed to stat "+targetPath)
@@ -324,6 +512,49 @@ func (o *FilesystemOutput) copyFileContent(ctx context.Context, targetPath strin
 	return atomicfile.Write(targetPath, r)
 }
 
+// existingContentMatches returns true if the content of the existing file matches the snapshot file
+// or if content verification is disabled.
+func (o *FilesystemOutput) existingContentMatches(ctx context.Context, targetPath string, f fs.File) (bool, error) {
+	if !o.VerifyContentBeforeSkip {
+		return true, nil
+	}
+
+	existing, err := os.Open(targetPath) //nolint:gosec
+	if err != nil {
+		return false, errors.Wrap(err, "unable to open existing file")
+	}
+
+	defer existing.Close() //nolint:errcheck,gosec
+
+	existingHash, err := hashContent(existing)
+	if err != nil {
+		return false, errors.Wrap(err, "error hashing existing file "+targetPath)
+	}
+
+	r, err := f.Open(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to open snapshot file for "+targetPath)
+	}
+	defer r.Close() //nolint:errcheck
+
+	snapshotHash, err := hashContent(r)
+	if err != nil {
+		return false, errors.Wrap(err, "error hashing snapshot file for "+targetPath)
+	}
+
+	return bytes.Equal(existingHash, snapshotHash), nil
+}
+
+func hashContent(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, errors.Wrap(err, "read error")
+	}
+
+	return h.Sum(nil), nil
+}
+
 func isEmptyDirectory(name string) (bool, error) {
 	f, err := os.Open(name) //nolint:gosec
 	if err != nil {
@@ -0,0 +1,11 @@
+// +build !windows
+
+package restore
+
+import (
+	"time"
+)
+
+func setBirthTime(path string, btime time.Time, symlink bool) error {
+	return errBirthTimeUnsupported
+}
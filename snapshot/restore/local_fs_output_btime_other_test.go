@@ -0,0 +1,14 @@
+// +build !windows
+
+package restore
+
+import (
+	"testing"
+	"time"
+)
+
+func verifyBirthTimeRestored(t *testing.T, path string, want time.Time) {
+	t.Helper()
+
+	t.Skip("setting birth time is not supported on this platform")
+}
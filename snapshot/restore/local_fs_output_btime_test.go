@@ -0,0 +1,49 @@
+package restore
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/localfs"
+)
+
+// entryWithBirthTime overrides birth time of the wrapped entry.
+type entryWithBirthTime struct {
+	fs.Entry
+
+	btime time.Time
+}
+
+func (e entryWithBirthTime) BirthTime() time.Time {
+	return e.btime
+}
+
+func TestRestoreBirthTime(t *testing.T) {
+	td := t.TempDir()
+	src := filepath.Join(td, "src")
+	dst := filepath.Join(td, "dst")
+
+	require.NoError(t, ioutil.WriteFile(src, []byte{1, 2, 3}, 0o600))
+	require.NoError(t, ioutil.WriteFile(dst, []byte{1, 2, 3}, 0o600))
+
+	srcEntry, err := localfs.NewEntry(src)
+	require.NoError(t, err)
+
+	want := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	o := &FilesystemOutput{
+		TargetPath:        td,
+		SkipOwners:        true,
+		RestoreBirthTimes: true,
+	}
+
+	// must succeed regardless of platform support.
+	require.NoError(t, o.setAttributes(dst, entryWithBirthTime{srcEntry, want}, 0))
+
+	verifyBirthTimeRestored(t, dst, want)
+}
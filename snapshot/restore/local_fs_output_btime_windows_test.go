@@ -0,0 +1,20 @@
+package restore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/localfs"
+)
+
+func verifyBirthTimeRestored(t *testing.T, path string, want time.Time) {
+	t.Helper()
+
+	e, err := localfs.NewEntry(path)
+	require.NoError(t, err)
+
+	require.True(t, e.(fs.EntryWithBirthTime).BirthTime().Equal(want))
+}
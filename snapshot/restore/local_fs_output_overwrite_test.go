@@ -0,0 +1,256 @@
+package restore
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestOverwriteOnlyIfDifferent(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	modTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	root := mockfs.NewDirectory()
+	f := root.AddFile("f", []byte("abc"), 0o644)
+	f.SetModTime(modTime)
+
+	out := &FilesystemOutput{
+		TargetPath:               t.TempDir(),
+		OverwriteFiles:           true,
+		OverwriteOnlyIfDifferent: true,
+		SkipOwners:               true,
+	}
+
+	fname := filepath.Join(out.TargetPath, "f")
+
+	// replaces the contents of the restored file while keeping the metadata that restore compares.
+	tamper := func(content string) {
+		t.Helper()
+
+		require.NoError(t, ioutil.WriteFile(fname, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(fname, modTime, modTime))
+	}
+
+	verifyContent := func(want string) {
+		t.Helper()
+
+		got, err := ioutil.ReadFile(fname)
+		require.NoError(t, err)
+		require.Equal(t, want, string(got))
+	}
+
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("abc")
+
+	// size and modification time match, the file is not rewritten.
+	tamper("xyz")
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("xyz")
+
+	// size differs, the file is rewritten.
+	tamper("wxyz")
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("abc")
+
+	// modification time differs, the file is rewritten.
+	tamper("xyz")
+	require.NoError(t, os.Chtimes(fname, modTime, modTime.Add(time.Hour)))
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("abc")
+
+	// with content verification, metadata match is not enough.
+	out.VerifyContentBeforeSkip = true
+
+	tamper("xyz")
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("abc")
+
+	st1, err := os.Stat(fname)
+	require.NoError(t, err)
+
+	// matching content is skipped, which is observable because the file is not replaced.
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("abc")
+
+	st2, err := os.Stat(fname)
+	require.NoError(t, err)
+	require.True(t, os.SameFile(st1, st2))
+
+	// without OverwriteOnlyIfDifferent the file is always rewritten.
+	out.OverwriteOnlyIfDifferent = false
+
+	tamper("xyz")
+	require.NoError(t, out.WriteFile(ctx, "f", f))
+	verifyContent("abc")
+}
+
+func TestRemoveExtraEntries(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	root := mockfs.NewDirectory()
+	root.AddFile("f", []byte("abc"), 0o644)
+	root.AddDir("sub", 0o755).AddFile("g", []byte("def"), 0o644)
+
+	cases := []struct {
+		desc               string
+		removeExtraEntries bool
+		want               []string
+	}{
+		{"extras kept", false, []string{"extra", "f", "sub", "sub/extra2", "sub/g"}},
+		{"extras removed", true, []string{"f", "sub", "sub/g"}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			target := t.TempDir()
+
+			require.NoError(t, os.MkdirAll(filepath.Join(target, "sub"), 0o755))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(target, "f"), []byte("old"), 0o644))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(target, "extra"), []byte("extra"), 0o644))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(target, "sub", "extra2"), []byte("extra2"), 0o644))
+
+			out := &FilesystemOutput{
+				TargetPath:           target,
+				OverwriteDirectories: true,
+				OverwriteFiles:       true,
+				SkipOwners:           true,
+				RemoveExtraEntries:   tc.removeExtraEntries,
+			}
+
+			_, err := Entry(ctx, nil, out, root, Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, listTree(t, target))
+
+			got, err := ioutil.ReadFile(filepath.Join(target, "f"))
+			require.NoError(t, err)
+			require.Equal(t, "abc", string(got))
+		})
+	}
+}
+
+func TestRemoveExtraEntriesRequiresOverwriteDirectories(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	root := mockfs.NewDirectory()
+	root.AddFile("f", []byte("abc"), 0o644)
+
+	target := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "extra"), []byte("extra"), 0o644))
+
+	out := &FilesystemOutput{
+		TargetPath:         target,
+		SkipOwners:         true,
+		RemoveExtraEntries: true,
+	}
+
+	// without permission to overwrite directories nothing is restored or pruned.
+	_, err := Entry(ctx, nil, out, root, Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.Error(t, err)
+	require.Equal(t, []string{"extra"}, listTree(t, target))
+
+	// FinishDirectory on its own doesn't prune either.
+	require.NoError(t, out.FinishDirectory(ctx, "", root))
+	require.Equal(t, []string{"extra"}, listTree(t, target))
+}
+
+func TestRestoreDirectoryTimesAfterChildren(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	rootTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	subTime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+	fileTime := time.Date(2019, 11, 12, 13, 14, 15, 0, time.UTC)
+
+	root := mockfs.NewDirectory()
+	root.SetModTime(rootTime)
+
+	sub := root.AddDir("sub", 0o755)
+	sub.SetModTime(subTime)
+	sub.AddFile("f", []byte("abc"), 0o644).SetModTime(fileTime)
+
+	target := t.TempDir()
+
+	// leftovers which are removed from restored directories while restoring their children.
+	require.NoError(t, os.MkdirAll(filepath.Join(target, "sub"), 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "sub", "f"+localfs.ShallowEntrySuffix), []byte("placeholder"), 0o644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "sub", "extra"), []byte("extra"), 0o644))
+
+	out := &FilesystemOutput{
+		TargetPath:           target,
+		OverwriteDirectories: true,
+		OverwriteFiles:       true,
+		SkipOwners:           true,
+		RemoveExtraEntries:   true,
+	}
+
+	_, err := Entry(ctx, nil, out, root, Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.NoError(t, err)
+	require.Equal(t, []string{"sub", "sub/f"}, listTree(t, target))
+
+	for rel, want := range map[string]time.Time{
+		".":     rootTime,
+		"sub":   subTime,
+		"sub/f": fileTime,
+	} {
+		st, err := os.Stat(filepath.Join(target, rel))
+		require.NoError(t, err)
+		require.True(t, st.ModTime().Equal(want), "invalid modification time of %v: %v, want %v", rel, st.ModTime(), want)
+	}
+}
+
+func TestRestoreDirectoryTimesAfterFailedChildren(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	rootTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	subTime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+
+	root := mockfs.NewDirectory()
+	root.SetModTime(rootTime)
+
+	sub := root.AddDir("sub", 0o755)
+	sub.SetModTime(subTime)
+	sub.AddFile("f", []byte("abc"), 0o644)
+	sub.AddDir("broken", 0o755).FailReaddir(errors.New("some error"))
+
+	for _, opt := range []Options{
+		{ContinueOnError: true},
+		{IgnoreErrors: true},
+	} {
+		target := t.TempDir()
+
+		out := &FilesystemOutput{
+			TargetPath:           target,
+			OverwriteDirectories: true,
+			SkipOwners:           true,
+		}
+
+		opt.Parallel = 1
+		opt.RestoreDirEntryAtDepth = math.MaxInt32
+
+		// directories containing entries which failed to restore are still finished.
+		_, err := Entry(ctx, nil, out, root, opt)
+		require.Equal(t, opt.ContinueOnError, err != nil)
+		require.Equal(t, []string{"sub", "sub/broken", "sub/f"}, listTree(t, target))
+
+		for rel, want := range map[string]time.Time{
+			".":   rootTime,
+			"sub": subTime,
+		} {
+			st, err := os.Stat(filepath.Join(target, rel))
+			require.NoError(t, err)
+			require.True(t, st.ModTime().Equal(want), "invalid modification time of %v: %v, want %v", rel, st.ModTime(), want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package restore
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/localfs"
+)
+
+const (
+	stagingDirSuffix  = ".kopia-staging"
+	replacedDirSuffix = ".kopia-replaced"
+)
+
+// outputPath returns the local path where the entry with a given relative path is written.
+func (o *FilesystemOutput) outputPath(relativePath string) string {
+	root := o.TargetPath
+	if o.StagingMode {
+		root = siblingPath(o.TargetPath, stagingDirSuffix)
+	}
+
+	return filepath.Join(root, filepath.FromSlash(relativePath))
+}
+
+// siblingPath returns the path of a hidden sibling of the provided path with a given suffix.
+func siblingPath(path, suffix string) string {
+	path = filepath.Clean(path)
+
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+suffix)
+}
+
+// maybePrepareStaging ensures that the staged root entry can later replace TargetPath and removes any
+// staging directory left behind by a previous failed restore. It is a no-op except for the root entry.
+func (o *FilesystemOutput) maybePrepareStaging(relativePath string, e fs.Entry) error {
+	if !o.StagingMode || relativePath != "" {
+		return nil
+	}
+
+	if o.OverwriteOnlyIfDifferent {
+		return errors.New("overwriting only different files is not supported in staging mode")
+	}
+
+	switch st, err := os.Lstat(o.TargetPath); {
+	case os.IsNotExist(err):
+	case err != nil:
+		return errors.Wrap(err, "failed to stat target path")
+	case st.IsDir() && e.IsDir():
+		// the staged tree replaces the whole target, which would drop any entries not in the snapshot.
+		if !o.OverwriteDirectories || !o.RemoveExtraEntries {
+			if empty, _ := isEmptyDirectory(o.TargetPath); !empty {
+				return errors.Errorf("non-empty directory already exists, not replacing it without removing extra entries: %q", o.TargetPath)
+			}
+		}
+	case st.IsDir() || e.IsDir():
+		return errors.Errorf("unable to replace %q with an entry of a different type", o.TargetPath)
+	case !o.OverwriteFiles:
+		return errors.Errorf("unable to create %q, it already exists", o.TargetPath)
+	}
+
+	// nolint:wrapcheck
+	return os.RemoveAll(o.outputPath(""))
+}
+
+func (o *FilesystemOutput) stagingEnabled() bool {
+	return o.StagingMode
+}
+
+// discardStaging removes the staged tree leaving TargetPath untouched.
+func (o *FilesystemOutput) discardStaging(ctx context.Context) error {
+	log(ctx).Debugf("discarding staged tree %v", o.outputPath(""))
+
+	return errors.Wrap(os.RemoveAll(o.outputPath("")), "unable to remove staged tree")
+}
+
+// commitStaging moves the staged tree into place over TargetPath.
+func (o *FilesystemOutput) commitStaging(ctx context.Context) error {
+	staged := o.outputPath("")
+
+	if _, err := os.Lstat(staged); os.IsNotExist(err) {
+		// nothing was restored.
+		return nil
+	}
+
+	replaced := siblingPath(o.TargetPath, replacedDirSuffix)
+
+	if err := os.RemoveAll(replaced); err != nil {
+		return errors.Wrap(err, "unable to remove leftover replaced directory")
+	}
+
+	switch err := os.Rename(o.TargetPath, replaced); {
+	case os.IsNotExist(err):
+		replaced = ""
+	case err != nil:
+		// target can't be moved aside, for example because it is a mount point.
+		log(ctx).Debugf("unable to move %v aside, copying staged tree instead: %v", o.TargetPath, err)
+		return o.copyStagedTree(ctx, staged)
+	}
+
+	if err := os.Rename(staged, o.TargetPath); err != nil {
+		if replaced != "" {
+			if rerr := os.Rename(replaced, o.TargetPath); rerr != nil {
+				return errors.Wrapf(rerr, "unable to put back original %v after failed rename (%v)", o.TargetPath, err)
+			}
+		}
+
+		log(ctx).Debugf("unable to rename %v, copying staged tree instead: %v", staged, err)
+
+		return o.copyStagedTree(ctx, staged)
+	}
+
+	if replaced == "" {
+		return nil
+	}
+
+	return errors.Wrap(os.RemoveAll(replaced), "unable to remove replaced directory")
+}
+
+// copyStagedTree is a fallback for when the staged tree can't be renamed into place, for example
+// when it would cross devices. It copies the staged tree over TargetPath and removes it.
+func (o *FilesystemOutput) copyStagedTree(ctx context.Context, staged string) error {
+	e, err := localfs.NewEntry(staged)
+	if err != nil {
+		return errors.Wrap(err, "unable to read staged tree")
+	}
+
+	out := *o
+	out.StagingMode = false
+	out.OverwriteDirectories = true
+	out.OverwriteFiles = true
+	out.OverwriteSymlinks = true
+	out.OverwriteOnlyIfDifferent = false
+
+	if _, err := Entry(ctx, nil, &out, e, Options{RestoreDirEntryAtDepth: math.MaxInt32}); err != nil {
+		return errors.Wrap(err, "unable to copy staged tree")
+	}
+
+	return errors.Wrap(os.RemoveAll(staged), "unable to remove staged tree")
+}
@@ -0,0 +1,274 @@
+package restore
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func makeStagingTestTree() *mockfs.Directory {
+	root := mockfs.NewDirectory()
+	root.AddFile("f1", []byte("f1"), 0o644)
+	root.AddDir("d1", 0o755).AddFile("f2", []byte("f2"), 0o644)
+	root.AddDir("d2", 0o755).AddFile("f3", []byte("f3"), 0o644)
+
+	return root
+}
+
+// listTree returns sorted relative paths of all entries under the provided directory.
+func listTree(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var result []string
+
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." {
+			result = append(result, filepath.ToSlash(rel))
+		}
+
+		return nil
+	}))
+
+	sort.Strings(result)
+
+	return result
+}
+
+func TestStagingModeNeverExposesPartialTree(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	require.NoError(t, os.MkdirAll(target, 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "old"), []byte("old"), 0o644))
+
+	root := makeStagingTestTree()
+
+	var observed [][]string
+
+	observe := func() {
+		observed = append(observed, listTree(t, target))
+	}
+
+	root.Subdir("d1").OnReaddir(observe)
+	root.Subdir("d2").OnReaddir(observe)
+
+	out := &FilesystemOutput{
+		TargetPath:           target,
+		OverwriteDirectories: true,
+		OverwriteFiles:       true,
+		RemoveExtraEntries:   true,
+		SkipOwners:           true,
+		StagingMode:          true,
+	}
+
+	_, err := Entry(ctx, nil, out, root, Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.NoError(t, err)
+
+	// while restoring, the target only ever has its original contents.
+	require.NotEmpty(t, observed)
+
+	for _, o := range observed {
+		require.Equal(t, []string{"old"}, o)
+	}
+
+	// once done, the target is entirely replaced and no temporary directories are left behind.
+	require.Equal(t, []string{"d1", "d1/f2", "d2", "d2/f3", "f1"}, listTree(t, target))
+	require.NoDirExists(t, siblingPath(target, stagingDirSuffix))
+	require.NoDirExists(t, siblingPath(target, replacedDirSuffix))
+}
+
+func TestStagingModeFailureLeavesTargetUntouched(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	require.NoError(t, os.MkdirAll(target, 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "old"), []byte("old"), 0o644))
+
+	root := makeStagingTestTree()
+	root.Subdir("d2").FailReaddir(errors.New("some error"))
+
+	out := &FilesystemOutput{
+		TargetPath:           target,
+		OverwriteDirectories: true,
+		OverwriteFiles:       true,
+		RemoveExtraEntries:   true,
+		SkipOwners:           true,
+		StagingMode:          true,
+	}
+
+	_, err := Entry(ctx, nil, out, root, Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.Error(t, err)
+	require.Equal(t, []string{"old"}, listTree(t, target))
+
+	// the next restore discards anything staged by the failed one.
+	root.Subdir("d2").FailReaddir(nil)
+
+	_, err = Entry(ctx, nil, out, root, Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.NoError(t, err)
+	require.Equal(t, []string{"d1", "d1/f2", "d2", "d2/f3", "f1"}, listTree(t, target))
+	require.NoDirExists(t, siblingPath(target, stagingDirSuffix))
+}
+
+func TestStagingModeRespectsOverwriteDirectories(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	require.NoError(t, os.MkdirAll(target, 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "old"), []byte("old"), 0o644))
+
+	out := &FilesystemOutput{
+		TargetPath:  target,
+		SkipOwners:  true,
+		StagingMode: true,
+	}
+
+	_, err := Entry(ctx, nil, out, makeStagingTestTree(), Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.Error(t, err)
+	require.Equal(t, []string{"old"}, listTree(t, target))
+	require.NoDirExists(t, siblingPath(target, stagingDirSuffix))
+}
+
+func TestStagingModeRequiresRemoveExtraEntries(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	require.NoError(t, os.MkdirAll(target, 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "old"), []byte("old"), 0o644))
+
+	out := &FilesystemOutput{
+		TargetPath:           target,
+		OverwriteDirectories: true,
+		OverwriteFiles:       true,
+		SkipOwners:           true,
+		StagingMode:          true,
+	}
+
+	_, err := Entry(ctx, nil, out, makeStagingTestTree(), Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.Error(t, err)
+	require.Equal(t, []string{"old"}, listTree(t, target))
+
+	// empty directory can be replaced.
+	require.NoError(t, os.Remove(filepath.Join(target, "old")))
+
+	_, err = Entry(ctx, nil, out, makeStagingTestTree(), Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.NoError(t, err)
+	require.Equal(t, []string{"d1", "d1/f2", "d2", "d2/f3", "f1"}, listTree(t, target))
+}
+
+func TestStagingModeDiscardsIncompleteRestore(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	cases := map[string]func(root *mockfs.Directory, opt *Options){
+		"continue-on-error": func(root *mockfs.Directory, opt *Options) {
+			opt.ContinueOnError = true
+
+			root.Subdir("d2").FailReaddir(errors.New("some error"))
+		},
+		"canceled": func(root *mockfs.Directory, opt *Options) {
+			var once sync.Once
+
+			opt.Cancel = make(chan struct{})
+
+			root.Subdir("d1").OnReaddir(func() { once.Do(func() { close(opt.Cancel) }) })
+		},
+	}
+
+	for name, setup := range cases {
+		setup := setup
+
+		t.Run(name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "target")
+
+			require.NoError(t, os.MkdirAll(target, 0o755))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(target, "old"), []byte("old"), 0o644))
+
+			root := makeStagingTestTree()
+			opt := Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32}
+
+			setup(root, &opt)
+
+			out := &FilesystemOutput{
+				TargetPath:           target,
+				OverwriteDirectories: true,
+				OverwriteFiles:       true,
+				RemoveExtraEntries:   true,
+				SkipOwners:           true,
+				StagingMode:          true,
+			}
+
+			_, err := Entry(ctx, nil, out, root, opt)
+			require.Error(t, err)
+			require.Equal(t, []string{"old"}, listTree(t, target))
+			require.NoDirExists(t, siblingPath(target, stagingDirSuffix))
+		})
+	}
+}
+
+func TestStagingModeRejectsIncremental(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	out := &FilesystemOutput{
+		TargetPath:  target,
+		SkipOwners:  true,
+		StagingMode: true,
+	}
+
+	_, err := Entry(ctx, nil, out, makeStagingTestTree(), Options{Parallel: 1, Incremental: true, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.Error(t, err)
+
+	out.OverwriteOnlyIfDifferent = true
+
+	_, err = Entry(ctx, nil, out, makeStagingTestTree(), Options{Parallel: 1, RestoreDirEntryAtDepth: math.MaxInt32})
+	require.Error(t, err)
+	require.NoDirExists(t, target)
+}
+
+func TestStagingModeCopyFallback(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	target := filepath.Join(t.TempDir(), "target")
+	staged := siblingPath(target, stagingDirSuffix)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(staged, "d1"), 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(staged, "d1", "f2"), []byte("f2"), 0o644))
+	require.NoError(t, os.Symlink("d1/f2", filepath.Join(staged, "link")))
+
+	out := &FilesystemOutput{
+		TargetPath:  target,
+		SkipOwners:  true,
+		StagingMode: true,
+	}
+
+	require.NoError(t, out.copyStagedTree(ctx, staged))
+	require.Equal(t, []string{"d1", "d1/f2", "link"}, listTree(t, target))
+	require.NoDirExists(t, staged)
+
+	link, err := os.Readlink(filepath.Join(target, "link"))
+	require.NoError(t, err)
+	require.Equal(t, "d1/f2", link)
+}
@@ -0,0 +1,85 @@
+// +build !windows
+
+package restore
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestRestoreSymlinkRewrite(t *testing.T) {
+	root := mockfs.NewDirectory()
+	root.AddFile("file", []byte{1, 2, 3}, 0o644)
+	root.AddSymlink("abs-under-root", "/orig/root/file", 0o777)
+	root.AddSymlink("abs-root-itself", "/orig/root", 0o777)
+	root.AddSymlink("abs-outside", "/etc/passwd", 0o777)
+	root.AddSymlink("abs-sibling-prefix", "/orig/rootfile", 0o777)
+	root.AddSymlink("abs-escaping", "/orig/root/../file", 0o777)
+	root.AddSymlink("relative", "../orig/root/file", 0o777)
+
+	cases := []struct {
+		name     string
+		from, to string
+		want     map[string]string
+	}{
+		{
+			name: "no rewrite",
+			want: map[string]string{
+				"abs-under-root":     "/orig/root/file",
+				"abs-root-itself":    "/orig/root",
+				"abs-outside":        "/etc/passwd",
+				"abs-sibling-prefix": "/orig/rootfile",
+				"abs-escaping":       "/orig/root/../file",
+				"relative":           "../orig/root/file",
+			},
+		},
+		{
+			name: "rewrite",
+			from: "/orig/root/",
+			to:   "/new/place",
+			want: map[string]string{
+				"abs-under-root":     "/new/place/file",
+				"abs-root-itself":    "/new/place",
+				"abs-outside":        "/etc/passwd",
+				"abs-sibling-prefix": "/orig/rootfile",
+				"abs-escaping":       "/orig/root/../file",
+				"relative":           "../orig/root/file",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := testlogging.Context(t)
+
+			out := &FilesystemOutput{
+				TargetPath:         t.TempDir(),
+				SkipOwners:         true,
+				SkipPermissions:    true,
+				SymlinkRewriteFrom: tc.from,
+				SymlinkRewriteTo:   tc.to,
+			}
+
+			_, err := Entry(ctx, nil, out, root, Options{
+				Parallel:               1,
+				RestoreDirEntryAtDepth: math.MaxInt32,
+			})
+			require.NoError(t, err)
+
+			for name, want := range tc.want {
+				got, err := os.Readlink(filepath.Join(out.TargetPath, name))
+				require.NoError(t, err, name)
+				require.Equal(t, want, got, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package restore
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestRestoreRecordsProvenance(t *testing.T) {
+	ctx := testlogging.Context(t)
+	td := t.TempDir()
+
+	probe := filepath.Join(td, "probe")
+	require.NoError(t, ioutil.WriteFile(probe, nil, 0o600))
+
+	if err := unix.Setxattr(probe, ProvenanceXattrName, []byte("x"), 0); errors.Is(err, unix.ENOTSUP) {
+		t.Skip("user extended attributes are not supported in temporary directory")
+	}
+
+	out := &FilesystemOutput{
+		TargetPath:         filepath.Join(td, "out"),
+		SkipOwners:         true,
+		SnapshotProvenance: "kabcdef/some/path",
+	}
+
+	_, err := Entry(ctx, nil, out, makeTestTree(2, 3), Options{
+		Parallel:               1,
+		RestoreDirEntryAtDepth: math.MaxInt32,
+	})
+	require.NoError(t, err)
+
+	for _, fname := range []string{"dir0/file0", "dir0/file2", "dir1/file1"} {
+		buf := make([]byte, 100)
+
+		n, err := unix.Getxattr(filepath.Join(out.TargetPath, fname), ProvenanceXattrName, buf)
+		require.NoError(t, err, fname)
+		require.Equal(t, "kabcdef/some/path", string(buf[:n]), fname)
+	}
+}
+
+func TestRestoreWithoutProvenance(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	out := &FilesystemOutput{
+		TargetPath: t.TempDir(),
+		SkipOwners: true,
+	}
+
+	_, err := Entry(ctx, nil, out, makeTestTree(1, 1), Options{
+		Parallel:               1,
+		RestoreDirEntryAtDepth: math.MaxInt32,
+	})
+	require.NoError(t, err)
+
+	_, err = unix.Getxattr(filepath.Join(out.TargetPath, "dir0/file0"), ProvenanceXattrName, make([]byte, 100))
+	require.Error(t, err)
+}
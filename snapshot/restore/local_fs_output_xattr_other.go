@@ -0,0 +1,7 @@
+// +build !linux
+
+package restore
+
+func setXattr(path, name string, value []byte) error {
+	return errXattrUnsupported
+}
@@ -4,6 +4,7 @@
 	"context"
 	"path"
 	"runtime"
+	"sync"
 	"sync/atomic"
 
 	"github.com/pkg/errors"
@@ -44,6 +45,15 @@ type Stats struct {
 	EnqueuedSymlinkCount int32
 	SkippedCount         int32
 	IgnoredErrorCount    int32
+
+	// FailedEntries contains entries which failed to restore when Options.ContinueOnError is set.
+	FailedEntries []EntryError
+}
+
+// EntryError describes an error restoring a single entry.
+type EntryError struct {
+	Path  string
+	Error error
 }
 
 func (s *Stats) clone() Stats {
@@ -68,9 +78,12 @@ type Options struct {
 	Parallel               int   `json:"parallel"`
 	Incremental            bool  `json:"incremental"`
 	IgnoreErrors           bool  `json:"ignoreErrors"`
+	ContinueOnError        bool  `json:"continueOnError"`
 	RestoreDirEntryAtDepth int32 `json:"restoreDirEntryAtDepth"`
 	MinSizeForPlaceholder  int32 `json:"minSizeForPlaceholder"`
 
+	// ProgressCallback is invoked periodically during restore and once more at the end with
+	// final statistics (or partial statistics when the restore has been canceled).
 	ProgressCallback func(ctx context.Context, s Stats)
 	Cancel           chan struct{} // channel that can be externally closed to signal cancelation
 }
@@ -83,13 +96,17 @@ func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs
 		q:             parallelwork.NewQueue(),
 		incremental:   options.Incremental,
 		ignoreErrors:  options.IgnoreErrors,
+		continueOnErr: options.ContinueOnError,
 		cancel:        options.Cancel,
+		progress:      options.ProgressCallback,
+	}
+
+	if so, ok := output.(stagedOutput); ok && so.stagingEnabled() && options.Incremental {
+		return Stats{}, errors.New("incremental restore is not supported in staging mode")
 	}
 
 	c.q.ProgressCallback = func(ctx context.Context, enqueued, active, completed int64) {
-		if options.ProgressCallback != nil {
-			options.ProgressCallback(ctx, c.stats.clone())
-		}
+		c.reportProgress(ctx)
 	}
 
 	// Control the depth of a restore. Default (options.MaxDepth = 0) is to restore to full depth.
@@ -109,14 +126,81 @@ func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs
 	}
 
 	if err := c.q.Process(ctx, numWorkers); err != nil {
+		c.discardStaged(ctx)
+
+		if ctx.Err() != nil {
+			// context canceled - return statistics for the work that has been completed so far.
+			c.reportProgress(ctx)
+
+			return c.currentStats(), errors.Wrap(ctx.Err(), "restore canceled")
+		}
+
 		return Stats{}, errors.Wrap(err, "restore error")
 	}
 
+	c.reportProgress(ctx)
+
+	st := c.currentStats()
+
+	// staged output is only committed when everything has been restored.
+	if len(st.FailedEntries) > 0 || c.isCanceled() {
+		if c.discardStaged(ctx) {
+			if err := restoreIncompleteError(st); err != nil {
+				return st, err
+			}
+
+			return st, errors.New("restore canceled, staged output discarded")
+		}
+	}
+
 	if err := c.output.Close(ctx); err != nil {
 		return Stats{}, errors.Wrap(err, "error closing output")
 	}
 
-	return c.stats, nil
+	return st, restoreIncompleteError(st)
+}
+
+// stagedOutput is implemented by outputs which can restore into a staging area and
+// commit it on Close.
+type stagedOutput interface {
+	stagingEnabled() bool
+	discardStaging(ctx context.Context) error
+}
+
+// discardStaged discards anything staged by the output and returns true if the output is staged.
+func (c *copier) discardStaged(ctx context.Context) bool {
+	so, ok := c.output.(stagedOutput)
+	if !ok || !so.stagingEnabled() {
+		return false
+	}
+
+	if err := so.discardStaging(ctx); err != nil {
+		log(ctx).Errorf("unable to discard staged output: %v", err)
+	}
+
+	return true
+}
+
+func (c *copier) isCanceled() bool {
+	if c.cancel == nil {
+		return false
+	}
+
+	select {
+	case <-c.cancel:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func restoreIncompleteError(st Stats) error {
+	if n := len(st.FailedEntries); n > 0 {
+		return errors.Errorf("%v entries failed to restore, first error: %v: %v", n, st.FailedEntries[0].Path, st.FailedEntries[0].Error)
+	}
+
+	return nil
 }
 
 type copier struct {
@@ -126,10 +210,36 @@ type copier struct {
 	q             *parallelwork.Queue
 	incremental   bool
 	ignoreErrors  bool
+	continueOnErr bool
 	cancel        chan struct{}
+	progress      func(ctx context.Context, s Stats)
+
+	failedMutex   sync.Mutex
+	failedEntries []EntryError
+}
+
+func (c *copier) reportProgress(ctx context.Context) {
+	if c.progress != nil {
+		c.progress(ctx, c.currentStats())
+	}
+}
+
+func (c *copier) currentStats() Stats {
+	st := c.stats.clone()
+
+	c.failedMutex.Lock()
+	st.FailedEntries = append([]EntryError(nil), c.failedEntries...)
+	c.failedMutex.Unlock()
+
+	return st
 }
 
 func (c *copier) copyEntry(ctx context.Context, e fs.Entry, targetPath string, currentdepth, maxdepth int32, onCompletion func() error) error {
+	if err := ctx.Err(); err != nil {
+		// context canceled - abort the walk, the queue will stop dispatching remaining work.
+		return errors.Wrap(err, "restore canceled")
+	}
+
 	if c.cancel != nil {
 		select {
 		case <-c.cancel:
@@ -161,21 +271,47 @@ func (c *copier) copyEntry(ctx context.Context, e fs.Entry, targetPath string, c
 		}
 	}
 
-	err := c.copyEntryInternal(ctx, e, targetPath, currentdepth, maxdepth, onCompletion)
+	// track whether the entry has signaled its completion, so that it can be signaled
+	// exactly once when the error is ignored, otherwise the parent directory would never be finished.
+	var completed int32
+
+	err := c.copyEntryInternal(ctx, e, targetPath, currentdepth, maxdepth, func() error {
+		atomic.StoreInt32(&completed, 1)
+		return onCompletion()
+	})
 	if err == nil {
 		return nil
 	}
 
-	if c.ignoreErrors {
+	if c.ignoreErrors && ctx.Err() == nil {
 		atomic.AddInt32(&c.stats.IgnoredErrorCount, 1)
 		log(ctx).Errorf("ignored error %v on %v", err, targetPath)
 
-		return nil
+		return c.completeFailedEntry(&completed, onCompletion)
+	}
+
+	if c.continueOnErr && ctx.Err() == nil {
+		log(ctx).Errorf("error restoring %v: %v", targetPath, err)
+
+		c.failedMutex.Lock()
+		c.failedEntries = append(c.failedEntries, EntryError{Path: targetPath, Error: err})
+		c.failedMutex.Unlock()
+
+		return c.completeFailedEntry(&completed, onCompletion)
 	}
 
 	return err
 }
 
+// completeFailedEntry signals completion of an entry which failed to restore, unless it has already done so.
+func (c *copier) completeFailedEntry(completed *int32, onCompletion func() error) error {
+	if atomic.LoadInt32(completed) != 0 {
+		return nil
+	}
+
+	return onCompletion()
+}
+
 func (c *copier) copyEntryInternal(ctx context.Context, e fs.Entry, targetPath string, currentdepth, maxdepth int32, onCompletion func() error) error {
 	switch e := e.(type) {
 	case fs.Directory:
@@ -0,0 +1,220 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// countingOutput is an Output that does not write anything but counts files and invokes
+// a callback after each one.
+type countingOutput struct {
+	mu          sync.Mutex
+	filesCopied int
+
+	onFile func()
+}
+
+func (o *countingOutput) Parallelizable() bool { return true }
+
+func (o *countingOutput) BeginDirectory(ctx context.Context, relativePath string, e fs.Directory) error {
+	return nil
+}
+
+func (o *countingOutput) WriteDirEntry(ctx context.Context, relativePath string, de *snapshot.DirEntry, e fs.Directory) error {
+	return nil
+}
+
+func (o *countingOutput) FinishDirectory(ctx context.Context, relativePath string, e fs.Directory) error {
+	return nil
+}
+
+func (o *countingOutput) WriteFile(ctx context.Context, relativePath string, e fs.File) error {
+	o.mu.Lock()
+	o.filesCopied++
+	o.mu.Unlock()
+
+	if o.onFile != nil {
+		o.onFile()
+	}
+
+	return nil
+}
+
+func (o *countingOutput) FileExists(ctx context.Context, relativePath string, e fs.File) bool {
+	return false
+}
+
+func (o *countingOutput) CreateSymlink(ctx context.Context, relativePath string, e fs.Symlink) error {
+	return nil
+}
+
+func (o *countingOutput) SymlinkExists(ctx context.Context, relativePath string, e fs.Symlink) bool {
+	return false
+}
+
+func (o *countingOutput) Close(ctx context.Context) error {
+	return nil
+}
+
+func makeTestTree(numDirs, filesPerDir int) *mockfs.Directory {
+	root := mockfs.NewDirectory()
+
+	for i := 0; i < numDirs; i++ {
+		d := root.AddDir(fmt.Sprintf("dir%v", i), 0o755)
+
+		for j := 0; j < filesPerDir; j++ {
+			d.AddFile(fmt.Sprintf("file%v", j), []byte{1, 2, 3}, 0o644)
+		}
+	}
+
+	return root
+}
+
+func TestRestoreProgress(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var (
+		mu        sync.Mutex
+		lastStats Stats
+	)
+
+	out := &countingOutput{}
+
+	st, err := Entry(ctx, nil, out, makeTestTree(3, 5), Options{
+		Parallel:               2,
+		RestoreDirEntryAtDepth: math.MaxInt32,
+		ProgressCallback: func(ctx context.Context, s Stats) {
+			mu.Lock()
+			lastStats = s
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("restore error: %v", err)
+	}
+
+	if got, want := st.RestoredFileCount, int32(15); got != want {
+		t.Fatalf("unexpected restored file count: %v, want %v", got, want)
+	}
+
+	// final progress report must reflect final stats.
+	if !reflect.DeepEqual(lastStats, st) {
+		t.Fatalf("last progress report %+v does not match final stats %+v", lastStats, st)
+	}
+}
+
+func TestRestoreCancelation(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlogging.Context(t))
+	defer cancel()
+
+	const (
+		numDirs     = 100
+		filesPerDir = 100
+	)
+
+	out := &countingOutput{
+		onFile: cancel,
+	}
+
+	var progressReports int
+
+	st, err := Entry(ctx, nil, out, makeTestTree(numDirs, filesPerDir), Options{
+		Parallel:               1,
+		RestoreDirEntryAtDepth: math.MaxInt32,
+		ProgressCallback: func(ctx context.Context, s Stats) {
+			progressReports++
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v, want %v", err, context.Canceled)
+	}
+
+	if st.RestoredFileCount == 0 {
+		t.Fatalf("expected partial stats, got %+v", st)
+	}
+
+	if st.RestoredFileCount >= numDirs*filesPerDir {
+		t.Fatalf("restore did not stop promptly, restored %v files", st.RestoredFileCount)
+	}
+
+	if out.filesCopied != 1 {
+		t.Fatalf("unexpected number of files copied after cancelation: %v", out.filesCopied)
+	}
+
+	if progressReports == 0 {
+		t.Fatalf("progress was not reported")
+	}
+}
+
+// failingOutput is a countingOutput that fails to write files with the provided paths.
+type failingOutput struct {
+	countingOutput
+
+	failPaths map[string]bool
+}
+
+func (o *failingOutput) WriteFile(ctx context.Context, relativePath string, e fs.File) error {
+	if o.failPaths[relativePath] {
+		return errors.Errorf("simulated read error")
+	}
+
+	return o.countingOutput.WriteFile(ctx, relativePath, e)
+}
+
+func TestRestoreContinueOnError(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	root := makeTestTree(3, 5)
+	root.Subdir("dir2").FailReaddir(errors.New("simulated readdir error"))
+
+	out := &failingOutput{
+		failPaths: map[string]bool{"dir1/file2": true},
+	}
+
+	st, err := Entry(ctx, nil, out, root, Options{
+		Parallel:               2,
+		RestoreDirEntryAtDepth: math.MaxInt32,
+		ContinueOnError:        true,
+	})
+	if err == nil {
+		t.Fatalf("expected aggregate error")
+	}
+
+	// all siblings of the failed file were still restored.
+	if got, want := out.filesCopied, 9; got != want {
+		t.Fatalf("unexpected number of files copied: %v, want %v", got, want)
+	}
+
+	failed := map[string]bool{}
+	for _, fe := range st.FailedEntries {
+		failed[fe.Path] = true
+	}
+
+	if want := map[string]bool{"dir1/file2": true, "dir2": true}; !reflect.DeepEqual(failed, want) {
+		t.Fatalf("unexpected failed entries: %v, want %v", st.FailedEntries, want)
+	}
+
+	// without ContinueOnError the restore fails without reporting failed entries.
+	st, err = Entry(ctx, nil, &failingOutput{failPaths: out.failPaths}, makeTestTree(3, 5), Options{
+		Parallel:               1,
+		RestoreDirEntryAtDepth: math.MaxInt32,
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if len(st.FailedEntries) != 0 {
+		t.Fatalf("unexpected failed entries: %v", st.FailedEntries)
+	}
+}
@@ -75,7 +75,7 @@ func (o *ShallowFilesystemOutput) WriteFile(ctx context.Context, relativePath st
 const readonlyfilemode = 0222
 
 func (o *ShallowFilesystemOutput) writeShallowEntry(ctx context.Context, relativePath string, de *snapshot.DirEntry) (string, error) {
-	path := filepath.Join(o.TargetPath, filepath.FromSlash(relativePath))
+	path := o.outputPath(relativePath)
 	if _, err := os.Lstat(path); err == nil {
 		// Having both a placeholder and a real will cause snapshot to fail. But
 		// removing the real path risks destroying data forever.
@@ -89,6 +89,10 @@ func (o *ShallowFilesystemOutput) writeShallowEntry(ctx context.Context, relativ
 		return "", errors.Wrap(err, "error writing placeholder")
 	}
 
+	if err := o.writeProvenance(ctx, placeholderpath); err != nil {
+		return "", errors.Wrap(err, "error writing provenance")
+	}
+
 	return placeholderpath, nil
 }
 
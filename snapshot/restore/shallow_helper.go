@@ -19,7 +19,8 @@ func PathIfPlaceholder(path string) string {
 }
 
 // SafeRemoveAll removes the shallow placeholder file(s) for path if they
-// exist without experiencing errors caused by long file names.
+// exist without experiencing errors caused by long file names. The path
+// itself is never removed.
 func SafeRemoveAll(path string) error {
 	if SafelySuffixablePath(path) {
 		// nolint:wrapcheck
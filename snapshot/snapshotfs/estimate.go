@@ -3,7 +3,6 @@
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/fs/ignorefs"
@@ -133,7 +132,8 @@ func estimate(ctx context.Context, relativePath string, entry fs.Entry, policyTr
 			progress.Error(ctx, relativePath, err, isIgnored)
 		} else {
 			for _, child := range children {
-				if err := estimate(ctx, filepath.Join(relativePath, child.Name()), child, policyTree.Child(child.Name()), stats, ib, eb, ed, progress, maxExamplesPerBucket); err != nil {
+				// use the same format of relative paths as ignorefs, so that included and excluded files are consistent.
+				if err := estimate(ctx, relativePath+"/"+child.Name(), child, policyTree.Child(child.Name()), stats, ib, eb, ed, progress, maxExamplesPerBucket); err != nil {
 					return err
 				}
 			}
@@ -142,6 +142,10 @@ func estimate(ctx context.Context, relativePath string, entry fs.Entry, policyTr
 		progress.Stats(ctx, stats, ib, eb, *ed, false)
 
 	case fs.File:
+		if fp, ok := progress.(EstimateFileProgress); ok {
+			fp.IncludedFile(relativePath, entry.Size())
+		}
+
 		ib.add(relativePath, entry.Size(), maxExamplesPerBucket)
 		stats.TotalFileCount++
 		stats.TotalFileSize += entry.Size()
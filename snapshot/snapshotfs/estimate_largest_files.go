@@ -0,0 +1,81 @@
+package snapshotfs
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// EstimateFileProgress can be optionally implemented by EstimateProgress to be notified about
+// each file that would be included in the snapshot.
+type EstimateFileProgress interface {
+	IncludedFile(relativePath string, size int64)
+}
+
+// FileWithSize describes a single file discovered during estimation.
+type FileWithSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// LargestFiles keeps track of N largest files using bounded amount of memory.
+type LargestFiles struct {
+	max int
+	h   fileSizeMinHeap
+}
+
+// Add registers the provided file, evicting the smallest file if more than N files are tracked.
+func (l *LargestFiles) Add(relativePath string, size int64) {
+	if l.max <= 0 {
+		return
+	}
+
+	if len(l.h) < l.max {
+		heap.Push(&l.h, FileWithSize{relativePath, size})
+		return
+	}
+
+	if size > l.h[0].Size {
+		l.h[0] = FileWithSize{relativePath, size}
+		heap.Fix(&l.h, 0)
+	}
+}
+
+// Files returns the largest files sorted by descending size.
+func (l *LargestFiles) Files() []FileWithSize {
+	result := append([]FileWithSize(nil), l.h...)
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+
+		return result[i].Path < result[j].Path
+	})
+
+	return result
+}
+
+// NewLargestFiles returns LargestFiles that tracks up to n largest files.
+func NewLargestFiles(n int) *LargestFiles {
+	return &LargestFiles{max: n}
+}
+
+// fileSizeMinHeap implements heap.Interface with the smallest file at the root.
+type fileSizeMinHeap []FileWithSize
+
+func (h fileSizeMinHeap) Len() int           { return len(h) }
+func (h fileSizeMinHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h fileSizeMinHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *fileSizeMinHeap) Push(x interface{}) {
+	*h = append(*h, x.(FileWithSize))
+}
+
+func (h *fileSizeMinHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+
+	return x
+}
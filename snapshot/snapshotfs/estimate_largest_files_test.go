@@ -0,0 +1,47 @@
+package snapshotfs
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLargestFiles(t *testing.T) {
+	l := NewLargestFiles(3)
+	require.Empty(t, l.Files())
+
+	l.Add("a", 10)
+	l.Add("b", 30)
+	require.Equal(t, []FileWithSize{{"b", 30}, {"a", 10}}, l.Files())
+
+	l.Add("c", 20)
+	l.Add("d", 5)
+	l.Add("e", 40)
+	l.Add("f", 20)
+	require.Equal(t, []FileWithSize{{"e", 40}, {"b", 30}, {"c", 20}}, l.Files())
+
+	// zero means no files are tracked.
+	l0 := NewLargestFiles(0)
+	l0.Add("a", 10)
+	require.Empty(t, l0.Files())
+}
+
+func TestLargestFilesRandom(t *testing.T) {
+	const n = 10
+
+	l := NewLargestFiles(n)
+
+	perm := rand.Perm(1000)
+	for _, v := range perm {
+		l.Add(fmt.Sprintf("f%v", v), int64(v))
+	}
+
+	files := l.Files()
+	require.Len(t, files, n)
+
+	for i, f := range files {
+		require.Equal(t, int64(999-i), f.Size)
+	}
+}
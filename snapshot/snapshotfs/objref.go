@@ -129,6 +129,18 @@ func latestManifest(mans []*snapshot.Manifest) *snapshot.Manifest {
 	return latest
 }
 
+// FindSnapshotByIDWithPath returns the snapshot manifest that the root of the provided ID with optional
+// nested path resolves to or nil if the root is an object that does not belong to any snapshot.
+func FindSnapshotByIDWithPath(ctx context.Context, rep repo.Repository, rootID string, consistentAttributes bool) (*snapshot.Manifest, error) {
+	pathElements := strings.Split(rootID, "/")
+
+	if len(pathElements) > 1 {
+		consistentAttributes = false
+	}
+
+	return findSnapshotByRootObjectIDOrManifestID(ctx, rep, pathElements[0], consistentAttributes)
+}
+
 // FilesystemEntryFromIDWithPath returns a filesystem entry for the provided object ID, which
 // can be a snapshot manifest ID or an object ID with path.
 // If multiple snapshots match and they don't agree on root object attributes and consistentAttributes==true
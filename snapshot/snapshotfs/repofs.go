@@ -56,6 +56,15 @@ func (e *repositoryEntry) ModTime() time.Time {
 	return e.metadata.ModTime
 }
 
+// BirthTime implements fs.EntryWithBirthTime.
+func (e *repositoryEntry) BirthTime() time.Time {
+	if e.metadata.BirthTime == nil {
+		return time.Time{}
+	}
+
+	return *e.metadata.BirthTime
+}
+
 func (e *repositoryEntry) ObjectID() object.ID {
 	return e.metadata.ObjectID
 }
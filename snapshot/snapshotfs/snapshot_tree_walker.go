@@ -22,6 +22,9 @@ type TreeWalker struct {
 	// EntryID extracts or generates an id from an fs.Entry.
 	// It can be used to eliminate duplicate entries when in a FS
 	EntryID func(entry fs.Entry) interface{}
+	// ProgressCallback is periodically invoked with the number of discovered and processed entries,
+	// by default progress is logged, nil disables progress reporting.
+	ProgressCallback func(ctx context.Context, enqueued, active, completed int64)
 
 	enqueued sync.Map
 	queue    *parallelwork.Queue
@@ -62,9 +65,7 @@ func (w *TreeWalker) Run(ctx context.Context) error {
 		w.enqueueEntry(ctx, root)
 	}
 
-	w.queue.ProgressCallback = func(ctx context.Context, enqueued, active, completed int64) {
-		log(ctx).Infof("  Processed %v contents, discovered %v...", completed, enqueued)
-	}
+	w.queue.ProgressCallback = w.ProgressCallback
 
 	// nolint:wrapcheck
 	return w.queue.Process(ctx, w.Parallelism)
@@ -74,6 +75,9 @@ func (w *TreeWalker) Run(ctx context.Context) error {
 func NewTreeWalker() *TreeWalker {
 	return &TreeWalker{
 		Parallelism: walkersPerCPU * runtime.NumCPU(),
-		queue:       parallelwork.NewQueue(),
+		ProgressCallback: func(ctx context.Context, enqueued, active, completed int64) {
+			log(ctx).Infof("  Processed %v contents, discovered %v...", completed, enqueued)
+		},
+		queue: parallelwork.NewQueue(),
 	}
 }
@@ -22,6 +22,7 @@
 	"github.com/kopia/kopia/fs/ignorefs"
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
@@ -44,6 +45,9 @@
 	IncompleteReasonLimitReached = "limit reached"
 )
 
+// DefaultLargeFileParallelHashingMinSize is the default minimum size of a file to be hashed in parallel.
+const DefaultLargeFileParallelHashingMinSize = 64 << 20
+
 // Uploader supports efficient uploading files and directories to repository.
 type Uploader struct {
 	// values aligned to 8-bytes due to atomic access
@@ -62,6 +66,13 @@ type Uploader struct {
 	// Number of files to hash and upload in parallel.
 	ParallelUploads int
 
+	// Number of chunks of a single large file to hash and upload in parallel, 0 or 1 disables it.
+	// Each chunk being hashed is held in memory, which bounds memory usage per file.
+	LargeFileParallelHashing int
+
+	// Minimum size of a file to be hashed in parallel, defaults to DefaultLargeFileParallelHashingMinSize.
+	LargeFileParallelHashingMinSize int64
+
 	// Enable snapshot actions
 	EnableActions bool
 
@@ -71,6 +82,9 @@ type Uploader struct {
 	// How frequently to create checkpoint snapshot entries.
 	CheckpointInterval time.Duration
 
+	// Compression algorithm to use instead of the one specified by policy, empty means use policy.
+	OverrideCompressor compression.Name
+
 	repo repo.RepositoryWriter
 
 	// stats must be allocated on heap to enforce 64-bit alignment due to atomic access on ARM.
@@ -135,8 +149,8 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
 		Description: "FILE:" + f.Name(),
-		Compressor:  pol.CompressionPolicy.CompressorForFile(f),
-		AsyncWrites: asyncWrites,
+		Compressor:  u.compressorForFile(pol, f),
+		AsyncWrites: u.asyncWritesForFile(f, asyncWrites),
 	})
 	defer writer.Close() //nolint:errcheck
 
@@ -184,6 +198,18 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 	return de, nil
 }
 
+// compressorForFile returns the compressor for a given file, honoring OverrideCompressor while still
+// applying remaining compression policy rules such as size limits and extension lists.
+func (u *Uploader) compressorForFile(pol *policy.Policy, f fs.File) compression.Name {
+	cp := pol.CompressionPolicy
+
+	if u.OverrideCompressor != "" {
+		cp.CompressorName = u.OverrideCompressor
+	}
+
+	return cp.CompressorForFile(f)
+}
+
 func (u *Uploader) uploadSymlinkInternal(ctx context.Context, relativePath string, f fs.Symlink) (*snapshot.DirEntry, error) {
 	u.Progress.HashingFile(relativePath)
 	defer u.Progress.FinishedHashingFile(relativePath, f.Size())
@@ -342,7 +368,7 @@ func newDirEntry(md fs.Entry, oid object.ID) (*snapshot.DirEntry, error) {
 		return nil, errors.Errorf("invalid entry type %T", md)
 	}
 
-	return &snapshot.DirEntry{
+	de := &snapshot.DirEntry{
 		Name:        md.Name(),
 		Type:        entryType,
 		Permissions: snapshot.Permissions(md.Mode() & os.ModePerm),
@@ -351,7 +377,15 @@ func newDirEntry(md fs.Entry, oid object.ID) (*snapshot.DirEntry, error) {
 		UserID:      md.Owner().UserID,
 		GroupID:     md.Owner().GroupID,
 		ObjectID:    oid,
-	}, nil
+	}
+
+	if bt, ok := md.(fs.EntryWithBirthTime); ok {
+		if t := bt.BirthTime(); !t.IsZero() {
+			de.BirthTime = &t
+		}
+	}
+
+	return de, nil
 }
 
 // uploadFileWithCheckpointing uploads the specified File to the repository.
@@ -802,6 +836,25 @@ func (u *Uploader) effectiveParallelUploads() int {
 	return p
 }
 
+// asyncWritesForFile returns the number of asynchronous writes to use when uploading the provided file,
+// which is increased for large files when parallel hashing is enabled.
+func (u *Uploader) asyncWritesForFile(f fs.File, asyncWrites int) int {
+	if u.LargeFileParallelHashing <= 1 {
+		return asyncWrites
+	}
+
+	minSize := u.LargeFileParallelHashingMinSize
+	if minSize == 0 {
+		minSize = DefaultLargeFileParallelHashingMinSize
+	}
+
+	if f.Size() < minSize || asyncWrites >= u.LargeFileParallelHashing {
+		return asyncWrites
+	}
+
+	return u.LargeFileParallelHashing
+}
+
 func (u *Uploader) processNonDirectories(ctx context.Context, parentCheckpointRegistry *checkpointRegistry, parentDirBuilder *dirManifestBuilder, dirRelativePath string, entries fs.Entries, policyTree *policy.Tree, prevEntries []fs.Entries) error {
 	workerCount := u.effectiveParallelUploads()
 
@@ -858,6 +911,11 @@ func (u *Uploader) processNonDirectories(ctx context.Context, parentCheckpointRe
 			atomic.AddInt32(&u.stats.NonCachedFiles, 1)
 
 			de, err := u.uploadFileInternal(ctx, parentCheckpointRegistry, entryRelativePath, entry, policyTree.Child(entry.Name()).EffectivePolicy(), asyncWritesPerFile)
+			if errors.Is(err, errCanceled) {
+				// file interrupted by cancellation is left out of the partial snapshot, not reported as an error.
+				return err
+			}
+
 			if err != nil {
 				isIgnoredError := policyTree.EffectivePolicy().ErrorHandlingPolicy.IgnoreFileErrorsOrDefault(false)
 
@@ -884,6 +942,10 @@ func (u *Uploader) processNonDirectories(ctx context.Context, parentCheckpointRe
 			atomic.AddInt32(&u.stats.NonCachedFiles, 1)
 
 			de, err := u.uploadStreamingFileInternal(ctx, entryRelativePath, entry)
+			if errors.Is(err, errCanceled) {
+				return err
+			}
+
 			if err != nil {
 				isIgnoredError := policyTree.EffectivePolicy().ErrorHandlingPolicy.IgnoreFileErrorsOrDefault(false)
 
@@ -1014,6 +1076,7 @@ func uploadDirInternal(
 	}
 
 	childCheckpointRegistry := &checkpointRegistry{}
+	metadataComp := policyTree.EffectivePolicy().CompressionPolicy.MetadataCompressor()
 
 	thisCheckpointRegistry.addCheckpointCallback(directory, func() (*snapshot.DirEntry, error) {
 		// when snapshotting the parent, snapshot all our children and tell them to populate
@@ -1026,7 +1089,7 @@ func uploadDirInternal(
 		}
 
 		checkpointManifest := thisCheckpointBuilder.Build(directory.ModTime(), IncompleteReasonCheckpoint)
-		oid, err := u.writeDirManifest(ctx, dirRelativePath, checkpointManifest)
+		oid, err := u.writeDirManifest(ctx, dirRelativePath, checkpointManifest, metadataComp)
 		if err != nil {
 			return nil, errors.Wrap(err, "error writing dir manifest")
 		}
@@ -1041,7 +1104,7 @@ func uploadDirInternal(
 
 	dirManifest := thisDirBuilder.Build(directory.ModTime(), u.incompleteReason())
 
-	oid, err := u.writeDirManifest(ctx, dirRelativePath, dirManifest)
+	oid, err := u.writeDirManifest(ctx, dirRelativePath, dirManifest, metadataComp)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error writing dir manifest: %v", directory.Name())
 	}
@@ -1049,10 +1112,12 @@ func uploadDirInternal(
 	return newDirEntryWithSummary(directory, oid, dirManifest.Summary)
 }
 
-func (u *Uploader) writeDirManifest(ctx context.Context, dirRelativePath string, dirManifest *snapshot.DirManifest) (object.ID, error) {
+func (u *Uploader) writeDirManifest(ctx context.Context, dirRelativePath string, dirManifest *snapshot.DirManifest, metadataComp compression.Name) (object.ID, error) {
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
-		Description: "DIR:" + dirRelativePath,
-		Prefix:      objectIDPrefixDirectory,
+		Description:        "DIR:" + dirRelativePath,
+		Prefix:             objectIDPrefixDirectory,
+		Compressor:         metadataComp,
+		MetadataCompressor: metadataComp,
 	})
 
 	defer writer.Close() //nolint:errcheck
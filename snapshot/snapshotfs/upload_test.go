@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"crypto/rand"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -21,6 +22,7 @@
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob/filesystem"
+	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
@@ -548,6 +550,66 @@ func TestUploadWithCheckpointing(t *testing.T) {
 	}
 }
 
+func TestUploadWithCheckpointInterval(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	u := NewUploader(th.repo)
+	u.CheckpointInterval = 10 * time.Millisecond
+	u.disableEstimation = true
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	si := snapshot.SourceInfo{
+		UserName: "user",
+		Host:     "host",
+		Path:     "path",
+	}
+
+	// block the upload in the middle until a periodic checkpoint has been written.
+	th.sourceDir.Subdir("d2").OnReaddir(func() {
+		deadline := clock.Now().Add(10 * time.Second)
+
+		for clock.Now().Before(deadline) {
+			snapshots, err := snapshot.ListSnapshots(ctx, th.repo, si)
+			require.NoError(t, err)
+
+			if len(snapshots) > 0 {
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		t.Errorf("checkpoint was not created")
+	})
+
+	man, err := u.Upload(ctx, th.sourceDir, policyTree, si)
+	require.NoError(t, err)
+
+	snapshots, err := snapshot.ListSnapshots(ctx, th.repo, si)
+	require.NoError(t, err)
+	require.NotEmpty(t, snapshots)
+
+	for _, sn := range snapshots {
+		require.Equal(t, IncompleteReasonCheckpoint, sn.IncompleteReason)
+	}
+
+	// final snapshot supersedes all checkpoints.
+	_, err = snapshot.SaveSnapshot(ctx, th.repo, man)
+	require.NoError(t, err)
+
+	_, err = policy.ApplyRetentionPolicy(ctx, th.repo, si, true)
+	require.NoError(t, err)
+
+	snapshots, err = snapshot.ListSnapshots(ctx, th.repo, si)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	require.Empty(t, snapshots[0].IncompleteReason)
+}
+
 func TestUploadScanStopsOnContextCancel(t *testing.T) {
 	ctx := testlogging.Context(t)
 	th := newUploadTestHarness(ctx, t)
@@ -667,3 +729,151 @@ func TestUpload_VirtualDirectoryWithStreamingFile(t *testing.T) {
 		t.Fatalf("unexpected manifest file count: %v, want %v", got, want)
 	}
 }
+
+func TestUploadLargeFileParallelHashing(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	data := make([]byte, 20<<20)
+
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	large := th.sourceDir.AddFile("large", data, defaultPermissions)
+	small := mockfs.NewDirectory().AddFile("small", []byte{1}, defaultPermissions)
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	serial := NewUploader(th.repo)
+	require.Equal(t, 0, serial.asyncWritesForFile(large, 0))
+
+	s1, err := serial.Upload(ctx, th.sourceDir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	parallel := NewUploader(th.repo)
+	parallel.LargeFileParallelHashing = 4
+	parallel.LargeFileParallelHashingMinSize = 1 << 20
+	require.Equal(t, 4, parallel.asyncWritesForFile(large, 0))
+	require.Equal(t, 0, parallel.asyncWritesForFile(small, 0))
+
+	s2, err := parallel.Upload(ctx, th.sourceDir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.Equal(t, s1.RootObjectID(), s2.RootObjectID())
+	require.Equal(t, int32(0), s2.Stats.CachedFiles)
+}
+
+func TestUploadDirectoryMetadataCompression(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	// returns the compression header of the root directory manifest, each upload uses a fresh repository
+	// since identical directory manifests are deduplicated regardless of compression.
+	dirCompressionHeaderID := func(comp compression.Name) compression.HeaderID {
+		t.Helper()
+
+		th := newUploadTestHarness(ctx, t)
+		defer th.cleanup()
+
+		pol := *policy.DefaultPolicy
+		pol.CompressionPolicy.MetadataCompressorName = comp
+
+		man, err := NewUploader(th.repo).Upload(ctx, th.sourceDir, policy.BuildTree(nil, &pol), snapshot.SourceInfo{})
+		require.NoError(t, err)
+
+		cid, isCompressed, ok := man.RootObjectID().ContentID()
+		require.True(t, ok)
+
+		cr := th.repo.(repo.DirectRepository).ContentReader()
+		if !cr.SupportsContentCompression() {
+			if isCompressed {
+				return compression.ByName[comp].HeaderID()
+			}
+
+			return 0
+		}
+
+		info, err := cr.ContentInfo(ctx, cid)
+		require.NoError(t, err)
+
+		return info.GetCompressionHeaderID()
+	}
+
+	require.Equal(t, compression.HeaderID(0), dirCompressionHeaderID(""))
+	require.Equal(t, compression.HeaderID(0), dirCompressionHeaderID("none"))
+	require.Equal(t, compression.ByName["zstd-fastest"].HeaderID(), dirCompressionHeaderID("zstd-fastest"))
+}
+
+// cancelingFile is a file which cancels the upload as soon as its contents are read.
+type cancelingFile struct {
+	fs.File
+	u *Uploader
+}
+
+func (f cancelingFile) Open(ctx context.Context) (fs.Reader, error) {
+	r, err := f.File.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cancelingReader{r, f.u}, nil
+}
+
+type cancelingReader struct {
+	fs.Reader
+	u *Uploader
+}
+
+func (r cancelingReader) Read(b []byte) (int, error) {
+	r.u.Cancel()
+
+	return r.Reader.Read(b)
+}
+
+func TestUploadCanceledProducesResumablePartialSnapshot(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	u := NewUploader(th.repo)
+	u.disableEstimation = true
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+	si := snapshot.SourceInfo{UserName: "user", Host: "host", Path: "path"}
+
+	// directories are uploaded before files, so 'd1' completes before 'big' cancels the upload mid-way.
+	big := mockfs.NewDirectory().AddFile("big", make([]byte, 4*copyBufferSize), defaultPermissions)
+	root := virtualfs.NewStaticDirectory("root", fs.Entries{
+		th.sourceDir.Subdir("d1"),
+		cancelingFile{big, u},
+	})
+
+	man, err := u.Upload(ctx, root, policyTree, si)
+	require.NoError(t, err)
+	require.Equal(t, IncompleteReasonCanceled, man.IncompleteReason)
+	require.Zero(t, man.RootEntry.DirSummary.FatalErrorCount)
+
+	_, err = snapshot.SaveSnapshot(ctx, th.repo, man)
+	require.NoError(t, err)
+
+	saved, err := snapshot.ListSnapshots(ctx, th.repo, si)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.Equal(t, IncompleteReasonCanceled, saved[0].IncompleteReason)
+
+	// resuming from the partial snapshot reuses all files uploaded before cancellation.
+	u2 := NewUploader(th.repo)
+	u2.disableEstimation = true
+
+	man2, err := u2.Upload(ctx, virtualfs.NewStaticDirectory("root", fs.Entries{
+		th.sourceDir.Subdir("d1"),
+		big,
+	}), policyTree, si, saved...)
+	require.NoError(t, err)
+	require.Empty(t, man2.IncompleteReason)
+	// all files but the one interrupted by cancellation.
+	require.Equal(t, man.Stats.NonCachedFiles-1, man2.Stats.CachedFiles)
+	require.Equal(t, int32(1), man2.Stats.NonCachedFiles)
+}
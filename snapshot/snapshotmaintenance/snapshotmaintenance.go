@@ -7,23 +7,47 @@
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/snapshot/snapshotgc"
 )
 
+var log = logging.GetContextLoggerFunc("snapshotmaintenance")
+
 // Run runs the complete snapshot and repository maintenance.
 func Run(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, force bool, safety maintenance.SafetyParameters) error {
 	// nolint:wrapcheck
 	return maintenance.RunExclusive(ctx, dr, mode, force,
 		func(runParams maintenance.RunParameters) error {
-			// run snapshot GC before full maintenance
-			if runParams.Mode == maintenance.ModeFull {
-				if _, err := snapshotgc.Run(ctx, dr, true, safety); err != nil {
-					return errors.Wrap(err, "snapshot GC failure")
-				}
+			sum := &Summary{
+				Mode:  runParams.Mode,
+				Start: dr.Time(),
 			}
 
-			// nolint:wrapcheck
-			return maintenance.Run(ctx, runParams, safety)
+			err := runInternal(ctx, dr, runParams, safety, sum)
+
+			if serr := writeSummary(ctx, dr, sum, err); serr != nil {
+				log(ctx).Errorf("unable to write maintenance summary: %v", serr)
+			}
+
+			return err
 		})
 }
+
+func runInternal(ctx context.Context, dr repo.DirectRepositoryWriter, runParams maintenance.RunParameters, safety maintenance.SafetyParameters, sum *Summary) error {
+	// run snapshot GC before full maintenance
+	if runParams.Mode == maintenance.ModeFull {
+		st, err := snapshotgc.Run(ctx, dr, true, safety)
+		if err != nil {
+			return errors.Wrap(err, "snapshot GC failure")
+		}
+
+		sum.SnapshotGC = &st
+	}
+
+	res, err := maintenance.Run(ctx, runParams, safety)
+	sum.RunResult = res
+
+	// nolint:wrapcheck
+	return err
+}
@@ -292,3 +292,49 @@ func mustSnapshot(t *testing.T, r repo.RepositoryWriter, source fs.Entry, si sna
 
 	return s1
 }
+
+func TestMaintenanceSummary(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t)
+
+	sum, err := snapshotmaintenance.GetLastSummary(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Nil(t, sum)
+
+	th.sourceDir.AddFile("f1", []byte{1, 2, 3, 4}, defaultPermissions)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+
+	mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.MustReopen(t)
+
+	sum, err = snapshotmaintenance.GetLastSummary(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.NotNil(t, sum)
+
+	require.Equal(t, maintenance.ModeFull, sum.Mode)
+	require.True(t, sum.Success)
+	require.True(t, sum.End.After(sum.Start))
+	require.NotNil(t, sum.SnapshotGC)
+	require.Contains(t, sum.Tasks, maintenance.TaskType(maintenance.TaskSnapshotGarbageCollection))
+	require.Contains(t, sum.Tasks, maintenance.TaskType(maintenance.TaskRewriteContentsFull))
+
+	// running maintenance again replaces the summary.
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeQuick, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	sum2, err := snapshotmaintenance.GetLastSummary(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Equal(t, maintenance.ModeQuick, sum2.Mode)
+	require.Nil(t, sum2.SnapshotGC)
+	require.True(t, sum2.Start.After(sum.End))
+}
@@ -0,0 +1,83 @@
+package snapshotmaintenance
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot/snapshotgc"
+)
+
+// Summary is a durable record of the most recent maintenance run, stored in the repository
+// together with maintenance schedule, so that recording it does not write any index or pack blobs.
+type Summary struct {
+	Mode    maintenance.Mode `json:"mode"`
+	Start   time.Time        `json:"start"`
+	End     time.Time        `json:"end"`
+	Success bool             `json:"success,omitempty"`
+	Error   string           `json:"error,omitempty"`
+
+	// Tasks contains information about maintenance tasks that ran as part of this maintenance run.
+	Tasks map[maintenance.TaskType]maintenance.RunInfo `json:"tasks,omitempty"`
+
+	SnapshotGC *snapshotgc.Stats `json:"snapshotGC,omitempty"`
+
+	maintenance.RunResult
+}
+
+// writeSummary completes the provided summary and writes it to the repository replacing previous summary.
+func writeSummary(ctx context.Context, dr repo.DirectRepositoryWriter, sum *Summary, runErr error) error {
+	sum.End = dr.Time()
+
+	if runErr != nil {
+		sum.Error = runErr.Error()
+	} else {
+		sum.Success = true
+	}
+
+	s, err := maintenance.GetSchedule(ctx, dr)
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance schedule")
+	}
+
+	for taskType, runs := range s.Runs {
+		// runs are ordered from newest to oldest.
+		if len(runs) > 0 && !runs[0].Start.Before(sum.Start) {
+			if sum.Tasks == nil {
+				sum.Tasks = map[maintenance.TaskType]maintenance.RunInfo{}
+			}
+
+			sum.Tasks[taskType] = runs[0]
+		}
+	}
+
+	s.LastRunSummary, err = json.Marshal(sum)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize summary")
+	}
+
+	return errors.Wrap(maintenance.SetSchedule(ctx, dr, s), "unable to set maintenance schedule")
+}
+
+// GetLastSummary returns the summary of the most recent maintenance run or nil if maintenance never ran.
+func GetLastSummary(ctx context.Context, rep repo.DirectRepository) (*Summary, error) {
+	s, err := maintenance.GetSchedule(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get maintenance schedule")
+	}
+
+	if len(s.LastRunSummary) == 0 {
+		return nil, nil
+	}
+
+	sum := &Summary{}
+	if err := json.Unmarshal(s.LastRunSummary, sum); err != nil {
+		return nil, errors.Wrap(err, "malformed maintenance summary")
+	}
+
+	return sum, nil
+}
@@ -87,6 +87,62 @@ func TestCompression(t *testing.T) {
 	}
 }
 
+func TestCompressionOverride(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	supportsContentLevelCompression := containsLineStartingWith(
+		e.RunAndExpectSuccess(t, "repo", "status"),
+		"Content compression: true",
+	)
+
+	isCompressed := func(oid string) bool {
+		if !supportsContentLevelCompression {
+			return strings.HasPrefix(oid, "Z")
+		}
+
+		for _, l := range e.RunAndExpectSuccess(t, "content", "ls", "-c") {
+			if strings.HasPrefix(l, oid) {
+				return strings.Contains(l, "pgzip")
+			}
+		}
+
+		t.Fatalf("content %v not found", oid)
+
+		return false
+	}
+
+	dataDir := testutil.TempDirectory(t)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, "some-file1"), []byte(strings.Repeat("hello world\n", 100)), 0o600))
+
+	// global policy does not compress, override it for a single snapshot.
+	e.RunAndExpectSuccess(t, "snapshot", "create", dataDir, "--compression", "pgzip")
+
+	sources := clitestutil.ListSnapshotsAndExpectSuccess(t, e)
+	entries := clitestutil.ListDirectory(t, e, sources[0].Snapshots[0].ObjectID)
+	require.True(t, isCompressed(entries[0].ObjectID), "expected compressed object %v", entries[0].ObjectID)
+
+	// policy must remain unchanged, so next snapshot of a new file is not compressed.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, "some-file2"), []byte(strings.Repeat("how are you\n", 100)), 0o600))
+	require.NotContains(t, strings.Join(e.RunAndExpectSuccess(t, "policy", "show", "--global"), "\n"), "pgzip")
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", dataDir)
+
+	sources = clitestutil.ListSnapshotsAndExpectSuccess(t, e)
+	entries = clitestutil.ListDirectory(t, e, sources[0].Snapshots[1].ObjectID)
+	require.Equal(t, "some-file2", entries[1].Name)
+	require.False(t, isCompressed(entries[1].ObjectID), "expected uncompressed object %v", entries[1].ObjectID)
+
+	e.RunAndExpectFailure(t, "snapshot", "create", dataDir, "--compression", "no-such-compressor")
+}
+
 func containsLineStartingWith(lines []string, prefix string) bool {
 	for _, l := range lines {
 		if strings.HasPrefix(l, prefix) {
@@ -96,3 +152,29 @@ func containsLineStartingWith(lines []string, prefix string) bool {
 
 	return false
 }
+
+func TestMetadataCompressionPolicyShow(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression", "none", "--metadata-compression", "zstd")
+
+	out := strings.Join(e.RunAndExpectSuccess(t, "policy", "show", "--global"), "\n")
+	require.Contains(t, out, "Compression:\n  Metadata compressor: \"zstd\"")
+	require.Contains(t, out, "  File compression disabled.")
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression", "pgzip")
+
+	out = strings.Join(e.RunAndExpectSuccess(t, "policy", "show", "--global"), "\n")
+	require.Contains(t, out, "Compression:\n  Compressor: \"pgzip\"")
+	require.Contains(t, out, "\n  Metadata compressor: \"zstd\"")
+	require.NotContains(t, out, "File compression disabled.")
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression", "none", "--metadata-compression", "none")
+	require.Contains(t, e.RunAndExpectSuccess(t, "policy", "show", "--global"), "Compression disabled.")
+}
@@ -51,6 +51,8 @@ func testContentListAndStats(t *testing.T, indexVersion string) {
 	require.True(t, containsLineStartingWith(e.RunAndExpectSuccess(t, "content", "list", "--summary"), "Total: "))
 
 	e.RunAndExpectSuccess(t, "content", "stats")
+	require.True(t, containsLineStartingWith(e.RunAndExpectSuccess(t, "content", "stats", "--percentiles"), "  p50"))
+	require.True(t, containsLineStartingWith(e.RunAndExpectSuccess(t, "content", "stats", "--by-prefix"), "  k "))
 
 	// sleep a bit to ensure at least one second passes, otherwise delete may end up happen on the same
 	// second as create, in which case creation will prevail.
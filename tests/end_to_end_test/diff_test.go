@@ -72,4 +72,14 @@ func TestDiff(t *testing.T) {
 			e.RunAndExpectSuccess(t, "diff", "-f", s1.ObjectID, s2.ObjectID)
 		}
 	}
+
+	// content-only diff reports files whose contents changed.
+	require.Equal(t, []string{
+		"added ./bar (0 bytes)",
+		"modified ./some-file2 (size 41 -> 44)",
+	}, e.RunAndExpectSuccess(t, "diff", "--content-only", snapshots[1].ObjectID, snapshots[2].ObjectID))
+
+	// structured output is only available for content-only diff.
+	e.RunAndExpectFailure(t, "diff", "--json", snapshots[1].ObjectID, snapshots[2].ObjectID)
+	e.RunAndExpectSuccess(t, "diff", "--json", "--content-only", snapshots[1].ObjectID, snapshots[2].ObjectID)
 }
@@ -5,7 +5,9 @@
 	"time"
 
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -41,8 +43,26 @@ func TestFullMaintenance(t *testing.T) {
 		t.Fatalf("full maintenance is not expected to change any blobs due to safety margins (got %v, was %v)", got, originalBlobCount)
 	}
 
-	// now rerun with --safety=none
-	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none", "--disable-internal-log")
+	// --safety=none requires confirmation.
+	e.RunAndExpectFailure(t, "maintenance", "run", "--full", "--safety=none", "--disable-internal-log")
+
+	if got := len(e.RunAndExpectSuccess(t, "blob", "list")); got != originalBlobCount {
+		t.Fatalf("aborted maintenance is not expected to change blobs (got %v, was %v)", got, originalBlobCount)
+	}
+
+	// now rerun with --safety=none and confirm on stdin
+	e.RunWithStdin(t, "yes\n", "maintenance", "run", "--full", "--safety=none", "--disable-internal-log")
+
+	// --no-confirm skips the confirmation prompt.
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none", "--no-confirm", "--disable-internal-log")
+
+	var sum snapshotmaintenance.Summary
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "last", "--json"), &sum)
+
+	if !sum.Success || sum.BlobsDeleted == 0 {
+		t.Fatalf("unexpected maintenance summary: %+v", sum)
+	}
 
 	if got := len(e.RunAndExpectSuccess(t, "blob", "list")); got >= originalBlobCount {
 		t.Fatalf("maintenance did not remove blobs: %v, had %v", got, originalBlobCount)
@@ -60,3 +80,63 @@ func TestFullMaintenance(t *testing.T) {
 		t.Fatalf("maintenance left unwanted blobs: %v, want %v", got, want)
 	}
 }
+
+func TestMaintenanceEstimate(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--disable-internal-log")
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	var snap snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1, "--json", "--disable-internal-log"), &snap)
+
+	// avoid create and delete in the same second.
+	time.Sleep(2 * time.Second)
+	e.RunAndExpectSuccess(t, "snapshot", "delete", string(snap.ID), "--delete", "--disable-internal-log")
+
+	var est maintenance.ReclaimEstimate
+
+	// nothing is reclaimable until contents of the deleted snapshot are marked as deleted.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "estimate", "--safety=none", "--json", "--disable-internal-log"), &est)
+
+	if est != (maintenance.ReclaimEstimate{}) {
+		t.Fatalf("unexpected estimate before GC: %+v", est)
+	}
+
+	e.RunAndExpectSuccess(t, "snapshot", "gc", "--delete", "--safety=none", "--disable-internal-log")
+
+	originalBlobCount := len(e.RunAndExpectSuccess(t, "blob", "list"))
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "estimate", "--safety=none", "--json", "--disable-internal-log"), &est)
+
+	if est.DeletedContentCount == 0 || est.DeletedContentBytes == 0 || est.DroppablePackCount == 0 || est.DroppablePackBytes == 0 {
+		t.Fatalf("unexpected estimate after GC: %+v", est)
+	}
+
+	if got := len(e.RunAndExpectSuccess(t, "blob", "list")); got != originalBlobCount {
+		t.Fatalf("estimate is not expected to change blobs (got %v, was %v)", got, originalBlobCount)
+	}
+
+	// with default safety contents deleted just now can't be dropped yet.
+	var safeEst maintenance.ReclaimEstimate
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "estimate", "--json", "--disable-internal-log"), &safeEst)
+
+	if safeEst.ReclaimableBlobCount() != 0 {
+		t.Fatalf("unexpected estimate with default safety: %+v", safeEst)
+	}
+
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none", "--no-confirm", "--disable-internal-log")
+
+	var sum snapshotmaintenance.Summary
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "last", "--json"), &sum)
+
+	if sum.BlobsDeleted < est.ReclaimableBlobCount() {
+		t.Fatalf("maintenance deleted fewer blobs than estimated: %+v, estimate %+v", sum, est)
+	}
+}
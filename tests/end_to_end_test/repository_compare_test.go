@@ -0,0 +1,57 @@
+package endtoend_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob/filesystem"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositoryCompare(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	dir2 := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2)
+
+	// synchronized repositories have no differences.
+	require.Empty(t, e.RunAndExpectSuccess(t, "repo", "compare", "filesystem", "--path", dir2))
+	require.Empty(t, e.RunAndExpectSuccess(t, "repo", "compare", "filesystem", "--path", dir2, "--sample-percent=10"))
+
+	// make repositories diverge.
+	pblobs := listFilesystemBlobsWithPrefix(ctx, t, dir2, "p")
+	require.NotEmpty(t, pblobs)
+
+	st, err := filesystem.New(ctx, &filesystem.Options{Path: dir2})
+	require.NoError(t, err)
+
+	require.NoError(t, st.DeleteBlob(ctx, pblobs[0].BlobID))
+	require.NoError(t, st.PutBlob(ctx, "xextra", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, st.Close(ctx))
+
+	out := e.RunAndExpectFailure(t, "repo", "compare", "filesystem", "--path", dir2)
+	require.Len(t, out, 2)
+	require.True(t, strings.HasPrefix(out[0], "missing    "+string(pblobs[0].BlobID)), out[0])
+	require.True(t, strings.HasPrefix(out[1], "extra      xextra"), out[1])
+
+	// prefix filtering excludes the extra blob.
+	out = e.RunAndExpectFailure(t, "repo", "compare", "filesystem", "--path", dir2, "--blob-prefix=p")
+	require.Len(t, out, 1)
+
+	e.RunAndExpectSuccess(t, "repo", "compare", "filesystem", "--path", dir2, "--blob-prefix=q")
+}
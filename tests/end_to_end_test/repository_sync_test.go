@@ -1,9 +1,19 @@
 package endtoend_test
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/filesystem"
 	"github.com/kopia/kopia/tests/clitestutil"
 	"github.com/kopia/kopia/tests/testenv"
 )
@@ -27,6 +37,13 @@ func TestRepositorySync(t *testing.T) {
 	dir2 := testutil.TempDirectory(t)
 	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2, "--times")
 
+	// bidirectional synchronization of already synchronized repositories copies nothing to destination.
+	var summary cli.BidirectionalSyncSummary
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2, "--bidirectional", "--times", "--json"), &summary)
+	require.Zero(t, summary.ToDestination.BlobsCopied)
+	require.NotZero(t, summary.ToDestination.InSyncBlobs)
+
 	// synchronizing to empty directory fails with --must-exist
 	dir3 := testutil.TempDirectory(t)
 	e.RunAndExpectFailure(t, "repo", "sync-to", "filesystem", "--path", dir3, "--must-exist")
@@ -50,3 +67,128 @@ func TestRepositorySync(t *testing.T) {
 	// syncing to the directory should fail because it contains incompatible format blob.
 	e2.RunAndExpectFailure(t, "repo", "sync-to", "filesystem", "--path", dir2)
 }
+
+func TestRepositorySyncBidirectionalRefreshesIndexList(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	dir2 := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2, "--times")
+
+	// write new index blobs to the destination using another client.
+	e2 := testenv.NewCLITest(t, runner)
+
+	defer e2.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e2.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", dir2)
+	e2.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir2)
+
+	// populate the list cache of the source.
+	before := e.RunAndExpectSuccess(t, "index", "list")
+	want := e2.RunAndExpectSuccess(t, "index", "list")
+	require.Greater(t, len(want), len(before))
+
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2, "--bidirectional", "--times")
+
+	// index blobs copied to the source are visible immediately.
+	require.ElementsMatch(t, want, e.RunAndExpectSuccess(t, "index", "list"))
+	require.Len(t, clitestutil.ListSnapshotsAndExpectSuccess(t, e), 2)
+}
+
+func TestRepositorySyncTimeout(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	// synchronization is canceled when the global --timeout elapses.
+	dir2 := testutil.TempDirectory(t)
+
+	_, _, err := e.Run(t, true, "repo", "sync-to", "filesystem", "--path", dir2, "--timeout=1ns")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "command timed out after 1ns")
+
+	// the same synchronization succeeds without the timeout.
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2)
+}
+
+func TestRepositorySyncWithPrefix(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	// synchronize only 'p' blobs to an empty directory.
+	dir2 := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir2, "--blob-prefix=p")
+
+	for _, bm := range listFilesystemBlobs(ctx, t, dir2) {
+		if bm.BlobID != repo.FormatBlobID && !strings.HasPrefix(string(bm.BlobID), "p") {
+			t.Errorf("unexpected blob synchronized: %v", bm.BlobID)
+		}
+	}
+
+	if got, want := len(listFilesystemBlobsWithPrefix(ctx, t, dir2, "p")), len(listFilesystemBlobsWithPrefix(ctx, t, e.RepoDir, "p")); got != want {
+		t.Errorf("unexpected number of 'p' blobs synchronized: %v, want %v", got, want)
+	}
+
+	// full sync to another directory, then add extra blobs that are not in the source.
+	dir3 := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir3)
+
+	st, err := filesystem.New(ctx, &filesystem.Options{Path: dir3})
+	require.NoError(t, err)
+
+	require.NoError(t, st.PutBlob(ctx, "pextra", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, st.PutBlob(ctx, "xextra", gather.FromSlice([]byte{1, 2, 3})))
+	require.NoError(t, st.Close(ctx))
+
+	// without --delete nothing is removed.
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir3, "--blob-prefix=p")
+	require.Len(t, listFilesystemBlobsWithPrefix(ctx, t, dir3, "pextra"), 1)
+	require.Len(t, listFilesystemBlobsWithPrefix(ctx, t, dir3, "xextra"), 1)
+
+	// with --delete only blobs matching the prefix are removed.
+	e.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", dir3, "--blob-prefix=p", "--delete")
+	require.Len(t, listFilesystemBlobsWithPrefix(ctx, t, dir3, "pextra"), 0)
+	require.Len(t, listFilesystemBlobsWithPrefix(ctx, t, dir3, "xextra"), 1)
+}
+
+func listFilesystemBlobs(ctx context.Context, t *testing.T, dir string) []blob.Metadata {
+	t.Helper()
+
+	return listFilesystemBlobsWithPrefix(ctx, t, dir, "")
+}
+
+func listFilesystemBlobsWithPrefix(ctx context.Context, t *testing.T, dir string, prefix blob.ID) []blob.Metadata {
+	t.Helper()
+
+	st, err := filesystem.New(ctx, &filesystem.Options{Path: dir})
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	bms, err := blob.ListAllBlobs(ctx, st, prefix)
+	require.NoError(t, err)
+
+	return bms
+}
@@ -0,0 +1,77 @@
+package endtoend_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/snapshot/restore"
+	"github.com/kopia/kopia/tests/clitestutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRestoreRecordsSnapshotProvenance(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	source := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(source, "sub"), 0o700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(source, "sub", "file1"), []byte("some-data"), 0o600))
+
+	if err := unix.Setxattr(filepath.Join(source, "sub", "file1"), restore.ProvenanceXattrName, []byte("x"), 0); errors.Is(err, unix.ENOTSUP) {
+		t.Skip("user extended attributes are not supported in temporary directory")
+	}
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", source)
+
+	si := clitestutil.ListSnapshotsAndExpectSuccess(t, e, source)
+	require.Len(t, si, 1)
+	require.Len(t, si[0].Snapshots, 1)
+
+	snapID := si[0].Snapshots[0].SnapshotID
+	rootID := si[0].Snapshots[0].ObjectID
+
+	// root object ID is resolved to the ID of the snapshot manifest.
+	full := filepath.Join(t.TempDir(), "full")
+	e.RunAndExpectSuccess(t, "restore", "--record-provenance", rootID, full)
+	verifyProvenance(t, filepath.Join(full, "sub", "file1"), snapID)
+
+	nested := filepath.Join(t.TempDir(), "nested")
+	e.RunAndExpectSuccess(t, "restore", "--record-provenance", rootID+"/sub", nested)
+	verifyProvenance(t, filepath.Join(nested, "file1"), snapID+"/sub")
+
+	// placeholders written by shallow restore record provenance too.
+	shallow := filepath.Join(t.TempDir(), "shallow")
+	e.RunAndExpectSuccess(t, "restore", "--record-provenance", "--shallow=0", snapID, shallow)
+	verifyProvenance(t, filepath.Join(shallow, "sub"+localfs.ShallowEntrySuffix, localfs.ShallowEntrySuffix), snapID)
+
+	// expanded placeholder only knows the object it references.
+	de, err := localfs.PlaceholderFilePath(filepath.Join(shallow, "sub"+localfs.ShallowEntrySuffix)).DirEntryOrNil(testlogging.Context(t))
+	require.NoError(t, err)
+
+	e.RunAndExpectSuccess(t, "restore", "--record-provenance", "--shallow=1000", filepath.Join(shallow, "sub"+localfs.ShallowEntrySuffix))
+	verifyProvenance(t, filepath.Join(shallow, "sub", "file1"), de.ObjectID.String())
+}
+
+func verifyProvenance(t *testing.T, fname, want string) {
+	t.Helper()
+
+	buf := make([]byte, 1000)
+
+	n, err := unix.Lgetxattr(fname, restore.ProvenanceXattrName, buf)
+	require.NoError(t, err, fname)
+	require.Equal(t, want, string(buf[:n]), fname)
+}
@@ -1,6 +1,7 @@
 package endtoend_test
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -12,6 +13,7 @@
 
 	"github.com/kylelemons/godebug/pretty"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo"
@@ -675,3 +677,48 @@ func createFileStructure(baseDir string, files []testFileEntry) error {
 
 	return nil
 }
+
+func TestSnapshotCreateIncrementalFrom(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	dir := testutil.TempDirectory(t)
+	file1 := filepath.Join(dir, "file1.txt")
+	file2 := filepath.Join(dir, "file2.txt")
+
+	require.NoError(t, ioutil.WriteFile(file1, []byte("one"), 0o600))
+	require.NoError(t, ioutil.WriteFile(file2, []byte("two"), 0o600))
+
+	var man1, man2, man3, man4 snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json", "--json-verbose"), &man1)
+
+	// modify one file, making it different from the first snapshot.
+	require.NoError(t, ioutil.WriteFile(file1, []byte("one-modified"), 0o600))
+	require.NoError(t, os.Chtimes(file1, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json", "--json-verbose"), &man2)
+	require.EqualValues(t, 1, man2.Stats.NonCachedFiles)
+
+	// by default, latest snapshot is used as a base, so all files are cached.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json", "--json-verbose"), &man3)
+	require.EqualValues(t, 0, man3.Stats.NonCachedFiles)
+	require.EqualValues(t, 2, man3.Stats.CachedFiles)
+
+	// using the first snapshot as a base, the modified file is not cached.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json", "--json-verbose", "--incremental-from", string(man1.ID)), &man4)
+	require.EqualValues(t, 1, man4.Stats.NonCachedFiles)
+	require.EqualValues(t, 1, man4.Stats.CachedFiles)
+
+	// base snapshot must belong to the same source.
+	e.RunAndExpectFailure(t, "snapshot", "create", sharedTestDataDir1, "--incremental-from", string(man1.ID))
+
+	// base snapshot must exist.
+	e.RunAndExpectFailure(t, "snapshot", "create", dir, "--incremental-from", "no-such-snapshot")
+}
@@ -0,0 +1,63 @@
+package endtoend_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+type snapshotDuplicatesGroup struct {
+	RootID    string `json:"rootID"`
+	Snapshots []struct {
+		ID        string              `json:"id"`
+		Source    snapshot.SourceInfo `json:"source"`
+		StartTime time.Time           `json:"startTime"`
+	} `json:"snapshots"`
+}
+
+func TestSnapshotDuplicates(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	var man1, man2, man3 snapshot.Manifest
+
+	// two snapshots of unchanged data have the same root.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1, "--json"), &man1)
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1, "--json"), &man2)
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir2, "--json"), &man3)
+
+	require.Equal(t, man1.RootObjectID(), man2.RootObjectID())
+	require.NotEqual(t, man1.RootObjectID(), man3.RootObjectID())
+
+	var groups []snapshotDuplicatesGroup
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "duplicates", "--json"), &groups)
+	require.Len(t, groups, 2)
+
+	require.Equal(t, man1.RootObjectID().String(), groups[0].RootID)
+	require.Len(t, groups[0].Snapshots, 2)
+	require.Equal(t, string(man1.ID), groups[0].Snapshots[0].ID)
+	require.Equal(t, string(man2.ID), groups[0].Snapshots[1].ID)
+
+	require.Equal(t, man3.RootObjectID().String(), groups[1].RootID)
+	require.Len(t, groups[1].Snapshots, 1)
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "duplicates", "--only", "--json"), &groups)
+	require.Len(t, groups, 1)
+	require.Equal(t, man1.RootObjectID().String(), groups[0].RootID)
+
+	// text output lists each group followed by its snapshots.
+	e.RunAndVerifyOutputLineCount(t, 5, "snapshot", "duplicates")
+	e.RunAndVerifyOutputLineCount(t, 3, "snapshot", "duplicates", "--only")
+}
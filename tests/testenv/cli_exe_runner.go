@@ -24,6 +24,16 @@ type CLIExeRunner struct {
 func (e *CLIExeRunner) Start(t *testing.T, args []string) (stdout, stderr io.Reader, wait func() error, kill func()) {
 	t.Helper()
 
+	stdin := e.NextCommandStdin
+	e.NextCommandStdin = nil
+
+	return e.StartWithStdin(t, args, stdin)
+}
+
+// StartWithStdin implements CLIRunner.
+func (e *CLIExeRunner) StartWithStdin(t *testing.T, args []string, stdin io.Reader) (stdout, stderr io.Reader, wait func() error, kill func()) {
+	t.Helper()
+
 	c := exec.Command(e.Exe, append([]string{
 		"--log-dir", e.LogsDir,
 	}, args...)...)
@@ -40,8 +50,7 @@ func (e *CLIExeRunner) Start(t *testing.T, args []string) (stdout, stderr io.Rea
 		t.Fatalf("can't set up stderr pipe reader: %v", err)
 	}
 
-	c.Stdin = e.NextCommandStdin
-	e.NextCommandStdin = nil
+	c.Stdin = stdin
 
 	if err := c.Start(); err != nil {
 		t.Fatalf("unable to start: %v", err)
@@ -4,6 +4,7 @@
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/kopia/kopia/cli"
 	"github.com/kopia/kopia/internal/buf"
@@ -13,18 +14,29 @@
 // CLIInProcRunner is a CLIRunner that invokes provided commands in the current process.
 type CLIInProcRunner struct {
 	RepoPassword string
+
+	// NowFunc, when set, replaces the wall clock of repositories opened by the invoked commands.
+	NowFunc func() time.Time
 }
 
 // Start implements CLIRunner.
 func (e *CLIInProcRunner) Start(t *testing.T, args []string) (stdout, stderr io.Reader, wait func() error, kill func()) {
 	t.Helper()
 
+	return e.StartWithStdin(t, args, nil)
+}
+
+// StartWithStdin implements CLIRunner.
+func (e *CLIInProcRunner) StartWithStdin(t *testing.T, args []string, stdin io.Reader) (stdout, stderr io.Reader, wait func() error, kill func()) {
+	t.Helper()
+
 	ctx := testlogging.Context(t)
 
 	a := cli.NewApp()
 	a.AdvancedCommands = "enabled"
+	a.TimeNowFunc = e.NowFunc
 
-	return a.RunSubcommand(ctx, append([]string{
+	return a.RunSubcommandWithStdin(ctx, stdin, append([]string{
 		"--password", e.RepoPassword,
 	}, args...))
 }
@@ -22,10 +22,24 @@
 	TestRepoPassword = "qWQPJ2hiiLgWRRCr"
 )
 
+// Output streams reported in TimedLine.
+const (
+	StdoutStream = "stdout"
+	StderrStream = "stderr"
+)
+
+// TimedLine is a single line of output captured by RunAndCaptureInterleaved.
+type TimedLine struct {
+	Stream  string        // StdoutStream or StderrStream
+	Elapsed time.Duration // time since the command was started
+	Text    string
+}
+
 // CLIRunner encapsulates running kopia subcommands for testing purposes.
 // It supports implementations that use subprocesses or in-process invocations.
 type CLIRunner interface {
 	Start(t *testing.T, args []string) (stdout, stderr io.Reader, wait func() error, kill func())
+	StartWithStdin(t *testing.T, args []string, stdin io.Reader) (stdout, stderr io.Reader, wait func() error, kill func())
 }
 
 // CLITest encapsulates state for a CLI-based test.
@@ -141,6 +155,61 @@ func (e *CLITest) RunAndExpectFailure(t *testing.T, args ...string) []string {
 	return stdout
 }
 
+// RunAndCaptureInterleaved runs the given command, expects it to succeed and returns lines from both stdout and stderr
+// in the order in which they arrived, each tagged with the stream and the time elapsed since the start of the command.
+func (e *CLITest) RunAndCaptureInterleaved(t *testing.T, args ...string) []TimedLine {
+	t.Helper()
+
+	t.Logf("running 'kopia %v'", strings.Join(args, " "))
+
+	args = e.cmdArgs(args)
+	t0 := clock.Now()
+
+	stdoutReader, stderrReader, wait, _ := e.Runner.Start(t, args)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		result []TimedLine
+	)
+
+	capture := func(stream string, r io.Reader) {
+		defer wg.Done()
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			mu.Lock()
+			result = append(result, TimedLine{stream, clock.Since(t0), scanner.Text()})
+			mu.Unlock()
+		}
+	}
+
+	wg.Add(2) //nolint:gomnd
+
+	go capture(StdoutStream, stdoutReader)
+	go capture(StderrStream, stderrReader)
+
+	wg.Wait()
+
+	require.NoError(t, wait(), "unexpected error when running 'kopia %v'", strings.Join(args, " "))
+
+	t.Logf("finished in %v: 'kopia %v'", clock.Since(t0).Milliseconds(), strings.Join(args, " "))
+
+	return result
+}
+
+// RunWithStdin runs the given command feeding it the provided input on stdin, expects it to succeed and returns its output lines.
+func (e *CLITest) RunWithStdin(t *testing.T, input string, args ...string) []string {
+	t.Helper()
+
+	stdout, _, err := e.runWithStdin(t, false, strings.NewReader(input), args...)
+	if err != nil {
+		t.Fatalf("'kopia %v' failed with %v", strings.Join(args, " "), err)
+	}
+
+	return stdout
+}
+
 // RunAndVerifyOutputLineCount runs the given command and asserts it returns the given number of output lines, then returns them.
 func (e *CLITest) RunAndVerifyOutputLineCount(t *testing.T, wantLines int, args ...string) []string {
 	t.Helper()
@@ -169,12 +238,27 @@ func (e *CLITest) cmdArgs(args []string) []string {
 func (e *CLITest) Run(t *testing.T, expectedError bool, args ...string) (stdout, stderr []string, err error) {
 	t.Helper()
 
+	return e.runWithStdin(t, expectedError, nil, args...)
+}
+
+func (e *CLITest) runWithStdin(t *testing.T, expectedError bool, stdin io.Reader, args ...string) (stdout, stderr []string, err error) {
+	t.Helper()
+
 	t.Logf("running 'kopia %v'", strings.Join(args, " "))
 
 	args = e.cmdArgs(args)
 	t0 := clock.Now()
 
-	stdoutReader, stderrReader, wait, _ := e.Runner.Start(t, args)
+	var (
+		stdoutReader, stderrReader io.Reader
+		wait                       func() error
+	)
+
+	if stdin != nil {
+		stdoutReader, stderrReader, wait, _ = e.Runner.StartWithStdin(t, args, stdin)
+	} else {
+		stdoutReader, stderrReader, wait, _ = e.Runner.Start(t, args)
+	}
 
 	var wg sync.WaitGroup
 
@@ -0,0 +1,73 @@
+package testenv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRunAndCaptureInterleaved(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// 'cache info' prints cache directories to stdout followed by hints on stderr.
+	wantStdout := env.RunAndExpectSuccess(t, "cache", "info")
+	require.NotEmpty(t, wantStdout)
+
+	lines := env.RunAndCaptureInterleaved(t, "cache", "info")
+
+	var gotStdout, gotStderr []string
+
+	for i, l := range lines {
+		if i > 0 {
+			require.GreaterOrEqual(t, l.Elapsed, lines[i-1].Elapsed, "lines not in arrival order")
+		}
+
+		switch l.Stream {
+		case testenv.StdoutStream:
+			gotStdout = append(gotStdout, l.Text)
+		case testenv.StderrStream:
+			gotStderr = append(gotStderr, l.Text)
+		default:
+			t.Fatalf("unexpected stream %q", l.Stream)
+		}
+	}
+
+	require.Equal(t, wantStdout, gotStdout)
+	require.Contains(t, gotStderr, "To adjust cache sizes use 'kopia cache set'.")
+}
+
+func TestRunWithStdin(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunWithStdin(t, "line one\nline two\n", "snapshot", "create", "rootdir", "--stdin-file", "stream-file", "--json"), &man)
+
+	require.Equal(t, []string{"line one", "line two"}, env.RunAndExpectSuccess(t, "show", string(man.RootObjectID())+"/stream-file"))
+}
+
+func TestInProcRunnerFakeClock(t *testing.T) {
+	ft := faketime.NewTimeAdvance(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	runner := testenv.NewInProcRunner(t)
+	runner.NowFunc = ft.NowFunc()
+
+	env := testenv.NewCLITest(t, runner)
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "maintenance", "run")
+
+	// quick maintenance was just run, so the next one is scheduled exactly one quick interval from now.
+	require.Contains(t, env.RunAndExpectSuccess(t, "maintenance", "info"), "  next run: 2021-01-01 01:00:00 UTC (in 1h0m0s)")
+
+	ft.Advance(2 * time.Hour)
+
+	require.Contains(t, env.RunAndExpectSuccess(t, "maintenance", "info"), "  next run: now")
+}